@@ -0,0 +1,37 @@
+// Command apigen generates the typed Go client in pkg/client from the server's
+// OpenAPI document. With -check it fails instead if the generated file is stale.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+
+	"backend/internal/openapi"
+	"backend/internal/router"
+)
+
+
+func main() {
+	out := flag.String("out", "pkg/client/client_gen.go", "file to write")
+	check := flag.Bool("check", false, "exit non-zero if the file is not up to date")
+	flag.Parse()
+
+	src, err := openapi.GoClient(router.Spec())
+	if err != nil {
+		log.Fatalf("apigen: %v", err)
+	}
+
+	if *check {
+		current, err := os.ReadFile(*out)
+		if err != nil || !bytes.Equal(current, src) {
+			log.Fatalf("apigen: %s is out of date; run go generate ./pkg/client", *out)
+		}
+		return
+	}
+
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("apigen: %v", err)
+	}
+}
@@ -0,0 +1,1728 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/pkg/client"
+)
+
+
+// stdin is shared by the prompts, so that answers piped in line by line aren't
+// lost to the buffer of an earlier prompt.
+var stdin = bufio.NewReader(os.Stdin)
+
+
+func newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("ra "+name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+func parseIDs(args []string) ([]int64, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing ID")
+	}
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid ID %q", arg)
+		}
+		ids = append(ids, n)
+	}
+	return ids, nil
+}
+
+func optionalInt(n int64) *int64 {
+	if n < 0 {
+		return nil
+	}
+	return &n
+}
+
+func login(a *app, args []string) error {
+	flags := newFlags("login")
+	username := flags.String("u", "", "username")
+	password := flags.String("p", "", "password; read from $RA_PASSWORD or stdin when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("missing -u USER")
+	}
+
+	if *password == "" {
+		*password = os.Getenv("RA_PASSWORD")
+	}
+	if *password == "" {
+		var err error
+		if *password, err = prompt("Password: "); err != nil {
+			return err
+		}
+	}
+
+	resp, err := a.api.Login(a.ctx, client.LoginRequest{Username: *username, Password: *password})
+	if err != nil {
+		return err
+	}
+
+	a.config.Server = a.api.BaseURL
+	a.config.Token = resp.Token
+	a.config.UserID = resp.UserID
+	a.config.Username = *username
+	if err := a.config.save(); err != nil {
+		return err
+	}
+
+	a.printMessage("Logged in as %s; token valid until %s", *username, date(resp.ExpiresAt))
+	return nil
+}
+
+func logout(a *app, args []string) error {
+	if a.config.Token == "" {
+		return errors.New("not logged in")
+	}
+
+	err := a.api.Logout(a.ctx)
+	var apiErr *client.APIError
+	if err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == 401) {
+		return err
+	}
+
+	a.config.Token, a.config.UserID = "", 0
+	if err := a.config.save(); err != nil {
+		return err
+	}
+	a.printMessage("Logged out")
+	return nil
+}
+
+func whoami(a *app, args []string) error {
+	user, err := a.api.Me(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	lastLogin := ""
+	if user.LastLoginAt != nil {
+		lastLogin = date(*user.LastLoginAt)
+	}
+	return a.print(user, []string{"ID", "USERNAME", "EMAIL", "LAST LOGIN"},
+		[][]string{{id(user.ID), user.Username, user.Email, lastLogin}})
+}
+
+func upload(a *app, args []string) error {
+	flags := newFlags("upload")
+	workspace := flags.Int64("workspace", 0, "workspace to upload into")
+	title := flags.String("title", "", "title; defaults to the file name")
+	year := flags.Int64("year", 0, "publication year")
+	authors := flags.String("authors", "", "authors separated by ';'")
+	onDuplicate := flags.String("on-duplicate", "reject", "for files already in the library: reject, link or copy")
+	password := flags.String("password", "", "password of encrypted PDFs")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("missing PATH")
+	}
+
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	files, err := collectPDFs(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *title != "" && len(files) > 1 {
+		return errors.New("-title can only be used with a single file")
+	}
+
+	var uploaded []*client.Document
+	var rows [][]string
+	failed := 0
+	for _, path := range files {
+		doc, err := a.uploadFile(path, client.UploadDocumentForm{
+			UserID:			userID,
+			WorkspaceID:	*workspace,
+			Title:			*title,
+			Year:			*year,
+			Authors:		*authors,
+			OnDuplicate:	*onDuplicate,
+			Password:		*password,
+		})
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "ra: %s: %v\n", path, err)
+			continue
+		}
+		uploaded = append(uploaded, doc)
+		rows = append(rows, []string{id(doc.ID), truncate(doc.Title, 60), path})
+	}
+
+	if err := a.print(uploaded, []string{"ID", "TITLE", "FILE"}, rows); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d uploads failed", failed, len(files))
+	}
+	return nil
+}
+
+func importFiles(a *app, args []string) error {
+	flags := newFlags("import")
+	workspace := flags.Int64("workspace", 0, "workspace to import into")
+	dir := flags.Bool("dir", false, "PATH is a directory on the server, relative to its import root")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: ra import [-dir] [-workspace ID] PATH")
+	}
+
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	var report *client.ImportReport
+	if *dir {
+		report, err = a.api.ImportDirectory(a.ctx, client.ImportDirectoryRequest{UserID: userID, WorkspaceID: *workspace, Path: flags.Arg(0)})
+	} else {
+		var f *os.File
+		if f, err = os.Open(flags.Arg(0)); err != nil {
+			return err
+		}
+		defer f.Close()
+		form := client.ImportArchiveForm{UserID: userID, WorkspaceID: *workspace}
+		report, err = a.api.ImportArchive(a.ctx, form, filepath.Base(flags.Arg(0)), f)
+	}
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Files))
+	for _, f := range report.Files {
+		docID := ""
+		if f.DocumentID != 0 {
+			docID = id(f.DocumentID)
+		}
+		rows = append(rows, []string{f.File, f.Status, docID, f.Reason})
+	}
+	if err := a.print(report, []string{"FILE", "STATUS", "DOCUMENT", "REASON"}, rows); err != nil {
+		return err
+	}
+	if a.output == "table" {
+		fmt.Printf("\n%d imported, %d skipped, %d failed\n", report.Imported, report.Skipped, report.Failed)
+	}
+	return nil
+}
+
+func fetchDocuments(a *app, args []string) error {
+	flags := newFlags("fetch")
+	workspace := flags.Int64("workspace", 0, "workspace to import into")
+	title := flags.String("title", "", "title; defaults to the publisher's or the file name")
+	onDuplicate := flags.String("on-duplicate", "reject", "for files already in the library: reject, link or copy")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("missing URL, DOI or arXiv ID")
+	}
+	if *title != "" && flags.NArg() > 1 {
+		return errors.New("-title can only be used with a single source")
+	}
+
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	var fetched []*client.Document
+	var rows [][]string
+	failed := 0
+	for _, source := range flags.Args() {
+		doc, err := a.api.ImportURL(a.ctx, client.ImportURLRequest{
+			UserID:			userID,
+			WorkspaceID:	*workspace,
+			Source:			source,
+			Title:			*title,
+			OnDuplicate:	*onDuplicate,
+		})
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "ra: %s: %v\n", source, err)
+			continue
+		}
+		fetched = append(fetched, doc)
+		rows = append(rows, []string{id(doc.ID), truncate(doc.Title, 60), source})
+	}
+
+	if err := a.print(fetched, []string{"ID", "TITLE", "SOURCE"}, rows); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d imports failed", failed, flags.NArg())
+	}
+	return nil
+}
+
+// clipPage saves a web page as a document. The page is read from -file, or "-"
+// for stdin, as saved from a browser where it may be behind a login, or else
+// downloaded from URL.
+func clipPage(a *app, args []string) error {
+	flags := newFlags("clip")
+	file := flags.String("file", "", "HTML of the page as saved from a browser, or - for stdin")
+	workspace := flags.Int64("workspace", 0, "workspace to save into")
+	title := flags.String("title", "", "title; defaults to the page's")
+	onDuplicate := flags.String("on-duplicate", "reject", "for pages already in the library: reject, link or copy")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: ra clip [flags] URL")
+	}
+	pageURL := flags.Arg(0)
+
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	var page []byte
+	switch *file {
+	case "":
+		page, err = download(a.ctx, pageURL)
+	case "-":
+		page, err = io.ReadAll(os.Stdin)
+	default:
+		page, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.api.ClipPage(a.ctx, client.ClipRequest{
+		UserID:			userID,
+		WorkspaceID:	*workspace,
+		URL:			pageURL,
+		Html:			string(page),
+		Title:			*title,
+		OnDuplicate:	*onDuplicate,
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(doc, []string{"ID", "TITLE", "SOURCE"}, [][]string{{id(doc.ID), truncate(doc.Title, 60), pageURL}})
+}
+
+func download(ctx context.Context, pageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", pageURL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (a *app) uploadFile(path string, form client.UploadDocumentForm) (*client.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if form.Title == "" {
+		form.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return a.api.UploadDocument(a.ctx, form, filepath.Base(path), f)
+}
+
+// collectPDFs expands directories into the PDF files below them.
+func collectPDFs(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".pdf") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func listDocuments(a *app, args []string) error {
+	flags := newFlags("docs list")
+	tag := flags.Int64("tag", -1, "tag ID, including its descendants")
+	workspace := flags.Int64("workspace", -1, "workspace ID; 0 for documents in no workspace")
+	year := flags.Int64("year", -1, "publication year")
+	author := flags.String("author", "", "author name")
+	status := flags.String("status", "", "reading status")
+	extraction := flags.String("extraction", "", "extraction outcome: ok, encrypted, no_text or corrupt")
+	from := flags.String("from", "", "uploaded on or after (YYYY-MM-DD)")
+	to := flags.String("to", "", "uploaded on or before (YYYY-MM-DD)")
+	sort := flags.String("sort", "", "sort key: title, uploaded_at or year; prefix '-' for descending")
+	limit := flags.Int64("limit", 50, "page size")
+	all := flags.Bool("all", false, "follow cursors and list every page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	params := client.ListDocumentsParams{
+		UserID:			&userID,
+		Tag:			optionalInt(*tag),
+		WorkspaceID:	optionalInt(*workspace),
+		Year:			optionalInt(*year),
+		Author:			*author,
+		Status:			*status,
+		Extraction:		*extraction,
+		UploadedFrom:	*from,
+		UploadedTo:		*to,
+		Sort:			*sort,
+		Limit:			limit,
+	}
+
+	var docs []client.Document
+	for {
+		page, err := a.api.ListDocuments(a.ctx, params)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, page.Items...)
+		if !*all || page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+
+	rows := make([][]string, 0, len(docs))
+	for _, d := range docs {
+		year := ""
+		if d.Year != 0 {
+			year = id(d.Year)
+		}
+		rows = append(rows, []string{id(d.ID), truncate(d.Title, 60), year, d.ReadingStatus, id(d.WorkspaceID), date(d.UploadedAt)})
+	}
+	return a.print(docs, []string{"ID", "TITLE", "YEAR", "STATUS", "WORKSPACE", "UPLOADED"}, rows)
+}
+
+func getDocument(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.api.GetDocument(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	var authors, tags []string
+	for _, au := range doc.Authors {
+		authors = append(authors, au.Name)
+	}
+	for _, t := range doc.Tags {
+		tags = append(tags, t.Path)
+	}
+	return a.print(doc, []string{"FIELD", "VALUE"}, [][]string{
+		{"ID", id(doc.ID)},
+		{"Title", doc.Title},
+		{"Authors", strings.Join(authors, "; ")},
+		{"Year", id(doc.Year)},
+		{"Status", doc.ReadingStatus},
+		{"Workspace", id(doc.WorkspaceID)},
+		{"Tags", strings.Join(tags, ", ")},
+		{"Uploaded", date(doc.UploadedAt)},
+		{"Extraction", doc.ExtractionStatus},
+		{"OCR", doc.OcrStatus},
+		{"DOI", doc.Doi},
+		{"arXiv", doc.ArxivID},
+		{"Venue", doc.Venue},
+		{"Citations", citationCount(doc.CitationCount)},
+		{"Open access", doc.OpenAccessURL},
+		{"Enrichment", doc.EnrichmentStatus},
+		{"Abstract", truncate(doc.Abstract, 80)},
+		{"Text", truncate(doc.ExtractedText, 80)},
+	})
+}
+
+func deleteDocuments(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	for _, docID := range ids {
+		if err := a.api.DeleteDocument(a.ctx, docID); err != nil {
+			return fmt.Errorf("document %d: %w", docID, err)
+		}
+	}
+	a.printMessage("Deleted %d documents", len(ids))
+	return nil
+}
+
+func setReadingStatus(a *app, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: ra docs status STATUS ID...")
+	}
+	ids, err := parseIDs(args[1:])
+	if err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.api.UpdateReadingStatus(a.ctx, client.ReadingStatusRequest{UserID: userID, DocumentIDs: ids, Status: args[0]})
+	if err != nil {
+		return err
+	}
+	a.printMessage("Updated %d documents", resp["updated"])
+	return nil
+}
+
+func listDuplicates(a *app, args []string) error {
+	flags := newFlags("docs duplicates")
+	threshold := flags.Float64("threshold", 0, "minimum similarity from 0.5 to 1 (server default 0.8)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	params := client.ListDuplicateDocumentsParams{UserID: &userID}
+	if *threshold != 0 {
+		params.Threshold = threshold
+	}
+	groups, err := a.api.ListDuplicateDocuments(a.ctx, params)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for i, g := range groups {
+		for _, d := range g.Documents {
+			rows = append(rows, []string{id(int64(i + 1)), fmt.Sprintf("%.2f", g.Similarity), id(d.ID), truncate(d.Title, 60), date(d.UploadedAt)})
+		}
+	}
+	return a.print(groups, []string{"GROUP", "SIMILARITY", "ID", "TITLE", "UPLOADED"}, rows)
+}
+
+func mergeDocuments(a *app, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: ra docs merge KEEP DUPLICATE...")
+	}
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.api.MergeDocuments(a.ctx, ids[0], client.MergeRequest{UserID: userID, DuplicateIDs: ids[1:]})
+	if err != nil {
+		return err
+	}
+	a.printMessage("Merged %d documents into %d (%s)", len(ids)-1, doc.ID, doc.Title)
+	return nil
+}
+
+func replaceFile(a *app, args []string) error {
+	flags := newFlags("docs replace")
+	password := flags.String("password", "", "password of an encrypted PDF")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: ra docs replace [-password P] ID PATH")
+	}
+	ids, err := parseIDs(flags.Args()[:1])
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	form := client.ReplaceDocumentFileForm{Password: *password}
+	doc, err := a.api.ReplaceDocumentFile(a.ctx, ids[0], form, filepath.Base(flags.Arg(1)), f)
+	if err != nil {
+		return err
+	}
+	a.printMessage("Document %d is now at version %d (text: %s)", doc.ID, doc.Version, doc.ExtractionStatus)
+	return nil
+}
+
+func extractText(a *app, args []string) error {
+	flags := newFlags("docs extract")
+	password := flags.String("password", "", "password of an encrypted PDF")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.api.ExtractDocument(a.ctx, ids[0], client.ExtractRequest{Password: *password})
+	if err != nil {
+		return err
+	}
+	if doc.ExtractionError != "" {
+		a.printMessage("Document %d: %s (%s)", doc.ID, doc.ExtractionStatus, doc.ExtractionError)
+		return nil
+	}
+	a.printMessage("Document %d: %s", doc.ID, doc.ExtractionStatus)
+	return nil
+}
+
+func listVersions(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	limit := int64(200)
+	params := client.ListDocumentVersionsParams{Limit: &limit}
+	var versions []client.DocumentVersion
+	for {
+		page, err := a.api.ListDocumentVersions(a.ctx, ids[0], params)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+
+	var rows [][]string
+	for _, v := range versions {
+		rows = append(rows, []string{id(v.Version), date(v.ReplacedAt), v.ContentHash})
+	}
+	return a.print(versions, []string{"VERSION", "REPLACED", "HASH"}, rows)
+}
+
+func diffVersions(a *app, args []string) error {
+	flags := newFlags("docs diff")
+	from := flags.Int64("from", -1, "earlier version (default: the one before -to)")
+	to := flags.Int64("to", -1, "later version (default: the current version)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	diff, err := a.api.DiffDocumentVersions(a.ctx, ids[0], client.DiffDocumentVersionsParams{From: optionalInt(*from), To: optionalInt(*to)})
+	if err != nil {
+		return err
+	}
+	if a.output == "json" {
+		return a.print(diff, nil, nil)
+	}
+
+	fmt.Printf("--- version %d\n+++ version %d\n", diff.From, diff.To)
+	for _, h := range diff.Hunks {
+		fmt.Printf("@@ -%d,%d +%d,%d @@\n", h.FromLine, h.FromCount, h.ToLine, h.ToCount)
+		for _, l := range h.Lines {
+			fmt.Println(l.Op + l.Text)
+		}
+	}
+	return nil
+}
+
+func listPages(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	pages, err := a.api.ListDocumentPages(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, p := range pages {
+		confidence := ""
+		if p.Confidence != nil {
+			confidence = fmt.Sprintf("%.1f", *p.Confidence)
+		}
+		rows = append(rows, []string{id(p.Page), p.Source, confidence, truncate(strings.Join(strings.Fields(p.Text), " "), 60)})
+	}
+	return a.print(pages, []string{"PAGE", "SOURCE", "CONFIDENCE", "TEXT"}, rows)
+}
+
+func showOutline(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	sections, err := a.api.GetDocumentOutline(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, sec := range sections {
+		heading := strings.Repeat("  ", int(max(sec.Level-1, 0))) + sec.Heading
+		rows = append(rows, []string{id(sec.Position), sec.Kind, id(sec.Page), truncate(heading, 60)})
+	}
+	return a.print(sections, []string{"#", "KIND", "PAGE", "HEADING"}, rows)
+}
+
+func showSections(a *app, args []string) error {
+	flags := newFlags("docs sections")
+	kind := flags.String("kind", "", "only sections of this kind, e.g. methods")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	sections, err := a.api.ListDocumentSections(a.ctx, ids[0], client.ListDocumentSectionsParams{Kind: *kind})
+	if err != nil {
+		return err
+	}
+	if a.output == "json" {
+		return a.print(sections, nil, nil)
+	}
+	for _, sec := range sections {
+		fmt.Printf("== %s [%s, page %d]\n%s\n\n", sec.Heading, sec.Kind, sec.Page, sec.Text)
+	}
+	return nil
+}
+
+func listReferences(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	refs, err := a.api.ListDocumentReferences(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, ref := range refs {
+		year, cited, title := "", "", ref.Title
+		if ref.Year != 0 {
+			year = id(ref.Year)
+		}
+		if ref.CitedDocumentID != nil {
+			cited = id(*ref.CitedDocumentID)
+		}
+		if title == "" {
+			title = ref.Raw
+		}
+		rows = append(rows, []string{id(ref.Position + 1), truncate(title, 60), year, cited})
+	}
+	return a.print(refs, []string{"#", "TITLE", "YEAR", "DOCUMENT"}, rows)
+}
+
+func listCited(a *app, args []string) error {
+	return listCitations(a, args, a.api.ListCitedDocuments)
+}
+
+func listCiting(a *app, args []string) error {
+	return listCitations(a, args, a.api.ListCitingDocuments)
+}
+
+func listCitations(a *app, args []string, fetch func(context.Context, int64) ([]client.Document, error)) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	docs, err := fetch(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(docs))
+	for _, d := range docs {
+		year := ""
+		if d.Year != 0 {
+			year = id(d.Year)
+		}
+		rows = append(rows, []string{id(d.ID), truncate(d.Title, 60), year, id(d.WorkspaceID)})
+	}
+	return a.print(docs, []string{"ID", "TITLE", "YEAR", "WORKSPACE"}, rows)
+}
+
+func recognizeDocument(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.api.RecognizeDocument(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	a.printMessage("Document %d queued for OCR", doc.ID)
+	return nil
+}
+
+func enrichDocument(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.api.EnrichDocument(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	a.printMessage("Document %d queued for a metadata lookup", doc.ID)
+	return nil
+}
+
+func citationCount(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return id(*n)
+}
+
+func listWorkspaces(a *app, args []string) error {
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	limit := int64(200)
+	params := client.ListWorkspacesParams{UserID: &userID, Limit: &limit}
+	var workspaces []client.Workspace
+	for {
+		page, err := a.api.ListWorkspaces(a.ctx, params)
+		if err != nil {
+			return err
+		}
+		workspaces = append(workspaces, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+
+	rows := make([][]string, 0, len(workspaces))
+	for _, ws := range workspaces {
+		rows = append(rows, []string{id(ws.ID), ws.Title, date(ws.CreatedAt)})
+	}
+	return a.print(workspaces, []string{"ID", "TITLE", "CREATED"}, rows)
+}
+
+// showCitations lists the documents of a workspace by how often the others cite
+// them, so the foundational papers come first.
+func showCitations(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	graph, err := a.api.GetCitationGraph(a.ctx, ids[0], client.GetCitationGraphParams{})
+	if err != nil {
+		return err
+	}
+	if a.output == "json" {
+		return a.print(graph, nil, nil)
+	}
+
+	nodes := graph.Nodes
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].CitedBy > nodes[j].CitedBy })
+	rows := make([][]string, 0, len(nodes))
+	for _, n := range nodes {
+		rows = append(rows, []string{id(n.ID), truncate(n.Title, 60), id(n.CitedBy), id(n.Cites)})
+	}
+	return a.print(graph, []string{"ID", "TITLE", "CITED BY", "CITES"}, rows)
+}
+
+func createWorkspace(a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing TITLE")
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	ws, err := a.api.CreateWorkspace(a.ctx, client.CreateWorkspaceRequest{UserID: userID, Title: strings.Join(args, " ")})
+	if err != nil {
+		return err
+	}
+	return a.print(ws, []string{"ID", "TITLE", "CREATED"}, [][]string{{id(ws.ID), ws.Title, date(ws.CreatedAt)}})
+}
+
+func updateWorkspace(a *app, args []string) error {
+	flags := newFlags("ws set")
+	title := flags.String("title", "", "new title")
+	skipOCR := flags.Bool("skip-ocr", false, "leave scanned documents without OCR")
+	lang := flags.String("ocr-lang", "", "Tesseract language, e.g. deu+eng; \"default\" for the server's")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var req client.UpdateWorkspaceRequest
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			req.Title = title
+		case "skip-ocr":
+			req.SkipOcr = skipOCR
+		case "ocr-lang":
+			if *lang == "default" {
+				*lang = ""
+			}
+			req.OcrLanguage = lang
+		}
+	})
+
+	ws, err := a.api.UpdateWorkspace(a.ctx, ids[0], req)
+	if err != nil {
+		return err
+	}
+	return a.print(ws, []string{"ID", "TITLE", "SKIP OCR", "OCR LANGUAGE"}, [][]string{{id(ws.ID), ws.Title, fmt.Sprint(ws.SkipOcr), ws.OcrLanguage}})
+}
+
+func deleteWorkspace(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	if err := a.api.DeleteWorkspace(a.ctx, ids[0]); err != nil {
+		return err
+	}
+	a.printMessage("Deleted workspace %d", ids[0])
+	return nil
+}
+
+func addToWorkspace(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil || len(ids) < 2 {
+		return errors.New("usage: ra ws add WORKSPACE DOCUMENT...")
+	}
+	for _, docID := range ids[1:] {
+		if _, err := a.api.AddDocumentToWorkspace(a.ctx, ids[0], docID); err != nil {
+			return fmt.Errorf("document %d: %w", docID, err)
+		}
+	}
+	a.printMessage("Added %d documents to workspace %d", len(ids)-1, ids[0])
+	return nil
+}
+
+func removeFromWorkspace(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil || len(ids) < 2 {
+		return errors.New("usage: ra ws remove WORKSPACE DOCUMENT...")
+	}
+	for _, docID := range ids[1:] {
+		if _, err := a.api.RemoveDocumentFromWorkspace(a.ctx, ids[0], docID); err != nil {
+			return fmt.Errorf("document %d: %w", docID, err)
+		}
+	}
+	a.printMessage("Removed %d documents from workspace %d", len(ids)-1, ids[0])
+	return nil
+}
+
+func listFeeds(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	page, err := a.api.ListWorkspaceFeeds(a.ctx, ids[0], client.ListWorkspaceFeedsParams{})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(page.Items))
+	for _, f := range page.Items {
+		polled := ""
+		if f.LastPolledAt != nil {
+			polled = date(*f.LastPolledAt)
+		}
+		rows = append(rows, []string{id(f.ID), truncate(f.Title, 40), f.Kind, f.Mode, fmt.Sprint(f.Enabled), polled, truncate(f.LastError, 40)})
+	}
+	return a.print(page.Items, []string{"ID", "TITLE", "KIND", "MODE", "ENABLED", "POLLED", "ERROR"}, rows)
+}
+
+// addFeed subscribes a workspace to a feed URL, or with -arxiv to an arXiv query.
+func addFeed(a *app, args []string) error {
+	flags := newFlags("feeds add")
+	arXiv := flags.Bool("arxiv", false, "treat the argument as an arXiv search query, e.g. \"cat:cs.LG AND abs:diffusion\"")
+	title := flags.String("title", "", "title; defaults to the feed's host or query")
+	mode := flags.String("mode", "inbox", "what to do with new entries: inbox or import")
+	keywords := flags.String("keywords", "", "comma-separated keywords that entries must mention")
+	interval := flags.Int64("interval", 60, "minutes between polls")
+	backfill := flags.Bool("backfill", false, "also take the entries already in the feed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return errors.New("usage: ra feeds add [flags] WORKSPACE URL|QUERY")
+	}
+	ids, err := parseIDs(flags.Args()[:1])
+	if err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	req := client.CreateFeedRequest{
+		UserID:				userID,
+		WorkspaceID:		ids[0],
+		Kind:				"rss",
+		Title:				*title,
+		Mode:				*mode,
+		Keywords:			*keywords,
+		IntervalMinutes:	*interval,
+		Backfill:			*backfill,
+	}
+	if *arXiv {
+		req.Kind, req.Query = "arxiv", strings.Join(flags.Args()[1:], " ")
+	} else {
+		req.URL = flags.Arg(1)
+	}
+
+	f, err := a.api.CreateFeed(a.ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.print(f, []string{"ID", "TITLE", "KIND", "MODE"}, [][]string{{id(f.ID), f.Title, f.Kind, f.Mode}})
+}
+
+func updateFeed(a *app, args []string) error {
+	flags := newFlags("feeds set")
+	title := flags.String("title", "", "new title")
+	mode := flags.String("mode", "", "what to do with new entries: inbox or import")
+	keywords := flags.String("keywords", "", "comma-separated keywords that entries must mention; empty takes all")
+	interval := flags.Int64("interval", 0, "minutes between polls")
+	enabled := flags.Bool("enabled", true, "poll the feed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var req client.UpdateFeedRequest
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			req.Title = title
+		case "mode":
+			req.Mode = mode
+		case "keywords":
+			req.Keywords = keywords
+		case "interval":
+			req.IntervalMinutes = interval
+		case "enabled":
+			req.Enabled = enabled
+		}
+	})
+
+	f, err := a.api.UpdateFeed(a.ctx, ids[0], req)
+	if err != nil {
+		return err
+	}
+	return a.print(f, []string{"ID", "TITLE", "MODE", "INTERVAL", "ENABLED"}, [][]string{{id(f.ID), f.Title, f.Mode, id(f.IntervalMinutes), fmt.Sprint(f.Enabled)}})
+}
+
+func deleteFeed(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	if err := a.api.DeleteFeed(a.ctx, ids[0]); err != nil {
+		return err
+	}
+	a.printMessage("Deleted feed %d", ids[0])
+	return nil
+}
+
+func pollFeed(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.api.PollFeed(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	if res.NotModified {
+		a.printMessage("Feed %d has not changed", ids[0])
+		return nil
+	}
+	return a.print(res, []string{"ENTRIES", "NEW", "IMPORTED", "INBOX", "DUPLICATES", "SKIPPED", "FAILED"}, [][]string{{
+		id(res.Entries), id(res.New), id(res.Imported), id(res.Inbox), id(res.Duplicates), id(res.Skipped), id(res.Failed),
+	}})
+}
+
+func listFeedItems(a *app, args []string) error {
+	flags := newFlags("feeds items")
+	status := flags.String("status", "", "only entries with this status: inbox, imported, duplicate, dismissed, skipped or failed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	page, err := a.api.ListFeedItems(a.ctx, ids[0], client.ListFeedItemsParams{Status: *status})
+	if err != nil {
+		return err
+	}
+	return a.printItems(page.Items)
+}
+
+func listInbox(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	page, err := a.api.ListWorkspaceInbox(a.ctx, ids[0], client.ListWorkspaceInboxParams{})
+	if err != nil {
+		return err
+	}
+	return a.printItems(page.Items)
+}
+
+func (a *app) printItems(items []client.FeedItem) error {
+	rows := make([][]string, 0, len(items))
+	for _, it := range items {
+		published := ""
+		if it.PublishedAt != nil {
+			published = date(*it.PublishedAt)
+		}
+		doc := ""
+		if it.DocumentID != nil {
+			doc = id(*it.DocumentID)
+		}
+		rows = append(rows, []string{id(it.ID), truncate(it.Title, 60), published, it.Status, doc})
+	}
+	return a.print(items, []string{"ID", "TITLE", "PUBLISHED", "STATUS", "DOCUMENT"}, rows)
+}
+
+func acceptItems(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	items := make([]client.FeedItem, 0, len(ids))
+	for _, itemID := range ids {
+		it, err := a.api.AcceptFeedItem(a.ctx, itemID)
+		if err != nil {
+			return fmt.Errorf("feed item %d: %w", itemID, err)
+		}
+		items = append(items, *it)
+	}
+	return a.printItems(items)
+}
+
+func dismissItems(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	for _, itemID := range ids {
+		if _, err := a.api.DismissFeedItem(a.ctx, itemID); err != nil {
+			return fmt.Errorf("feed item %d: %w", itemID, err)
+		}
+	}
+	a.printMessage("Dismissed %d entries", len(ids))
+	return nil
+}
+
+func listNotes(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	page, err := a.api.ListWorkspaceNotes(a.ctx, ids[0], client.ListWorkspaceNotesParams{})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(page.Items))
+	for _, n := range page.Items {
+		rows = append(rows, []string{id(n.ID), truncate(n.Title, 60), date(n.UpdatedAt)})
+	}
+	return a.print(page.Items, []string{"ID", "TITLE", "UPDATED"}, rows)
+}
+
+func listTags(a *app, args []string) error {
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	limit := int64(200)
+	page, err := a.api.ListTags(a.ctx, client.ListTagsParams{UserID: &userID, Limit: &limit})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(page.Items))
+	for _, t := range page.Items {
+		rows = append(rows, []string{id(t.ID), t.Path})
+	}
+	return a.print(page.Items, []string{"ID", "PATH"}, rows)
+}
+
+func search(a *app, args []string) error {
+	flags := newFlags("search")
+	workspace := flags.Int64("workspace", 0, "only search this workspace")
+	section := flags.String("section", "", "only search sections of this kind, e.g. methods")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("missing QUERY")
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	params := client.SearchParams{UserID: &userID, Q: strings.Join(flags.Args(), " "), Section: *section}
+	if *workspace != 0 {
+		params.WorkspaceID = workspace
+	}
+	resp, err := a.api.Search(a.ctx, params)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, d := range resp.Documents {
+		rows = append(rows, []string{"document", id(d.ID), truncate(d.Title, 60)})
+	}
+	for _, n := range resp.Notes {
+		rows = append(rows, []string{"note", id(n.ID), truncate(n.Title, 60)})
+	}
+	for _, sec := range resp.Sections {
+		rows = append(rows, []string{"section", fmt.Sprintf("%d#%d", sec.DocumentID, sec.Position), truncate(sec.Kind+": "+sec.Heading, 60)})
+	}
+	return a.print(resp, []string{"TYPE", "ID", "TITLE"}, rows)
+}
+
+func listSavedSearches(a *app, args []string) error {
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	limit := int64(200)
+	params := client.ListSavedSearchesParams{UserID: &userID, Limit: &limit}
+	var list []client.SavedSearch
+	for {
+		page, err := a.api.ListSavedSearches(a.ctx, params)
+		if err != nil {
+			return err
+		}
+		list = append(list, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+
+	rows := make([][]string, 0, len(list))
+	for _, s := range list {
+		scope := "library"
+		if s.WorkspaceID != 0 {
+			scope = "workspace " + id(s.WorkspaceID)
+		}
+		matched := ""
+		if s.LastMatchedAt != nil {
+			matched = date(*s.LastMatchedAt)
+		}
+		rows = append(rows, []string{id(s.ID), truncate(s.Name, 30), s.Mode, truncate(s.Query, 40), scope, matched})
+	}
+	return a.print(list, []string{"ID", "NAME", "MODE", "QUERY", "SCOPE", "MATCHED"}, rows)
+}
+
+// saveSearch saves a search whose new matches are listed by "ra notifications".
+func saveSearch(a *app, args []string) error {
+	flags := newFlags("searches save")
+	name := flags.String("name", "", "name; defaults to the query")
+	workspace := flags.Int64("workspace", 0, "only alert on documents added to this workspace")
+	semantic := flags.Bool("semantic", false, "match documents on the same topic rather than containing the query")
+	minScore := flags.Float64("min-score", 0, "how alike a semantic match must be, from 0 to 1")
+	year := flags.Int64("year", 0, "only documents from this year")
+	author := flags.String("author", "", "only documents by this author")
+	format := flags.String("format", "", "only documents in this format: pdf or html")
+	email := flags.Bool("email", false, "also send matches by email")
+	webhook := flags.String("webhook", "", "also post matches to this URL")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	req := client.SaveSearchRequest{
+		UserID:			userID,
+		WorkspaceID:	*workspace,
+		Name:			*name,
+		Mode:			"keyword",
+		Query:			strings.Join(flags.Args(), " "),
+		MinScore:		*minScore,
+		Year:			*year,
+		Author:			*author,
+		Format:			*format,
+		NotifyEmail:	*email,
+		WebhookURL:		*webhook,
+	}
+	if *semantic {
+		req.Mode = "semantic"
+	}
+
+	s, err := a.api.SaveSearch(a.ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.print(s, []string{"ID", "NAME", "MODE"}, [][]string{{id(s.ID), s.Name, s.Mode}})
+}
+
+func deleteSavedSearch(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	if err := a.api.DeleteSavedSearch(a.ctx, ids[0]); err != nil {
+		return err
+	}
+	a.printMessage("Deleted saved search %d", ids[0])
+	return nil
+}
+
+func listNotifications(a *app, args []string) error {
+	flags := newFlags("notifications list")
+	unread := flags.Bool("unread", false, "only unread notifications")
+	kind := flags.String("kind", "", "only notifications of this kind: search.match, document.ready or feed.entries")
+	search := flags.Int64("search", 0, "only the matches of this saved search")
+	limit := flags.Int64("limit", 50, "number of notifications")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	params := client.ListNotificationsParams{UserID: &userID, Unread: *unread, Kind: *kind, Limit: limit}
+	if *search != 0 {
+		params.SavedSearchID = search
+	}
+	page, err := a.api.ListNotifications(a.ctx, params)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(page.Items))
+	for _, n := range page.Items {
+		doc := ""
+		if n.DocumentID != nil {
+			doc = id(*n.DocumentID)
+		}
+		status := "unread"
+		if n.ReadAt != nil {
+			status = "read"
+		}
+		rows = append(rows, []string{id(n.ID), date(n.CreatedAt), n.Kind, status, truncate(n.Title, 60), doc})
+	}
+	return a.print(page.Items, []string{"ID", "DATE", "KIND", "STATUS", "TITLE", "DOCUMENT"}, rows)
+}
+
+// readNotifications marks the given notifications read, or all of them.
+func readNotifications(a *app, args []string) error {
+	var ids []int64
+	if len(args) > 0 {
+		var err error
+		if ids, err = parseIDs(args); err != nil {
+			return err
+		}
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	res, err := a.api.ReadNotifications(a.ctx, client.MarkReadRequest{UserID: userID, IDs: ids})
+	if err != nil {
+		return err
+	}
+	a.printMessage("Marked %d notifications read", res["updated"])
+	return nil
+}
+
+// notificationSettings shows the user's notification settings, or changes those
+// given as flags.
+func notificationSettings(a *app, args []string) error {
+	flags := newFlags("notifications settings")
+	email := flags.String("email", "", "email notifications: off, instant, hourly or daily")
+	webhook := flags.String("webhook", "", "URL to post every notification to; \"none\" removes it")
+	mute := flags.String("mute", "", "comma-separated kinds not to be notified of; \"none\" unmutes all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	s, err := a.api.GetNotificationSettings(a.ctx, client.GetNotificationSettingsParams{UserID: &userID})
+	if err != nil {
+		return err
+	}
+	if flags.NFlag() > 0 {
+		req := client.NotificationSettingsRequest{UserID: userID, Email: s.Email, WebhookURL: s.WebhookURL}
+		if s.Muted != "" {
+			req.Muted = strings.Split(s.Muted, ",")
+		}
+		if *email != "" {
+			req.Email = *email
+		}
+		switch *webhook {
+		case "":
+		case "none":
+			req.WebhookURL = ""
+		default:
+			req.WebhookURL = *webhook
+		}
+		switch *mute {
+		case "":
+		case "none":
+			req.Muted = nil
+		default:
+			req.Muted = strings.Split(*mute, ",")
+		}
+		if s, err = a.api.UpdateNotificationSettings(a.ctx, req); err != nil {
+			return err
+		}
+	}
+	return a.print(s, []string{"EMAIL", "WEBHOOK", "MUTED"}, [][]string{{s.Email, s.WebhookURL, s.Muted}})
+}
+
+func listWebhooks(a *app, args []string) error {
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	limit := int64(200)
+	params := client.ListWebhooksParams{UserID: &userID, Limit: &limit}
+	var list []client.Webhook
+	for {
+		page, err := a.api.ListWebhooks(a.ctx, params)
+		if err != nil {
+			return err
+		}
+		list = append(list, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+
+	rows := make([][]string, 0, len(list))
+	for _, h := range list {
+		rows = append(rows, webhookRow(h))
+	}
+	return a.print(list, []string{"ID", "URL", "EVENTS", "SCOPE", "ENABLED"}, rows)
+}
+
+// addWebhook subscribes a URL to events and prints the secret that signs them,
+// which is not shown again.
+func addWebhook(a *app, args []string) error {
+	flags := newFlags("webhooks add")
+	events := flags.String("events", "", "comma-separated events to send; empty sends all")
+	workspace := flags.Int64("workspace", 0, "only send events of this workspace")
+	secret := flags.String("secret", "", "signing secret; one is generated if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected one URL")
+	}
+	userID, err := a.userID()
+	if err != nil {
+		return err
+	}
+
+	req := client.CreateWebhookRequest{UserID: userID, WorkspaceID: *workspace, URL: flags.Arg(0), Secret: *secret}
+	if *events != "" {
+		req.Events = strings.Split(*events, ",")
+	}
+	h, err := a.api.CreateWebhook(a.ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.print(h, []string{"ID", "URL", "EVENTS", "SCOPE", "ENABLED", "SECRET"}, [][]string{append(webhookRow(*h), h.Secret)})
+}
+
+func updateWebhook(a *app, args []string) error {
+	flags := newFlags("webhooks set")
+	url := flags.String("url", "", "new URL")
+	events := flags.String("events", "", "comma-separated events to send")
+	enabled := flags.Bool("enabled", true, "send events")
+	rotate := flags.Bool("rotate-secret", false, "replace the signing secret and print the new one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	req := client.UpdateWebhookRequest{RotateSecret: *rotate}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			req.URL = url
+		case "events":
+			req.Events = strings.Split(*events, ",")
+		case "enabled":
+			req.Enabled = enabled
+		}
+	})
+
+	h, err := a.api.UpdateWebhook(a.ctx, ids[0], req)
+	if err != nil {
+		return err
+	}
+	return a.print(h, []string{"ID", "URL", "EVENTS", "SCOPE", "ENABLED", "SECRET"}, [][]string{append(webhookRow(*h), h.Secret)})
+}
+
+func deleteWebhook(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	if err := a.api.DeleteWebhook(a.ctx, ids[0]); err != nil {
+		return err
+	}
+	a.printMessage("Deleted webhook %d", ids[0])
+	return nil
+}
+
+func listWebhookDeliveries(a *app, args []string) error {
+	flags := newFlags("webhooks deliveries")
+	status := flags.String("status", "", "only deliveries with this status: pending, delivered or failed")
+	limit := flags.Int64("limit", 50, "number of deliveries")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	page, err := a.api.ListWebhookDeliveries(a.ctx, ids[0], client.ListWebhookDeliveriesParams{Status: *status, Limit: limit})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(page.Items))
+	for _, d := range page.Items {
+		response := d.Error
+		if d.ResponseStatus != 0 {
+			response = id(d.ResponseStatus)
+		}
+		rows = append(rows, []string{id(d.ID), date(d.CreatedAt), d.Event, d.Status, id(d.Attempts), truncate(response, 40)})
+	}
+	return a.print(page.Items, []string{"ID", "DATE", "EVENT", "STATUS", "ATTEMPTS", "RESPONSE"}, rows)
+}
+
+func redeliverWebhook(a *app, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	d, err := a.api.RedeliverWebhookDelivery(a.ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	a.printMessage("Delivery %d queued as delivery %d", ids[0], d.ID)
+	return nil
+}
+
+func webhookRow(h client.Webhook) []string {
+	events := h.Events
+	if events == "" {
+		events = "all"
+	}
+	scope := "library"
+	if h.WorkspaceID != 0 {
+		scope = "workspace " + id(h.WorkspaceID)
+	}
+	return []string{id(h.ID), truncate(h.URL, 50), events, scope, fmt.Sprint(h.Enabled)}
+}
+
+// changePassword changes the password of the logged-in user. Every other login is
+// signed out; this one keeps working with the new token.
+func changePassword(a *app, args []string) error {
+	flags := newFlags("password change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if a.config.Token == "" {
+		return errors.New("not logged in")
+	}
+
+	current, err := prompt("Current password: ")
+	if err != nil {
+		return err
+	}
+	next, err := newPassword()
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.api.ChangePassword(a.ctx, client.ChangePasswordRequest{CurrentPassword: current, NewPassword: next})
+	if err != nil {
+		return err
+	}
+
+	a.config.Token = resp.Token
+	if err := a.config.save(); err != nil {
+		return err
+	}
+	a.printMessage("Password changed; other sessions have been logged out")
+	return nil
+}
+
+func forgotPassword(a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected one email address")
+	}
+
+	resp, err := a.api.ForgotPassword(a.ctx, client.ForgotPasswordRequest{Email: args[0]})
+	if err != nil {
+		return err
+	}
+	a.printMessage("%s", resp.Message)
+	return nil
+}
+
+// resetPassword sets a new password with the token of a reset email.
+func resetPassword(a *app, args []string) error {
+	flags := newFlags("password reset")
+	token := flags.String("token", "", "token from the reset email")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *token == "" {
+		return errors.New("missing -token TOKEN")
+	}
+
+	next, err := newPassword()
+	if err != nil {
+		return err
+	}
+	resp, err := a.api.ResetPassword(a.ctx, client.ResetPasswordRequest{Token: *token, NewPassword: next})
+	if err != nil {
+		return err
+	}
+	a.printMessage("%s", resp.Message)
+	return nil
+}
+
+// newPassword asks for a new password twice.
+func newPassword() (string, error) {
+	password, err := prompt("New password: ")
+	if err != nil {
+		return "", err
+	}
+	again, err := prompt("Repeat new password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != again {
+		return "", errors.New("the passwords don't match")
+	}
+	return password, nil
+}
+
+// prompt reads a line from stdin after printing label to stderr.
+func prompt(label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
\ No newline at end of file
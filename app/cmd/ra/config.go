@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+
+// config is what "ra login" remembers between runs. The token is a credential, so
+// the file is only readable by its owner.
+type config struct {
+	Server		string		`json:"server"`
+	Token		string		`json:"token"`
+	UserID		int64		`json:"user_id"`
+	Username	string		`json:"username"`
+}
+
+
+func configPath() (string, error) {
+	if p := os.Getenv("RA_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ra", "config.json"), nil
+}
+
+func loadConfig() (*config, error) {
+	cfg := &config{}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg, json.Unmarshal(b, cfg)
+}
+
+func (c *config) save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
\ No newline at end of file
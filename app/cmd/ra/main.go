@@ -0,0 +1,256 @@
+// Command ra scripts the research library from the terminal through the /api/v2 API.
+//
+//	ra [-server URL] [-o table|json] <command> [arguments]
+//
+// Run "ra help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"backend/pkg/client"
+)
+
+
+const usage = `usage: ra [-server URL] [-o table|json] <command> [arguments]
+
+Commands:
+  login -u USER [-p PASSWORD]     log in and store the API token
+  logout                          revoke the stored token
+  whoami                          show the logged-in user
+  password change                 change the password, logging out other sessions
+  password forgot EMAIL           mail a password reset link to EMAIL
+  password reset -token TOKEN     set a new password with the token of a reset email
+  upload [flags] PATH...          upload PDFs; directories are walked recursively
+  import [flags] ARCHIVE          import a .zip, .tar or .tar.gz of PDFs, skipping duplicates
+  import -dir [flags] PATH        import a directory under the server's import root
+  fetch [flags] SOURCE...         import PDFs by URL, DOI or arXiv ID
+  clip [-file PATH] [flags] URL   save the article of a web page, read from PATH or downloaded
+  docs list [flags]               list and filter documents
+  docs get ID                     show one document
+  docs delete ID...               delete documents
+  docs status STATUS ID...        set the reading status (unread, reading, read)
+  docs replace [flags] ID PATH    replace the file of a document, keeping the old version
+  docs extract [-password P] ID   extract the text again, e.g. to unlock an encrypted PDF
+  docs versions ID                list the earlier versions of a document
+  docs diff [flags] ID            show text changes between versions
+  docs pages ID                   show the text of each page and its OCR confidence
+  docs ocr ID                     run OCR on the scanned pages of a document
+  docs enrich ID                  look up the metadata of a document by its DOI or arXiv ID
+  docs outline ID                 show the sections of a document
+  docs sections [-kind K] ID      print the text of a document's sections
+  docs refs ID                    list the references of a document and the ones in the library
+  docs cites ID                   list the documents in the library that a document cites
+  docs cited-by ID                list the documents in the library that cite a document
+  docs duplicates [-threshold N]  group documents whose text is nearly the same
+  docs merge KEEP DUPLICATE...    merge duplicates into the document KEEP
+  ws list                         list workspaces
+  ws create TITLE                 create a workspace
+  ws set [flags] ID               rename a workspace or change its OCR settings
+  ws delete ID                    delete a workspace
+  ws add WORKSPACE DOCUMENT...    move documents into a workspace
+  ws remove WORKSPACE DOCUMENT... take documents out of a workspace
+  ws citations ID                 rank the documents of a workspace by citations from the others
+  feeds list WORKSPACE            list the feeds a workspace subscribes to
+  feeds add [flags] WS URL        subscribe a workspace to an RSS or Atom feed
+  feeds add -arxiv [flags] WS Q   subscribe a workspace to an arXiv search query
+  feeds set [flags] ID            rename a feed or change its settings
+  feeds delete ID                 unsubscribe, keeping the imported documents
+  feeds poll ID                   poll a feed now
+  feeds items [-status S] ID      list the entries seen in a feed
+  inbox list WORKSPACE            list the feed entries waiting for triage
+  inbox accept ITEM...            import entries from the inbox
+  inbox dismiss ITEM...           dismiss entries from the inbox
+  notes list WORKSPACE            list the notes in a workspace
+  tags list                       list tags
+  search [flags] QUERY            search documents and notes, or one kind of section
+  searches list                   list saved searches
+  searches save [flags] [QUERY]   save a search to be notified of new matching documents
+  searches delete ID              delete a saved search
+  notifications list [flags]      list notifications, such as new matches of saved searches
+  notifications read [ID...]      mark notifications read, or all of them
+  notifications settings [flags]  show or change how notifications are emailed and posted
+  webhooks list                   list webhooks
+  webhooks add [flags] URL        send library events to URL, signed with a secret
+  webhooks set [flags] ID         change a webhook or rotate its secret
+  webhooks delete ID              delete a webhook
+  webhooks deliveries [flags] ID  list the deliveries of a webhook and their responses
+  webhooks redeliver DELIVERY     send the event of a delivery again
+
+Run "ra <command> -h" for the flags of a command. The server defaults to the one
+used at login, then $RA_SERVER, then http://localhost:8080.
+`
+
+// app is the state shared by every command.
+type app struct {
+	ctx			context.Context
+	api			*client.Client
+	config		*config
+	output		string
+}
+
+type command func(a *app, args []string) error
+
+
+var commands = map[string]command{
+	"login":	login,
+	"logout":	logout,
+	"whoami":	whoami,
+	"password":	subcommands(map[string]command{
+		"change":		changePassword,
+		"forgot":		forgotPassword,
+		"reset":		resetPassword,
+	}),
+	"upload":	upload,
+	"import":	importFiles,
+	"fetch":	fetchDocuments,
+	"clip":		clipPage,
+	"docs":		subcommands(map[string]command{
+		"list":			listDocuments,
+		"get":			getDocument,
+		"delete":		deleteDocuments,
+		"status":		setReadingStatus,
+		"replace":		replaceFile,
+		"extract":		extractText,
+		"versions":		listVersions,
+		"diff":			diffVersions,
+		"pages":		listPages,
+		"outline":		showOutline,
+		"sections":		showSections,
+		"ocr":			recognizeDocument,
+		"enrich":		enrichDocument,
+		"refs":			listReferences,
+		"cites":		listCited,
+		"cited-by":		listCiting,
+		"duplicates":	listDuplicates,
+		"merge":		mergeDocuments,
+	}),
+	"ws":		subcommands(map[string]command{
+		"list":			listWorkspaces,
+		"create":		createWorkspace,
+		"set":			updateWorkspace,
+		"delete":		deleteWorkspace,
+		"add":			addToWorkspace,
+		"remove":		removeFromWorkspace,
+		"citations":	showCitations,
+	}),
+	"feeds":	subcommands(map[string]command{
+		"list":			listFeeds,
+		"add":			addFeed,
+		"set":			updateFeed,
+		"delete":		deleteFeed,
+		"poll":			pollFeed,
+		"items":		listFeedItems,
+	}),
+	"inbox":	subcommands(map[string]command{
+		"list":			listInbox,
+		"accept":		acceptItems,
+		"dismiss":		dismissItems,
+	}),
+	"notes":	subcommands(map[string]command{"list": listNotes}),
+	"tags":		subcommands(map[string]command{"list": listTags}),
+	"search":	search,
+	"searches":	subcommands(map[string]command{
+		"list":			listSavedSearches,
+		"save":			saveSearch,
+		"delete":		deleteSavedSearch,
+	}),
+	"notifications":	subcommands(map[string]command{
+		"list":			listNotifications,
+		"read":			readNotifications,
+		"settings":		notificationSettings,
+	}),
+	"webhooks":	subcommands(map[string]command{
+		"list":			listWebhooks,
+		"add":			addWebhook,
+		"set":			updateWebhook,
+		"delete":		deleteWebhook,
+		"deliveries":	listWebhookDeliveries,
+		"redeliver":	redeliverWebhook,
+	}),
+}
+
+func main() {
+	flags := flag.NewFlagSet("ra", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := flags.String("server", "", "API base URL")
+	output := flags.String("o", "table", "output format: table or json")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	if flags.NArg() == 0 || flags.Arg(0) == "help" {
+		fmt.Print(usage)
+		return
+	}
+	if *output != "table" && *output != "json" {
+		fatal(fmt.Errorf("unknown output format %q", *output))
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fatal(fmt.Errorf("unknown command %q; run \"ra help\"", flags.Arg(0)))
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal(err)
+	}
+	base := cfg.Server
+	if *server != "" {
+		base = *server
+	} else if env := os.Getenv("RA_SERVER"); env != "" && base == "" {
+		base = env
+	}
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+
+	api := client.New(base)
+	api.Token = cfg.Token
+	a := &app{ctx: context.Background(), api: api, config: cfg, output: *output}
+
+	if err := cmd(a, flags.Args()[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+func subcommands(cmds map[string]command) command {
+	return func(a *app, args []string) error {
+		if len(args) == 0 {
+			return errors.New("missing subcommand; run \"ra help\"")
+		}
+		cmd, ok := cmds[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown subcommand %q; run \"ra help\"", args[0])
+		}
+		return cmd(a, args[1:])
+	}
+}
+
+// userID returns the logged-in user. Most endpoints still take the owner explicitly.
+func (a *app) userID() (int64, error) {
+	if a.config.Token == "" || a.config.UserID == 0 {
+		return 0, errors.New("not logged in; run \"ra login\"")
+	}
+	return a.config.UserID, nil
+}
+
+func fatal(err error) {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		msg := "ra: " + apiErr.Message
+		if len(apiErr.Details) > 0 && string(apiErr.Details) != "null" {
+			msg += " " + strings.TrimSpace(string(apiErr.Details))
+		}
+		fmt.Fprintln(os.Stderr, msg)
+	} else {
+		fmt.Fprintln(os.Stderr, "ra:", err)
+	}
+	os.Exit(1)
+}
\ No newline at end of file
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+
+// print writes v as indented JSON, or as a table built from the header and rows.
+func (a *app) print(v any, header []string, rows [][]string) error {
+	if a.output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printMessage reports the outcome of a command that returns nothing to list.
+func (a *app) printMessage(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if a.output == "json" {
+		json.NewEncoder(os.Stdout).Encode(map[string]string{"message": msg})
+		return
+	}
+	fmt.Println(msg)
+}
+
+func id(n int64) string {
+	return fmt.Sprint(n)
+}
+
+func date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// truncate shortens s to n runes for table cells.
+func truncate(s string, n int) string {
+	r := []rune(strings.Join(strings.Fields(s), " "))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n-1]) + "…"
+}
\ No newline at end of file
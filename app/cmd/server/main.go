@@ -1,15 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 	"net/http"
 
+	"backend/internal/alerts"
 	"backend/internal/config"
+	"backend/internal/feeds"
+	"backend/internal/fetch"
 	"backend/internal/handler"
+	"backend/internal/ingest"
+	"backend/internal/mail"
 	"backend/internal/model"
 	"backend/internal/middleware"
+	"backend/internal/notify"
+	"backend/internal/ocr"
 	"backend/internal/repository"
+	"backend/internal/router"
+	"backend/internal/scholar"
+	"backend/internal/storage"
+	"backend/internal/validate"
+	"backend/internal/webhooks"
 
 	"github.com/joho/godotenv"
 )
@@ -23,40 +39,149 @@ func main() {
 		log.Println(".env file loaded successfully")
 	}
 
+	config.LoadConfig()
+
 	log.Println("Connecting to database...")
 	config.ConnectDatabase()
 	log.Println("Database connection established")
 
 	log.Println("Running database migrations...")
-	if err := config.DB.AutoMigrate(&model.User{}, &model.Document{}, &model.Workspace{}); err != nil {
+	if err := config.DB.AutoMigrate(&model.User{}, &model.Document{}, &model.Workspace{},
+		&model.Note{}, &model.NoteRevision{}, &model.NoteLink{},
+		&model.Tag{}, &model.DocumentTag{}, &model.DocumentAuthor{}, &model.Session{}, &model.Blob{},
+		&model.DocumentVersion{}, &model.Upload{}, &model.DocumentPage{}, &model.DocumentSection{}, &model.DocumentReference{},
+		&model.Feed{}, &model.FeedItem{}, &model.SavedSearch{}, &model.Notification{}, &model.NotificationSettings{},
+		&model.Webhook{}, &model.WebhookDelivery{}, &model.PasswordReset{}); err != nil {
 		log.Fatalf("AutoMigrate failed: %v", err)
 	}
 	log.Println("Database migrations completed")
 
 	log.Println("Initializing repositiories and handlers...")
 	userRepo := repository.NewUserRepository(config.DB)
-	authHandler := handler.NewAuthHandler(userRepo)
+	sessionRepo := repository.NewSessionRepository(config.DB)
+	passwordResetRepo := repository.NewPasswordResetRepository(config.DB)
+	authHandler := handler.NewAuthHandler(userRepo, sessionRepo, passwordResetRepo)
 	workspaceRepo := repository.NewWorkspaceRepository(config.DB)
 	workspaceHandler := handler.NewWorkspaceHandler(workspaceRepo)
 	documentRepo := repository.NewDocumentRepository(config.DB)
-	documentHandler := handler.NewDocumentHandler(documentRepo)
+	blobRepo := repository.NewBlobRepository(config.DB)
+	store := storage.NewStore(config.StorageRoot, blobRepo)
+	importer := ingest.NewImporter(documentRepo, store, ingest.Limits{MaxFileSize: config.MaxUploadSize, UserQuota: config.UserQuota})
+	guard, err := fetch.ParseGuard(config.FetchAllow)
+	if err != nil {
+		log.Fatalf("Invalid FETCH_ALLOW: %v", err)
+	}
+	var mailer *mail.Mailer
+	if config.SMTPAddr != "" {
+		if mailer, err = mail.NewMailer(config.SMTPAddr, config.SMTPFrom, config.SMTPUsername, config.SMTPPassword); err != nil {
+			log.Fatalf("Invalid SMTP settings: %v", err)
+		}
+		authHandler.Mailer = mailer
+	}
+	authHandler.ResetURL = config.PasswordResetURL
+	if config.BreachedPasswords != "" {
+		n, err := validate.LoadBreached(config.BreachedPasswords)
+		if err != nil {
+			log.Fatalf("Invalid BREACHED_PASSWORDS: %v", err)
+		}
+		log.Printf("Loaded %d breached passwords\n", n)
+	}
+	notificationRepo := repository.NewNotificationRepository(config.DB)
+	notifier := notify.NewNotifier(notificationRepo, userRepo, mailer)
+	if mailer != nil {
+		go notifier.RunDigests(context.Background())
+	}
+	webhookRepo := repository.NewWebhookRepository(config.DB)
+	dispatcher := webhooks.NewDispatcher(webhookRepo, fetch.NewClient(guard, 30*time.Second))
+	if config.WebhooksEnabled {
+		importer.Events = dispatcher
+		workspaceHandler.Webhooks = dispatcher
+		notifier.Webhooks = dispatcher
+		go dispatcher.Run(context.Background())
+	}
+	savedSearchRepo := repository.NewSavedSearchRepository(config.DB)
+	if config.AlertsEnabled {
+		alertWorker := alerts.NewWorker(savedSearchRepo, documentRepo, notifier)
+		importer.Alerts = alertWorker
+		go alertWorker.Run(context.Background())
+	}
+	var scholarResolvers []scholar.Resolver
+	if config.EnrichEnabled {
+		scholarResolvers = resolvers()
+		enricher := scholar.NewEnricher(scholarResolvers, documentRepo)
+		enricher.Alerts = importer.Alerts
+		importer.Enrich = enricher
+		go enricher.Run(context.Background())
+	}
+	if config.OCREnabled {
+		if !ocr.ValidLanguage(config.OCRLanguage) {
+			log.Fatalf("Invalid OCR_LANG %q", config.OCRLanguage)
+		}
+		if engine, err := ocr.NewEngine(config.PdftoppmPath, config.TesseractPath); err != nil {
+			log.Printf("OCR disabled: %v\n", err)
+		} else {
+			ocrWorker := ocr.NewWorker(engine, documentRepo, workspaceRepo, config.OCRLanguage)
+			ocrWorker.Enrich = importer.Enrich
+			ocrWorker.Alerts = importer.Alerts
+			ocrWorker.Events = importer.Events
+			importer.OCR = ocrWorker
+			go ocrWorker.Run(context.Background())
+		}
+	}
+	documentHandler := handler.NewDocumentHandler(documentRepo, workspaceRepo, importer, config.ImportRoot)
+	fetcher := &fetch.Fetcher{
+		Client:			fetch.NewClient(guard, 2*time.Minute),
+		Resolvers:		scholarResolvers,
+		MaxPageSize:	5 << 20,
+		DOIURL:			config.DOIURL,
+		ArXivURL:		config.ArXivSiteURL,
+	}
+	documentHandler.Fetcher = fetcher
+	uploadRepo := repository.NewUploadRepository(config.DB)
+	uploadHandler := handler.NewUploadHandler(uploadRepo, workspaceRepo, importer, store)
+	noteRepo := repository.NewNoteRepository(config.DB)
+	noteHandler := handler.NewNoteHandler(noteRepo, workspaceRepo, documentRepo)
+	searchHandler := handler.NewSearchHandler(documentRepo, noteRepo)
+	tagRepo := repository.NewTagRepository(config.DB)
+	tagHandler := handler.NewTagHandler(tagRepo)
+	feedRepo := repository.NewFeedRepository(config.DB)
+	poller := feeds.NewPoller(feedRepo, documentRepo, importer, fetcher, config.ArXivURL)
+	poller.Notifier = notifier
+	if config.FeedsEnabled {
+		go poller.Run(context.Background())
+	}
+	feedHandler := handler.NewFeedHandler(feedRepo, workspaceRepo, poller)
+	savedSearchHandler := handler.NewSavedSearchHandler(savedSearchRepo, workspaceRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationRepo)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo, workspaceRepo, dispatcher)
 
 	log.Println("Registering routes...")
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", handler.HealthCheck)
-	mux.HandleFunc("/register", authHandler.Register)
-	mux.HandleFunc("/login", authHandler.Login)
-	mux.HandleFunc("/documents/get", documentHandler.GetDocuments)
-	mux.HandleFunc("/documents/upload", documentHandler.UploadDocuments)
-	mux.HandleFunc("/documents/view", documentHandler.ViewDocument)
-	mux.HandleFunc("/workspace/create", workspaceHandler.CreateWorkspace)
-	mux.HandleFunc("/workspace/get", workspaceHandler.GetUserWorkspaces)
-	mux.HandleFunc("/workspace/delete", workspaceHandler.DeleteWorkspace)
-	mux.HandleFunc("/workspace/add-document", workspaceHandler.AddDocumentToWorkspace)
-	mux.HandleFunc("/workspace/remove-document", workspaceHandler.RemoveDocumentFromWorkspace)
+	mux := router.New(router.Handlers{
+		Auth:		authHandler,
+		Documents:	documentHandler,
+		Workspaces:	workspaceHandler,
+		Notes:		noteHandler,
+		Tags:		tagHandler,
+		Search:		searchHandler,
+		Uploads:	uploadHandler,
+		Feeds:		feedHandler,
+		SavedSearches:	savedSearchHandler,
+		Notifications:	notificationHandler,
+		Webhooks:		webhookHandler,
+	})
 
 	log.Println("Applying CORS middleware...")
-	handleWithCors := middleware.EnableCORS(mux)
+	resolveToken := func(token string) (uint, error) {
+		session, err := sessionRepo.GetByToken(token)
+		if errors.Is(err, repository.ErrUnauthorized) {
+			return 0, middleware.ErrUnauthenticated
+		}
+		if err != nil {
+			return 0, err
+		}
+		return session.UserID, nil
+	}
+	handleWithCors := middleware.EnableCORS(middleware.RequestID(middleware.Authenticate(resolveToken, mux)))
 
 	addr := ":8080"
 	log.Printf("Server starting at %s...\n", addr)
@@ -66,3 +191,30 @@ func main() {
 	}
 	log.Printf("Server stopped after %s\n", time.Since(start).String())
 }
+
+// resolvers builds the metadata resolvers named in ENRICH_RESOLVERS, which share
+// one response cache.
+func resolvers() []scholar.Resolver {
+	userAgent := "ResearchAssistant/1.0"
+	if config.ContactEmail != "" {
+		userAgent += fmt.Sprintf(" (mailto:%s)", config.ContactEmail)
+	}
+	client := scholar.NewClient(scholar.NewCache(24*time.Hour, 10000), userAgent)
+
+	var list []scholar.Resolver
+	for _, name := range config.EnrichResolvers {
+		switch strings.TrimSpace(name) {
+		case "crossref":
+			list = append(list, scholar.NewCrossref(client, config.CrossrefURL))
+		case "arxiv":
+			list = append(list, scholar.NewArXiv(client, config.ArXivURL))
+		case "semanticscholar":
+			list = append(list, scholar.NewSemanticScholar(client, config.SemanticScholarURL, config.SemanticScholarKey))
+		case "":
+		default:
+			log.Fatalf("Unknown resolver %q in ENRICH_RESOLVERS", name)
+		}
+	}
+	log.Printf("Metadata resolvers: %s\n", strings.Join(config.EnrichResolvers, ", "))
+	return list
+}
@@ -0,0 +1,138 @@
+package alerts
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"backend/internal/model"
+)
+
+
+// defaultMinScore is the similarity a semantic search asks of a document unless
+// it sets its own.
+const defaultMinScore = 0.2
+
+// maxTopicText bounds the text of a document that stands in for its abstract.
+const maxTopicText = 3000
+
+var stopWords = map[string]bool{}
+
+
+func init() {
+	for _, w := range strings.Fields(`about above after again against all also among and any are because been
+		before being below between both but can could did does doing down during each few for from further had has
+		have having here how into its itself more most not now off once only other our out over own same should
+		some such than that the their them then there these they this those through too under until upon using very
+		was were what when where which while who whom why will with within without would you your we our paper
+		study show shows shown propose proposed approach method methods results based new two one`) {
+		stopWords[w] = true
+	}
+}
+
+// Match reports whether a new document matches the saved search. topic is the
+// text that semantic searches compare the query with: the document's title and
+// abstract, or the beginning of its text when it has no abstract.
+func Match(s *model.SavedSearch, doc *model.Document, topic string) bool {
+	if s.Year != 0 && doc.Year != s.Year {
+		return false
+	}
+	if s.Format != "" && doc.Format != s.Format {
+		return false
+	}
+	if s.Author != "" && !hasAuthor(doc, s.Author) {
+		return false
+	}
+	if s.Query == "" {
+		return true
+	}
+
+	if s.Mode == model.SearchModeSemantic {
+		min := s.MinScore
+		if min == 0 {
+			min = defaultMinScore
+		}
+		return Similarity(s.Query, topic) >= min
+	}
+	q := strings.ToLower(s.Query)
+	return strings.Contains(strings.ToLower(doc.Title), q) || strings.Contains(strings.ToLower(doc.ExtractedText), q)
+}
+
+func hasAuthor(doc *model.Document, name string) bool {
+	name = strings.ToLower(name)
+	for _, a := range doc.Authors {
+		if strings.Contains(strings.ToLower(a.Name), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Similarity is the cosine of the term vectors of two texts, from 0 to 1. The
+// terms are their words, without stop words and reduced to a common stem, so
+// that "transformers" and "transformer" count alike. There is no language model
+// on the server; texts on the same topic are alike because they share its terms.
+func Similarity(a, b string) float64 {
+	va, vb := terms(a), terms(b)
+	if len(va) == 0 || len(vb) == 0 {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for t, wa := range va {
+		dot += wa * vb[t]
+		na += wa * wa
+	}
+	for _, wb := range vb {
+		nb += wb * wb
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// terms weighs the stems of a text by the logarithm of their counts, so that a
+// long text repeating one word doesn't outweigh the rest.
+func terms(text string) map[string]float64 {
+	counts := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if len([]rune(w)) < 3 || stopWords[w] {
+			continue
+		}
+		counts[stem(w)]++
+	}
+
+	v := make(map[string]float64, len(counts))
+	for t, n := range counts {
+		v[t] = 1 + math.Log(float64(n))
+	}
+	return v
+}
+
+// stem reduces plurals to the singular with Harman's S-stemmer, which is enough
+// for the nouns that make up most topics and leaves other words alone.
+func stem(w string) string {
+	switch {
+	case strings.HasSuffix(w, "ies") && !strings.HasSuffix(w, "eies") && !strings.HasSuffix(w, "aies"):
+		return strings.TrimSuffix(w, "ies") + "y"
+	case strings.HasSuffix(w, "es") && !strings.HasSuffix(w, "aes") && !strings.HasSuffix(w, "ees") && !strings.HasSuffix(w, "oes"):
+		return strings.TrimSuffix(w, "s")
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "ss"):
+		return strings.TrimSuffix(w, "s")
+	}
+	return w
+}
+
+// topicText is what a semantic search compares with: the title and abstract of
+// the document, or the beginning of its text.
+func topicText(doc *model.Document, abstract string) string {
+	if abstract == "" {
+		abstract = doc.Abstract
+	}
+	if abstract == "" {
+		r := []rune(doc.ExtractedText)
+		abstract = string(r[:min(len(r), maxTopicText)])
+	}
+	return doc.Title + "\n" + abstract
+}
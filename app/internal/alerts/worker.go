@@ -0,0 +1,193 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/model"
+	"backend/internal/notify"
+	"backend/internal/repository"
+)
+
+
+// Worker runs the saved searches on new documents in the background, once their
+// ingestion is over, and tells the owner that a document is ready when OCR or the
+// metadata lookup kept it waiting. Like the OCR worker it keeps its queue in the
+// database, as the documents' AlertsPending flag, and the channel only saves
+// polling.
+type Worker struct {
+	Searches		repository.SavedSearchRepository
+	Docs			repository.DocumentRepository
+	Notifier		*notify.Notifier
+
+	queue			chan uint
+	overflow		atomic.Bool
+}
+
+const queueSize = 256
+
+
+func NewWorker(searches repository.SavedSearchRepository, docs repository.DocumentRepository, notifier *notify.Notifier) *Worker {
+	log.Println("Initializing saved search alerts...")
+	return &Worker{Searches: searches, Docs: docs, Notifier: notifier, queue: make(chan uint, queueSize)}
+}
+
+// Enqueue schedules the saved searches to run on a document. Documents that
+// aren't pending or are still waiting for OCR or enrichment are skipped when they
+// come up, so callers may enqueue whenever a stage of ingestion ends. It never
+// blocks.
+func (w *Worker) Enqueue(docID uint) {
+	select {
+	case w.queue <- docID:
+	default:
+		w.overflow.Store(true)
+		log.Printf("Alert queue is full; document ID=%d will be picked up later\n", docID)
+	}
+}
+
+// Run processes documents until ctx is cancelled, starting with those left
+// pending by a previous run.
+func (w *Worker) Run(ctx context.Context) {
+	log.Println("Saved search alerts started")
+	w.overflow.Store(true)
+
+	for {
+		if len(w.queue) == 0 && w.overflow.Swap(false) {
+			w.resume(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Saved search alerts stopped")
+			return
+		case id := <-w.queue:
+			w.process(ctx, id)
+		}
+	}
+}
+
+func (w *Worker) resume(ctx context.Context) {
+	ids, err := w.Docs.PendingAlerts()
+	if err != nil {
+		log.Printf("Failed to list documents pending alerts: %v\n", err)
+		return
+	}
+	if len(ids) > 0 {
+		log.Printf("Resuming alerts for %d pending documents\n", len(ids))
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		w.process(ctx, id)
+	}
+}
+
+// process runs the searches covering a document and notifies the owner of each
+// one it matches. The document stops being pending even if a notification fails,
+// so that a broken mail server doesn't repeat the others.
+func (w *Worker) process(ctx context.Context, id uint) {
+	doc, err := w.Docs.GetByDocumentID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Alerts for document ID=%d: Failed to fetch document: %v\n", id, err)
+		return
+	}
+	if !doc.AlertsPending || doc.OCRStatus == model.OCRPending || doc.EnrichmentStatus == model.EnrichmentPending {
+		return
+	}
+
+	searches, err := w.Searches.ForDocument(doc.UserID, doc.WorkspaceID)
+	if err != nil {
+		log.Printf("Alerts for document ID=%d: Failed to fetch saved searches: %v\n", id, err)
+		return
+	}
+
+	abstract := ""
+	for _, s := range searches {
+		if s.Mode == model.SearchModeSemantic && s.Query != "" {
+			sections, err := w.Docs.GetSections(id, model.SectionAbstract)
+			if err == nil && len(sections) > 0 {
+				abstract = sections[0].Text
+			}
+			break
+		}
+	}
+	topic := topicText(&doc, abstract)
+
+	matched := 0
+	for _, s := range searches {
+		if !Match(&s, &doc, topic) {
+			continue
+		}
+		matched++
+		if err := w.notify(ctx, &s, &doc); err != nil {
+			log.Printf("Alerts for document ID=%d: Failed to notify saved search ID=%d: %v\n", id, s.ID, err)
+		}
+	}
+
+	if doc.OCRStatus != "" || doc.EnrichmentStatus != "" {
+		if err := w.notifyReady(ctx, &doc); err != nil {
+			log.Printf("Alerts for document ID=%d: Failed to notify that it is ready: %v\n", id, err)
+		}
+	}
+
+	if err := w.Docs.SetAlertsPending(id, false); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Alerts for document ID=%d: Failed to clear pending flag: %v\n", id, err)
+		return
+	}
+	if matched > 0 {
+		log.Printf("Document ID=%d matched %d of %d saved searches\n", id, matched, len(searches))
+	}
+}
+
+func (w *Worker) notify(ctx context.Context, s *model.SavedSearch, doc *model.Document) error {
+	docID, searchID := doc.ID, s.ID
+	note := &model.Notification{
+		UserID:			s.UserID,
+		Kind:			model.NotificationSearchMatch,
+		Title:			truncate(fmt.Sprintf("New match for %q: %s", s.Name, doc.Title), 255),
+		Body:			fmt.Sprintf("The document %q (ID %d) was added to your library and matches your saved search %q.\n", doc.Title, doc.ID, s.Name),
+		DocumentID:		&docID,
+		SavedSearchID:	&searchID,
+	}
+	if err := w.Notifier.Notify(ctx, note, notify.Delivery{Email: s.NotifyEmail, WebhookURL: s.WebhookURL}); err != nil {
+		return err
+	}
+	return w.Searches.SetLastMatched(s.ID, time.Now())
+}
+
+// notifyReady tells the owner how the background stages of ingestion went.
+func (w *Worker) notifyReady(ctx context.Context, doc *model.Document) error {
+	var stages []string
+	if doc.OCRStatus != "" {
+		stages = append(stages, "OCR: "+doc.OCRStatus)
+	}
+	if doc.EnrichmentStatus != "" {
+		stages = append(stages, "metadata lookup: "+doc.EnrichmentStatus)
+	}
+	docID := doc.ID
+	note := &model.Notification{
+		UserID:			doc.UserID,
+		Kind:			model.NotificationDocumentReady,
+		Title:			truncate("Document ready: "+doc.Title, 255),
+		Body:			fmt.Sprintf("Processing of %q (ID %d) is over. %s.\n", doc.Title, doc.ID, strings.Join(stages, ", ")),
+		DocumentID:		&docID,
+	}
+	return w.Notifier.Notify(ctx, note, notify.Delivery{})
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,354 @@
+package clip
+
+import (
+	"html"
+	"strings"
+)
+
+
+// node is an element or a run of text in the tree parsed from a page. Text nodes
+// have no tag.
+type node struct {
+	tag			string
+	attrs		map[string]string
+	text		string
+	parent		*node
+	children	[]*node
+}
+
+var voidElements = set("area", "base", "br", "col", "embed", "hr", "img", "input", "keygen", "link", "meta",
+	"param", "source", "track", "wbr")
+
+// rawTextElements hold text that isn't markup. Only the text of title and
+// textarea is kept.
+var rawTextElements = set("script", "style", "title", "textarea", "noscript", "iframe", "noembed",
+	"noframes", "xmp", "template")
+
+// closesParagraph lists the elements whose start tag ends an open paragraph.
+var closesParagraph = set("address", "article", "aside", "blockquote", "details", "div", "dl", "fieldset",
+	"figcaption", "figure", "footer", "form", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "main",
+	"menu", "nav", "ol", "p", "pre", "section", "table", "ul")
+
+// scopeBoundaries stop the search for an open element to close implicitly.
+var scopeBoundaries = set("html", "table", "td", "th", "caption", "button", "object", "template")
+
+// inlineElements are laid out within a line of text rather than as blocks.
+var inlineElements = set("a", "abbr", "b", "bdi", "bdo", "cite", "code", "data", "dfn", "em", "font", "i",
+	"kbd", "mark", "q", "s", "samp", "small", "span", "strike", "strong", "sub", "sup", "time", "tt", "u", "var")
+
+var (
+	paragraphTags	= set("p")
+	listItemTags	= set("li")
+	listTags		= set("ul", "ol", "menu", "table", "td", "th")
+	termTags		= set("dt", "dd")
+	termListTags	= set("dl", "table", "td", "th")
+	rowTags			= set("tr")
+	tableTags		= set("table")
+	cellTags		= set("td", "th")
+	rowBoundaries	= set("tr", "table")
+	headTags		= set("head")
+)
+
+
+func set(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+func (n *node) attr(name string) string {
+	return n.attrs[name]
+}
+
+func (n *node) isText() bool {
+	return n.tag == ""
+}
+
+// parse reads an HTML document into a tree. It is forgiving in the way browsers
+// are, closing paragraphs, list items and table cells that markup leaves open
+// and ignoring stray end tags, without implementing the full HTML5 algorithm.
+func parse(src string) *node {
+	root := &node{tag: "#document"}
+	stack := []*node{root}
+	lower := asciiLower(src)
+
+	top := func() *node { return stack[len(stack)-1] }
+	// closeTo pops the stack up to and including the innermost open tag named in
+	// names, unless a boundary comes first.
+	closeTo := func(names map[string]bool, boundaries map[string]bool) {
+		for i := len(stack) - 1; i > 0; i-- {
+			if names[stack[i].tag] {
+				stack = stack[:i]
+				return
+			}
+			if boundaries[stack[i].tag] {
+				return
+			}
+		}
+	}
+
+	i := 0
+	for i < len(src) {
+		lt := strings.IndexByte(src[i:], '<')
+		if lt < 0 {
+			appendText(top(), src[i:])
+			break
+		}
+		if lt > 0 {
+			appendText(top(), src[i:i+lt])
+			i += lt
+		}
+
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return root
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "</") && len(rest) > 2 && isLetter(rest[2]):
+			name, _ := scanName(lower[i+2:])
+			i = skipTag(src, i)
+			for j := len(stack) - 1; j > 0; j-- {
+				if stack[j].tag == name {
+					stack = stack[:j]
+					break
+				}
+			}
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?") || strings.HasPrefix(rest, "</"):
+			i = skipTag(src, i)
+		case len(rest) > 1 && isLetter(rest[1]):
+			el, n, selfClosing := scanStartTag(src[i:], lower[i:])
+			i += n
+
+			switch {
+			case closesParagraph[el.tag]:
+				closeTo(paragraphTags, scopeBoundaries)
+				if isHeading(el.tag) && isHeading(top().tag) {
+					stack = stack[:len(stack)-1]
+				}
+			case el.tag == "li":
+				closeTo(listItemTags, listTags)
+			case el.tag == "dt" || el.tag == "dd":
+				closeTo(termTags, termListTags)
+			case el.tag == "tr":
+				closeTo(rowTags, tableTags)
+			case el.tag == "td" || el.tag == "th":
+				closeTo(cellTags, rowBoundaries)
+			case el.tag == "option" && top().tag == "option":
+				stack = stack[:len(stack)-1]
+			case el.tag == "body":
+				closeTo(headTags, nil)
+			}
+
+			el.parent = top()
+			el.parent.children = append(el.parent.children, el)
+			if voidElements[el.tag] || selfClosing {
+				continue
+			}
+			if rawTextElements[el.tag] {
+				end := strings.Index(lower[i:], "</"+el.tag)
+				if end < 0 {
+					end = len(src) - i
+				}
+				if el.tag == "title" || el.tag == "textarea" {
+					appendText(el, src[i:i+end])
+				}
+				i = skipTag(src, i+end)
+				continue
+			}
+			stack = append(stack, el)
+		default:
+			appendText(top(), "<")
+			i++
+		}
+	}
+	return root
+}
+
+// scanStartTag reads the start tag at the beginning of src, returning the
+// element, the length of the tag and whether it ended with "/>". lower is src in
+// lower case.
+func scanStartTag(src, lower string) (*node, int, bool) {
+	name, i := scanName(lower[1:])
+	i++
+	el := &node{tag: name, attrs: map[string]string{}}
+
+	for i < len(src) {
+		for i < len(src) && (isSpace(src[i]) || src[i] == '/') {
+			if src[i] == '/' && i+1 < len(src) && src[i+1] == '>' {
+				return el, i + 2, true
+			}
+			i++
+		}
+		if i >= len(src) {
+			break
+		}
+		if src[i] == '>' {
+			return el, i + 1, false
+		}
+
+		start := i
+		for i < len(src) && !isSpace(src[i]) && src[i] != '=' && src[i] != '>' && src[i] != '/' {
+			i++
+		}
+		key := lower[start:i]
+		if i == start {
+			i++
+			continue
+		}
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		value := ""
+		if i < len(src) && src[i] == '=' {
+			i++
+			for i < len(src) && isSpace(src[i]) {
+				i++
+			}
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+				q := src[i]
+				end := strings.IndexByte(src[i+1:], q)
+				if end < 0 {
+					end = len(src) - i - 1
+				}
+				value = src[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(src) && !isSpace(src[i]) && src[i] != '>' {
+					i++
+				}
+				value = src[start:i]
+			}
+		}
+		if _, ok := el.attrs[key]; !ok {
+			el.attrs[key] = html.UnescapeString(value)
+		}
+	}
+	return el, len(src), false
+}
+
+// scanName reads a tag name, returning it and its length.
+func scanName(s string) (string, int) {
+	i := 0
+	for i < len(s) && !isSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	return s[:i], i
+}
+
+// skipTag returns the position after the '>' that ends the tag starting at i.
+func skipTag(src string, i int) int {
+	end := strings.IndexByte(src[i:], '>')
+	if end < 0 {
+		return len(src)
+	}
+	return i + end + 1
+}
+
+func appendText(parent *node, raw string) {
+	if raw == "" {
+		return
+	}
+	text := html.UnescapeString(raw)
+	if n := len(parent.children); n > 0 && parent.children[n-1].isText() {
+		parent.children[n-1].text += text
+		return
+	}
+	parent.children = append(parent.children, &node{text: text, parent: parent})
+}
+
+// asciiLower lowercases ASCII letters only, so that positions in the result are
+// positions in s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isHeading(tag string) bool {
+	return len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6'
+}
+
+// walk calls fn for n and its descendants in document order. Returning false
+// skips the descendants of a node.
+func walk(n *node, fn func(*node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.children {
+		walk(c, fn)
+	}
+}
+
+// find returns the first element named tag under n, or nil.
+func find(n *node, tag string) *node {
+	var found *node
+	walk(n, func(c *node) bool {
+		if found == nil && c.tag == tag {
+			found = c
+		}
+		return found == nil
+	})
+	return found
+}
+
+// remove takes n out of its parent.
+func remove(n *node) {
+	p := n.parent
+	if p == nil {
+		return
+	}
+	for i, c := range p.children {
+		if c == n {
+			p.children = append(p.children[:i], p.children[i+1:]...)
+			break
+		}
+	}
+	n.parent = nil
+}
+
+// textContent is the text under n with whitespace collapsed. Blocks are kept
+// apart by a space.
+func textContent(n *node) string {
+	var b strings.Builder
+	var write func(*node)
+	write = func(c *node) {
+		if c.isText() {
+			b.WriteString(c.text)
+			return
+		}
+		block := !inlineElements[c.tag]
+		if block {
+			b.WriteByte(' ')
+		}
+		for _, cc := range c.children {
+			write(cc)
+		}
+		if block {
+			b.WriteByte(' ')
+		}
+	}
+	write(n)
+	return collapse(b.String())
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,156 @@
+package clip
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+
+// TestParse checks the tree read from markup that browsers accept but that
+// doesn't close what it opens, or doesn't close it in order.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name	string
+		src		string
+		want	string
+	}{
+		{"well formed", `<div><p>One <b>two</b></p></div>`, `<div><p>One <b>two</b></p></div>`},
+		{"upper case", `<DIV Class=x><P>text</P></DIV>`, `<div class="x"><p>text</p></div>`},
+		{"open paragraphs", `<p>one<p>two<div>three</div>`, `<p>one</p><p>two</p><div>three</div>`},
+		{"open list items", `<ul><li>one<li>two</ul><p>after`, `<ul><li>one</li><li>two</li></ul><p>after</p>`},
+		{"open terms", `<dl><dt>term<dd>definition<dt>next</dl>`, `<dl><dt>term</dt><dd>definition</dd><dt>next</dt></dl>`},
+		{"open cells", `<table><tr><td>a<td>b<tr><th>c</table>`, `<table><tr><td>a</td><td>b</td></tr><tr><th>c</th></tr></table>`},
+		{"nested heading", `<h1>one<h2>two</h2>`, `<h1>one</h1><h2>two</h2>`},
+		{"stray end tags", `</p><div>a</span>b</div></div>c`, `<div>ab</div>c`},
+		{"misnested inline", `<b>bold <i>both</b> after</i>`, `<b>bold <i>both</i></b> after`},
+		{"unclosed head", `<head><title>t</title><body><p>a`, `<head><title>t</title></head><body><p>a</p></body>`},
+		{"unclosed at end", `<div><p>text`, `<div><p>text</p></div>`},
+		{"void elements", `<p>a<br>b<img src=x.png>c<hr>d`, `<p>a<br>b<img src="x.png">c</p><hr>d`},
+		{"self closing", `<div/>after<span />x`, `<div></div>after<span></span>x`},
+		{"comments", `a<!-- <p>hidden</p> -->b`, `ab`},
+		{"unterminated comment", `a<!-- never closed <p>b`, `a`},
+		{"doctype and processing", `<!DOCTYPE html><?xml version="1.0"?><p>a`, `<p>a</p>`},
+		{"bare angle brackets", `1 < 2 and 3 > 2, a<-b`, `1 &lt; 2 and 3 &gt; 2, a&lt;-b`},
+		{"attribute quoting", `<a href='x y' title="it's" data-n=3 hidden>t</a>`, `<a data-n="3" hidden="" href="x y" title="it's">t</a>`},
+		{"duplicate attribute", `<a href=one href=two>t</a>`, `<a href="one">t</a>`},
+		{"unterminated attribute", `<a href="x>text`, `<a href="x&gt;text"></a>`},
+		{"unterminated tag", `<p>a<b`, `<p>a<b></b></p>`},
+		{"entities in text", `<p>&lt;b&gt; &amp; &quot;q&quot; &copy; &#233; &#x263A; &nbsp;</p>`, "<p>&lt;b&gt; &amp; \"q\" © é ☺  </p>"},
+		{"entities in attributes", `<a title="a &amp; b &lt;c&gt;">t</a>`, `<a title="a &amp; b &lt;c&gt;">t</a>`},
+		{"unknown entities", `<p>&bogus; &amp &</p>`, `<p>&amp;bogus; &amp; &amp;</p>`},
+		{"script", `<p>a<script>if (a < b) document.write("</p>")</script>b</p>`, `<p>a<script></script>b</p>`},
+		{"style", `<style>p > a { color: red }</style><p>a`, `<style></style><p>a</p>`},
+		{"unterminated script", `<p>a<script>var x = "<p>"`, `<p>a<script></script></p>`},
+		{"title", `<title>A &amp; <b>B</b></title>`, `<title>A &amp; &lt;b&gt;B&lt;/b&gt;</title>`},
+	}
+	for _, tt := range tests {
+		if got := render(parse(tt.src)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestTextContent checks that blocks are kept apart and whitespace collapsed,
+// while inline elements run on.
+func TestTextContent(t *testing.T) {
+	tests := []struct {
+		src		string
+		want	string
+	}{
+		{"<p>one</p><p>two</p>", "one two"},
+		{"<p>un<b>bold</b>ed</p>", "unbolded"},
+		{"<ul>\n  <li>a\n  <li>b\n</ul>", "a b"},
+		{"<td>a</td><td>b</td>", "a b"},
+		{"  \n\t ", ""},
+	}
+	for _, tt := range tests {
+		if got := textContent(parse(tt.src)); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// FuzzParse checks that no input makes the parser or the extraction built on
+// it panic, and that the tree it builds is consistent.
+func FuzzParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		root := parse(string(data))
+		walk(root, func(n *node) bool {
+			for _, c := range n.children {
+				if c.parent != n {
+					t.Fatalf("child %q of %q has parent %v", c.tag, n.tag, c.parent)
+				}
+			}
+			if n.isText() && len(n.children) > 0 {
+				t.Fatalf("text %q has children", n.text)
+			}
+			return true
+		})
+
+		a, err := Extract(data, "https://example.org/a/page.html")
+		if err != nil {
+			return
+		}
+		if a.Markdown == "" {
+			t.Fatal("no Markdown for extracted article")
+		}
+		Lines(a.Markdown)
+		Snapshot(t.Context(), a, nil)
+	})
+}
+
+// render writes a tree back as markup, with attributes in order and text
+// escaped, so that trees compare as strings.
+func render(n *node) string {
+	var b strings.Builder
+	var write func(*node)
+	write = func(n *node) {
+		if n.isText() {
+			b.WriteString(escape(n.text))
+			return
+		}
+		if n.tag != "#document" {
+			b.WriteString("<" + n.tag)
+			keys := make([]string, 0, len(n.attrs))
+			for k := range n.attrs {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				b.WriteString(" " + k + `="` + escape(n.attrs[k]) + `"`)
+			}
+			b.WriteString(">")
+		}
+		for _, c := range n.children {
+			write(c)
+		}
+		if n.tag != "#document" && !voidElements[n.tag] {
+			b.WriteString("</" + n.tag + ">")
+		}
+	}
+	write(n)
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// readSeed decodes a file of the fuzz corpus, which holds the input as a quoted
+// []byte literal after a version line.
+func readSeed(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("testdata", "fuzz", "FuzzParse", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, lit, _ := strings.Cut(strings.TrimSpace(string(b)), "\n")
+	s, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(lit, "[]byte("), ")"))
+	if err != nil {
+		t.Fatalf("seed %s: %v", name, err)
+	}
+	return []byte(s)
+}
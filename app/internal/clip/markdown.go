@@ -0,0 +1,327 @@
+package clip
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"backend/internal/util"
+)
+
+
+// lineBreak stands in for <br> until the whitespace of a paragraph has been
+// collapsed.
+const lineBreak = "\x00"
+
+var markdownHeading = regexp.MustCompile(`^(#{1,6}) (.+)$`)
+
+// renderer turns the article tree into Markdown blocks, resolving links
+// against base.
+type renderer struct {
+	base		*url.URL
+}
+
+
+// markdown renders the article as Markdown headed by its title. Only what
+// survives as text is kept: headings, paragraphs, lists, quotes, code, tables,
+// emphasis and links.
+func markdown(a *Article) string {
+	r := renderer{base: a.URL}
+	blocks := r.blocks(a.content)
+	if a.Title != "" {
+		blocks = append([]string{"# " + a.Title}, blocks...)
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// blocks renders the children of n. Runs of text and inline elements between
+// block elements become paragraphs.
+func (r *renderer) blocks(n *node) []string {
+	var out []string
+	var para strings.Builder
+	flush := func() {
+		if t := inlineText(para.String()); t != "" {
+			out = append(out, t)
+		}
+		para.Reset()
+	}
+
+	for _, c := range n.children {
+		switch {
+		case c.isText():
+			para.WriteString(c.text)
+		case inlineElements[c.tag] || c.tag == "br" || c.tag == "img":
+			para.WriteString(r.inline(c))
+		default:
+			flush()
+			out = append(out, r.block(c)...)
+		}
+	}
+	flush()
+	return out
+}
+
+func (r *renderer) block(n *node) []string {
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		t := collapse(inlineText(r.inline(n)))
+		if t == "" {
+			return nil
+		}
+		return []string{strings.Repeat("#", int(n.tag[1]-'0')) + " " + t}
+	case "ul", "ol", "menu":
+		return r.list(n)
+	case "blockquote":
+		quote := strings.Join(r.blocks(n), "\n\n")
+		if quote == "" {
+			return nil
+		}
+		lines := strings.Split(quote, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return []string{strings.Join(lines, "\n")}
+	case "pre":
+		code := strings.Trim(rawText(n), "\n")
+		if strings.TrimSpace(code) == "" {
+			return nil
+		}
+		return []string{"```\n" + code + "\n```"}
+	case "hr":
+		return []string{"---"}
+	case "table":
+		return r.table(n)
+	}
+	return r.blocks(n)
+}
+
+func (r *renderer) list(n *node) []string {
+	start := 1
+	if s, err := strconv.Atoi(n.attr("start")); err == nil {
+		start = s
+	}
+
+	var items []string
+	for _, c := range n.children {
+		if c.tag != "li" {
+			continue
+		}
+		marker := "- "
+		if n.tag == "ol" {
+			marker = strconv.Itoa(start+len(items)) + ". "
+		}
+		body := strings.Join(r.blocks(c), "\n")
+		if body == "" {
+			continue
+		}
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+strings.Repeat(" ", len(marker))))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return []string{strings.Join(items, "\n")}
+}
+
+// table renders a table as a Markdown table, or as the blocks of its cells
+// when it only has one column, as tables used for layout do.
+func (r *renderer) table(n *node) []string {
+	var rows [][]*node
+	walk(n, func(c *node) bool {
+		if c.tag == "table" && c != n {
+			return false
+		}
+		if c.tag == "tr" {
+			var cells []*node
+			for _, cell := range c.children {
+				if cell.tag == "td" || cell.tag == "th" {
+					cells = append(cells, cell)
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, cells)
+			}
+			return false
+		}
+		return true
+	})
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width <= 1 {
+		var out []string
+		for _, row := range rows {
+			for _, cell := range row {
+				out = append(out, r.blocks(cell)...)
+			}
+		}
+		return out
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		cells := make([]string, width)
+		for j, cell := range row {
+			text := strings.Join(r.blocks(cell), " ")
+			cells[j] = strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", `\|`)
+		}
+		lines = append(lines, strings.TrimRight("| "+strings.Join(cells, " | ")+" |", " "))
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return []string{strings.Join(lines, "\n")}
+}
+
+// inline renders an element inside a paragraph. Its whitespace is left for
+// inlineText to collapse.
+func (r *renderer) inline(n *node) string {
+	switch n.tag {
+	case "br":
+		return lineBreak
+	case "img":
+		return ""
+	}
+
+	var b strings.Builder
+	for _, c := range n.children {
+		if c.isText() {
+			b.WriteString(c.text)
+		} else {
+			b.WriteString(r.inline(c))
+		}
+	}
+	inner := b.String()
+
+	switch n.tag {
+	case "a":
+		if href := resolve(r.base, n.attr("href")); href != "" {
+			href = strings.NewReplacer("(", "%28", ")", "%29").Replace(href)
+			return wrap("[", "]("+href+")", inner)
+		}
+	case "strong", "b":
+		return wrap("**", "**", inner)
+	case "em", "i", "cite":
+		return wrap("*", "*", inner)
+	case "code", "kbd", "samp", "tt":
+		return wrap("`", "`", inner)
+	}
+	if !inlineElements[n.tag] {
+		return " " + inner + " "
+	}
+	return inner
+}
+
+// wrap puts Markdown around text, outside the whitespace it starts or ends with,
+// which separates it from the words around it.
+func wrap(open, close, text string) string {
+	t := collapse(strings.ReplaceAll(text, lineBreak, " "))
+	if t == "" {
+		if text != "" {
+			return " "
+		}
+		return ""
+	}
+	var lead, trail string
+	if strings.TrimLeft(text, " \t\r\n") != text {
+		lead = " "
+	}
+	if strings.TrimRight(text, " \t\r\n") != text {
+		trail = " "
+	}
+	return lead + open + t + close + trail
+}
+
+// inlineText collapses the whitespace of a paragraph, keeping its line breaks.
+// Pages that separate paragraphs with two breaks get paragraphs.
+func inlineText(s string) string {
+	var b strings.Builder
+	blank := false
+	for _, l := range strings.Split(s, lineBreak) {
+		if l = collapse(l); l == "" {
+			blank = true
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+			if blank {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(l)
+		blank = false
+	}
+	return b.String()
+}
+
+// rawText is the text under n as it is, for preformatted text.
+func rawText(n *node) string {
+	var b strings.Builder
+	walk(n, func(c *node) bool {
+		if c.isText() {
+			b.WriteString(c.text)
+		} else if c.tag == "br" {
+			b.WriteByte('\n')
+		}
+		return true
+	})
+	return b.String()
+}
+
+// resolve makes ref absolute, returning "" for links that lead nowhere outside
+// the page, such as fragments and scripts.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return u.String()
+	}
+	return ""
+}
+
+// Lines splits the Markdown of an article into lines for util.Segment. The title
+// is set larger than the rest, and headings carry their level counted from the
+// top level the article uses, so that they needn't be guessed from the text.
+func Lines(md string) []util.TextLine {
+	var lines []util.TextLine
+	top := 0
+	fenced := false
+	for i, l := range strings.Split(md, "\n") {
+		if strings.HasPrefix(l, "```") {
+			fenced = !fenced
+			continue
+		}
+		if l = strings.TrimSpace(l); l == "" {
+			continue
+		}
+
+		line := util.TextLine{Text: l, Size: 1, Page: 1}
+		if m := markdownHeading.FindStringSubmatch(l); m != nil && !fenced {
+			if i == 0 && len(m[1]) == 1 {
+				line.Text, line.Size = m[2], 2
+			} else {
+				line.Text, line.Heading = m[2], len(m[1])
+				if top == 0 || line.Heading < top {
+					top = line.Heading
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	for i := range lines {
+		if lines[i].Heading > 0 {
+			lines[i].Heading -= top - 1
+		}
+	}
+	return lines
+}
@@ -0,0 +1,586 @@
+package clip
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+
+// Article is the main content of a web page along with what the page says about
+// itself.
+type Article struct {
+	Title			string
+	// Authors come from the citation tags of scholarly pages, or else the byline.
+	Authors			[]string
+	SiteName		string
+	Published		time.Time
+	DOI				string
+	// URL is the address of the page, which relative links are resolved against.
+	URL				*url.URL
+	// Markdown is the text of the article, headed by its title.
+	Markdown		string
+
+	content			*node
+}
+
+// ErrNoContent reports a page in which no article text was found.
+var ErrNoContent = errors.New("no article content found on the page")
+
+// The content is found as Mozilla's Readability finds it: paragraphs score
+// their parent and grandparent by their length and commas, the best scored
+// element less the share of its text that is links is the article, and siblings
+// that score well or read like prose join it. Class names and ids hint at
+// what is content and what is clutter.
+var (
+	unlikelyCandidates	= regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|newsletter|subscribe|cookie|share`)
+	maybeCandidate		= regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveHint		= regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeHint		= regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	byline				= regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+	titleSeparator		= regexp.MustCompile(`\s+[|\-–—\\/>»:]\s+`)
+	sentenceEnd			= regexp.MustCompile(`\.( |$)`)
+)
+
+// clutter are elements that are never article content.
+var clutter = set("script", "style", "noscript", "iframe", "object", "embed", "form", "button", "input",
+	"select", "textarea", "svg", "canvas", "template", "link", "meta", "nav", "aside", "footer", "dialog",
+	"head", "title", "base", "audio", "video", "map")
+
+// clutterRoles are ARIA roles of navigation and other page furniture.
+var clutterRoles = set("navigation", "banner", "complementary", "contentinfo", "dialog", "alertdialog",
+	"menu", "menubar", "search", "toolbar")
+
+// scoredTags are the elements whose text scores their ancestors.
+var scoredTags = set("p", "pre", "td", "blockquote", "section", "h2", "h3", "h4", "h5", "h6")
+
+// blockTags are the elements that keep a div from being read as a paragraph.
+var blockTags = set("a", "blockquote", "dl", "div", "img", "ol", "p", "pre", "table", "ul", "section",
+	"article", "figure", "h1", "h2", "h3", "h4", "h5", "h6")
+
+// publishedLayouts are the dates that publication meta tags start with.
+var publishedLayouts = []string{"2006-01-02", "2006/01/02", "2006-01", "2006"}
+
+// clipMarker marks the article element of a snapshot, which is taken as the
+// content when a snapshot is extracted again.
+const clipMarker = "data-clipped"
+
+const (
+	minParagraphLength	= 25
+	maxBylineLength		= 100
+)
+
+
+// Extract finds the article in page, an HTML document fetched from pageURL, and
+// renders it as Markdown.
+func Extract(page []byte, pageURL string) (*Article, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	root := parse(strings.ToValidUTF8(string(page), "�"))
+
+	if b := find(root, "base"); b != nil {
+		if u, err := base.Parse(b.attr("href")); err == nil && b.attr("href") != "" {
+			base = u
+		}
+	}
+	a := &Article{URL: base}
+	a.readMeta(root)
+
+	var content *node
+	walk(root, func(n *node) bool {
+		if content == nil && n.tag == "article" {
+			if _, ok := n.attrs[clipMarker]; ok {
+				content = n
+			}
+		}
+		return content == nil
+	})
+	if content == nil {
+		prune(root)
+		content = candidate(root)
+		clean(content)
+	}
+	for _, h := range headings(content) {
+		if strings.EqualFold(textContent(h), a.Title) {
+			remove(h)
+		}
+	}
+
+	if a.Title == "" {
+		if h := find(content, "h1"); h != nil {
+			a.Title = textContent(h)
+			remove(h)
+		}
+	}
+	if textContent(content) == "" {
+		return nil, ErrNoContent
+	}
+	a.content = content
+	a.Markdown = markdown(a)
+	return a, nil
+}
+
+// readMeta reads the title, authors, date, site name and DOI of the page from
+// its head, preferring the citation tags of scholarly pages and Open Graph tags
+// to plain ones.
+func (a *Article) readMeta(root *node) {
+	meta := map[string][]string{}
+	walk(root, func(n *node) bool {
+		if n.tag == "meta" {
+			key := strings.ToLower(firstNonEmpty(n.attr("property"), n.attr("name"), n.attr("itemprop")))
+			if v := collapse(n.attr("content")); key != "" && v != "" {
+				meta[key] = append(meta[key], v)
+			}
+		}
+		return true
+	})
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; len(v) > 0 {
+				return v[0]
+			}
+		}
+		return ""
+	}
+
+	a.Title = first("citation_title", "og:title", "twitter:title", "dc.title")
+	if a.Title == "" {
+		if t := find(root, "title"); t != nil {
+			a.Title = pageTitle(textContent(t), root)
+		}
+	}
+	a.SiteName = first("og:site_name", "application-name")
+	a.DOI = strings.TrimPrefix(strings.ToLower(first("citation_doi", "dc.identifier")), "doi:")
+	if !strings.HasPrefix(a.DOI, "10.") {
+		a.DOI = ""
+	}
+
+	a.Authors = meta["citation_author"]
+	if len(a.Authors) == 0 {
+		if by := first("author", "article:author", "dc.creator", "parsely-author"); by != "" && !strings.Contains(by, "://") {
+			a.Authors = splitByline(by)
+		} else if by := findByline(root); by != "" {
+			a.Authors = splitByline(by)
+		}
+	}
+
+	published := first("citation_publication_date", "citation_date", "article:published_time", "datepublished",
+		"date", "dc.date", "dc.date.issued", "parsely-pub-date")
+	if published == "" {
+		if t := find(root, "time"); t != nil {
+			published = t.attr("datetime")
+		}
+	}
+	a.Published = parseDate(published)
+}
+
+// pageTitle drops the site name that titles often end or start with, keeping
+// the part that matches a top heading of the page, or else the first part
+// when it is long enough to be a title of its own.
+func pageTitle(title string, root *node) string {
+	for _, tag := range []string{"h1", "h2"} {
+		var match string
+		walk(root, func(n *node) bool {
+			if match == "" && n.tag == tag {
+				if t := textContent(n); len(t)*2 >= len(title) && t != title && strings.Contains(title, t) {
+					match = t
+				}
+			}
+			return match == ""
+		})
+		if match != "" {
+			return match
+		}
+	}
+
+	loc := titleSeparator.FindAllStringIndex(title, -1)
+	if len(loc) == 0 {
+		return title
+	}
+	if head := title[:loc[len(loc)-1][0]]; len(strings.Fields(head)) >= 3 {
+		return head
+	}
+	if tail := title[loc[0][1]:]; len(strings.Fields(tail)) >= 3 {
+		return tail
+	}
+	return title
+}
+
+// findByline finds the author credited on the page by its markup.
+func findByline(root *node) string {
+	var by string
+	walk(root, func(n *node) bool {
+		if by != "" || n.isText() {
+			return false
+		}
+		if n.attr("rel") == "author" || strings.Contains(n.attr("itemprop"), "author") ||
+			byline.MatchString(n.attr("class")+" "+n.attr("id")) {
+			if t := textContent(n); t != "" && utf8.RuneCountInString(t) <= maxBylineLength {
+				by = t
+			}
+		}
+		return by == ""
+	})
+	return by
+}
+
+// splitByline turns "By Ann Smith and Bo Li" into the names it credits.
+func splitByline(s string) []string {
+	s = strings.TrimSpace(s)
+	if len(s) > 3 && strings.EqualFold(s[:3], "by ") {
+		s = s[3:]
+	}
+	s = strings.NewReplacer(" and ", ",", " & ", ",", ";", ",").Replace(s)
+
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = collapse(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range publishedLayouts {
+		if len(s) >= len(layout) {
+			if t, err := time.Parse(layout, s[:len(layout)]); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// prune removes what is never content: scripts, forms, navigation, hidden
+// elements and those whose class or id marks them as page furniture.
+func prune(root *node) {
+	var doomed []*node
+	walk(root, func(n *node) bool {
+		if n.isText() || n.tag == "#document" {
+			return true
+		}
+		if unwanted(n) {
+			doomed = append(doomed, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range doomed {
+		remove(n)
+	}
+}
+
+func unwanted(n *node) bool {
+	if clutter[n.tag] || clutterRoles[n.attr("role")] {
+		return true
+	}
+	if _, hidden := n.attrs["hidden"]; hidden || n.attr("aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(n.attr("style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	if n.tag == "header" && !inside(n, "article", "main") {
+		return true
+	}
+
+	switch n.tag {
+	case "html", "body", "article", "main", "a", "table", "tbody", "tr", "td", "th", "code", "pre":
+		return false
+	}
+	hint := n.attr("class") + " " + n.attr("id")
+	return unlikelyCandidates.MatchString(hint) && !maybeCandidate.MatchString(hint)
+}
+
+func inside(n *node, tags ...string) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		for _, t := range tags {
+			if p.tag == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// candidate picks the element holding the article and gathers it with the
+// siblings that belong to it under a new div.
+func candidate(root *node) *node {
+	scores := map[*node]float64{}
+	score := func(n *node, add float64) {
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+		}
+		scores[n] += add
+	}
+
+	walk(root, func(n *node) bool {
+		if n.isText() || !(scoredTags[n.tag] || n.tag == "div" && !hasBlockChildren(n)) {
+			return true
+		}
+		text := textContent(n)
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return true
+		}
+		points := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length/100), 3)
+		ancestor := n.parent
+		for level := 0; level < 3 && ancestor != nil && ancestor.tag != "#document"; level++ {
+			divider := 1.0
+			if level == 1 {
+				divider = 2
+			} else if level > 1 {
+				divider = float64(level * 3)
+			}
+			score(ancestor, points/divider)
+			ancestor = ancestor.parent
+		}
+		return true
+	})
+
+	var top *node
+	best := 0.0
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		scores[n] = s
+		if top == nil || s > best || s == best && before(n, top) {
+			top, best = n, s
+		}
+	}
+	if top == nil {
+		if body := find(root, "body"); body != nil {
+			return body
+		}
+		return root
+	}
+	for top.parent != nil && top.parent.tag != "body" && top.parent.tag != "#document" && elementChildren(top.parent) == 1 {
+		top = top.parent
+	}
+	if top.parent == nil {
+		return top
+	}
+
+	threshold := math.Max(10, best*0.2)
+	content := &node{tag: "div"}
+	for _, sib := range top.parent.children {
+		if sib.isText() {
+			continue
+		}
+		keep := sib == top
+		if s, ok := scores[sib]; ok && s >= threshold {
+			keep = true
+		}
+		if sib.tag == "p" {
+			text := textContent(sib)
+			density, length := linkDensity(sib), utf8.RuneCountInString(text)
+			if length > 80 && density < 0.25 || length <= 80 && length > 0 && density == 0 && sentenceEnd.MatchString(text) {
+				keep = true
+			}
+		}
+		if keep {
+			sib.parent = content
+			content.children = append(content.children, sib)
+		}
+	}
+	return content
+}
+
+// before reports whether a comes before b in the document, which settles ties
+// between candidates the same way on every run.
+func before(a, b *node) bool {
+	path := func(n *node) []int {
+		var p []int
+		for ; n.parent != nil; n = n.parent {
+			for i, c := range n.parent.children {
+				if c == n {
+					p = append([]int{i}, p...)
+					break
+				}
+			}
+		}
+		return p
+	}
+	pa, pb := path(a), path(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+func initialScore(n *node) float64 {
+	s := classWeight(n)
+	switch n.tag {
+	case "div", "article", "main":
+		s += 5
+	case "pre", "td", "blockquote":
+		s += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		s -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		s -= 5
+	}
+	return s
+}
+
+// classWeight scores the class and id of an element for looking like content
+// or like clutter.
+func classWeight(n *node) float64 {
+	var w float64
+	for _, hint := range []string{n.attr("class"), n.attr("id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHint.MatchString(hint) {
+			w -= 25
+		}
+		if positiveHint.MatchString(hint) {
+			w += 25
+		}
+	}
+	return w
+}
+
+// linkDensity is the share of the text of n that is the text of links.
+func linkDensity(n *node) float64 {
+	length := utf8.RuneCountInString(textContent(n))
+	if length == 0 {
+		return 0
+	}
+	var links int
+	walk(n, func(c *node) bool {
+		if c.tag == "a" {
+			links += utf8.RuneCountInString(textContent(c))
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(length)
+}
+
+func hasBlockChildren(n *node) bool {
+	for _, c := range n.children {
+		if blockTags[c.tag] {
+			return true
+		}
+	}
+	return false
+}
+
+func elementChildren(n *node) int {
+	count := 0
+	for _, c := range n.children {
+		if !c.isText() {
+			count++
+		} else if strings.TrimSpace(c.text) != "" {
+			return 2
+		}
+	}
+	return count
+}
+
+// clean removes what is left of the clutter inside the article: blocks that
+// look like clutter by their class, or are mostly links, images or list items
+// rather than prose.
+func clean(content *node) {
+	var doomed []*node
+	walk(content, func(n *node) bool {
+		if n.isText() || n == content {
+			return true
+		}
+		if isHeading(n.tag) && classWeight(n) < 0 {
+			doomed = append(doomed, n)
+			return false
+		}
+		switch n.tag {
+		case "div", "section", "ul", "ol", "table", "header", "figure", "aside", "dl":
+		default:
+			return true
+		}
+		if cluttered(n) {
+			doomed = append(doomed, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range doomed {
+		remove(n)
+	}
+}
+
+func cluttered(n *node) bool {
+	weight := classWeight(n)
+	if weight < 0 {
+		return true
+	}
+	text := textContent(n)
+	if strings.Count(text, ",") >= 10 {
+		return false
+	}
+
+	var paragraphs, images, items, headings, code int
+	walk(n, func(c *node) bool {
+		switch {
+		case c.tag == "p":
+			paragraphs++
+		case c.tag == "img":
+			images++
+		case c.tag == "li":
+			items++
+		case isHeading(c.tag):
+			headings++
+		case c.tag == "pre" || c.tag == "code":
+			code++
+		}
+		return true
+	})
+	list := n.tag == "ul" || n.tag == "ol" || n.tag == "dl"
+	length := utf8.RuneCountInString(text)
+	density := linkDensity(n)
+
+	switch {
+	case code > 0 || n.tag == "table" && density < 0.5:
+		return false
+	case n.tag != "figure" && images > 1 && float64(paragraphs)/float64(images) < 0.5:
+		return true
+	case !list && items > paragraphs+headings && items > 3:
+		return true
+	case weight < 25 && density > 0.2 && !(list && density <= 0.5 && length > 200):
+		return true
+	case weight >= 25 && density > 0.5:
+		return true
+	case !list && n.tag != "figure" && length < minParagraphLength && headings == 0 && (images == 0 || images > 2):
+		return true
+	}
+	return false
+}
+
+func headings(n *node) []*node {
+	var hs []*node
+	walk(n, func(c *node) bool {
+		if c.tag == "h1" || c.tag == "h2" {
+			hs = append(hs, c)
+		}
+		return true
+	})
+	return hs
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package clip
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/util"
+)
+
+
+// The seed corpus in testdata/fuzz/FuzzParse holds whole pages: a blog post
+// among navigation, comments and scripts, a page of unclosed and misnested
+// markup, an article laid out with tables, and pages with nothing to read.
+func TestExtractCorpus(t *testing.T) {
+	tests := []struct {
+		seed	string
+		want	[]string
+		omit	[]string
+		err		error
+	}{
+		{
+			seed: "article",
+			want: []string{
+				"# Reading Notes on Sparse Attention\n\nSparse attention restricts",
+				"## Method",
+				"10,000 steps — with the same budget, learning rate & warm-up – on sequences of 4 096 tokens.",
+				"- Sliding window\n  - width 256\n  - width 512\n- Global tokens, *dilated*\n- Random blocks",
+				"3. Third step\n4. Fourth step",
+				"| Pattern | Accuracy | Memory |\n| --- | --- | --- |\n| Window | 71.2 | 1.0x |\n| Global \\| window | 73.4 | 1.1x |",
+				"[LongBench suite](https://example.org/datasets/longbench.html)",
+				"[Longformer](https://arxiv.org/abs/2004.05150)",
+				"[appendix](https://example.org/blog/2024/figures/)",
+				"following the method below",
+				"the code is on request.",
+				"```\nfor step in range(10_000):\n    loss = model(batch) < 1\n```",
+			},
+			omit: []string{"tracking", "dataLayer", "font-family", "Home", "NLP", "Great post", "rights reserved", "javascript:", "The Lab Blog", "plot.png"},
+		},
+		{
+			seed: "malformed",
+			want: []string{
+				"# Unclosed & Misnested",
+				"This page never closes its head or its paragraphs",
+				"Its **bold *and italic*** text overlaps, and its markup is \"sloppy\" & out of order.",
+				"Stray end tags are ignored, while 1 < 2 keeps its angle bracket.",
+			},
+			omit: []string{"swallows", "hidden", "</"},
+		},
+		{
+			seed: "table",
+			want: []string{
+				"Pages laid out with tables put the whole article in one cell, which is read as ordinary paragraphs.\n\n",
+				"[next page](https://example.org/blog/next.html)",
+			},
+			omit: []string{"|"},
+		},
+		{seed: "empty", err: ErrNoContent},
+		{seed: "script-only", err: ErrNoContent},
+	}
+	for _, tt := range tests {
+		a, err := Extract(readSeed(t, tt.seed), "https://example.org/blog/post.html")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got error %v, want %v", tt.seed, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(a.Markdown, w) {
+				t.Errorf("%s: Markdown lacks %q:\n%s", tt.seed, w, a.Markdown)
+			}
+		}
+		for _, o := range tt.omit {
+			if strings.Contains(a.Markdown, o) {
+				t.Errorf("%s: Markdown has %q:\n%s", tt.seed, o, a.Markdown)
+			}
+		}
+	}
+}
+
+// TestExtractMeta checks what the blog post says about itself. Its base element
+// moves the address that links resolve against.
+func TestExtractMeta(t *testing.T) {
+	a, err := Extract(readSeed(t, "article"), "https://example.org/blog/post.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "Reading Notes on Sparse Attention" {
+		t.Errorf("title: got %q", a.Title)
+	}
+	if strings.Join(a.Authors, "; ") != "Ann Smith; Bo Li" {
+		t.Errorf("authors: got %q", a.Authors)
+	}
+	if a.SiteName != "The Lab Blog" {
+		t.Errorf("site name: got %q", a.SiteName)
+	}
+	if want := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC); !a.Published.Equal(want) {
+		t.Errorf("published: got %v, want %v", a.Published, want)
+	}
+	if a.URL.String() != "https://example.org/blog/2024/" {
+		t.Errorf("url: got %v", a.URL)
+	}
+}
+
+// TestSnapshotRoundTrip checks that a snapshot is extracted as the article it
+// was made from, and carries nothing that runs or loads from the web.
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, seed := range []string{"article", "malformed", "table"} {
+		a, err := Extract(readSeed(t, seed), "https://example.org/blog/post.html")
+		if err != nil {
+			t.Fatalf("%s: %v", seed, err)
+		}
+		page := Snapshot(t.Context(), a, nil)
+		for _, bad := range []string{"<script", "<img", "javascript:", "<iframe"} {
+			if strings.Contains(string(page), bad) {
+				t.Errorf("%s: snapshot has %q", seed, bad)
+			}
+		}
+
+		again, err := Extract(page, "https://clips.example.com/1")
+		if err != nil {
+			t.Errorf("%s: extracting the snapshot: %v", seed, err)
+			continue
+		}
+		if again.Markdown != a.Markdown {
+			t.Errorf("%s: got Markdown\n%s\nwant\n%s", seed, again.Markdown, a.Markdown)
+		}
+		if again.Title != a.Title || strings.Join(again.Authors, ",") != strings.Join(a.Authors, ",") || !again.Published.Equal(a.Published) {
+			t.Errorf("%s: got meta %q %q %v, want %q %q %v", seed, again.Title, again.Authors, again.Published, a.Title, a.Authors, a.Published)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base, _ := url.Parse("https://example.org/blog/2024/post.html?x=1")
+	tests := []struct {
+		ref		string
+		want	string
+	}{
+		{"other.html", "https://example.org/blog/2024/other.html"},
+		{"../../about", "https://example.org/about"},
+		{"/", "https://example.org/"},
+		{"?page=2", "https://example.org/blog/2024/post.html?page=2"},
+		{"//cdn.example.net/a.png", "https://cdn.example.net/a.png"},
+		{" http://other.org/x ", "http://other.org/x"},
+		{"mailto:ann@example.org", "mailto:ann@example.org"},
+		{"#section", ""},
+		{"", ""},
+		{"javascript:alert(1)", ""},
+		{"data:text/html,<script>", ""},
+		{"ftp://example.org/file", ""},
+		{"http://[::1", ""},
+	}
+	for _, tt := range tests {
+		if got := resolve(base, tt.ref); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+// TestLines checks that the title is set apart and heading levels count from
+// the top level the article uses, leaving code alone.
+func TestLines(t *testing.T) {
+	md := "# Title\n\nIntro.\n\n### Part\n\nText.\n\n```\n# not a heading\n```\n\n#### Sub\n"
+	want := []util.TextLine{
+		{Text: "Title", Size: 2, Page: 1},
+		{Text: "Intro.", Size: 1, Page: 1},
+		{Text: "Part", Size: 1, Page: 1, Heading: 1},
+		{Text: "Text.", Size: 1, Page: 1},
+		{Text: "# not a heading", Size: 1, Page: 1},
+		{Text: "Sub", Size: 1, Page: 1, Heading: 2},
+	}
+	got := Lines(md)
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package clip
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+
+// ImageLoader downloads an image of the article for its snapshot.
+type ImageLoader func(ctx context.Context, url string) ([]byte, error)
+
+// snapshotTags are the elements kept in a snapshot. Other elements are replaced
+// by their content, and those in snapshotBlocks by a div.
+var snapshotTags = set("p", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "li", "blockquote", "pre", "code",
+	"em", "strong", "b", "i", "a", "img", "br", "hr", "table", "thead", "tbody", "tfoot", "tr", "th", "td",
+	"caption", "figure", "figcaption", "sup", "sub", "dl", "dt", "dd", "cite", "q", "kbd", "samp", "mark", "s",
+	"del", "ins", "small", "abbr", "time")
+
+var snapshotBlocks = set("div", "section", "article", "main", "header", "center", "address", "details", "summary")
+
+// snapshotAttrs are the attributes kept on the elements of a snapshot. Links
+// and images are handled apart.
+var snapshotAttrs = map[string][]string{
+	"td":		{"colspan", "rowspan"},
+	"th":		{"colspan", "rowspan"},
+	"ol":		{"start"},
+	"abbr":		{"title"},
+	"time":		{"datetime"},
+}
+
+// imageTypes are the image formats embedded in snapshots. SVG is left out as
+// it may carry scripts.
+var imageTypes = set("image/png", "image/jpeg", "image/gif", "image/webp")
+
+const maxImages = 40
+
+const snapshotStyle = `body{max-width:42em;margin:2em auto;padding:0 1em;font:17px/1.6 Georgia,serif;color:#222}` +
+	`img{max-width:100%;height:auto}pre{overflow:auto;background:#f6f6f6;padding:.5em}` +
+	`blockquote{margin-left:0;padding-left:1em;border-left:3px solid #ddd;color:#555}` +
+	`table{border-collapse:collapse}td,th{border:1px solid #ddd;padding:.2em .5em}` +
+	`.source{font:14px sans-serif;color:#666;border-bottom:1px solid #eee}`
+
+
+// Snapshot renders the article as a self-contained HTML page: its content alone,
+// without scripts, styles or anything else that loads from the web, and with its
+// images embedded as data URIs. Images that can't be embedded are left out, all
+// of them when load is nil. The page records the metadata of the article, and
+// Extract reads the same article from it again.
+func Snapshot(ctx context.Context, a *Article, load ImageLoader) []byte {
+	images := map[string]string{}
+	if load != nil {
+		images = embedImages(ctx, a, load)
+	}
+	esc := html.EscapeString
+
+	var b bytes.Buffer
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>" + esc(a.Title) + "</title>\n")
+	b.WriteString(`<meta property="og:title" content="` + esc(a.Title) + "\">\n")
+	for _, name := range a.Authors {
+		b.WriteString(`<meta name="citation_author" content="` + esc(name) + "\">\n")
+	}
+	if !a.Published.IsZero() {
+		b.WriteString(`<meta property="article:published_time" content="` + a.Published.Format("2006-01-02") + "\">\n")
+	}
+	if a.SiteName != "" {
+		b.WriteString(`<meta property="og:site_name" content="` + esc(a.SiteName) + "\">\n")
+	}
+	if a.DOI != "" {
+		b.WriteString(`<meta name="citation_doi" content="` + esc(a.DOI) + "\">\n")
+	}
+	b.WriteString(`<link rel="canonical" href="` + esc(a.URL.String()) + "\">\n")
+	b.WriteString("<style>" + snapshotStyle + "</style>\n</head>\n<body>\n")
+
+	source := a.SiteName
+	if source == "" {
+		source = a.URL.Hostname()
+	}
+	credits := []string{`<a href="` + esc(a.URL.String()) + `">` + esc(source) + "</a>"}
+	if len(a.Authors) > 0 {
+		credits = append(credits, esc(strings.Join(a.Authors, ", ")))
+	}
+	if !a.Published.IsZero() {
+		credits = append(credits, a.Published.Format("2 January 2006"))
+	}
+	b.WriteString(`<header class="source"><p>` + strings.Join(credits, " · ") + "</p></header>\n")
+
+	b.WriteString("<article " + clipMarker + ">\n<h1>" + esc(a.Title) + "</h1>\n")
+	s := snapshotWriter{b: &b, base: a.URL, images: images}
+	for _, c := range a.content.children {
+		s.write(c)
+	}
+	b.WriteString("\n</article>\n</body>\n</html>\n")
+	return b.Bytes()
+}
+
+// embedImages downloads the images of the article, returning their data URIs
+// by their address in the page.
+func embedImages(ctx context.Context, a *Article, load ImageLoader) map[string]string {
+	images := map[string]string{}
+	walk(a.content, func(n *node) bool {
+		if n.tag != "img" || len(images) >= maxImages || ctx.Err() != nil {
+			return ctx.Err() == nil
+		}
+		src := imageSource(n)
+		if _, seen := images[src]; seen || strings.HasPrefix(src, "data:") {
+			return true
+		}
+		u := resolve(a.URL, src)
+		if !strings.HasPrefix(u, "http") {
+			return true
+		}
+
+		data, err := load(ctx, u)
+		if err != nil {
+			log.Printf("Leaving image %s out of the snapshot: %v\n", u, err)
+			images[src] = ""
+			return true
+		}
+		images[src] = dataURI(data)
+		return true
+	})
+	return images
+}
+
+// imageSource is where an image loads from, looking past the placeholders of
+// images that pages load lazily.
+func imageSource(n *node) string {
+	src := strings.TrimSpace(n.attr("src"))
+	if src != "" && !strings.HasPrefix(src, "data:") {
+		return src
+	}
+	for _, key := range []string{"data-src", "data-original", "data-lazy-src"} {
+		if v := strings.TrimSpace(n.attr(key)); v != "" {
+			return v
+		}
+	}
+	if set := firstNonEmpty(n.attr("srcset"), n.attr("data-srcset")); set != "" {
+		if f := strings.Fields(strings.Split(set, ",")[0]); len(f) > 0 {
+			return f[0]
+		}
+	}
+	return src
+}
+
+// dataURI embeds image data, or returns "" for data that isn't an image type
+// that is safe to embed.
+func dataURI(data []byte) string {
+	ct := http.DetectContentType(data)
+	if !imageTypes[ct] {
+		return ""
+	}
+	return "data:" + ct + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// snapshotWriter writes the content of an article as sanitised HTML.
+type snapshotWriter struct {
+	b			*bytes.Buffer
+	base		*url.URL
+	images		map[string]string
+}
+
+func (s snapshotWriter) write(n *node) {
+	if n.isText() {
+		s.b.WriteString(html.EscapeString(n.text))
+		return
+	}
+
+	tag := n.tag
+	switch {
+	case snapshotBlocks[tag]:
+		tag = "div"
+	case !snapshotTags[tag]:
+		for _, c := range n.children {
+			s.write(c)
+		}
+		return
+	}
+
+	attrs := ""
+	switch tag {
+	case "a":
+		if href := resolve(s.base, n.attr("href")); href != "" {
+			attrs += ` href="` + html.EscapeString(href) + `"`
+		}
+	case "img":
+		src := s.images[imageSource(n)]
+		if src == "" {
+			if v := imageSource(n); strings.HasPrefix(v, "data:") {
+				src = embedded(v)
+			}
+		}
+		if src == "" {
+			return
+		}
+		attrs += ` src="` + src + `"`
+		if alt := n.attr("alt"); alt != "" {
+			attrs += ` alt="` + html.EscapeString(alt) + `"`
+		}
+	}
+	for _, name := range snapshotAttrs[tag] {
+		if v, ok := n.attrs[name]; ok {
+			attrs += " " + name + `="` + html.EscapeString(v) + `"`
+		}
+	}
+
+	s.b.WriteString("<" + tag + attrs + ">")
+	if voidElements[tag] {
+		return
+	}
+	for _, c := range n.children {
+		s.write(c)
+	}
+	s.b.WriteString("</" + tag + ">")
+	if !inlineElements[tag] {
+		s.b.WriteByte('\n')
+	}
+}
+
+// embedded checks an image the page already embeds, returning it if it is a
+// base64 image of a type that is safe to embed.
+func embedded(uri string) string {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return ""
+	}
+	return dataURI(raw)
+}
@@ -3,15 +3,171 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 
 var Port string
 
+// StorageRoot is where uploaded files are kept, addressed by content hash.
+var StorageRoot string
+
+// ImportRoot is the only server directory that bulk imports may read from. Directory
+// imports are disabled when it is empty.
+var ImportRoot string
+
+// MaxUploadSize bounds a single uploaded or imported file, in bytes.
+var MaxUploadSize int64
+
+// UserQuota bounds the total size of each user's files, in bytes; 0 means unlimited.
+var UserQuota int64
+
+// OCREnabled turns on OCR of scanned pages. OCR still stays off if pdftoppm or
+// tesseract can't be found.
+var OCREnabled bool
+
+// OCRLanguage is the default Tesseract language, e.g. "eng" or "deu+eng".
+var OCRLanguage string
+
+// PdftoppmPath and TesseractPath name the OCR programs, as paths or as commands
+// looked up in PATH.
+var PdftoppmPath, TesseractPath string
+
+// EnrichEnabled turns on looking up the metadata of documents with a DOI or
+// arXiv ID in the EnrichResolvers, in that order.
+var EnrichEnabled bool
+
+var EnrichResolvers []string
+
+// CrossrefURL, ArXivURL and SemanticScholarURL are the base URLs of the scholarly
+// APIs, which can point at a mirror or a stub server.
+var CrossrefURL, ArXivURL, SemanticScholarURL string
+
+// DOIURL and ArXivSiteURL are where imports from URLs resolve DOIs and download
+// arXiv PDFs. Like the API URLs above they can point at a mirror or a stub server.
+var DOIURL, ArXivSiteURL string
+
+// SemanticScholarKey is an optional Semantic Scholar API key.
+var SemanticScholarKey string
+
+// ContactEmail is sent to the scholarly APIs with every request, as Crossref asks
+// of clients that want its faster pool.
+var ContactEmail string
+
+// FetchAllow lists the CIDR ranges, addresses and host names inside private
+// networks that imports from URLs may still reach, comma-separated.
+var FetchAllow string
+
+// FeedsEnabled turns on polling the feeds that workspaces subscribe to. Feeds can
+// still be polled on request when it is off. A feed served from this machine, such
+// as a stub for testing, needs its address in FetchAllow, e.g. 127.0.0.1.
+var FeedsEnabled bool
+
+// AlertsEnabled turns on running saved searches on new documents, and telling
+// users when a document they added is done with OCR and the metadata lookup.
+var AlertsEnabled bool
+
+// WebhooksEnabled turns on sending library events to users' webhooks. With it off
+// webhooks can still be managed, and their events are neither recorded nor sent.
+var WebhooksEnabled bool
+
+// SMTPAddr is the host:port of the mail server that notifications and digests are
+// sent through, e.g. localhost:1025 for MailHog. Email is off when it is empty.
+// SMTPUsername and SMTPPassword are only needed by servers that ask for them.
+var SMTPAddr, SMTPFrom, SMTPUsername, SMTPPassword string
+
+// PasswordResetURL is the page of the web app that password reset emails link
+// to, with the token added as the token query parameter.
+var PasswordResetURL string
+
+// BreachedPasswords names a file of passwords to refuse on top of the built-in
+// list, one per line or as SHA-1 hashes in the Have I Been Pwned format.
+var BreachedPasswords string
+
 func LoadConfig() {
 	Port = os.Getenv("PORT")
 	if Port == "" {
 		Port = "8080"
 	}
 	log.Println("Using port:", Port)
-}
\ No newline at end of file
+
+	StorageRoot = os.Getenv("STORAGE_ROOT")
+	if StorageRoot == "" {
+		StorageRoot = "uploads"
+	}
+
+	ImportRoot = os.Getenv("IMPORT_ROOT")
+	if ImportRoot != "" {
+		log.Println("Directory imports allowed under:", ImportRoot)
+	}
+
+	MaxUploadSize = envBytes("MAX_UPLOAD_SIZE", 1<<30)
+	UserQuota = envBytes("USER_QUOTA", 0)
+	log.Printf("Upload limit: %d bytes per file, quota: %d bytes per user (0 is unlimited)\n", MaxUploadSize, UserQuota)
+
+	OCREnabled = envBool("OCR_ENABLED", true)
+	OCRLanguage = envString("OCR_LANG", "eng")
+	PdftoppmPath = envString("PDFTOPPM", "pdftoppm")
+	TesseractPath = envString("TESSERACT", "tesseract")
+
+	EnrichEnabled = envBool("ENRICH_ENABLED", true)
+	EnrichResolvers = strings.Split(envString("ENRICH_RESOLVERS", "crossref,semanticscholar,arxiv"), ",")
+	CrossrefURL = envString("CROSSREF_URL", "https://api.crossref.org")
+	ArXivURL = envString("ARXIV_URL", "https://export.arxiv.org/api")
+	SemanticScholarURL = envString("SEMANTIC_SCHOLAR_URL", "https://api.semanticscholar.org/graph/v1")
+	DOIURL = envString("DOI_URL", "https://doi.org")
+	ArXivSiteURL = envString("ARXIV_SITE_URL", "https://arxiv.org")
+	SemanticScholarKey = os.Getenv("SEMANTIC_SCHOLAR_KEY")
+	ContactEmail = os.Getenv("CONTACT_EMAIL")
+
+	FetchAllow = os.Getenv("FETCH_ALLOW")
+	if FetchAllow != "" {
+		log.Println("URL imports may reach private addresses:", FetchAllow)
+	}
+
+	FeedsEnabled = envBool("FEEDS_ENABLED", true)
+
+	AlertsEnabled = envBool("ALERTS_ENABLED", true)
+	WebhooksEnabled = envBool("WEBHOOKS_ENABLED", true)
+	SMTPAddr = os.Getenv("SMTP_ADDR")
+	SMTPFrom = envString("SMTP_FROM", "research-assistant@localhost")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	if SMTPAddr != "" {
+		log.Println("Sending email through:", SMTPAddr)
+	}
+	PasswordResetURL = envString("PASSWORD_RESET_URL", "http://localhost:5173/reset-password")
+	BreachedPasswords = os.Getenv("BREACHED_PASSWORDS")
+}
+
+func envString(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func envBool(name string, def bool) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: must be true or false", name, v)
+	}
+	return b
+}
+
+func envBytes(name string, def int64) int64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid %s %q: must be a number of bytes", name, v)
+	}
+	return n
+}
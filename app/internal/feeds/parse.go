@@ -0,0 +1,260 @@
+package feeds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/internal/fetch"
+)
+
+
+// Entry is an item of an RSS feed or an entry of an Atom feed, with the
+// identifiers of the paper it announces when they can be told from it.
+type Entry struct {
+	GUID			string
+	Title			string
+	Link			string
+	// PDFURL is a link the feed marks as the PDF of the entry.
+	PDFURL			string
+	Summary			string
+	Authors			[]string
+	DOI				string
+	ArXivID			string
+	Published		time.Time
+}
+
+var ErrNotFeed = errors.New("not an RSS or Atom feed")
+
+// document decodes RSS 2.0, RSS 1.0 (RDF) and Atom alike. RSS 2.0 items are in
+// the channel, RSS 1.0 items are its siblings.
+type document struct {
+	XMLName		xml.Name
+	Channel		struct {
+		Items		[]rssItem		`xml:"item"`
+	}	`xml:"channel"`
+	Items		[]rssItem		`xml:"item"`
+	Entries		[]atomEntry		`xml:"entry"`
+}
+
+type rssItem struct {
+	Title			string		`xml:"title"`
+	// Links are plain links; an atom:link in an item decodes as an empty one.
+	Links			[]string	`xml:"link"`
+	GUID			string		`xml:"guid"`
+	About			string		`xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Description		string		`xml:"description"`
+	PubDate			string		`xml:"pubDate"`
+	Date			string		`xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creators		[]string	`xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Author			string		`xml:"author"`
+	Identifier		string		`xml:"http://purl.org/dc/elements/1.1/ identifier"`
+	DOI				string		`xml:"http://prismstandard.org/namespaces/basic/2.0/ doi"`
+	Enclosures		[]struct {
+		URL				string		`xml:"url,attr"`
+		Type			string		`xml:"type,attr"`
+	}	`xml:"enclosure"`
+}
+
+type atomEntry struct {
+	ID				string		`xml:"id"`
+	Title			string		`xml:"title"`
+	Summary			string		`xml:"summary"`
+	Content			string		`xml:"content"`
+	Published		string		`xml:"published"`
+	Updated			string		`xml:"updated"`
+	Authors			[]struct {
+		Name			string		`xml:"name"`
+	}	`xml:"author"`
+	Links			[]struct {
+		Href			string		`xml:"href,attr"`
+		Rel				string		`xml:"rel,attr"`
+		Type			string		`xml:"type,attr"`
+		Title			string		`xml:"title,attr"`
+	}	`xml:"link"`
+	// DOI is set by the arXiv API on papers that have one.
+	DOI				string		`xml:"http://arxiv.org/schemas/atom doi"`
+}
+
+const maxGUIDLength = 512
+
+var (
+	markup			= regexp.MustCompile(`<[^>]*>`)
+	doiPrefix		= regexp.MustCompile(`(?i)^(?:doi:\s*|https?://(?:dx\.)?doi\.org/)`)
+	dateLayouts		= []string{time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822, time.RFC3339,
+		"Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2 Jan 2006 15:04:05 -0700",
+		"2006-01-02T15:04:05", "2006-01-02"}
+)
+
+
+// Parse reads the entries of an RSS or Atom feed in the order the feed gives them.
+func Parse(data []byte) ([]Entry, error) {
+	var doc document
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(&doc); err != nil {
+		return nil, ErrNotFeed
+	}
+
+	var entries []Entry
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			entries = append(entries, rssEntry(it))
+		}
+	case "feed":
+		for _, e := range doc.Entries {
+			entries = append(entries, atomEntryOf(e))
+		}
+	default:
+		return nil, ErrNotFeed
+	}
+
+	for i := range entries {
+		identify(&entries[i])
+	}
+	return entries, nil
+}
+
+func rssEntry(it rssItem) Entry {
+	e := Entry{
+		Title:		plainText(it.Title),
+		Summary:	plainText(it.Description),
+		DOI:		it.DOI,
+		Published:	parseDate(firstNonEmpty(it.PubDate, it.Date)),
+	}
+	for _, l := range it.Links {
+		if l = strings.TrimSpace(l); l != "" {
+			e.Link = l
+			break
+		}
+	}
+	e.Link = firstNonEmpty(e.Link, it.About)
+	e.GUID = firstNonEmpty(strings.TrimSpace(it.GUID), e.Link, strings.TrimSpace(it.About), e.Title)
+	if e.DOI == "" && doiPrefix.MatchString(strings.TrimSpace(it.Identifier)) {
+		e.DOI = it.Identifier
+	}
+	for _, enc := range it.Enclosures {
+		if enc.Type == "application/pdf" && e.PDFURL == "" {
+			e.PDFURL = strings.TrimSpace(enc.URL)
+		}
+	}
+
+	// Some feeds give each author an element, others all of them in one.
+	switch {
+	case len(it.Creators) > 1:
+		for _, c := range it.Creators {
+			if name := plainText(c); name != "" {
+				e.Authors = append(e.Authors, name)
+			}
+		}
+	case len(it.Creators) == 1:
+		e.Authors = splitAuthors(it.Creators[0])
+	case it.Author != "":
+		e.Authors = splitAuthors(it.Author)
+	}
+	return e
+}
+
+func atomEntryOf(a atomEntry) Entry {
+	e := Entry{
+		Title:		plainText(a.Title),
+		Summary:	plainText(firstNonEmpty(a.Summary, a.Content)),
+		DOI:		a.DOI,
+		Published:	parseDate(firstNonEmpty(a.Published, a.Updated)),
+	}
+	for _, l := range a.Links {
+		switch {
+		case l.Type == "application/pdf" || l.Title == "pdf":
+			if e.PDFURL == "" {
+				e.PDFURL = strings.TrimSpace(l.Href)
+			}
+		case l.Rel == "" || l.Rel == "alternate":
+			if e.Link == "" {
+				e.Link = strings.TrimSpace(l.Href)
+			}
+		}
+	}
+	e.GUID = firstNonEmpty(strings.TrimSpace(a.ID), e.Link, e.Title)
+	for _, au := range a.Authors {
+		if name := plainText(au.Name); name != "" {
+			e.Authors = append(e.Authors, name)
+		}
+	}
+	return e
+}
+
+// identify fills in the DOI and arXiv ID of an entry from its links and GUID,
+// which for arXiv and many publishers are doi.org or arxiv.org addresses.
+func identify(e *Entry) {
+	e.DOI = strings.ToLower(doiPrefix.ReplaceAllString(strings.TrimSpace(e.DOI), ""))
+	for _, s := range []string{e.Link, e.GUID, e.PDFURL} {
+		if e.DOI != "" && e.ArXivID != "" {
+			break
+		}
+		src, err := fetch.ParseSource(s)
+		if err != nil {
+			continue
+		}
+		if e.DOI == "" {
+			e.DOI = src.DOI
+		}
+		if e.ArXivID == "" {
+			e.ArXivID = src.ArXivID
+		}
+	}
+
+	if len(e.GUID) > maxGUIDLength {
+		sum := sha256.Sum256([]byte(e.GUID))
+		e.GUID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+}
+
+// plainText strips the markup that feeds put into titles and summaries.
+func plainText(s string) string {
+	s = markup.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// splitAuthors splits a list of names, as RSS feeds give them in one element.
+func splitAuthors(s string) []string {
+	s = plainText(s)
+	sep := ","
+	if strings.Contains(s, ";") {
+		sep = ";"
+	}
+
+	var names []string
+	for _, name := range strings.Split(strings.ReplaceAll(s, " and ", sep), sep) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package feeds
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+
+func TestParseRSS2(t *testing.T) {
+	checkEntries(t, "rss2.xml", []Entry{
+		{
+			GUID:		"jmlr-v25-23-0412",
+			Title:		"Scaling Laws for Sparse Mixture-of-Experts Models",
+			Link:		"https://jmlr.example.org/papers/v25/23-0412.html",
+			PDFURL:		"https://jmlr.example.org/papers/volume25/23-0412/23-0412.pdf",
+			Summary:	"We study how the loss of sparse models scales with the number of experts.",
+			Authors:	[]string{"Ada Lovelace", "Alan Turing"},
+			DOI:		"10.5555/jmlr.2024.0412",
+			Published:	time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			GUID:		"https://doi.org/10.5555/jmlr.2024.0399",
+			Title:		"On the Convergence of Adam & Beyond",
+			Link:		"https://doi.org/10.5555/jmlr.2024.0399",
+			Summary:	"A counterexample and a fix.",
+			Authors:	[]string{"Grace Hopper", "Edsger Dijkstra"},
+			DOI:		"10.5555/jmlr.2024.0399",
+			Published:	time.Date(2024, 3, 4, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			GUID:		"https://arxiv.org/abs/2403.01234v2",
+			Title:		"Preprint: Diffusion Models Revisited",
+			Link:		"https://arxiv.org/abs/2403.01234v2",
+			Summary:	"Also on arXiv.",
+			Authors:	[]string{"Emmy Noether", "Sofia Kovalevskaya"},
+			ArXivID:	"2403.01234",
+			Published:	time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+		},
+	})
+}
+
+func TestParseAtom(t *testing.T) {
+	checkEntries(t, "atom.xml", []Entry{
+		{
+			GUID:		"tag:lab.example.edu,2024:pub-17",
+			Title:		"Robust Graph Neural Networks",
+			Link:		"https://lab.example.edu/publications/17",
+			PDFURL:		"https://lab.example.edu/publications/17.pdf",
+			Summary:	"Graph networks that withstand & recover from edge noise.",
+			Authors:	[]string{"Marie Curie", "Pierre Curie"},
+			Published:	time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			GUID:		"tag:lab.example.edu,2024:pub-16",
+			Title:		"A Note Without a Summary",
+			Link:		"https://doi.org/10.5555/Lab.2024.16",
+			Summary:	"Only the content is given.",
+			DOI:		"10.5555/lab.2024.16",
+			Published:	time.Date(2024, 2, 20, 8, 0, 0, 0, time.UTC),
+		},
+	})
+}
+
+// arXiv feeds are answers of the arXiv API, Atom with the DOI in its own
+// namespace and the PDF as a link titled "pdf".
+func TestParseArXiv(t *testing.T) {
+	checkEntries(t, "arxiv.xml", []Entry{
+		{
+			GUID:		"http://arxiv.org/abs/2401.00003v1",
+			Title:		"Contrastive Pretraining for Tabular Data",
+			Link:		"http://arxiv.org/abs/2401.00003v1",
+			PDFURL:		"http://arxiv.org/pdf/2401.00003v1",
+			Summary:	"We pretrain tabular encoders with a contrastive objective.",
+			Authors:	[]string{"Ada Lovelace"},
+			ArXivID:	"2401.00003",
+			Published:	time.Date(2024, 1, 3, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			GUID:		"http://arxiv.org/abs/2401.00002v2",
+			Title:		"Calibrated Uncertainty in Deep Ensembles",
+			Link:		"http://arxiv.org/abs/2401.00002v2",
+			PDFURL:		"http://arxiv.org/pdf/2401.00002v2",
+			Summary:	"Ensembles are calibrated after temperature scaling.",
+			Authors:	[]string{"Alan Turing", "Grace Hopper"},
+			DOI:		"10.5555/icml.2024.123",
+			ArXivID:	"2401.00002",
+			Published:	time.Date(2024, 1, 2, 17, 30, 0, 0, time.UTC),
+		},
+		{
+			GUID:		"http://arxiv.org/abs/2401.00001v1",
+			Title:		"Sparse Attention at Scale",
+			Link:		"http://arxiv.org/abs/2401.00001v1",
+			PDFURL:		"http://arxiv.org/pdf/2401.00001v1",
+			Summary:	"Attention that skips most of the sequence.",
+			Authors:	[]string{"Edsger Dijkstra"},
+			ArXivID:	"2401.00001",
+			Published:	time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	})
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"not xml at all",
+		`<?xml version="1.0"?><html><body>Not a feed</body></html>`,
+	} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrNotFeed) {
+			t.Errorf("Parse(%q): err %v, want ErrNotFeed", data, err)
+		}
+	}
+}
+
+func TestParseHashesLongGUIDs(t *testing.T) {
+	long := "https://example.org/" + strings.Repeat("a", maxGUIDLength)
+	entries, err := Parse([]byte(`<rss version="2.0"><channel><item><title>T</title><guid>` + long + `</guid></item></channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := entries[0].GUID; len(g) != len("sha256:")+64 || !strings.HasPrefix(g, "sha256:") {
+		t.Errorf("GUID %q, want a sha256 of the long one", g)
+	}
+}
+
+// checkEntries parses a feed in testdata and compares its entries with want.
+func checkEntries(t *testing.T, fixture string, want []Entry) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Published.Equal(want[i].Published) {
+			t.Errorf("entry %d: published %v, want %v", i, got[i].Published, want[i].Published)
+		}
+		got[i].Published, want[i].Published = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("entry %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/fetch"
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/notify"
+	"backend/internal/repository"
+)
+
+
+// Poller polls the feeds that are due in the background, one at a time, and
+// imports or holds in the inbox the entries it hasn't seen yet. What each feed has
+// seen lives in the database, so a restart loses nothing but the timer.
+type Poller struct {
+	Feeds			repository.FeedRepository
+	Docs			repository.DocumentRepository
+	Importer		*ingest.Importer
+	// Fetcher downloads both the feeds and their papers, which keeps them off
+	// private networks.
+	Fetcher			*fetch.Fetcher
+	// ArXivAPI is the base URL of the arXiv API, which arXiv feeds query.
+	ArXivAPI		string
+	// MaxFeedSize bounds the size of a feed document.
+	MaxFeedSize		int64
+	// Notifier, if set, tells the owner of a feed about the entries that scheduled
+	// polls imported or put in the inbox.
+	Notifier		*notify.Notifier
+
+	// mu serialises polls, so that a poll asked for through the API doesn't race
+	// the scheduler over the same entries.
+	mu				sync.Mutex
+}
+
+// PollResult counts what a poll did with the entries of a feed. Entries seen on
+// earlier polls are only counted in Entries.
+type PollResult struct {
+	NotModified		bool		`json:"not_modified"`
+	Entries			int			`json:"entries"`
+	New				int			`json:"new"`
+	Imported		int			`json:"imported"`
+	Inbox			int			`json:"inbox"`
+	Duplicates		int			`json:"duplicates"`
+	Skipped			int			`json:"skipped"`
+	Failed			int			`json:"failed"`
+}
+
+const (
+	// pollTick is how often the scheduler looks for feeds that are due.
+	pollTick		= time.Minute
+	// pollTimeout bounds one poll, the downloads of the papers it imports included.
+	pollTimeout		= 10 * time.Minute
+	// arXivResults is how many of the newest papers an arXiv feed asks for.
+	arXivResults	= 50
+)
+
+
+func NewPoller(feeds repository.FeedRepository, docs repository.DocumentRepository, importer *ingest.Importer, fetcher *fetch.Fetcher, arXivAPI string) *Poller {
+	log.Println("Initializing feed poller...")
+	return &Poller{Feeds: feeds, Docs: docs, Importer: importer, Fetcher: fetcher, ArXivAPI: strings.TrimRight(arXivAPI, "/"), MaxFeedSize: 10 << 20}
+}
+
+// Run polls the feeds that are due until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	log.Println("Feed poller started")
+	ticker := time.NewTicker(pollTick)
+	defer ticker.Stop()
+
+	for {
+		p.pollDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Feed poller stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) pollDue(ctx context.Context) {
+	ids, err := p.Feeds.Due(time.Now())
+	if err != nil {
+		log.Printf("Failed to list feeds due for polling: %v\n", err)
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		res, err := p.Poll(ctx, id)
+		if err != nil {
+			log.Printf("Polling feed ID=%d failed: %v\n", id, err)
+			continue
+		}
+		if res.New > 0 {
+			log.Printf("Polled feed ID=%d: %d new, %d imported, %d to the inbox, %d duplicates, %d skipped, %d failed\n",
+				id, res.New, res.Imported, res.Inbox, res.Duplicates, res.Skipped, res.Failed)
+		}
+		if res.Imported+res.Inbox > 0 && p.Notifier != nil {
+			p.notify(ctx, id, res)
+		}
+	}
+}
+
+func (p *Poller) notify(ctx context.Context, feedID uint, res PollResult) {
+	feed, err := p.Feeds.GetByID(feedID)
+	if err != nil {
+		log.Printf("Failed to notify of feed ID=%d: %v\n", feedID, err)
+		return
+	}
+
+	var parts []string
+	if res.Imported > 0 {
+		parts = append(parts, fmt.Sprintf("%d imported", res.Imported))
+	}
+	if res.Inbox > 0 {
+		parts = append(parts, fmt.Sprintf("%d waiting in the inbox", res.Inbox))
+	}
+	title := fmt.Sprintf("%d new entries from %s", res.Imported+res.Inbox, feed.Title)
+	if res.Imported+res.Inbox == 1 {
+		title = "1 new entry from " + feed.Title
+	}
+	id := feed.ID
+	note := &model.Notification{
+		UserID:		feed.UserID,
+		Kind:		model.NotificationFeedEntries,
+		Title:		truncate(title, 255),
+		Body:		fmt.Sprintf("The feed %q of workspace ID %d has new entries: %s.\n", feed.Title, feed.WorkspaceID, strings.Join(parts, ", ")),
+		FeedID:		&id,
+	}
+	if err := p.Notifier.Notify(ctx, note, notify.Delivery{}); err != nil {
+		log.Printf("Failed to notify of feed ID=%d: %v\n", feedID, err)
+	}
+}
+
+// Poll downloads the feed now and handles its new entries. The next poll is due
+// an interval later whether or not this one succeeded; its error is kept on the
+// feed.
+func (p *Poller) Poll(ctx context.Context, feedID uint) (PollResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	feed, err := p.Feeds.GetByID(feedID)
+	if err != nil {
+		return PollResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+	res, err := p.poll(ctx, &feed)
+
+	now := time.Now()
+	feed.LastPolledAt = &now
+	feed.NextPollAt = now.Add(time.Duration(feed.IntervalMinutes) * time.Minute)
+	feed.LastError = ""
+	if err != nil {
+		feed.LastError = truncate(err.Error(), 255)
+	}
+	if saveErr := p.Feeds.SavePollState(&feed); saveErr != nil && !errors.Is(saveErr, repository.ErrNotFound) {
+		log.Printf("Failed to save the state of feed ID=%d: %v\n", feedID, saveErr)
+	}
+	return res, err
+}
+
+func (p *Poller) poll(ctx context.Context, feed *model.Feed) (PollResult, error) {
+	var res PollResult
+	entries, err := p.download(ctx, feed)
+	if errors.Is(err, errNotModified) {
+		res.NotModified = true
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	res.Entries = len(entries)
+
+	guids := make([]string, len(entries))
+	for i, e := range entries {
+		guids[i] = e.GUID
+	}
+	seen, err := p.Feeds.SeenGUIDs(feed.ID, guids)
+	if err != nil {
+		return res, fmt.Errorf("look up seen entries: %w", err)
+	}
+
+	// Oldest first, so that documents are imported in the order they appeared.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Published.Before(entries[j].Published) })
+	first := feed.LastPolledAt == nil
+	keywords := splitKeywords(feed.Keywords)
+
+	for _, e := range entries {
+		if seen[e.GUID] {
+			continue
+		}
+		seen[e.GUID] = true
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+
+		item := newItem(feed, e)
+		switch {
+		case first && !feed.Backfill:
+			item.Status = model.FeedItemSkipped
+		case !matches(e, keywords):
+			item.Status = model.FeedItemSkipped
+		case p.findDuplicate(&item):
+		case feed.Mode == model.FeedModeImport:
+			p.importItem(ctx, &item)
+		default:
+			item.Status = model.FeedItemInbox
+		}
+
+		if err := p.Feeds.CreateItem(&item); err != nil {
+			return res, fmt.Errorf("save entry: %w", err)
+		}
+		res.New++
+		switch item.Status {
+		case model.FeedItemImported:
+			res.Imported++
+		case model.FeedItemInbox:
+			res.Inbox++
+		case model.FeedItemDuplicate:
+			res.Duplicates++
+		case model.FeedItemSkipped:
+			res.Skipped++
+		case model.FeedItemFailed:
+			res.Failed++
+		}
+	}
+	return res, nil
+}
+
+var errNotModified = errors.New("feed not modified")
+
+// download gets the feed, sending the validators of the last response so that an
+// unchanged feed costs a 304. The validators of this response are set on feed.
+func (p *Poller) download(ctx context.Context, feed *model.Feed) ([]Entry, error) {
+	u := feed.URL
+	if feed.Kind == model.FeedKindArXiv {
+		u = p.arXivQueryURL(feed.Query)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/atom+xml, application/rss+xml, application/rdf+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.1")
+	if feed.ETag != "" {
+		req.Header.Set("If-None-Match", feed.ETag)
+	}
+	if feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	resp, err := p.Fetcher.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &fetch.StatusError{URL: u, Status: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.MaxFeedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.MaxFeedSize {
+		return nil, fetch.ErrTooLarge
+	}
+	entries, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	feed.ETag = truncate(resp.Header.Get("ETag"), 255)
+	feed.LastModified = truncate(resp.Header.Get("Last-Modified"), 64)
+	return entries, nil
+}
+
+// arXivQueryURL asks the arXiv API for the newest papers matching the query.
+func (p *Poller) arXivQueryURL(query string) string {
+	q := url.Values{
+		"search_query":	{query},
+		"sortBy":		{"submittedDate"},
+		"sortOrder":	{"descending"},
+		"max_results":	{fmt.Sprint(arXivResults)},
+	}
+	return p.ArXivAPI + "/query?" + q.Encode()
+}
+
+// Accept imports an entry waiting in the inbox, or one that failed to import
+// before. The entry is returned with what became of it; the error is that of the
+// download or import when it failed.
+func (p *Poller) Accept(ctx context.Context, itemID uint) (model.FeedItem, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	item, err := p.Feeds.GetItem(itemID)
+	if err != nil {
+		return item, err
+	}
+	if item.Status != model.FeedItemInbox && item.Status != model.FeedItemFailed {
+		return item, &repository.ConflictError{Resource: "feed item", Message: "Only entries in the inbox or that failed to import can be accepted"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+	var importErr error
+	if !p.findDuplicate(&item) {
+		importErr = p.importItem(ctx, &item)
+	}
+	if err := p.Feeds.UpdateItem(&item); err != nil {
+		return item, err
+	}
+	return item, importErr
+}
+
+// Dismiss takes an entry out of the inbox without importing it.
+func (p *Poller) Dismiss(itemID uint) (model.FeedItem, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	item, err := p.Feeds.GetItem(itemID)
+	if err != nil {
+		return item, err
+	}
+	if item.Status != model.FeedItemInbox && item.Status != model.FeedItemFailed {
+		return item, &repository.ConflictError{Resource: "feed item", Message: "Only entries in the inbox or that failed to import can be dismissed"}
+	}
+	item.Status = model.FeedItemDismissed
+	return item, p.Feeds.UpdateItem(&item)
+}
+
+// findDuplicate marks the item as a duplicate if the library already has a
+// document with its DOI, arXiv ID or link.
+func (p *Poller) findDuplicate(item *model.FeedItem) bool {
+	doc, err := p.Docs.FindBySource(item.UserID, item.DOI, item.ArXivID, item.Link)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Failed to look up entry %q of feed ID=%d in the library: %v\n", item.GUID, item.FeedID, err)
+		}
+		return false
+	}
+	item.Status = model.FeedItemDuplicate
+	item.DocumentID = &doc.ID
+	return true
+}
+
+// importItem downloads the paper of an entry and imports it into the feed's
+// workspace, setting the item's status to the outcome.
+func (p *Poller) importItem(ctx context.Context, item *model.FeedItem) error {
+	src := itemSource(item)
+	err := p.fetchAndCreate(ctx, item, src)
+
+	var dup *ingest.DuplicateError
+	switch {
+	case errors.As(err, &dup):
+		item.Status, item.DocumentID, item.Error = model.FeedItemDuplicate, &dup.Existing.ID, ""
+		return nil
+	case err != nil:
+		log.Printf("Importing entry %q of feed ID=%d from %s failed: %v\n", item.GUID, item.FeedID, src.URL, err)
+		item.Status, item.Error = model.FeedItemFailed, truncate(err.Error(), 255)
+		return err
+	}
+	item.Status, item.Error = model.FeedItemImported, ""
+	return nil
+}
+
+func (p *Poller) fetchAndCreate(ctx context.Context, item *model.FeedItem, src fetch.Source) error {
+	dl, err := p.Fetcher.Fetch(ctx, src)
+	if err != nil {
+		return err
+	}
+	file, err := p.Importer.Stash(dl.Body, dl.Filename)
+	dl.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	meta := ingest.Metadata{
+		UserID:			item.UserID,
+		WorkspaceID:	item.WorkspaceID,
+		Title:			truncate(firstNonEmpty(item.Title, dl.Title, file.Filename), 255),
+		SourceURL:		firstNonEmpty(item.Link, src.URL),
+		DOI:			item.DOI,
+		ArXivID:		item.ArXivID,
+	}
+	if item.PublishedAt != nil {
+		meta.Year = item.PublishedAt.Year()
+	}
+	for _, name := range strings.Split(item.Authors, ";") {
+		if name = strings.TrimSpace(name); name != "" {
+			meta.Authors = append(meta.Authors, model.DocumentAuthor{Name: truncate(name, 255), Position: len(meta.Authors)})
+		}
+	}
+
+	doc, err := p.Importer.Create(file, meta, false)
+	if err != nil {
+		return err
+	}
+	item.DocumentID = &doc.ID
+	return nil
+}
+
+// itemSource is where the paper of an entry is downloaded from: arXiv for arXiv
+// papers, a PDF the feed links to, an open-access copy or the publisher for a DOI,
+// or else the entry's link, whose landing page is searched for the PDF.
+func itemSource(item *model.FeedItem) fetch.Source {
+	switch {
+	case item.ArXivID != "":
+		return fetch.Source{URL: "https://arxiv.org/abs/" + item.ArXivID, ArXivID: item.ArXivID}
+	case item.PDFURL != "":
+		return fetch.Source{URL: item.PDFURL}
+	case item.DOI != "":
+		return fetch.Source{URL: "https://doi.org/" + item.DOI, DOI: item.DOI}
+	}
+	return fetch.Source{URL: item.Link}
+}
+
+func newItem(feed *model.Feed, e Entry) model.FeedItem {
+	item := model.FeedItem{
+		FeedID:			feed.ID,
+		GUID:			e.GUID,
+		UserID:			feed.UserID,
+		WorkspaceID:	feed.WorkspaceID,
+		Title:			truncate(e.Title, 512),
+		Link:			truncate(e.Link, 2048),
+		PDFURL:			truncate(e.PDFURL, 2048),
+		Summary:		truncate(e.Summary, 10000),
+		Authors:		truncate(strings.Join(e.Authors, "; "), 2000),
+		DOI:			truncate(e.DOI, 255),
+		ArXivID:		truncate(e.ArXivID, 32),
+	}
+	if !e.Published.IsZero() {
+		published := e.Published
+		item.PublishedAt = &published
+	}
+	return item
+}
+
+// splitKeywords splits the comma-separated keywords of a feed, lowercased.
+func splitKeywords(s string) []string {
+	var keywords []string
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+// matches reports whether the entry mentions one of the keywords in its title or
+// summary. Every entry matches no keywords.
+func matches(e Entry, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(e.Title + " " + e.Summary)
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,311 @@
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/internal/fetch"
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/storage"
+)
+
+
+// feedServer stands in for a feed publisher and for arXiv. It serves the fixture
+// in feed as the RSS feed and as the answer of the arXiv API, with the fixture's
+// name as ETag, and testdata/paper.pdf, with the placeholder 0123456789 replaced by
+// the arXiv ID so that every paper has its own content, as arXiv PDFs.
+type feedServer struct {
+	*httptest.Server
+	mu			sync.Mutex
+	feed		string
+	pdfs		[]string
+}
+
+// fakeFeeds keeps a single feed and its entries in memory.
+type fakeFeeds struct {
+	repository.FeedRepository
+	feed		model.Feed
+	items		[]model.FeedItem
+}
+
+// fakeDocs is a library in memory, enough for the poller and the importer.
+type fakeDocs struct {
+	repository.DocumentRepository
+	docs		[]model.Document
+}
+
+type fakeBlobs struct{}
+
+
+func newFeedServer(t *testing.T, feed string) *feedServer {
+	pdf, err := os.ReadFile(filepath.Join("testdata", "paper.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &feedServer{feed: feed}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/feed.xml" || r.URL.Path == "/api/query":
+			etag := `"` + s.feed + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			body, err := os.ReadFile(filepath.Join("testdata", s.feed))
+			if err != nil {
+				t.Errorf("fixture %s: %v", s.feed, err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Type", "application/atom+xml; charset=UTF-8")
+			w.Write(body)
+		case strings.HasPrefix(r.URL.Path, "/pdf/") && len(r.URL.Path) == len("/pdf/0123456789"):
+			id := strings.TrimPrefix(r.URL.Path, "/pdf/")
+			s.pdfs = append(s.pdfs, id)
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(bytes.ReplaceAll(pdf, []byte("0123456789"), []byte(id)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// serve makes the server answer with another fixture from now on.
+func (s *feedServer) serve(feed string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feed = feed
+}
+
+// PDFs returns the arXiv IDs of the papers downloaded so far.
+func (s *feedServer) PDFs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.pdfs...)
+}
+
+func (f *fakeFeeds) GetByID(id uint) (model.Feed, error) {
+	if id != f.feed.ID {
+		return model.Feed{}, &repository.NotFoundError{Resource: "feed", ID: id}
+	}
+	return f.feed, nil
+}
+
+func (f *fakeFeeds) SavePollState(feed *model.Feed) error {
+	f.feed = *feed
+	return nil
+}
+
+func (f *fakeFeeds) SeenGUIDs(feedID uint, guids []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	for _, item := range f.items {
+		if item.FeedID == feedID {
+			seen[item.GUID] = true
+		}
+	}
+	return seen, nil
+}
+
+func (f *fakeFeeds) CreateItem(item *model.FeedItem) error {
+	item.ID = uint(len(f.items) + 1)
+	f.items = append(f.items, *item)
+	return nil
+}
+
+// statuses maps the arXiv ID, or else the GUID, of each entry to its status.
+func (f *fakeFeeds) statuses() map[string]string {
+	m := make(map[string]string)
+	for _, item := range f.items {
+		key := item.ArXivID
+		if key == "" {
+			key = item.GUID
+		}
+		m[key] = item.Status
+	}
+	return m
+}
+
+func (d *fakeDocs) FindBySource(userID uint, doi, arXivID, sourceURL string) (model.Document, error) {
+	for _, doc := range d.docs {
+		if doc.UserID != userID {
+			continue
+		}
+		if (doi != "" && doc.DOI == doi) || (arXivID != "" && doc.ArXivID == arXivID) || (sourceURL != "" && doc.SourceURL == sourceURL) {
+			return doc, nil
+		}
+	}
+	return model.Document{}, &repository.NotFoundError{Resource: "document"}
+}
+
+func (d *fakeDocs) GetByContentHash(userID uint, hash string) (model.Document, error) {
+	for _, doc := range d.docs {
+		if doc.UserID == userID && doc.ContentHash == hash {
+			return doc, nil
+		}
+	}
+	return model.Document{}, &repository.NotFoundError{Resource: "document"}
+}
+
+func (d *fakeDocs) Save(doc *model.Document) error {
+	doc.ID = uint(len(d.docs) + 1)
+	d.docs = append(d.docs, *doc)
+	return nil
+}
+
+func (d *fakeDocs) LinkReferences(doc *model.Document) (int, error) {
+	return 0, nil
+}
+
+func (fakeBlobs) Acquire(hash string, size int64) error {
+	return nil
+}
+
+func (fakeBlobs) Release(hash string) (int, error) {
+	return 0, nil
+}
+
+func newTestPoller(t *testing.T, srv *feedServer, feeds *fakeFeeds, docs *fakeDocs) *Poller {
+	fetcher := &fetch.Fetcher{Client: srv.Client(), MaxPageSize: 1 << 20, DOIURL: srv.URL, ArXivURL: srv.URL}
+	importer := ingest.NewImporter(docs, storage.NewStore(t.TempDir(), fakeBlobs{}), ingest.Limits{})
+	return NewPoller(feeds, docs, importer, fetcher, srv.URL+"/api/")
+}
+
+// An arXiv feed that imports: papers already in the library are duplicates, the
+// others are downloaded once, and later polls take only the new papers.
+func TestPollImportsNewEntries(t *testing.T) {
+	srv := newFeedServer(t, "arxiv.xml")
+	feeds := &fakeFeeds{feed: model.Feed{ID: 7, UserID: 1, WorkspaceID: 3, Kind: model.FeedKindArXiv, Query: "cat:cs.LG", Mode: model.FeedModeImport, Backfill: true, IntervalMinutes: 60}}
+	docs := &fakeDocs{docs: []model.Document{{ID: 1, UserID: 1, ArXivID: "2401.00002"}}}
+	p := newTestPoller(t, srv, feeds, docs)
+	ctx := context.Background()
+
+	res, err := p.Poll(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PollResult{Entries: 3, New: 3, Imported: 2, Duplicates: 1}); res != want {
+		t.Errorf("first poll: %+v, want %+v", res, want)
+	}
+	if got, want := srv.PDFs(), []string{"2401.00001", "2401.00003"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("downloaded %v, want %v, oldest first", got, want)
+	}
+	if got, want := feeds.statuses(), map[string]string{
+		"2401.00001":	model.FeedItemImported,
+		"2401.00002":	model.FeedItemDuplicate,
+		"2401.00003":	model.FeedItemImported,
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+	for _, item := range feeds.items {
+		if item.DocumentID == nil {
+			t.Errorf("entry %s has no document", item.ArXivID)
+		}
+	}
+	if n := len(docs.docs); n != 3 {
+		t.Errorf("library has %d documents, want 3", n)
+	}
+	if feeds.feed.LastPolledAt == nil || feeds.feed.ETag != `"arxiv.xml"` || feeds.feed.LastError != "" {
+		t.Errorf("poll state not saved: %+v", feeds.feed)
+	}
+
+	res, err = p.Poll(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PollResult{NotModified: true}); res != want {
+		t.Errorf("unchanged feed: %+v, want %+v", res, want)
+	}
+
+	srv.serve("arxiv_next.xml")
+	res, err = p.Poll(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PollResult{Entries: 4, New: 1, Imported: 1}); res != want {
+		t.Errorf("changed feed: %+v, want %+v", res, want)
+	}
+	if got, want := srv.PDFs(), []string{"2401.00001", "2401.00003", "2401.00004"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("downloaded %v, want %v", got, want)
+	}
+	if n := len(feeds.items); n != 4 {
+		t.Errorf("%d entries recorded, want 4", n)
+	}
+}
+
+// A feed with an inbox downloads nothing; the entries wait there, except those
+// the library already has.
+func TestPollHoldsEntriesInTheInbox(t *testing.T) {
+	srv := newFeedServer(t, "rss2.xml")
+	feeds := &fakeFeeds{feed: model.Feed{ID: 2, UserID: 1, WorkspaceID: 3, Kind: model.FeedKindRSS, URL: srv.URL + "/feed.xml", Mode: model.FeedModeInbox, Backfill: true, IntervalMinutes: 60}}
+	docs := &fakeDocs{docs: []model.Document{
+		{ID: 1, UserID: 1, DOI: "10.5555/jmlr.2024.0399"},
+		{ID: 2, UserID: 9, ArXivID: "2403.01234"},
+	}}
+	p := newTestPoller(t, srv, feeds, docs)
+
+	res, err := p.Poll(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PollResult{Entries: 3, New: 3, Inbox: 2, Duplicates: 1}); res != want {
+		t.Errorf("%+v, want %+v", res, want)
+	}
+	if got, want := feeds.statuses(), map[string]string{
+		"jmlr-v25-23-0412":							model.FeedItemInbox,
+		"https://doi.org/10.5555/jmlr.2024.0399":	model.FeedItemDuplicate,
+		"2403.01234":								model.FeedItemInbox,
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries %v, want %v", got, want)
+	}
+	if pdfs := srv.PDFs(); len(pdfs) != 0 {
+		t.Errorf("downloaded %v for the inbox", pdfs)
+	}
+	if n := len(docs.docs); n != 2 {
+		t.Errorf("library has %d documents, want 2", n)
+	}
+}
+
+// Without backfill the entries already in a feed when it is first polled are
+// skipped, and only later ones are taken.
+func TestPollSkipsEntriesBeforeTheFirstPoll(t *testing.T) {
+	srv := newFeedServer(t, "arxiv.xml")
+	feeds := &fakeFeeds{feed: model.Feed{ID: 7, UserID: 1, WorkspaceID: 3, Kind: model.FeedKindArXiv, Query: "cat:cs.LG", Mode: model.FeedModeInbox, IntervalMinutes: 60}}
+	p := newTestPoller(t, srv, feeds, &fakeDocs{})
+	ctx := context.Background()
+
+	res, err := p.Poll(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PollResult{Entries: 3, New: 3, Skipped: 3}); res != want {
+		t.Errorf("first poll: %+v, want %+v", res, want)
+	}
+
+	srv.serve("arxiv_next.xml")
+	res, err = p.Poll(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PollResult{Entries: 4, New: 1, Inbox: 1}); res != want {
+		t.Errorf("second poll: %+v, want %+v", res, want)
+	}
+	if got := feeds.statuses()["2401.00004"]; got != model.FeedItemInbox {
+		t.Errorf("new entry %q, want %q", got, model.FeedItemInbox)
+	}
+}
@@ -0,0 +1,276 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"backend/internal/scholar"
+)
+
+
+// Source is what a user asked to import: a URL, a DOI or an arXiv ID. URL is
+// where the document is fetched from first, and also the source recorded on it.
+type Source struct {
+	URL			string
+	DOI			string
+	ArXivID		string
+}
+
+// Fetcher downloads the PDF behind a Source. Pages it gets instead of a PDF, such
+// as a publisher's landing page, are searched for a link to the PDF.
+type Fetcher struct {
+	Client			*http.Client
+	// Resolvers find open-access copies of DOIs before the publisher's site is
+	// tried. They may be empty.
+	Resolvers		[]scholar.Resolver
+	// MaxPageSize bounds the HTML read from a page while looking for a PDF link.
+	MaxPageSize		int64
+	DOIURL			string
+	ArXivURL		string
+}
+
+// Download is a PDF being downloaded. Body must be closed.
+type Download struct {
+	Body			io.ReadCloser
+	// URL is the address the PDF finally came from, after redirects.
+	URL				string
+	Filename		string
+	// Title is the title the landing page gave the document, if any.
+	Title			string
+}
+
+// StatusError is an unsuccessful response from the source.
+type StatusError struct {
+	URL				string
+	Status			int
+}
+
+var (
+	ErrInvalidSource	= errors.New("not a URL, DOI or arXiv ID")
+	ErrNoPDF			= errors.New("no PDF found at the source")
+	ErrTooLarge			= errors.New("response is too large")
+)
+
+var (
+	doiSource		= regexp.MustCompile(`(?i)^(?:doi:\s*)?(10\.\d{4,9}/\S+)$`)
+	arXivSource		= regexp.MustCompile(`(?i)^(?:arxiv:\s*)?(\d{4}\.\d{4,5}(?:v\d+)?|[a-z-]+(?:\.[a-z]{2})?/\d{7}(?:v\d+)?)$`)
+	// arXivPath matches the paths of arXiv abstract and PDF pages.
+	arXivPath		= regexp.MustCompile(`^/(?:abs|pdf)/(.+?)(?:\.pdf)?$`)
+	arXivVersion	= regexp.MustCompile(`v\d+$`)
+	metaTag			= regexp.MustCompile(`(?is)<(?:meta|link)\s[^>]*>`)
+	tagAttr			= regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// sniffLength matches what the importer reads to recognise a PDF.
+const sniffLength = 1024
+
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.URL, e.Status)
+}
+
+// ParseSource recognises a DOI, with or without "doi:" or a doi.org URL, an arXiv
+// ID, with or without "arXiv:" or an arxiv.org URL, or else an http(s) URL.
+func ParseSource(s string) (Source, error) {
+	s = strings.TrimSpace(s)
+	if m := doiSource.FindStringSubmatch(s); m != nil {
+		return Source{URL: "https://doi.org/" + m[1], DOI: strings.ToLower(m[1])}, nil
+	}
+	if m := arXivSource.FindStringSubmatch(s); m != nil {
+		return arXivSourceOf(m[1]), nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Source{}, ErrInvalidSource
+	}
+	u.Fragment = ""
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "doi.org" || host == "dx.doi.org":
+		if doi, err := url.PathUnescape(strings.TrimPrefix(u.Path, "/")); err == nil && doiSource.MatchString(doi) {
+			return Source{URL: "https://doi.org/" + doi, DOI: strings.ToLower(doi)}, nil
+		}
+	case host == "arxiv.org" || strings.HasSuffix(host, ".arxiv.org"):
+		if m := arXivPath.FindStringSubmatch(u.Path); m != nil && arXivSource.MatchString(m[1]) {
+			return arXivSourceOf(m[1]), nil
+		}
+	}
+	return Source{URL: u.String()}, nil
+}
+
+func arXivSourceOf(id string) Source {
+	return Source{URL: "https://arxiv.org/abs/" + id, ArXivID: arXivVersion.ReplaceAllString(id, "")}
+}
+
+// Fetch starts downloading the PDF of src. arXiv papers come straight from
+// arXiv. For DOIs an open-access copy found by the resolvers is tried first,
+// then the publisher's site through doi.org.
+func (f *Fetcher) Fetch(ctx context.Context, src Source) (*Download, error) {
+	var candidates []string
+	switch {
+	case src.ArXivID != "":
+		candidates = append(candidates, strings.TrimRight(f.ArXivURL, "/")+"/pdf/"+src.ArXivID)
+	case src.DOI != "":
+		if len(f.Resolvers) > 0 {
+			md, _, err := scholar.Lookup(ctx, f.Resolvers, scholar.Identifier{DOI: src.DOI})
+			if err == nil && md.OpenAccessURL != "" {
+				candidates = append(candidates, md.OpenAccessURL)
+			} else if err != nil && !errors.Is(err, scholar.ErrNotFound) {
+				log.Printf("Looking up an open-access copy of doi:%s failed: %v\n", src.DOI, err)
+			}
+		}
+		candidates = append(candidates, strings.TrimRight(f.DOIURL, "/")+"/"+src.DOI)
+	default:
+		candidates = append(candidates, src.URL)
+	}
+
+	var err error
+	for _, u := range candidates {
+		var dl *Download
+		dl, err = f.fetch(ctx, u, true)
+		if err == nil {
+			return dl, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("Fetching %s: %v\n", u, err)
+	}
+	return nil, err
+}
+
+// fetch gets rawURL and returns it if it is a PDF. An HTML page is searched for a
+// link to its PDF, which is followed if follow is set.
+func (f *Fetcher) fetch(ctx context.Context, rawURL string, follow bool) (*Download, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/pdf, text/html;q=0.9, */*;q=0.1")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{URL: rawURL, Status: resp.StatusCode}
+	}
+
+	br := bufio.NewReaderSize(resp.Body, sniffLength)
+	head, _ := br.Peek(sniffLength)
+	final := resp.Request.URL
+	if bytes.Contains(head, []byte("%PDF-")) {
+		return &Download{
+			Body:		struct{ io.Reader; io.Closer }{br, resp.Body},
+			URL:		final.String(),
+			Filename:	filename(resp, final),
+		}, nil
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !follow || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, ErrNoPDF
+	}
+	page, err := io.ReadAll(io.LimitReader(br, f.MaxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	link, title := pdfLink(page)
+	if link == "" {
+		return nil, ErrNoPDF
+	}
+	target, err := final.Parse(link)
+	if err != nil {
+		return nil, ErrNoPDF
+	}
+
+	dl, err := f.fetch(ctx, target.String(), false)
+	if err != nil {
+		return nil, err
+	}
+	dl.Title = title
+	return dl, nil
+}
+
+// Image downloads the image at rawURL, of at most max bytes, to be embedded in the
+// snapshot of a clipped web page.
+func (f *Fetcher) Image(ctx context.Context, rawURL string, max int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: rawURL, Status: resp.StatusCode}
+	}
+	if resp.ContentLength > max {
+		return nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// pdfLink finds the PDF a landing page links to, from the citation_pdf_url tag
+// that publishers add for Google Scholar or a link to an alternate PDF, and the
+// citation_title of the page.
+func pdfLink(page []byte) (string, string) {
+	var link, title string
+	for _, tag := range metaTag.FindAll(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range tagAttr.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(m[1]))] = html.UnescapeString(string(m[2]) + string(m[3]) + string(m[4]))
+		}
+		switch {
+		case strings.EqualFold(attrs["name"], "citation_pdf_url") && link == "":
+			link = attrs["content"]
+		case strings.EqualFold(attrs["name"], "citation_title") && title == "":
+			title = strings.Join(strings.Fields(attrs["content"]), " ")
+		case strings.EqualFold(attrs["rel"], "alternate") && strings.EqualFold(attrs["type"], "application/pdf") && link == "":
+			link = attrs["href"]
+		}
+	}
+	return strings.TrimSpace(link), title
+}
+
+// filename names a download after its Content-Disposition or else its URL.
+func filename(resp *http.Response, u *url.URL) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return params["filename"]
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = u.Hostname()
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".pdf") {
+		name += ".pdf"
+	}
+	return name
+}
@@ -0,0 +1,153 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+
+// Guard decides which addresses the server may connect to on behalf of a user.
+// Addresses inside the server's own networks are blocked, so that a URL can't
+// reach the database, cloud metadata endpoints or other internal services.
+// AllowNets and AllowHosts let an operator open up specific internal sources.
+type Guard struct {
+	AllowNets		[]netip.Prefix
+	AllowHosts		[]string
+}
+
+// BlockedError reports a connection refused by the Guard.
+type BlockedError struct {
+	Host			string
+	Addr			netip.Addr
+}
+
+// blockedNets are the special-purpose ranges that aren't covered by the netip
+// predicates in blocked.
+var blockedNets = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+const maxRedirects = 5
+
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("connection to %s (%s) is not allowed", e.Host, e.Addr)
+}
+
+// ParseGuard reads a comma-separated allowlist of CIDR ranges, IP addresses and
+// host names.
+func ParseGuard(allow string) (*Guard, error) {
+	g := &Guard{}
+	for _, entry := range strings.Split(allow, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, err
+			}
+			g.AllowNets = append(g.AllowNets, p.Masked())
+		default:
+			if a, err := netip.ParseAddr(entry); err == nil {
+				g.AllowNets = append(g.AllowNets, netip.PrefixFrom(a, a.BitLen()))
+			} else {
+				g.AllowHosts = append(g.AllowHosts, strings.ToLower(entry))
+			}
+		}
+	}
+	return g, nil
+}
+
+// NewClient returns an HTTP client whose every connection, redirects included, is
+// checked by g after the host name has been resolved, so DNS can't be used to
+// point an allowed name at a blocked address. Requests time out after timeout,
+// reading the body included.
+func NewClient(g *Guard, timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		// A proxy would be the only address checked.
+		Proxy:					nil,
+		DialContext:			g.dialContext,
+		ForceAttemptHTTP2:		true,
+		TLSHandshakeTimeout:	10 * time.Second,
+		ResponseHeaderTimeout:	30 * time.Second,
+		MaxIdleConns:			10,
+		IdleConnTimeout:		90 * time.Second,
+	}
+	return &http.Client{
+		Transport:	transport,
+		Timeout:	timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+func (g *Guard) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if !g.allowsHost(host) {
+		dialer.Control = func(_, addr string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(addr)
+			if err != nil {
+				return err
+			}
+			if g.blocked(ap.Addr()) {
+				return &BlockedError{Host: host, Addr: ap.Addr().Unmap()}
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+func (g *Guard) allowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range g.AllowHosts {
+		if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Guard) blocked(a netip.Addr) bool {
+	a = a.Unmap()
+	for _, p := range g.AllowNets {
+		if p.Contains(a) {
+			return false
+		}
+	}
+	if a.IsLoopback() || a.IsPrivate() || a.IsUnspecified() || a.IsLinkLocalUnicast() ||
+		a.IsLinkLocalMulticast() || a.IsInterfaceLocalMulticast() || a.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedNets {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,16 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"backend/internal/mail"
+	"backend/internal/middleware"
 	"backend/internal/model"
 	"backend/internal/util"
 	"backend/internal/repository"
@@ -12,30 +18,93 @@ import (
 
 
 type AuthHandler struct {
-	UserRepo repository.UserRepository
+	UserRepo		repository.UserRepository
+	SessionRepo		repository.SessionRepository
+	ResetRepo		repository.PasswordResetRepository
+	// Mailer sends password reset links; without it resets are refused.
+	Mailer			*mail.Mailer
+	// ResetURL is the page of the web app that resets a password. The token is
+	// added to it as the token query parameter.
+	ResetURL		string
 }
 
 type AuthRequest struct {
+	Username		string		`json:"username" validate:"required,min=3,max=32,username"`
+	Password		string		`json:"password" validate:"required,password"`
+	Email			string		`json:"email,omitempty" validate:"required,max=254,email"`
+}
+
+type LoginRequest struct {
+	Username		string		`json:"username" validate:"required,max=32"`
+	Password		string		`json:"password" validate:"required,max=72"`
+}
+
+// LoginResponse carries the API token for the Authorization: Bearer header. The
+// token is only ever returned here.
+type LoginResponse struct {
+	Message			string		`json:"message"`
+	Token			string		`json:"token"`
+	ExpiresAt		time.Time	`json:"expires_at"`
+	UserID			uint		`json:"user_id"`
+}
+
+// ChangePasswordRequest changes the password of the logged-in user, who must know
+// the current one.
+type ChangePasswordRequest struct {
+	CurrentPassword	string		`json:"current_password" validate:"required,max=72"`
+	NewPassword		string		`json:"new_password" validate:"required,password"`
+}
+
+// ForgotPasswordRequest asks for a reset link to be mailed to an account's email.
+type ForgotPasswordRequest struct {
+	Email			string		`json:"email" validate:"required,max=254"`
+}
+
+// ResetPasswordRequest sets a new password with the token of a reset link.
+type ResetPasswordRequest struct {
+	Token			string		`json:"token" validate:"required,max=128"`
+	NewPassword		string		`json:"new_password" validate:"required,password"`
+}
+
+// UserResponse is the public view of a user; the password hash never leaves the server.
+type UserResponse struct {
+	ID				uint		`json:"id"`
 	Username		string		`json:"username"`
-	Password		string		`json:"password"`
-	Email			string		`json:"email,omitempty"`
+	Email			string		`json:"email"`
+	CreatedAt		time.Time	`json:"created_at"`
+	LastLoginAt		*time.Time	`json:"last_login_at"`
 }
 
+const sessionTTL = 30 * 24 * time.Hour
+
+const (
+	// resetTTL is how long a reset link works.
+	resetTTL		= time.Hour
+	// maxResets bounds the reset links mailed to an account per hour, so the
+	// endpoint can't be used to flood a mailbox.
+	maxResets		= 3
+)
+
 
-func NewAuthHandler(repo repository.UserRepository) *AuthHandler {
-	return &AuthHandler{UserRepo: repo}
+func NewAuthHandler(repo repository.UserRepository, sessions repository.SessionRepository, resets repository.PasswordResetRepository) *AuthHandler {
+	return &AuthHandler{UserRepo: repo, SessionRepo: sessions, ResetRepo: resets}
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := passwordPolicy("password", req.Password, req.Username); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	hashed, err := util.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		writeError(w, r, internalErr("Server error", err))
 		return
 	}
 
@@ -47,37 +116,303 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.UserRepo.Create(&user); err != nil {
-		http.Error(w, "Could not create user", http.StatusBadRequest)
+		if !errors.Is(err, repository.ErrConflict) {
+			err = internalErr("Could not create user", err)
+		}
+		writeError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "User created"})
+	writeMessage(w, http.StatusCreated, "User created")
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	var req LoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	invalid := &repository.UnauthorizedError{Message: "Invalid username or password"}
+
 	user, err := h.UserRepo.GetByUsername(req.Username)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, r, invalid)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		writeError(w, r, internalErr("Login failed", err))
 		return
 	}
 
 	if !util.CheckPasswordHash(req.Password, user.Password) {
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		writeError(w, r, invalid)
 		return
 	}
 
 	if err := h.UserRepo.UpdateLastLogin(user); err != nil {
-		http.Error(w, "Failed to update last login", http.StatusInternalServerError)
+		writeError(w, r, internalErr("Failed to update last login", err))
+		return
+	}
+
+	token, session, err := h.SessionRepo.Create(user.ID, sessionTTL)
+	if err != nil {
+		writeError(w, r, internalErr("Failed to create session", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LoginResponse{
+		Message:	"Login successful",
+		Token:		token,
+		ExpiresAt:	session.ExpiresAt,
+		UserID:		user.ID,
+	})
+}
+
+// Me returns the user the bearer token belongs to.
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, r, &repository.UnauthorizedError{Message: "Authentication required"})
+		return
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to fetch user", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UserResponse{
+		ID:				user.ID,
+		Username:		user.Username,
+		Email:			user.Email,
+		CreatedAt:		user.CreatedAt,
+		LastLoginAt:	user.LastLoginAt,
+	})
+}
+
+// Logout revokes the bearer token the request was made with.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token := middleware.GetToken(r.Context())
+	if token == "" {
+		writeError(w, r, &repository.UnauthorizedError{Message: "Authentication required"})
+		return
+	}
+
+	if err := h.SessionRepo.Delete(token); err != nil {
+		writeError(w, r, internalErr("Failed to log out", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+// ChangePassword sets a new password for the user the bearer token belongs to.
+// Every session of the user is revoked, including the one the request was made
+// with, and a new token is returned in its place.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, r, &repository.UnauthorizedError{Message: "Authentication required"})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to fetch user", err))
+		return
+	}
+	if !util.CheckPasswordHash(req.CurrentPassword, user.Password) {
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "current_password", Message: "is incorrect"}},
+		})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "new_password", Message: "must differ from the current password"}},
+		})
+		return
+	}
+	if err := passwordPolicy("new_password", req.NewPassword, user.Username); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.setPassword(user.ID, req.NewPassword); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Login successful"})
+	token, session, err := h.SessionRepo.Create(user.ID, sessionTTL)
+	if err != nil {
+		writeError(w, r, internalErr("Failed to create session", err))
+		return
+	}
+
+	log.Printf("Password changed for user ID=%d; all sessions revoked\n", user.ID)
+	writeJSON(w, http.StatusOK, LoginResponse{
+		Message:	"Password changed",
+		Token:		token,
+		ExpiresAt:	session.ExpiresAt,
+		UserID:		user.ID,
+	})
+}
+
+// ForgotPassword mails a reset link to the account with the given email. The
+// response is the same whether or not there is such an account, so the endpoint
+// can't be used to find out who has one.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if h.Mailer == nil {
+		writeError(w, r, &statusError{http.StatusServiceUnavailable, "email_unavailable", "Password reset by email is not enabled on this server"})
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	const sent = "If the email belongs to an account, a reset link has been sent to it"
+	user, err := h.UserRepo.GetByEmail(strings.TrimSpace(req.Email))
+	if errors.Is(err, repository.ErrNotFound) {
+		writeMessage(w, http.StatusAccepted, sent)
+		return
+	}
+	if err != nil {
+		writeError(w, r, internalErr("Failed to fetch user", err))
+		return
+	}
+
+	n, err := h.ResetRepo.CountSince(user.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		writeError(w, r, internalErr("Failed to reset password", err))
+		return
+	}
+	if n >= maxResets {
+		log.Printf("Password reset for user ID=%d skipped: %d links sent in the last hour\n", user.ID, n)
+		writeMessage(w, http.StatusAccepted, sent)
+		return
+	}
+
+	token, reset, err := h.ResetRepo.Create(user.ID, resetTTL)
+	if err != nil {
+		writeError(w, r, internalErr("Failed to reset password", err))
+		return
+	}
+
+	// Sent in the background, so that the response takes as long for accounts
+	// that don't exist.
+	subject, body := resetEmail(user.Username, h.resetLink(token), token, reset.ExpiresAt)
+	go func() {
+		if err := h.Mailer.Send(user.Email, subject, body); err != nil {
+			log.Printf("Failed to mail password reset to user ID=%d: %v\n", user.ID, err)
+		}
+	}()
+
+	writeMessage(w, http.StatusAccepted, sent)
+}
+
+// ResetPassword sets a new password with the token of a reset link. The token
+// works once; afterwards every session and every other reset link of the user is
+// revoked.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	reset, err := h.ResetRepo.GetByToken(req.Token)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to reset password", err))
+		return
+	}
+
+	user, err := h.UserRepo.GetByID(reset.UserID)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to fetch user", err))
+		return
+	}
+	if err := passwordPolicy("new_password", req.NewPassword, user.Username); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The token is only used up by a password that passes the policy.
+	if _, err := h.ResetRepo.Consume(req.Token); err != nil {
+		writeError(w, r, repoErr("Failed to reset password", err))
+		return
+	}
+
+	if err := h.setPassword(user.ID, req.NewPassword); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	log.Printf("Password reset for user ID=%d; all sessions revoked\n", user.ID)
+	writeMessage(w, http.StatusOK, "Password reset; log in with the new password")
+}
+
+// setPassword stores a new password and revokes everything that was granted
+// under the old one: sessions and outstanding reset links.
+func (h *AuthHandler) setPassword(userID uint, password string) error {
+	hashed, err := util.HashPassword(password)
+	if err != nil {
+		return internalErr("Server error", err)
+	}
+	if err := h.UserRepo.UpdatePassword(userID, hashed); err != nil {
+		return repoErr("Failed to change password", err)
+	}
+	if err := h.SessionRepo.DeleteByUserID(userID); err != nil {
+		return internalErr("Failed to revoke sessions", err)
+	}
+	if err := h.ResetRepo.RevokeByUserID(userID); err != nil {
+		return internalErr("Failed to revoke reset links", err)
+	}
+	return nil
+}
+
+func (h *AuthHandler) resetLink(token string) string {
+	sep := "?"
+	if strings.Contains(h.ResetURL, "?") {
+		sep = "&"
+	}
+	return h.ResetURL + sep + "token=" + url.QueryEscape(token)
+}
+
+// passwordPolicy holds the rules of the password policy that depend on the user
+// rather than the password alone; validate.Password has the others.
+func passwordPolicy(field, password, username string) error {
+	if len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: field, Message: "must not contain the username"}},
+		}
+	}
+	return nil
+}
+
+func resetEmail(username, link, token string, expires time.Time) (string, string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello %s,\n\n", username)
+	b.WriteString("Someone asked to reset the password of your Research Assistant account.\n")
+	b.WriteString("To choose a new password, open this link:\n\n")
+	fmt.Fprintf(&b, "  %s\n\n", link)
+	fmt.Fprintf(&b, "or give this token to \"ra password reset -token\":\n\n  %s\n\n", token)
+	fmt.Fprintf(&b, "The link works once and expires at %s.\n", expires.UTC().Format("2006-01-02 15:04 MST"))
+	b.WriteString("Changing the password logs you out everywhere.\n\n")
+	b.WriteString("If you didn't ask for this, ignore this email; your password stays the same.\n")
+	return "Reset your password", b.String()
 }
\ No newline at end of file
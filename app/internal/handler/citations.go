@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// CitationGraph is the citation graph of a workspace. CitedBy counts citations
+// from within the workspace, so the most cited nodes are its foundational papers.
+type CitationGraph struct {
+	Nodes			[]CitationNode				`json:"nodes"`
+	Edges			[]repository.CitationEdge	`json:"edges"`
+}
+
+type CitationNode struct {
+	ID				uint		`json:"id"`
+	Title			string		`json:"title"`
+	Year			int			`json:"year,omitempty"`
+	DOI				string		`json:"doi,omitempty"`
+	Cites			int			`json:"cites"`
+	CitedBy			int			`json:"cited_by"`
+}
+
+
+// GetDocumentReferences lists the parsed reference list of a document. Entries
+// that refer to a document in the library carry its ID in cited_document_id.
+func (h *DocumentHandler) GetDocumentReferences(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocumentReferences request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetDocumentReferences request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("GetDocumentReferences request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	refs, err := h.DocRepo.GetReferences(id)
+	if err != nil {
+		log.Printf("GetDocumentReferences request failed: Failed to fetch references: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch references", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, refs)
+}
+
+// GetDocumentCites lists the documents in the library that a document cites.
+func (h *DocumentHandler) GetDocumentCites(w http.ResponseWriter, r *http.Request) {
+	h.citations(w, r, "GetDocumentCites", h.DocRepo.CitedDocuments)
+}
+
+// GetDocumentCitedBy lists the documents in the library that cite a document.
+func (h *DocumentHandler) GetDocumentCitedBy(w http.ResponseWriter, r *http.Request) {
+	h.citations(w, r, "GetDocumentCitedBy", h.DocRepo.CitingDocuments)
+}
+
+func (h *DocumentHandler) citations(w http.ResponseWriter, r *http.Request, name string, fetch func(uint) ([]model.Document, error)) {
+	log.Printf("Starting %s request\n", name)
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("%s request failed: %v\n", name, err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("%s request failed: Failed to fetch document: %v\n", name, err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	docs, err := fetch(id)
+	if err != nil {
+		log.Printf("%s request failed: Failed to fetch citations: %v\n", name, err)
+		writeError(w, r, repoErr("Failed to fetch citations", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, docs)
+}
+
+// GetCitationGraph exports the citations between the documents of a workspace,
+// as JSON or, with ?format=graphml, as GraphML for tools like Gephi.
+func (h *DocumentHandler) GetCitationGraph(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetCitationGraph request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetCitationGraph request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace ID"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "graphml" {
+		writeError(w, r, badRequest("format must be json or graphml"))
+		return
+	}
+
+	if _, err := ownWorkspace(r, h.WorkspaceRepo, id); err != nil {
+		log.Printf("GetCitationGraph request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	docs, edges, err := h.DocRepo.CitationGraph(id)
+	if err != nil {
+		log.Printf("GetCitationGraph request failed: Failed to fetch citations: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch citations", err))
+		return
+	}
+
+	graph := CitationGraph{Nodes: make([]CitationNode, len(docs)), Edges: edges}
+	index := make(map[uint]int, len(docs))
+	for i, d := range docs {
+		graph.Nodes[i] = CitationNode{ID: d.ID, Title: d.Title, Year: d.Year, DOI: d.DOI}
+		index[d.ID] = i
+	}
+	if graph.Edges == nil {
+		graph.Edges = []repository.CitationEdge{}
+	}
+	for _, e := range edges {
+		graph.Nodes[index[e.From]].Cites++
+		graph.Nodes[index[e.To]].CitedBy++
+	}
+
+	log.Printf("Citation graph of workspace ID=%d: %d documents, %d citations\n", id, len(graph.Nodes), len(graph.Edges))
+	if format == "graphml" {
+		w.Header().Set("Content-Type", "application/graphml+xml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="workspace-%d-citations.graphml"`, id))
+		w.WriteHeader(http.StatusOK)
+		if err := writeGraphML(w, graph); err != nil {
+			log.Printf("GetCitationGraph: Failed to write GraphML: %v\n", err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, graph)
+}
+
+type graphML struct {
+	XMLName		xml.Name		`xml:"graphml"`
+	Xmlns		string			`xml:"xmlns,attr"`
+	Keys		[]graphMLKey	`xml:"key"`
+	Graph		graphMLGraph	`xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID			string			`xml:"id,attr"`
+	For			string			`xml:"for,attr"`
+	Name		string			`xml:"attr.name,attr"`
+	Type		string			`xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID			string			`xml:"id,attr"`
+	EdgeDefault	string			`xml:"edgedefault,attr"`
+	Nodes		[]graphMLNode	`xml:"node"`
+	Edges		[]graphMLEdge	`xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID			string			`xml:"id,attr"`
+	Data		[]graphMLData	`xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source		string			`xml:"source,attr"`
+	Target		string			`xml:"target,attr"`
+}
+
+type graphMLData struct {
+	Key			string			`xml:"key,attr"`
+	Value		string			`xml:",chardata"`
+}
+
+func writeGraphML(w http.ResponseWriter, g CitationGraph) error {
+	doc := graphML{
+		Xmlns:	"http://graphml.graphdrawing.org/xmlns",
+		Keys:	[]graphMLKey{
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "year", For: "node", Name: "year", Type: "int"},
+			{ID: "doi", For: "node", Name: "doi", Type: "string"},
+			{ID: "cited_by", For: "node", Name: "cited_by", Type: "int"},
+		},
+		Graph:	graphMLGraph{ID: "citations", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		node := graphMLNode{ID: nodeID(n.ID), Data: []graphMLData{
+			{Key: "title", Value: n.Title},
+			{Key: "cited_by", Value: strconv.Itoa(n.CitedBy)},
+		}}
+		if n.Year != 0 {
+			node.Data = append(node.Data, graphMLData{Key: "year", Value: strconv.Itoa(n.Year)})
+		}
+		if n.DOI != "" {
+			node.Data = append(node.Data, graphMLData{Key: "doi", Value: n.DOI})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: nodeID(e.From), Target: nodeID(e.To)})
+	}
+
+	if _, err := fmt.Fprint(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func nodeID(id uint) string {
+	return "d" + strconv.FormatUint(uint64(id), 10)
+}
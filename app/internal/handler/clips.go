@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"backend/internal/clip"
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// maxClipSize bounds the request body of a clip, which carries the whole HTML of
+// the page.
+const maxClipSize = 16 << 20
+
+// The images embedded in a snapshot are bounded one by one, together and by the
+// time spent downloading them. Images beyond the bounds are left out.
+const (
+	maxClipImageSize	= 2 << 20
+	maxClipImagesSize	= 16 << 20
+	clipImageTimeout	= 30 * time.Second
+)
+
+// ClipRequest is a web page clipped in the browser, for example by a bookmarklet
+// that posts document.documentElement.outerHTML along with location.href. The
+// title defaults to the one the page gives its article.
+type ClipRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+	URL				string		`json:"url" validate:"required,max=2048"`
+	HTML			string		`json:"html" validate:"required"`
+	Title			string		`json:"title" validate:"max=255"`
+	OnDuplicate		string		`json:"on_duplicate" validate:"omitempty,oneof=reject|link|copy"`
+}
+
+
+// ClipPage turns a web page into a document. The main article of the page is
+// extracted as Markdown, which is the document's text, and a self-contained
+// snapshot of it, with its images embedded, is stored as the document's file.
+func (h *DocumentHandler) ClipPage(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ClipPage request")
+
+	var req ClipRequest
+	if err := decodeJSONLimit(r, &req, maxClipSize); err != nil {
+		log.Printf("ClipPage request failed: Invalid request: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("ClipPage request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		log.Printf("ClipPage request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "url", Message: "must be an http(s) URL"}},
+		})
+		return
+	}
+
+	article, err := clip.Extract([]byte(req.HTML), req.URL)
+	if errors.Is(err, clip.ErrNoContent) {
+		log.Printf("ClipPage request failed: No content in %s\n", req.URL)
+		writeError(w, r, &statusError{http.StatusUnprocessableEntity, "no_content", "No article could be found on the page"})
+		return
+	}
+	if err != nil {
+		log.Printf("ClipPage request failed: Failed to read %s: %v\n", req.URL, err)
+		writeError(w, r, internalErr("Failed to read page", err))
+		return
+	}
+	if req.Title != "" {
+		article.Title = req.Title
+	}
+	if article.Title == "" {
+		article.Title = article.URL.Hostname()
+	}
+	article.Title = truncateTitle(article.Title)
+
+	snapshot := clip.Snapshot(r.Context(), article, h.clipImageLoader())
+	file, err := h.Importer.StashSnapshot(snapshot, article.Title+".html")
+	if err != nil {
+		log.Printf("ClipPage request failed: Failed to store snapshot of %s: %v\n", req.URL, err)
+		writeError(w, r, importErr("Failed to save document", err))
+		return
+	}
+
+	meta := ingest.Metadata{
+		UserID:			req.UserID,
+		WorkspaceID:	req.WorkspaceID,
+		Title:			article.Title,
+		SourceURL:		req.URL,
+		Format:			model.FormatHTML,
+	}
+	if !article.Published.IsZero() {
+		meta.Year = article.Published.Year()
+	}
+	for _, name := range article.Authors {
+		meta.Authors = append(meta.Authors, model.DocumentAuthor{Name: truncateTitle(name), Position: len(meta.Authors)})
+	}
+	doc, err := h.Importer.Create(file, meta, req.OnDuplicate == "copy")
+
+	var dup *ingest.DuplicateError
+	if errors.As(err, &dup) {
+		if req.OnDuplicate == "link" {
+			log.Printf("Clip is a duplicate; linking existing document ID=%d\n", dup.Existing.ID)
+			writeJSON(w, http.StatusOK, dup.Existing)
+			return
+		}
+		err = &repository.ConflictError{
+			Resource:	"document",
+			Message:	"This page is already in your library",
+			Details:	DuplicateDetails{DocumentID: dup.Existing.ID, Title: dup.Existing.Title, Options: []string{"link", "copy"}},
+		}
+	}
+	if err != nil {
+		log.Printf("ClipPage request failed: Failed to import %s: %v\n", req.URL, err)
+		writeError(w, r, importErr("Failed to save document", err))
+		return
+	}
+
+	log.Printf("Clipped %s as document ID=%d\n", req.URL, doc.ID)
+	writeJSON(w, http.StatusCreated, doc)
+}
+
+// clipImageLoader downloads the images of a clipped page through the fetcher,
+// which keeps them off private networks. Without a fetcher snapshots have no
+// images.
+func (h *DocumentHandler) clipImageLoader() clip.ImageLoader {
+	if h.Fetcher == nil {
+		return nil
+	}
+
+	var (
+		budget		int64		= maxClipImagesSize
+		deadline	time.Time	= time.Now().Add(clipImageTimeout)
+	)
+	return func(ctx context.Context, u string) ([]byte, error) {
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+
+		data, err := h.Fetcher.Image(ctx, u, min(maxClipImageSize, budget))
+		if err != nil {
+			return nil, err
+		}
+		budget -= int64(len(data))
+		return data, nil
+	}
+}
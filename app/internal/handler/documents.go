@@ -1,168 +1,456 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"io"
+	"mime"
 	"time"
-	"path/filepath"
-	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"backend/internal/fetch"
+	"backend/internal/ingest"
 	"backend/internal/model"
-	"backend/internal/util"
 	"backend/internal/repository"
+	"backend/internal/util"
 )
 
 
 type DocumentHandler struct {
-	DocRepo repository.DocumentRepository
+	DocRepo			repository.DocumentRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+	Importer		*ingest.Importer
+	ImportRoot		string
+	// Fetcher downloads documents imported by URL.
+	Fetcher			*fetch.Fetcher
 }
 
 
-func NewDocumentHandler(repo repository.DocumentRepository) *DocumentHandler {
+func NewDocumentHandler(repo repository.DocumentRepository, workspaceRepo repository.WorkspaceRepository, importer *ingest.Importer, importRoot string) *DocumentHandler {
 	log.Println("Initializing document handler...")
-	return &DocumentHandler{DocRepo: repo}
+	return &DocumentHandler{DocRepo: repo, WorkspaceRepo: workspaceRepo, Importer: importer, ImportRoot: importRoot}
+}
+
+// documentSummaryFields is the default representation of a document in lists.
+// The extracted text is only returned when asked for through ?fields=.
+var documentSummaryFields = []string{
+	"id", "title", "uploaded_at", "year", "format", "reading_status", "version", "extraction_status",
+	"ocr_status", "enrichment_status", "doi", "arxiv_id", "citation_count", "workspace_id", "user_id",
+	"authors", "tags",
 }
 
 func (h *DocumentHandler) GetDocuments(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting GetDocuments request")
 
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		log.Println("GetDocuments request failed: Missing user_id parameter")
-		http.Error(w, "Missing user_id", http.StatusBadRequest)
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetDocuments request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	filter, err := parseDocumentFilter(r)
+	if err != nil {
+		log.Printf("GetDocuments request failed: Invalid filter: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
 		return
 	}
+	filter.UserID = userID
 
-	userId64, err := strconv.ParseUint(userIDStr, 10, 64)
+	page, err := parsePageRequest(r)
 	if err != nil {
-		log.Printf("GetDocuments request failed: Invalid user_id: %v\n", err)
-		http.Error(w, "Invalid user_id", http.StatusBadRequest)
+		log.Printf("GetDocuments request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
 		return
 	}
-	userID := uint(userId64)
 
-	log.Printf("Fetching documents for user_id=%d", userID)
-	docs, err := h.DocRepo.GetByUserID(userID)
+	fields, err := parseFields(r, model.Document{}, documentSummaryFields)
+	if err != nil {
+		log.Printf("GetDocuments request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+	page.Omit = omitUnrequested(fields, "extracted_text", "abstract")
+
+	log.Printf("Fetching documents for user_id=%d with filter %+v", userID, filter)
+	docs, err := h.DocRepo.List(filter, page)
 	if err != nil {
 		log.Printf("GetDocuments request failed: Failed to fetch documents: %v\n", err)
-		http.Error(w, "Failed to fetch documents", http.StatusInternalServerError)
+		writeError(w, r, repoErr("Failed to fetch documents", err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(docs); err != nil {
+	// Facets describe the whole filtered library, so they only come with the first page.
+	var facets *repository.DocumentFacets
+	if page.Cursor == "" {
+		f, err := h.DocRepo.Facets(filter)
+		if err != nil {
+			log.Printf("GetDocuments request failed: Failed to compute facets: %v\n", err)
+			writeError(w, r, repoErr("Failed to fetch documents", err))
+			return
+		}
+		facets = &f
+	}
+
+	if err := writeList(w, docs.Items, docs.NextCursor, fields, facets); err != nil {
 		log.Printf("GetDocuments request failed: Failed to encode documents to JSON: %v\n", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		writeError(w, r, internalErr("Internal error", err))
 		return
 	}
 	log.Println("GetDocuments request successful")
 }
 
-func parseUint(s string) uint {
-	var id uint
-	fmt.Sscanf(s, "%d", &id)
-	return id
+// parseDocumentFilter reads the optional tag, workspace_id, year, author, format,
+// status, extraction, uploaded_from and uploaded_to query parameters.
+func parseDocumentFilter(r *http.Request) (repository.DocumentFilter, error) {
+	var filter repository.DocumentFilter
+	q := r.URL.Query()
+
+	if v := q.Get("tag"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid tag")
+		}
+		filter.TagID = uint(id)
+	}
+	if v := q.Get("workspace_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid workspace_id")
+		}
+		wsID := uint(id)
+		filter.WorkspaceID = &wsID
+	}
+	if v := q.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid year")
+		}
+		filter.Year = year
+	}
+	if v := q.Get("status"); v != "" {
+		if !model.IsValidReadingStatus(v) {
+			return filter, fmt.Errorf("invalid status")
+		}
+		filter.ReadingStatus = v
+	}
+	if v := q.Get("extraction"); v != "" {
+		if !model.IsValidExtractionStatus(v) {
+			return filter, fmt.Errorf("invalid extraction")
+		}
+		filter.Extraction = v
+	}
+	filter.Author = q.Get("author")
+	filter.Format = q.Get("format")
+
+	if v := q.Get("uploaded_from"); v != "" {
+		t, _, err := parseDate(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid uploaded_from")
+		}
+		filter.UploadedFrom = &t
+	}
+	if v := q.Get("uploaded_to"); v != "" {
+		t, dateOnly, err := parseDate(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid uploaded_to")
+		}
+		if dateOnly {
+			// A bare date includes the whole day.
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.UploadedTo = &t
+	}
+
+	return filter, nil
+}
+
+// parseDate accepts either a full RFC 3339 timestamp or a plain YYYY-MM-DD date.
+func parseDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	return t, true, err
+}
+
+type ReadingStatusRequest struct {
+	UserID			uint		`json:"user_id"`
+	DocumentIDs		[]uint		`json:"document_ids" validate:"required,max=500"`
+	Status			string		`json:"status" validate:"required,oneof=unread|reading|read"`
+}
+
+func (h *DocumentHandler) UpdateReadingStatus(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateReadingStatus request")
+
+	var p ReadingStatusRequest
+	if err := decodeJSON(r, &p); err != nil {
+		log.Printf("UpdateReadingStatus request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &p.UserID); err != nil {
+		log.Printf("UpdateReadingStatus request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	updated, err := h.DocRepo.UpdateReadingStatus(p.UserID, p.DocumentIDs, p.Status)
+	if err != nil {
+		log.Printf("UpdateReadingStatus request failed: Failed to update documents: %v\n", err)
+		writeError(w, r, repoErr("Failed to update reading status", err))
+		return
+	}
+
+	log.Printf("Set reading status %q on %d documents\n", p.Status, updated)
+	writeJSON(w, http.StatusOK, map[string]int{"updated": updated})
+}
+
+// UploadRequest holds the non-file fields of the multipart upload form.
+type UploadRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+	Title			string		`json:"title" validate:"required,max=255"`
+	Year			int			`json:"year" validate:"omitempty,min=1000,max=2100"`
+	Authors			string		`json:"authors" validate:"max=2000"`
+	OnDuplicate		string		`json:"on_duplicate" validate:"omitempty,oneof=reject|link|copy"`
+	Password		string		`json:"password" validate:"max=1024"`
+}
+
+// DuplicateDetails is sent with the 409 for a file already in the user's library.
+// Repeating the upload with on_duplicate=link returns the existing document instead,
+// and on_duplicate=copy creates a second document for the same file.
+type DuplicateDetails struct {
+	DocumentID		uint		`json:"document_id"`
+	Title			string		`json:"title"`
+	Options			[]string	`json:"options"`
+}
+
+// parseAuthors splits a semicolon-separated author list such as "Smith, J.; Doe, A.".
+func parseAuthors(s string) []model.DocumentAuthor {
+	var authors []model.DocumentAuthor
+	for _, name := range strings.Split(s, ";") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		authors = append(authors, model.DocumentAuthor{Name: name, Position: len(authors)})
+	}
+	return authors
 }
 
 func (h *DocumentHandler) UploadDocuments(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting UploadDocuments request")
 
-	err := r.ParseMultipartForm(10 << 20)		// 10 MB max file size
+	var req UploadRequest
+	file, err := receiveUpload(w, r, h.Importer, "pdf", &req)
+	if err != nil {
+		log.Printf("UploadDocuments request failed: Invalid upload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		h.Importer.Discard(file)
+		log.Printf("UploadDocuments request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		h.Importer.Discard(file)
+		log.Printf("UploadDocuments request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	meta := ingest.Metadata{
+		UserID:			req.UserID,
+		WorkspaceID:	req.WorkspaceID,
+		Title:			req.Title,
+		Year:			req.Year,
+		Authors:		parseAuthors(req.Authors),
+		Password:		req.Password,
+	}
+
+	log.Printf("Importing uploaded file %s\n", file.Filename)
+	doc, err := h.Importer.Create(file, meta, req.OnDuplicate == "copy")
+
+	var dup *ingest.DuplicateError
+	if errors.As(err, &dup) {
+		if req.OnDuplicate == "link" {
+			log.Printf("Upload is a duplicate; linking existing document ID=%d\n", dup.Existing.ID)
+			writeJSON(w, http.StatusOK, dup.Existing)
+			return
+		}
+		err = &repository.ConflictError{
+			Resource:	"document",
+			Message:	"This file is already in your library",
+			Details:	DuplicateDetails{DocumentID: dup.Existing.ID, Title: dup.Existing.Title, Options: []string{"link", "copy"}},
+		}
+	}
 	if err != nil {
-		log.Printf("UploadDocuments request failed: Failed to parse multipart form: %v\n", err)
-		http.Error(w, "Could not parse form", http.StatusBadRequest)
+		log.Printf("UploadDocuments request failed: Failed to import file: %v", err)
+		writeError(w, r, importErr("Failed to save document", err))
 		return
 	}
 
-	file, handler, err := r.FormFile("pdf")
+	writeJSON(w, http.StatusCreated, doc)
+	log.Printf("Document upload successful: ID=%d\n", doc.ID)
+}
+
+func (h *DocumentHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocument request")
+
+	id, err := pathID(r, "id")
 	if err != nil {
-		log.Printf("PDF file not provided: %v\n", err)
-		http.Error(w, "PDF file not provided", http.StatusBadRequest)
+		log.Printf("GetDocument request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
 		return
 	}
-	defer file.Close()
 
-	userID := parseUint(r.FormValue("user_id"))
-	workspaceID := parseUint(r.FormValue("workspace_id"))
-	title := r.FormValue("title")
-	if title == "" {
-		log.Println("Missing document title")
-		http.Error(w, "Title is required", http.StatusBadRequest)
+	doc, err := h.ownDocument(r, id)
+	if err != nil {
+		log.Printf("GetDocument request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
 		return
 	}
 
-	// Save to file uploads directory (test directory. Will be S3 bucket eventually)
-	filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), handler.Filename)
-	savePath := filepath.Join("uploads", filename)
+	writeJSON(w, http.StatusOK, doc)
+}
 
-	dst, err := os.Create(savePath)
+func (h *DocumentHandler) ViewDocument(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ViewDocument request")
+
+	id, err := pathID(r, "id")
 	if err != nil {
-		log.Printf("UploadDocuments request failed: Unable to save file")
-		http.Error(w, "Unable to save file", http.StatusInternalServerError)
+		log.Printf("ViewDocument request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
 		return
 	}
-	defer dst.Close()
-	io.Copy(dst, file)
 
-	// Extract PDF text
-	log.Printf("Extracting text from PDF at: %s\n", savePath)
-	text, err := util.ExtractTextFromPDF(savePath)
+	log.Printf("Fetching document ID: %d\n", id)
+	doc, err := h.ownDocument(r, id)
+	if err == nil && doc.FilePath == "" {
+		err = &repository.NotFoundError{Resource: "document file", ID: id}
+	}
 	if err != nil {
-		log.Printf("UploadDocuments request failed: Failed to extract text: %v\n", err)
-		http.Error(w, "Failed to extract PDF text", http.StatusInternalServerError)
+		log.Printf("DocumentView request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
 		return
 	}
 
-	// Save record to database
-	doc := &model.Document{
-		Title:			title,
-		FilePath:		savePath,
-		ExtractedText: 	text,
-		WorkspaceID: 	workspaceID,
-		UserID:			userID,
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+		"filename": util.SanitizeFilename(doc.Title + "." + doc.Format),
+	}))
+	serveStored(w, r, doc.FilePath, doc.Format)
+	log.Printf("Serving file from: %s\n", doc.FilePath)
+}
+
+// serveStored serves a stored file. Snapshots of web pages are sandboxed, since
+// they are served from this origin but were written by someone else: nothing in
+// them may run or load, bar their own styles and embedded images.
+func serveStored(w http.ResponseWriter, r *http.Request, path, format string) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if format == model.FormatHTML {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'; img-src data:; style-src 'unsafe-inline'")
 	}
+	http.ServeFile(w, r, path)
+}
+
+func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteDocument request")
 
-	log.Printf("Saving document record: %+v\n", doc)
-	if err := h.DocRepo.Save(doc); err != nil {
-		log.Printf("UploadDocuments request failed: Failed to save document: %v", err)
-		http.Error(w, "Failed to save document", http.StatusInternalServerError)
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteDocument request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	log.Printf("Document upload successful: ID=%d\n", doc.ID)
+	doc, err := h.ownDocument(r, id)
+	if err != nil {
+		log.Printf("DeleteDocument request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	versions, err := h.DocRepo.GetVersions(id)
+	if err != nil {
+		log.Printf("DeleteDocument request failed: Failed to fetch versions: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	if err := h.DocRepo.Delete(id); err != nil {
+		log.Printf("DeleteDocument request failed: Failed to delete document in database: %v\n", err)
+		writeError(w, r, repoErr("Failed to delete document", err))
+		return
+	}
+
+	h.removeFiles(doc, versions)
+
+	log.Printf("Successfully deleted document with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *DocumentHandler) ViewDocument(w http.ResponseWriter, r *http.Request) {
-	log.Println("Starting ViewDocument request")
+// ExtractRequest optionally carries the password of an encrypted PDF.
+type ExtractRequest struct {
+	Password		string		`json:"password" validate:"max=1024"`
+}
+
+// ExtractDocument extracts the text of a document again, typically to unlock an
+// encrypted PDF with its password. The document is returned with the new outcome
+// in extraction_status.
+func (h *DocumentHandler) ExtractDocument(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ExtractDocument request")
 
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		log.Println("Missing document ID")
-		http.Error(w, "Missing document ID", http.StatusBadRequest)
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("ExtractDocument request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	var req ExtractRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("ExtractDocument request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
 		return
 	}
 
-	id, err := strconv.Atoi(idStr)
+	doc, err := h.ownDocument(r, id)
 	if err != nil {
-		log.Printf("Invalid document ID")
-		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		log.Printf("ExtractDocument request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
 		return
 	}
 
-	log.Printf("Fetching document ID: %d\n", id)
-	doc, err := h.DocRepo.GetByDocumentID(uint(id))
-	if err != nil || doc.FilePath == "" {
-		log.Printf("DocumentView request failed: Document not found or no file path: %v", err)
-		http.Error(w, "Document not found", http.StatusInternalServerError)
+	if err := h.Importer.Reextract(&doc, req.Password); err != nil {
+		log.Printf("ExtractDocument request failed: Failed to extract text: %v\n", err)
+		writeError(w, r, repoErr("Failed to extract text", err))
 		return
 	}
 
-	filePath := doc.FilePath
-	http.ServeFile(w, r, filePath)
-	log.Printf("Serving file from: %s\n", doc.FilePath)
-}
\ No newline at end of file
+	log.Printf("Extracted text of document ID=%d: %s\n", id, doc.ExtractionStatus)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// ownDocument fetches a document of the authenticated user.
+func (h *DocumentHandler) ownDocument(r *http.Request, id uint) (model.Document, error) {
+	return own(r, "document", id, h.DocRepo.GetByDocumentID, func(doc model.Document) uint { return doc.UserID })
+}
+
+// removeFiles releases the stored files of a deleted document and its versions.
+// The records are already gone, so failures are only logged.
+func (h *DocumentHandler) removeFiles(doc model.Document, versions []model.DocumentVersion) {
+	if err := h.Importer.Remove(doc); err != nil {
+		log.Printf("Failed to remove file %s: %v\n", doc.FilePath, err)
+	}
+	for _, v := range versions {
+		if err := h.Importer.RemoveVersion(v); err != nil {
+			log.Printf("Failed to remove file %s: %v\n", v.FilePath, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/util"
+)
+
+
+// defaultSimilarity is the fingerprint similarity above which documents are
+// reported as possible duplicates, e.g. a preprint and its published version.
+const defaultSimilarity = 0.8
+
+// DuplicateGroup is a set of documents whose texts are nearly the same.
+type DuplicateGroup struct {
+	Similarity		float64				`json:"similarity"`
+	Documents		[]model.Document	`json:"documents"`
+}
+
+// MergeRequest names the documents to fold into the one in the path.
+type MergeRequest struct {
+	UserID			uint		`json:"user_id"`
+	DuplicateIDs	[]uint		`json:"duplicate_ids" validate:"required,max=100"`
+}
+
+
+// GetDuplicates groups the user's documents whose extracted text is at least
+// ?threshold= alike (0.5 to 1, default 0.8).
+func (h *DocumentHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDuplicates request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetDuplicates request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	threshold := defaultSimilarity
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		threshold, err = strconv.ParseFloat(v, 64)
+		if err != nil || threshold < 0.5 || threshold > 1 {
+			log.Printf("GetDuplicates request failed: Invalid threshold %q\n", v)
+			writeError(w, r, badRequest("threshold must be a number between 0.5 and 1"))
+			return
+		}
+	}
+
+	if n, err := h.DocRepo.BackfillFingerprints(userID); err != nil {
+		log.Printf("GetDuplicates request failed: Failed to fingerprint documents: %v\n", err)
+		writeError(w, r, repoErr("Failed to fingerprint documents", err))
+		return
+	} else if n > 0 {
+		log.Printf("Fingerprinted %d older documents of user_id=%d\n", n, userID)
+	}
+
+	fps, err := h.DocRepo.Fingerprints(userID)
+	if err != nil {
+		log.Printf("GetDuplicates request failed: Failed to fetch fingerprints: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch documents", err))
+		return
+	}
+
+	similar := util.GroupSimilar(fps, threshold)
+	var ids []uint
+	for _, g := range similar {
+		ids = append(ids, g.IDs...)
+	}
+
+	byID := map[uint]model.Document{}
+	if len(ids) > 0 {
+		docs, err := h.DocRepo.GetByIDs(userID, ids)
+		if err != nil {
+			log.Printf("GetDuplicates request failed: Failed to fetch documents: %v\n", err)
+			writeError(w, r, repoErr("Failed to fetch documents", err))
+			return
+		}
+		for _, d := range docs {
+			byID[d.ID] = d
+		}
+	}
+
+	groups := []DuplicateGroup{}
+	for _, g := range similar {
+		group := DuplicateGroup{Similarity: g.Similarity}
+		for _, id := range g.IDs {
+			if d, ok := byID[id]; ok {
+				group.Documents = append(group.Documents, d)
+			}
+		}
+		if len(group.Documents) > 1 {
+			groups = append(groups, group)
+		}
+	}
+
+	log.Printf("Found %d groups of possible duplicates for user_id=%d\n", len(groups), userID)
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// MergeDocuments folds duplicates into the document in the path. Their tags and
+// the note links citing them move to it, missing metadata is filled in from them,
+// and they are then deleted.
+func (h *DocumentHandler) MergeDocuments(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting MergeDocuments request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("MergeDocuments request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	var req MergeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("MergeDocuments request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("MergeDocuments request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	slices.Sort(req.DuplicateIDs)
+	req.DuplicateIDs = slices.Compact(req.DuplicateIDs)
+	if slices.Contains(req.DuplicateIDs, id) {
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "duplicate_ids", Message: "must not contain the document being merged into"}},
+		})
+		return
+	}
+
+	versions := map[uint][]model.DocumentVersion{}
+	for _, dupID := range req.DuplicateIDs {
+		if _, err := h.ownDocument(r, dupID); err != nil {
+			log.Printf("MergeDocuments request failed: Failed to fetch document: %v\n", err)
+			writeError(w, r, repoErr("Failed to fetch documents", err))
+			return
+		}
+		if versions[dupID], err = h.DocRepo.GetVersions(dupID); err != nil {
+			log.Printf("MergeDocuments request failed: Failed to fetch versions: %v\n", err)
+			writeError(w, r, repoErr("Failed to fetch documents", err))
+			return
+		}
+	}
+
+	removed, err := h.DocRepo.Merge(req.UserID, id, req.DuplicateIDs)
+	if err != nil {
+		log.Printf("MergeDocuments request failed: Failed to merge documents: %v\n", err)
+		writeError(w, r, repoErr("Failed to merge documents", err))
+		return
+	}
+
+	for _, d := range removed {
+		h.removeFiles(d, versions[d.ID])
+	}
+
+	doc, err := h.DocRepo.GetByDocumentID(id)
+	if err != nil {
+		log.Printf("MergeDocuments request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	log.Printf("Merged %d documents into document ID=%d\n", len(removed), id)
+	writeJSON(w, http.StatusOK, doc)
+}
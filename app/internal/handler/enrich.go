@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// EnrichDocument queues a metadata lookup for a document, for example to refresh
+// its citation count or to retry one that failed. The lookup happens in the
+// background; the document is returned with enrichment_status pending.
+func (h *DocumentHandler) EnrichDocument(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting EnrichDocument request")
+
+	if h.Importer.Enrich == nil {
+		writeError(w, r, &statusError{http.StatusServiceUnavailable, "enrichment_unavailable", "Metadata enrichment is not enabled on this server"})
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("EnrichDocument request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	doc, err := h.ownDocument(r, id)
+	if err != nil {
+		log.Printf("EnrichDocument request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	if doc.DOI == "" && doc.ArXivID == "" {
+		log.Printf("EnrichDocument request failed: Document ID=%d has no DOI or arXiv ID\n", id)
+		writeError(w, r, &repository.ConflictError{
+			Resource:	"document",
+			Message:	"Document has no DOI or arXiv ID to look up",
+		})
+		return
+	}
+
+	if err := h.DocRepo.SetEnrichmentStatus(id, model.EnrichmentPending); err != nil {
+		log.Printf("EnrichDocument request failed: Failed to update document: %v\n", err)
+		writeError(w, r, repoErr("Failed to update document", err))
+		return
+	}
+	doc.EnrichmentStatus = model.EnrichmentPending
+	h.Importer.Enrich.Enqueue(id)
+
+	log.Printf("Queued document ID=%d for enrichment\n", id)
+	writeJSON(w, http.StatusAccepted, doc)
+}
@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"backend/internal/feeds"
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// CreateFeedRequest subscribes a workspace to an RSS or Atom feed by URL, or to an
+// arXiv search query. Entries go to the inbox unless mode is import; the interval
+// between polls defaults to an hour.
+type CreateFeedRequest struct {
+	UserID				uint		`json:"user_id"`
+	WorkspaceID			uint		`json:"workspace_id" validate:"required"`
+	Kind				string		`json:"kind" validate:"required,oneof=rss|arxiv"`
+	URL					string		`json:"url" validate:"max=2048"`
+	Query				string		`json:"query" validate:"max=1024"`
+	Title				string		`json:"title" validate:"max=255"`
+	Mode				string		`json:"mode" validate:"omitempty,oneof=import|inbox"`
+	Keywords			string		`json:"keywords" validate:"max=1024"`
+	IntervalMinutes		int			`json:"interval_minutes" validate:"omitempty,min=15,max=10080"`
+	Backfill			bool		`json:"backfill"`
+}
+
+// UpdateFeedRequest changes the fields that are present and leaves the rest.
+type UpdateFeedRequest struct {
+	Title				*string		`json:"title" validate:"min=1,max=255"`
+	Mode				*string		`json:"mode" validate:"omitempty,oneof=import|inbox"`
+	Keywords			*string		`json:"keywords" validate:"max=1024"`
+	IntervalMinutes		*int		`json:"interval_minutes" validate:"min=15,max=10080"`
+	Enabled				*bool		`json:"enabled"`
+}
+
+type FeedHandler struct {
+	FeedRepo		repository.FeedRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+	Poller			*feeds.Poller
+}
+
+const defaultFeedInterval = 60
+
+
+func NewFeedHandler(feedRepo repository.FeedRepository, workspaceRepo repository.WorkspaceRepository, poller *feeds.Poller) *FeedHandler {
+	log.Println("Initializing FeedHandler...")
+	return &FeedHandler{FeedRepo: feedRepo, WorkspaceRepo: workspaceRepo, Poller: poller}
+}
+
+func (h *FeedHandler) GetWorkspaceFeeds(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetWorkspaceFeeds request")
+
+	workspaceID, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetWorkspaceFeeds request failed: Invalid workspace_id: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace_id"))
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetWorkspaceFeeds request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.Feed{}, jsonFieldNames(reflect.TypeOf(model.Feed{})))
+	if err != nil {
+		log.Printf("GetWorkspaceFeeds request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	if _, err := ownWorkspace(r, h.WorkspaceRepo, workspaceID); err != nil {
+		log.Printf("GetWorkspaceFeeds request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	list, err := h.FeedRepo.GetByWorkspaceID(workspaceID, page)
+	if err != nil {
+		log.Printf("GetWorkspaceFeeds request failed: Failed to fetch feeds: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feeds", err))
+		return
+	}
+
+	log.Printf("Found %d feeds for workspace_id=%d\n", len(list.Items), workspaceID)
+	writeList(w, list.Items, list.NextCursor, fields, nil)
+}
+
+// CreateFeed subscribes a workspace to a feed. The feed is first polled within a
+// minute; unless backfill is set, that poll only records the entries already in
+// the feed so that later polls take what is new.
+func (h *FeedHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting CreateFeed request")
+
+	var req CreateFeedRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("CreateFeed request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("CreateFeed request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	feed := model.Feed{
+		UserID:				req.UserID,
+		WorkspaceID:		req.WorkspaceID,
+		Kind:				req.Kind,
+		Title:				strings.TrimSpace(req.Title),
+		Mode:				req.Mode,
+		Keywords:			strings.TrimSpace(req.Keywords),
+		IntervalMinutes:	req.IntervalMinutes,
+		Enabled:			true,
+		Backfill:			req.Backfill,
+		NextPollAt:			time.Now(),
+	}
+	switch req.Kind {
+	case model.FeedKindRSS:
+		u, err := url.Parse(strings.TrimSpace(req.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeError(w, r, &repository.ValidationError{
+				Message:	"Request validation failed",
+				Fields:		[]repository.FieldError{{Field: "url", Message: "must be the http(s) URL of an RSS or Atom feed"}},
+			})
+			return
+		}
+		feed.URL = u.String()
+		if feed.Title == "" {
+			feed.Title = u.Hostname()
+		}
+	case model.FeedKindArXiv:
+		feed.Query = strings.TrimSpace(req.Query)
+		if feed.Query == "" {
+			writeError(w, r, &repository.ValidationError{
+				Message:	"Request validation failed",
+				Fields:		[]repository.FieldError{{Field: "query", Message: "is required for arXiv feeds"}},
+			})
+			return
+		}
+		if feed.Title == "" {
+			feed.Title = truncateTitle("arXiv: " + feed.Query)
+		}
+	}
+	if feed.Mode == "" {
+		feed.Mode = model.FeedModeInbox
+	}
+	if feed.IntervalMinutes == 0 {
+		feed.IntervalMinutes = defaultFeedInterval
+	}
+
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		log.Printf("CreateFeed request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	if err := h.FeedRepo.Create(&feed); err != nil {
+		log.Printf("CreateFeed request failed: Failed to create feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to create feed", err))
+		return
+	}
+
+	log.Printf("Feed created with ID=%d for workspace ID=%d\n", feed.ID, feed.WorkspaceID)
+	writeJSON(w, http.StatusCreated, feed)
+}
+
+func (h *FeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetFeed request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetFeed request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed ID"))
+		return
+	}
+
+	feed, err := h.ownFeed(r, id)
+	if err != nil {
+		log.Printf("GetFeed request failed: Failed to fetch feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, feed)
+}
+
+// UpdateFeed renames a feed or changes its settings. A changed interval applies
+// from the last poll on.
+func (h *FeedHandler) UpdateFeed(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateFeed request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("UpdateFeed request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed ID"))
+		return
+	}
+
+	var req UpdateFeedRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("UpdateFeed request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	feed, err := h.ownFeed(r, id)
+	if err != nil {
+		log.Printf("UpdateFeed request failed: Failed to fetch feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed", err))
+		return
+	}
+
+	if req.Title != nil {
+		feed.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Mode != nil {
+		feed.Mode = *req.Mode
+	}
+	if req.Keywords != nil {
+		feed.Keywords = strings.TrimSpace(*req.Keywords)
+	}
+	if req.IntervalMinutes != nil {
+		feed.IntervalMinutes = *req.IntervalMinutes
+		if feed.LastPolledAt != nil {
+			feed.NextPollAt = feed.LastPolledAt.Add(time.Duration(feed.IntervalMinutes) * time.Minute)
+		}
+	}
+	if req.Enabled != nil {
+		feed.Enabled = *req.Enabled
+	}
+
+	if err := h.FeedRepo.Update(&feed); err != nil {
+		log.Printf("UpdateFeed request failed: Failed to update feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to update feed", err))
+		return
+	}
+
+	log.Printf("Updated feed ID=%d\n", id)
+	writeJSON(w, http.StatusOK, feed)
+}
+
+// DeleteFeed unsubscribes from a feed. The documents imported from it stay.
+func (h *FeedHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteFeed request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteFeed request failed: Invalid or missing feed ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed ID"))
+		return
+	}
+
+	if _, err := h.ownFeed(r, id); err != nil {
+		log.Printf("DeleteFeed request failed: Failed to fetch feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed", err))
+		return
+	}
+
+	if err := h.FeedRepo.Delete(id); err != nil {
+		log.Printf("DeleteFeed request failed: Failed to delete feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to delete feed", err))
+		return
+	}
+
+	log.Printf("Successfully deleted feed with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PollFeed polls a feed now rather than when it is next due, and reports what
+// became of its new entries.
+func (h *FeedHandler) PollFeed(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting PollFeed request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("PollFeed request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed ID"))
+		return
+	}
+
+	if _, err := h.ownFeed(r, id); err != nil {
+		log.Printf("PollFeed request failed: Failed to fetch feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed", err))
+		return
+	}
+
+	res, err := h.Poller.Poll(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, r, err)
+		return
+	}
+	if errors.Is(err, feeds.ErrNotFeed) {
+		log.Printf("PollFeed request failed: %v\n", err)
+		writeError(w, r, &statusError{http.StatusBadGateway, "not_a_feed", "The source did not return an RSS or Atom feed"})
+		return
+	}
+	if err != nil {
+		log.Printf("PollFeed request failed: Failed to poll feed ID=%d: %v\n", id, err)
+		writeError(w, r, fetchErr(err))
+		return
+	}
+
+	log.Printf("Polled feed ID=%d: %d new entries\n", id, res.New)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GetFeedItems lists the entries seen in a feed, newest first, optionally only
+// those with one status.
+func (h *FeedHandler) GetFeedItems(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetFeedItems request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetFeedItems request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed ID"))
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !model.IsValidFeedItemStatus(status) {
+		writeError(w, r, badRequest("Invalid status"))
+		return
+	}
+
+	if _, err := h.ownFeed(r, id); err != nil {
+		log.Printf("GetFeedItems request failed: Failed to fetch feed: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed", err))
+		return
+	}
+
+	h.writeItems(w, r, "GetFeedItems", repository.FeedItemFilter{FeedID: id, Status: status})
+}
+
+// GetWorkspaceInbox lists the entries of a workspace's feeds that wait to be
+// accepted or dismissed, newest first.
+func (h *FeedHandler) GetWorkspaceInbox(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetWorkspaceInbox request")
+
+	workspaceID, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetWorkspaceInbox request failed: Invalid workspace_id: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace_id"))
+		return
+	}
+
+	if _, err := ownWorkspace(r, h.WorkspaceRepo, workspaceID); err != nil {
+		log.Printf("GetWorkspaceInbox request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	h.writeItems(w, r, "GetWorkspaceInbox", repository.FeedItemFilter{WorkspaceID: workspaceID, Status: model.FeedItemInbox})
+}
+
+func (h *FeedHandler) writeItems(w http.ResponseWriter, r *http.Request, op string, filter repository.FeedItemFilter) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("%s request failed: %v\n", op, err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.FeedItem{}, jsonFieldNames(reflect.TypeOf(model.FeedItem{})))
+	if err != nil {
+		log.Printf("%s request failed: %v\n", op, err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	items, err := h.FeedRepo.ListItems(filter, page)
+	if err != nil {
+		log.Printf("%s request failed: Failed to fetch feed items: %v\n", op, err)
+		writeError(w, r, repoErr("Failed to fetch feed items", err))
+		return
+	}
+
+	log.Printf("Found %d feed items\n", len(items.Items))
+	writeList(w, items.Items, items.NextCursor, fields, nil)
+}
+
+// AcceptFeedItem imports an entry from the inbox into the feed's workspace. An
+// entry whose paper is already in the library is marked a duplicate of it instead.
+// Entries that failed to import can be accepted again.
+func (h *FeedHandler) AcceptFeedItem(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting AcceptFeedItem request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("AcceptFeedItem request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed item ID"))
+		return
+	}
+
+	if _, err := h.ownFeedItem(r, id); err != nil {
+		log.Printf("AcceptFeedItem request failed: Failed to fetch feed item: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed item", err))
+		return
+	}
+
+	item, err := h.Poller.Accept(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrConflict) {
+		writeError(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Printf("AcceptFeedItem request failed: Failed to import feed item ID=%d: %v\n", id, err)
+		writeError(w, r, fetchErr(err))
+		return
+	}
+
+	log.Printf("Accepted feed item ID=%d: %s\n", id, item.Status)
+	writeJSON(w, http.StatusOK, item)
+}
+
+// DismissFeedItem takes an entry out of the inbox without importing it.
+func (h *FeedHandler) DismissFeedItem(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DismissFeedItem request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DismissFeedItem request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing feed item ID"))
+		return
+	}
+
+	if _, err := h.ownFeedItem(r, id); err != nil {
+		log.Printf("DismissFeedItem request failed: Failed to fetch feed item: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch feed item", err))
+		return
+	}
+
+	item, err := h.Poller.Dismiss(id)
+	if err != nil {
+		log.Printf("DismissFeedItem request failed: %v\n", err)
+		writeError(w, r, repoErr("Failed to dismiss feed item", err))
+		return
+	}
+
+	log.Printf("Dismissed feed item ID=%d\n", id)
+	writeJSON(w, http.StatusOK, item)
+}
+
+// ownFeed fetches a feed of the authenticated user. Other users' feeds are
+// reported as not found.
+func (h *FeedHandler) ownFeed(r *http.Request, id uint) (model.Feed, error) {
+	return own(r, "feed", id, h.FeedRepo.GetByID, func(feed model.Feed) uint { return feed.UserID })
+}
+
+// ownFeedItem fetches a feed entry of the authenticated user.
+func (h *FeedHandler) ownFeedItem(r *http.Request, id uint) (model.FeedItem, error) {
+	return own(r, "feed item", id, h.FeedRepo.GetItem, func(item model.FeedItem) uint { return item.UserID })
+}
@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/fetch"
+	"backend/internal/ingest"
+	"backend/internal/repository"
+)
+
+
+// maxArchiveSize bounds the request body of an archive import.
+const maxArchiveSize = 4 << 30
+
+// ImportArchiveRequest holds the non-file fields of the archive import form.
+type ImportArchiveRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+}
+
+// ImportDirectoryRequest names a directory relative to the server's import root.
+type ImportDirectoryRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+	Path			string		`json:"path" validate:"required,max=1024"`
+}
+
+// ImportURLRequest names a document on the web by URL, DOI or arXiv ID. The title
+// defaults to the one the publisher's page gives, or else the file name; either is
+// replaced by the canonical title once the metadata has been looked up.
+type ImportURLRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+	Source			string		`json:"source" validate:"required,max=2048"`
+	Title			string		`json:"title" validate:"max=255"`
+	Year			int			`json:"year" validate:"omitempty,min=1000,max=2100"`
+	Authors			string		`json:"authors" validate:"max=2000"`
+	OnDuplicate		string		`json:"on_duplicate" validate:"omitempty,oneof=reject|link|copy"`
+}
+
+
+// ImportArchive imports every PDF in an uploaded ZIP, tar or gzipped tar archive.
+func (h *DocumentHandler) ImportArchive(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ImportArchive request")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		log.Printf("ImportArchive request failed: Failed to parse multipart form: %v\n", err)
+		writeError(w, r, badRequest("Could not parse form"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		log.Printf("ImportArchive request failed: Archive not provided: %v\n", err)
+		writeError(w, r, badRequest("Archive not provided"))
+		return
+	}
+	defer file.Close()
+
+	var req ImportArchiveRequest
+	if err := decodeForm(r.MultipartForm, &req, "archive"); err != nil {
+		log.Printf("ImportArchive request failed: Invalid form: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("ImportArchive request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		log.Printf("ImportArchive request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	var walk ingest.Walker
+	name := strings.ToLower(header.Filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		walk = ingest.Zip(file, header.Size)
+	case strings.HasSuffix(name, ".tar"):
+		walk = ingest.Tar(file)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			log.Printf("ImportArchive request failed: Invalid gzip stream: %v\n", err)
+			writeError(w, r, badRequest("Archive is not a valid gzip file"))
+			return
+		}
+		defer gz.Close()
+		walk = ingest.Tar(gz)
+	default:
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "archive", Message: "must be a .zip, .tar, .tar.gz or .tgz file"}},
+		})
+		return
+	}
+
+	h.runImport(w, header.Filename, walk, req.UserID, req.WorkspaceID)
+}
+
+// ImportDirectory imports every PDF below a directory on the server. Only paths
+// inside the configured import root can be read.
+func (h *DocumentHandler) ImportDirectory(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ImportDirectory request")
+
+	if h.ImportRoot == "" {
+		writeError(w, r, &repository.ForbiddenError{Message: "Directory imports are disabled on this server"})
+		return
+	}
+
+	var req ImportDirectoryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("ImportDirectory request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("ImportDirectory request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		log.Printf("ImportDirectory request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	dir, err := h.resolveImportPath(req.Path)
+	if err != nil {
+		log.Printf("ImportDirectory request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	h.runImport(w, req.Path, ingest.Dir(dir), req.UserID, req.WorkspaceID)
+}
+
+// resolveImportPath maps a client path onto the import root. Cleaning it as an
+// absolute path strips any leading "..", and resolving symlinks afterwards stops a
+// link inside the root from pointing outside of it.
+func (h *DocumentHandler) resolveImportPath(p string) (string, error) {
+	notFound := &repository.ValidationError{
+		Message:	"Request validation failed",
+		Fields:		[]repository.FieldError{{Field: "path", Message: "is not a directory under the import root"}},
+	}
+
+	root, err := filepath.EvalSymlinks(h.ImportRoot)
+	if err != nil {
+		return "", internalErr("Import root is unavailable", err)
+	}
+
+	dir, err := filepath.EvalSymlinks(filepath.Join(root, filepath.Clean("/"+p)))
+	if err != nil {
+		return "", notFound
+	}
+	if dir != root && !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		return "", notFound
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", notFound
+	}
+	return dir, nil
+}
+
+// runImport imports a walked source and writes the per-file report. A source that
+// fails part way is reported as a failed entry after the files that did import.
+func (h *DocumentHandler) runImport(w http.ResponseWriter, source string, walk ingest.Walker, userID, workspaceID uint) {
+	meta := ingest.Metadata{UserID: userID, WorkspaceID: workspaceID}
+
+	report, err := h.Importer.ImportAll(walk, meta)
+	if err != nil {
+		log.Printf("Import of %s stopped early: %v\n", source, err)
+		report.Files = append(report.Files, ingest.ImportedFile{
+			File:		source,
+			Status:		ingest.StatusFailed,
+			Reason:		"could not read source: " + err.Error(),
+		})
+		report.Failed++
+	}
+
+	log.Printf("Import of %s finished: %d imported, %d skipped, %d failed\n", source, report.Imported, report.Skipped, report.Failed)
+	writeJSON(w, http.StatusOK, report)
+}
+
+// ImportURL downloads a document from a URL, DOI or arXiv ID and imports it like
+// an upload, recording where it came from. Landing pages are followed to the PDF
+// they link to.
+func (h *DocumentHandler) ImportURL(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ImportURL request")
+
+	if h.Fetcher == nil {
+		writeError(w, r, &statusError{http.StatusServiceUnavailable, "fetch_unavailable", "Importing from URLs is not enabled on this server"})
+		return
+	}
+
+	var req ImportURLRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("ImportURL request failed: Invalid request: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("ImportURL request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		log.Printf("ImportURL request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	src, err := fetch.ParseSource(req.Source)
+	if err != nil {
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "source", Message: "must be an http(s) URL, a DOI or an arXiv ID"}},
+		})
+		return
+	}
+
+	log.Printf("Fetching %s\n", src.URL)
+	dl, err := h.Fetcher.Fetch(r.Context(), src)
+	if err != nil {
+		log.Printf("ImportURL request failed: Failed to fetch %s: %v\n", src.URL, err)
+		writeError(w, r, fetchErr(err))
+		return
+	}
+	file, err := h.Importer.Stash(dl.Body, dl.Filename)
+	dl.Body.Close()
+	if err != nil {
+		log.Printf("ImportURL request failed: Failed to store %s: %v\n", dl.URL, err)
+		writeError(w, r, fetchErr(err))
+		return
+	}
+
+	title := req.Title
+	if title == "" {
+		title = dl.Title
+	}
+	if title == "" {
+		title = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	}
+	meta := ingest.Metadata{
+		UserID:			req.UserID,
+		WorkspaceID:	req.WorkspaceID,
+		Title:			truncateTitle(title),
+		Year:			req.Year,
+		Authors:		parseAuthors(req.Authors),
+		SourceURL:		src.URL,
+		DOI:			src.DOI,
+		ArXivID:		src.ArXivID,
+	}
+	doc, err := h.Importer.Create(file, meta, req.OnDuplicate == "copy")
+
+	var dup *ingest.DuplicateError
+	if errors.As(err, &dup) {
+		if req.OnDuplicate == "link" {
+			log.Printf("Import is a duplicate; linking existing document ID=%d\n", dup.Existing.ID)
+			writeJSON(w, http.StatusOK, dup.Existing)
+			return
+		}
+		err = &repository.ConflictError{
+			Resource:	"document",
+			Message:	"This file is already in your library",
+			Details:	DuplicateDetails{DocumentID: dup.Existing.ID, Title: dup.Existing.Title, Options: []string{"link", "copy"}},
+		}
+	}
+	if err != nil {
+		log.Printf("ImportURL request failed: Failed to import %s: %v\n", dl.URL, err)
+		writeError(w, r, importErr("Failed to save document", err))
+		return
+	}
+
+	log.Printf("Imported %s as document ID=%d\n", dl.URL, doc.ID)
+	writeJSON(w, http.StatusCreated, doc)
+}
+
+// fetchErr maps the ways a download can fail onto HTTP statuses. The source's own
+// errors are a bad gateway rather than an error of this server.
+func fetchErr(err error) error {
+	var (
+		blocked		*fetch.BlockedError
+		status		*fetch.StatusError
+		netErr		net.Error
+	)
+	switch {
+	case errors.As(err, &blocked):
+		return &statusError{http.StatusForbidden, "source_blocked", "The source is on a private network"}
+	case errors.Is(err, fetch.ErrNoPDF):
+		return &statusError{http.StatusUnprocessableEntity, "no_pdf", "No PDF could be found at the source"}
+	case errors.As(err, &status):
+		return &statusError{http.StatusBadGateway, "fetch_failed", "The source responded with status " + http.StatusText(status.Status)}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &statusError{http.StatusGatewayTimeout, "fetch_timeout", "The source took too long to respond"}
+	case errors.Is(err, ingest.ErrNotPDF), errors.Is(err, ingest.ErrFileTooLarge), errors.Is(err, ingest.ErrQuotaExceeded):
+		return importErr("Failed to fetch document", err)
+	case errors.As(err, &netErr):
+		return &statusError{http.StatusBadGateway, "fetch_failed", "The source could not be reached"}
+	}
+	return internalErr("Failed to fetch document", err)
+}
+
+func truncateTitle(title string) string {
+	if r := []rune(title); len(r) > 255 {
+		return string(r[:255])
+	}
+	return title
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"backend/internal/repository"
+)
+
+
+// ListResponse is the envelope returned by every list endpoint.
+type ListResponse struct {
+	Items			any							`json:"items"`
+	NextCursor		string						`json:"next_cursor,omitempty"`
+	Facets			*repository.DocumentFacets	`json:"facets,omitempty"`
+}
+
+
+// parsePageRequest reads the limit, cursor and sort query parameters shared by all list endpoints.
+func parsePageRequest(r *http.Request) (repository.PageRequest, error) {
+	q := r.URL.Query()
+	page := repository.PageRequest{
+		Cursor:	q.Get("cursor"),
+		Sort:	q.Get("sort"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return page, fmt.Errorf("invalid limit")
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// parseFields reads the comma-separated ?fields= sparse fieldset. Without it the
+// defaults are used. Every requested field must be a JSON field of item.
+func parseFields(r *http.Request, item any, defaults []string) ([]string, error) {
+	v := r.URL.Query().Get("fields")
+	if v == "" {
+		return defaults, nil
+	}
+
+	allowed := jsonFieldNames(reflect.TypeOf(item))
+	fields := []string{"id"}
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || slices.Contains(fields, f) {
+			continue
+		}
+		if !slices.Contains(allowed, f) {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// projectFields re-encodes items keeping only the given JSON fields.
+func projectFields(items any, fields []string) ([]map[string]json.RawMessage, error) {
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]json.RawMessage, len(all))
+	for i, item := range all {
+		projected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := item[f]; ok {
+				projected[i][f] = v
+			}
+		}
+	}
+	return projected, nil
+}
+
+// writeList projects a page onto the requested fields and writes the list envelope.
+func writeList(w http.ResponseWriter, items any, nextCursor string, fields []string, facets *repository.DocumentFacets) error {
+	projected, err := projectFields(items, fields)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(ListResponse{Items: projected, NextCursor: nextCursor, Facets: facets})
+}
+
+// omitUnrequested returns the columns that can be left out of the query because
+// none of the requested fields need them.
+func omitUnrequested(fields []string, columns ...string) []string {
+	var omit []string
+	for _, c := range columns {
+		if !slices.Contains(fields, c) {
+			omit = append(omit, c)
+		}
+	}
+	return omit
+}
+
+func jsonFieldNames(t reflect.Type) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
\ No newline at end of file
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// noteSummaryFields is the default representation of a note in lists; the body
+// is only returned when asked for through ?fields=.
+var noteSummaryFields = []string{"id", "workspace_id", "user_id", "title", "created_at", "updated_at"}
+
+// revisionSummaryFields is the default representation of a note revision; like
+// notes, the body is only returned when asked for.
+var revisionSummaryFields = []string{"id", "note_id", "revision", "title", "created_at"}
+
+type CreateNoteRequest struct {
+	WorkspaceID		uint		`json:"workspace_id" validate:"required"`
+	UserID			uint		`json:"user_id"`
+	Title			string		`json:"title" validate:"required,max=255"`
+	Body			string		`json:"body" validate:"max=1000000"`
+}
+
+// UpdateNoteRequest changes the fields that are present and leaves the rest.
+type UpdateNoteRequest struct {
+	Title			*string		`json:"title" validate:"min=1,max=255"`
+	Body			*string		`json:"body" validate:"max=1000000"`
+}
+
+type NoteHandler struct {
+	NoteRepo		repository.NoteRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+	DocRepo			repository.DocumentRepository
+}
+
+
+func NewNoteHandler(repo repository.NoteRepository, workspaceRepo repository.WorkspaceRepository, docRepo repository.DocumentRepository) *NoteHandler {
+	log.Println("Initializing NoteHandler...")
+	return &NoteHandler{NoteRepo: repo, WorkspaceRepo: workspaceRepo, DocRepo: docRepo}
+}
+
+func (h *NoteHandler) GetWorkspaceNotes(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetWorkspaceNotes request")
+
+	workspaceID, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetWorkspaceNotes request failed: Invalid workspace_id: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace_id"))
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetWorkspaceNotes request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.Note{}, noteSummaryFields)
+	if err != nil {
+		log.Printf("GetWorkspaceNotes request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+	page.Omit = omitUnrequested(fields, "body")
+
+	if _, err := ownWorkspace(r, h.WorkspaceRepo, workspaceID); err != nil {
+		log.Printf("GetWorkspaceNotes request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	notes, err := h.NoteRepo.GetByWorkspaceID(workspaceID, page)
+	if err != nil {
+		log.Printf("GetWorkspaceNotes request failed: Failed to fetch notes: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch notes", err))
+		return
+	}
+
+	log.Printf("Found %d notes for workspace_id=%d\n", len(notes.Items), workspaceID)
+	writeList(w, notes.Items, notes.NextCursor, fields, nil)
+}
+
+func (h *NoteHandler) ViewNote(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ViewNote request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("ViewNote request failed: Invalid note ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing note ID"))
+		return
+	}
+
+	note, err := h.ownNote(r, id)
+	if err != nil {
+		log.Printf("ViewNote request failed: Failed to fetch note: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch note", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting CreateNote request")
+
+	var req CreateNoteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("CreateNote request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("CreateNote request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	if err := checkWorkspace(h.WorkspaceRepo, req.UserID, req.WorkspaceID); err != nil {
+		log.Printf("CreateNote request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	note := model.Note{
+		WorkspaceID:	req.WorkspaceID,
+		UserID:			req.UserID,
+		Title:			req.Title,
+		Body:			req.Body,
+	}
+	if err := h.NoteRepo.Create(&note); err != nil {
+		log.Printf("CreateNote request failed: Failed to create note in database: %v\n", err)
+		writeError(w, r, repoErr("Failed to create note", err))
+		return
+	}
+
+	log.Printf("Note created with ID=%d\n", note.ID)
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateNote request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("UpdateNote request failed: Invalid or missing note ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing note ID"))
+		return
+	}
+
+	var input UpdateNoteRequest
+	if err := decodeJSON(r, &input); err != nil {
+		log.Printf("UpdateNote request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	note, err := h.ownNote(r, id)
+	if err != nil {
+		log.Printf("UpdateNote request failed: Failed to fetch note: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch note", err))
+		return
+	}
+
+	if input.Title != nil {
+		note.Title = *input.Title
+	}
+	if input.Body != nil {
+		note.Body = *input.Body
+	}
+
+	if err := h.NoteRepo.Update(&note); err != nil {
+		log.Printf("UpdateNote request failed: Failed to update note in database: %v\n", err)
+		writeError(w, r, repoErr("Failed to update note", err))
+		return
+	}
+
+	log.Printf("Note ID=%d updated\n", note.ID)
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteNote request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteNote request failed: Invalid or missing note ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing note ID"))
+		return
+	}
+
+	if _, err := h.ownNote(r, id); err != nil {
+		log.Printf("DeleteNote request failed: Failed to fetch note: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch note", err))
+		return
+	}
+
+	err = h.NoteRepo.Delete(id)
+	if err != nil {
+		log.Printf("DeleteNote request failed: Failed to delete note in database: %v\n", err)
+		writeError(w, r, repoErr("Failed to delete note", err))
+		return
+	}
+
+	log.Printf("Successfully deleted note with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NoteHandler) GetNoteRevisions(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetNoteRevisions request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetNoteRevisions request failed: Invalid note ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing note ID"))
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetNoteRevisions request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.NoteRevision{}, revisionSummaryFields)
+	if err != nil {
+		log.Printf("GetNoteRevisions request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+	page.Omit = omitUnrequested(fields, "body")
+
+	if _, err := h.ownNote(r, id); err != nil {
+		log.Printf("GetNoteRevisions request failed: Failed to fetch note: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch note", err))
+		return
+	}
+
+	revisions, err := h.NoteRepo.GetRevisions(id, page)
+	if err != nil {
+		log.Printf("GetNoteRevisions request failed: Failed to fetch revisions: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch revisions", err))
+		return
+	}
+
+	writeList(w, revisions.Items, revisions.NextCursor, fields, nil)
+}
+
+// GetNoteBacklinks lists the notes that link to the note {id}.
+func (h *NoteHandler) GetNoteBacklinks(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetNoteBacklinks request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetNoteBacklinks request failed: Invalid note ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing note ID"))
+		return
+	}
+
+	note, err := h.ownNote(r, id)
+	if err != nil {
+		log.Printf("GetNoteBacklinks request failed: Failed to fetch note: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch note", err))
+		return
+	}
+
+	links, err := h.NoteRepo.GetNoteBacklinks(note.UserID, id)
+	if err != nil {
+		log.Printf("GetNoteBacklinks request failed: Failed to fetch backlinks: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch backlinks", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, links)
+}
+
+// GetDocumentBacklinks lists the notes that cite the document {id}.
+func (h *NoteHandler) GetDocumentBacklinks(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocumentBacklinks request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetDocumentBacklinks request failed: Invalid document ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	doc, err := own(r, "document", id, h.DocRepo.GetByDocumentID, func(doc model.Document) uint { return doc.UserID })
+	if err != nil {
+		log.Printf("GetDocumentBacklinks request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	links, err := h.NoteRepo.GetDocumentBacklinks(doc.UserID, id)
+	if err != nil {
+		log.Printf("GetDocumentBacklinks request failed: Failed to fetch backlinks: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch backlinks", err))
+		return
+	}
+
+	log.Printf("Found %d notes citing document ID=%d\n", len(links), id)
+	writeJSON(w, http.StatusOK, links)
+}
+
+// ownNote fetches a note of the authenticated user. Other users' notes are
+// reported as not found.
+func (h *NoteHandler) ownNote(r *http.Request, id uint) (model.Note, error) {
+	return own(r, "note", id, h.NoteRepo.GetByID, func(note model.Note) uint { return note.UserID })
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// MarkReadRequest marks notifications of a user read: those listed, or all of
+// them when ids is empty.
+type MarkReadRequest struct {
+	UserID			uint		`json:"user_id"`
+	IDs				[]uint		`json:"ids" validate:"max=500"`
+}
+
+// NotificationSettingsRequest replaces a user's notification settings. Email is
+// off, instant, hourly or daily; muted lists the kinds not to be notified of.
+type NotificationSettingsRequest struct {
+	UserID			uint		`json:"user_id"`
+	Email			string		`json:"email" validate:"required,oneof=off|instant|hourly|daily"`
+	WebhookURL		string		`json:"webhook_url" validate:"max=2048"`
+	Muted			[]string	`json:"muted"`
+}
+
+type UnreadCountResponse struct {
+	Unread			int64		`json:"unread"`
+}
+
+type NotificationHandler struct {
+	NotificationRepo	repository.NotificationRepository
+}
+
+
+func NewNotificationHandler(repo repository.NotificationRepository) *NotificationHandler {
+	log.Println("Initializing NotificationHandler...")
+	return &NotificationHandler{NotificationRepo: repo}
+}
+
+// GetNotifications lists a user's notifications, newest first, optionally only
+// the unread ones, those of one kind or those of one saved search.
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetNotifications request")
+
+	query := r.URL.Query()
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetNotifications request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	filter := repository.NotificationFilter{UserID: userID}
+	if v := query.Get("saved_search_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			log.Printf("GetNotifications request failed: Invalid saved_search_id: %v\n", err)
+			writeError(w, r, badRequest("Invalid saved_search_id"))
+			return
+		}
+		filter.SavedSearchID = uint(id)
+	}
+	filter.Kind = query.Get("kind")
+	if filter.Kind != "" && !model.IsValidNotificationKind(filter.Kind) {
+		writeError(w, r, badRequest("Invalid kind"))
+		return
+	}
+	if v := query.Get("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, badRequest("Invalid unread"))
+			return
+		}
+		filter.Unread = unread
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetNotifications request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.Notification{}, jsonFieldNames(reflect.TypeOf(model.Notification{})))
+	if err != nil {
+		log.Printf("GetNotifications request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	list, err := h.NotificationRepo.List(filter, page)
+	if err != nil {
+		log.Printf("GetNotifications request failed: Failed to fetch notifications: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch notifications", err))
+		return
+	}
+
+	log.Printf("Found %d notifications for user_id=%d\n", len(list.Items), userID)
+	writeList(w, list.Items, list.NextCursor, fields, nil)
+}
+
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetUnreadCount request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetUnreadCount request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	n, err := h.NotificationRepo.CountUnread(userID)
+	if err != nil {
+		log.Printf("GetUnreadCount request failed: Failed to count notifications: %v\n", err)
+		writeError(w, r, repoErr("Failed to count notifications", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UnreadCountResponse{Unread: n})
+}
+
+func (h *NotificationHandler) ReadNotification(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ReadNotification request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("ReadNotification request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing notification ID"))
+		return
+	}
+
+	note, err := own(r, "notification", id, h.NotificationRepo.GetByID, func(n model.Notification) uint { return n.UserID })
+	if err != nil {
+		log.Printf("ReadNotification request failed: Failed to fetch notification: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch notification", err))
+		return
+	}
+	if note.ReadAt == nil {
+		if _, err := h.NotificationRepo.MarkRead(note.UserID, []uint{id}); err != nil {
+			log.Printf("ReadNotification request failed: Failed to mark notification read: %v\n", err)
+			writeError(w, r, repoErr("Failed to mark notification read", err))
+			return
+		}
+		if note, err = h.NotificationRepo.GetByID(id); err != nil {
+			log.Printf("ReadNotification request failed: Failed to fetch notification: %v\n", err)
+			writeError(w, r, repoErr("Failed to fetch notification", err))
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, note)
+}
+
+// ReadNotifications marks several notifications of a user read at once, or all of
+// them.
+func (h *NotificationHandler) ReadNotifications(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ReadNotifications request")
+
+	var req MarkReadRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("ReadNotifications request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("ReadNotifications request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	updated, err := h.NotificationRepo.MarkRead(req.UserID, req.IDs)
+	if err != nil {
+		log.Printf("ReadNotifications request failed: Failed to mark notifications read: %v\n", err)
+		writeError(w, r, repoErr("Failed to mark notifications read", err))
+		return
+	}
+
+	log.Printf("Marked %d notifications read for user ID=%d\n", updated, req.UserID)
+	writeJSON(w, http.StatusOK, map[string]int{"updated": updated})
+}
+
+func (h *NotificationHandler) GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetNotificationSettings request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetNotificationSettings request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	settings, err := h.NotificationRepo.GetSettings(userID)
+	if err != nil {
+		log.Printf("GetNotificationSettings request failed: Failed to fetch settings: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch notification settings", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// UpdateNotificationSettings replaces a user's notification settings. Switching
+// from a digest to another setting sends what the digest gathered within a
+// minute.
+func (h *NotificationHandler) UpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateNotificationSettings request")
+
+	var req NotificationSettingsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("UpdateNotificationSettings request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("UpdateNotificationSettings request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	var fields []repository.FieldError
+	settings := model.NotificationSettings{UserID: req.UserID, Email: req.Email, WebhookURL: strings.TrimSpace(req.WebhookURL)}
+	if settings.WebhookURL != "" {
+		u, err := url.Parse(settings.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fields = append(fields, repository.FieldError{Field: "webhook_url", Message: "must be an http(s) URL"})
+		}
+	}
+	var muted []string
+	for _, kind := range req.Muted {
+		if !model.IsValidNotificationKind(kind) {
+			fields = append(fields, repository.FieldError{Field: "muted", Message: fmt.Sprintf("unknown kind %q", kind)})
+			continue
+		}
+		if !slices.Contains(muted, kind) {
+			muted = append(muted, kind)
+		}
+	}
+	if len(fields) > 0 {
+		writeError(w, r, &repository.ValidationError{Message: "Request validation failed", Fields: fields})
+		return
+	}
+	settings.Muted = strings.Join(muted, ",")
+
+	if err := h.NotificationRepo.SaveSettings(&settings); err != nil {
+		log.Printf("UpdateNotificationSettings request failed: Failed to save settings: %v\n", err)
+		writeError(w, r, repoErr("Failed to save notification settings", err))
+		return
+	}
+	saved, err := h.NotificationRepo.GetSettings(req.UserID)
+	if err != nil {
+		log.Printf("UpdateNotificationSettings request failed: Failed to fetch settings: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch notification settings", err))
+		return
+	}
+
+	log.Printf("Updated notification settings of user ID=%d\n", req.UserID)
+	writeJSON(w, http.StatusOK, saved)
+}
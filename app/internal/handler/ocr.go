@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// GetDocumentPages lists the per-page text of a document with the source of each
+// page and, for OCR pages, the recognition confidence. Pages are only stored once
+// OCR has run; other documents have none.
+func (h *DocumentHandler) GetDocumentPages(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocumentPages request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetDocumentPages request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("GetDocumentPages request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	pages, err := h.DocRepo.GetPages(id)
+	if err != nil {
+		log.Printf("GetDocumentPages request failed: Failed to fetch pages: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch pages", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pages)
+}
+
+// RecognizeDocument queues a document for OCR, for example after its workspace
+// has turned OCR back on. The work happens in the background; the document is
+// returned with ocr_status pending and can be polled.
+func (h *DocumentHandler) RecognizeDocument(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting RecognizeDocument request")
+
+	if h.Importer.OCR == nil {
+		writeError(w, r, &statusError{http.StatusServiceUnavailable, "ocr_unavailable", "OCR is not enabled on this server"})
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("RecognizeDocument request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	doc, err := h.ownDocument(r, id)
+	if err != nil {
+		log.Printf("RecognizeDocument request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	if doc.ExtractionStatus != model.ExtractionOK && doc.ExtractionStatus != model.ExtractionNoText {
+		log.Printf("RecognizeDocument request failed: Document ID=%d is %s\n", id, doc.ExtractionStatus)
+		writeError(w, r, &repository.ConflictError{
+			Resource:	"document",
+			Message:	"Document can't be read for OCR; extract its text first",
+		})
+		return
+	}
+
+	if err := h.DocRepo.SetOCRStatus(id, model.OCRPending); err != nil {
+		log.Printf("RecognizeDocument request failed: Failed to update document: %v\n", err)
+		writeError(w, r, repoErr("Failed to update document", err))
+		return
+	}
+	doc.OCRStatus = model.OCRPending
+	h.Importer.OCR.Enqueue(id)
+
+	log.Printf("Queued document ID=%d for OCR\n", id)
+	writeJSON(w, http.StatusAccepted, doc)
+}
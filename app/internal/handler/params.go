@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/internal/middleware"
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/validate"
+)
+
+
+// pathID parses the numeric path wildcard name, e.g. {id} in /api/v2/documents/{id}.
+func pathID(r *http.Request, name string) (uint, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		return 0, fmt.Errorf("missing %s", name)
+	}
+
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return uint(id), nil
+}
+
+// currentUser returns the user a request acts for: the one its bearer token
+// belongs to. Requests without a token are refused. The user_id a client sends,
+// claimed, may be left out; if it is given it must be that same user.
+func currentUser(r *http.Request, claimed uint) (uint, error) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		return 0, &repository.UnauthorizedError{Message: "Authentication required"}
+	}
+	if claimed != 0 && claimed != userID {
+		return 0, &repository.ForbiddenError{Message: "user_id is not the authenticated user"}
+	}
+	return userID, nil
+}
+
+// queryUser is currentUser for requests that name the user in the user_id query
+// parameter.
+func queryUser(r *http.Request) (uint, error) {
+	var claimed uint
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, badRequest("Invalid user_id")
+		}
+		claimed = uint(id)
+	}
+	return currentUser(r, claimed)
+}
+
+// bodyUser is currentUser for request bodies with a user_id field, which it sets
+// to the authenticated user.
+func bodyUser(r *http.Request, userID *uint) error {
+	id, err := currentUser(r, *userID)
+	*userID = id
+	return err
+}
+
+// own fetches the resource with the given ID for the authenticated user. Other
+// users' resources are reported as not found, like IDs that don't exist, so that
+// they can't be told apart. owner returns the user a resource belongs to.
+func own[T any](r *http.Request, resource string, id uint, get func(uint) (T, error), owner func(T) uint) (T, error) {
+	var zero T
+	userID, err := currentUser(r, 0)
+	if err != nil {
+		return zero, err
+	}
+	v, err := get(id)
+	if err == nil && owner(v) != userID {
+		return zero, &repository.NotFoundError{Resource: resource, ID: id}
+	}
+	return v, err
+}
+
+// ownWorkspace fetches a workspace of the authenticated user.
+func ownWorkspace(r *http.Request, workspaces repository.WorkspaceRepository, id uint) (model.Workspace, error) {
+	return own(r, "workspace", id, workspaces.GetByID, func(ws model.Workspace) uint { return ws.UserID })
+}
+
+// checkWorkspace checks that the workspace a request puts something into is one of
+// the user's own. 0 is no workspace.
+func checkWorkspace(workspaces repository.WorkspaceRepository, userID, workspaceID uint) error {
+	if workspaceID == 0 {
+		return nil
+	}
+	ws, err := workspaces.GetByID(workspaceID)
+	if err == nil && ws.UserID != userID {
+		err = &repository.ForbiddenError{Message: "The workspace belongs to another user"}
+	}
+	return err
+}
+
+// decodeJSON strictly decodes the request body into dst, rejecting unknown fields
+// and trailing data, and then runs the DTO's validation rules. Bodies larger than
+// middleware.MaxJSONBody are refused.
+func decodeJSON(r *http.Request, dst any) error {
+	return decodeJSONLimit(r, dst, middleware.MaxJSONBody)
+}
+
+// decodeJSONLimit is decodeJSON for requests whose body may be up to limit bytes.
+func decodeJSONLimit(r *http.Request, dst any, limit int64) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, limit))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if dec.More() {
+		return badRequest("Request body must contain a single JSON object")
+	}
+	return validate.Struct(dst)
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return badRequest("Request body is required")
+	case errors.As(err, &tooLarge):
+		return &statusError{http.StatusRequestEntityTooLarge, "too_large", "Request body is too large"}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest("Malformed JSON")
+	case errors.As(err, &typeErr):
+		return &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}},
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: field, Message: "is not a recognised field"}},
+		}
+	}
+	return badRequest("Invalid payload")
+}
+
+// decodeForm fills dst from multipart form values by the json names of its fields,
+// the form counterpart of decodeJSON. fileFields names the file parts the form may
+// carry. Unknown fields and numbers that don't parse are reported as field errors
+// instead of becoming zero, and the DTO's validation rules run last.
+func decodeForm(form *multipart.Form, dst any, fileFields ...string) error {
+	rv := reflect.ValueOf(dst).Elem()
+	rt := rv.Type()
+
+	index := map[string]int{}
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+
+	var names []string
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	for name := range form.File {
+		if _, ok := form.Value[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var fields []repository.FieldError
+	for _, name := range names {
+		i, ok := index[name]
+		if _, isFile := form.File[name]; isFile {
+			ok = slices.Contains(fileFields, name)
+		}
+		if !ok {
+			fields = append(fields, repository.FieldError{Field: name, Message: "is not a recognised field"})
+			continue
+		}
+		if len(form.Value[name]) == 0 {
+			continue
+		}
+
+		v := strings.TrimSpace(form.Value[name][0])
+		if v == "" {
+			continue
+		}
+		f := rv.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(v)
+		case reflect.Uint, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				fields = append(fields, repository.FieldError{Field: name, Message: "must be a positive integer"})
+			}
+			f.SetUint(n)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				fields = append(fields, repository.FieldError{Field: name, Message: "must be an integer"})
+			}
+			f.SetInt(n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				fields = append(fields, repository.FieldError{Field: name, Message: "must be true or false"})
+			}
+			f.SetBool(b)
+		}
+	}
+
+	if err := validate.Struct(dst); err != nil {
+		var ve *repository.ValidationError
+		if !errors.As(err, &ve) {
+			return err
+		}
+		fields = append(fields, ve.Fields...)
+	}
+
+	if len(fields) > 0 {
+		return &repository.ValidationError{Message: "Request validation failed", Fields: fields}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/internal/ingest"
+	"backend/internal/middleware"
+	"backend/internal/repository"
+)
+
+
+// ErrorResponse is the JSON envelope of every error returned by the API.
+type ErrorResponse struct {
+	Code			string		`json:"code"`
+	Message			string		`json:"message"`
+	Details			any			`json:"details,omitempty"`
+	RequestID		string		`json:"request_id"`
+}
+
+// MessageResponse is the body of operations that only report success.
+type MessageResponse struct {
+	Message			string		`json:"message"`
+}
+
+// internalError carries a client-safe message for an unexpected failure; the
+// wrapped error is only logged.
+type internalError struct {
+	message	string
+	err		error
+}
+
+
+// statusError refuses a request with a status of its own, for limits that aren't
+// about the request being malformed, such as its size or media type.
+type statusError struct {
+	status	int
+	code	string
+	message	string
+}
+
+
+func (e *statusError) Error() string { return e.message }
+
+func (e *internalError) Error() string { return e.message + ": " + e.err.Error() }
+
+func (e *internalError) Unwrap() error { return e.err }
+
+func internalErr(message string, err error) error {
+	return &internalError{message: message, err: err}
+}
+
+// repoErr passes domain errors from the repository through unchanged and wraps
+// anything else as an internal error with the given message.
+func repoErr(message string, err error) error {
+	for _, target := range []error{
+		repository.ErrNotFound, repository.ErrConflict, repository.ErrForbidden,
+		repository.ErrUnauthorized, repository.ErrValidation,
+	} {
+		if errors.Is(err, target) {
+			return err
+		}
+	}
+	return internalErr(message, err)
+}
+
+// importErr maps the ways a file can be refused by the importer onto their own
+// statuses and otherwise behaves like repoErr.
+func importErr(message string, err error) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, ingest.ErrNotPDF):
+		return &statusError{http.StatusUnsupportedMediaType, "unsupported_media_type", "File is not a PDF document"}
+	case errors.Is(err, ingest.ErrFileTooLarge), errors.As(err, &tooLarge):
+		return &statusError{http.StatusRequestEntityTooLarge, "too_large", "File is larger than the upload limit"}
+	case errors.Is(err, ingest.ErrQuotaExceeded):
+		return &statusError{http.StatusRequestEntityTooLarge, "quota_exceeded", "Storage quota exceeded"}
+	}
+	return repoErr(message, err)
+}
+
+// badRequest reports a malformed request as a validation error.
+func badRequest(message string) error {
+	return &repository.ValidationError{Message: message}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v\n", err)
+	}
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, MessageResponse{Message: message})
+}
+
+// writeError maps domain errors onto HTTP statuses and writes the error envelope.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := ErrorResponse{RequestID: middleware.GetRequestID(r.Context())}
+	status := http.StatusInternalServerError
+
+	var (
+		notFound		*repository.NotFoundError
+		conflict		*repository.ConflictError
+		forbidden		*repository.ForbiddenError
+		unauthorized	*repository.UnauthorizedError
+		validation		*repository.ValidationError
+		refused			*statusError
+		internal		*internalError
+	)
+
+	switch {
+	case errors.As(err, &notFound):
+		status, resp.Code, resp.Message = http.StatusNotFound, "not_found", capitalize(notFound.Error())
+	case errors.As(err, &conflict):
+		status, resp.Code, resp.Message = http.StatusConflict, "conflict", capitalize(conflict.Error())
+		resp.Details = conflict.Details
+	case errors.As(err, &forbidden):
+		status, resp.Code, resp.Message = http.StatusForbidden, "forbidden", capitalize(forbidden.Error())
+	case errors.As(err, &unauthorized):
+		status, resp.Code, resp.Message = http.StatusUnauthorized, "unauthorized", capitalize(unauthorized.Error())
+	case errors.As(err, &validation):
+		status, resp.Code, resp.Message = http.StatusBadRequest, "validation_failed", capitalize(validation.Error())
+		if len(validation.Fields) > 0 {
+			resp.Details = validation.Fields
+		}
+	case errors.As(err, &refused):
+		status, resp.Code, resp.Message = refused.status, refused.code, refused.message
+	case errors.As(err, &internal):
+		resp.Code, resp.Message = "internal_error", internal.message
+	default:
+		resp.Code, resp.Message = "internal_error", "Internal server error"
+	}
+
+	writeJSON(w, status, resp)
+}
+
+// NotFound answers a request that no route matches.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &repository.NotFoundError{Resource: "route"})
+}
+
+// MethodNotAllowed answers a request for a route that doesn't take its method. The
+// Allow header listing the methods it takes is left to the caller.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &statusError{http.StatusMethodNotAllowed, "method_not_allowed", "Method " + r.Method + " is not allowed"})
+}
+
+func capitalize(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+type SearchHandler struct {
+	DocRepo		repository.DocumentRepository
+	NoteRepo	repository.NoteRepository
+}
+
+type SearchResponse struct {
+	Documents		[]model.Document			`json:"documents"`
+	Notes			[]model.Note				`json:"notes"`
+	// Sections lists the matching sections when the search is limited to a
+	// kind of section. Notes have no sections and aren't searched then.
+	Sections		[]model.DocumentSection		`json:"sections,omitempty"`
+}
+
+
+func NewSearchHandler(docRepo repository.DocumentRepository, noteRepo repository.NoteRepository) *SearchHandler {
+	log.Println("Initializing SearchHandler...")
+	return &SearchHandler{DocRepo: docRepo, NoteRepo: noteRepo}
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting Search request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("Search request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		log.Println("Search request failed: Missing q parameter")
+		writeError(w, r, badRequest("Missing search query"))
+		return
+	}
+
+	var scope repository.SearchScope
+	if v := r.URL.Query().Get("workspace_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			log.Printf("Search request failed: Invalid workspace_id: %v\n", err)
+			writeError(w, r, badRequest("Invalid workspace_id"))
+			return
+		}
+		scope.WorkspaceID = uint(id)
+	}
+	if scope.Section = r.URL.Query().Get("section"); scope.Section != "" && !model.IsValidSectionKind(scope.Section) {
+		log.Printf("Search request failed: Invalid section %q\n", scope.Section)
+		writeError(w, r, badRequest("Invalid section"))
+		return
+	}
+
+	docs, err := h.DocRepo.Search(userID, query, scope)
+	if err != nil {
+		log.Printf("Search request failed: Failed to search documents: %v\n", err)
+		writeError(w, r, repoErr("Failed to search documents", err))
+		return
+	}
+
+	resp := SearchResponse{Documents: docs, Notes: []model.Note{}}
+	if scope.Section != "" {
+		resp.Sections, err = h.DocRepo.SearchSections(userID, query, scope)
+		if err != nil {
+			log.Printf("Search request failed: Failed to search sections: %v\n", err)
+			writeError(w, r, repoErr("Failed to search sections", err))
+			return
+		}
+	} else {
+		resp.Notes, err = h.NoteRepo.Search(userID, query, scope.WorkspaceID)
+		if err != nil {
+			log.Printf("Search request failed: Failed to search notes: %v\n", err)
+			writeError(w, r, repoErr("Failed to search notes", err))
+			return
+		}
+	}
+
+	log.Printf("Search for %q matched %d documents, %d notes and %d sections\n", query, len(resp.Documents), len(resp.Notes), len(resp.Sections))
+	writeJSON(w, http.StatusOK, resp)
+}
\ No newline at end of file
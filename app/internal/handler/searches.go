@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// SaveSearchRequest saves a search to run on every new document of the user, in
+// one workspace or, without workspace_id, the whole library. A search needs a
+// query or at least one filter; mode defaults to keyword.
+type SaveSearchRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+	Name			string		`json:"name" validate:"max=255"`
+	Mode			string		`json:"mode" validate:"omitempty,oneof=keyword|semantic"`
+	Query			string		`json:"query" validate:"max=1024"`
+	MinScore		float64		`json:"min_score" validate:"min=0,max=1"`
+	Year			int			`json:"year" validate:"min=0,max=9999"`
+	Author			string		`json:"author" validate:"max=255"`
+	Format			string		`json:"format" validate:"omitempty,oneof=pdf|html"`
+	NotifyEmail		bool		`json:"notify_email"`
+	WebhookURL		string		`json:"webhook_url" validate:"max=2048"`
+}
+
+// UpdateSearchRequest changes the fields that are present and leaves the rest.
+// Empty strings and zeros clear a filter.
+type UpdateSearchRequest struct {
+	WorkspaceID		*uint		`json:"workspace_id"`
+	Name			*string		`json:"name" validate:"min=1,max=255"`
+	Mode			*string		`json:"mode" validate:"omitempty,oneof=keyword|semantic"`
+	Query			*string		`json:"query" validate:"max=1024"`
+	MinScore		*float64	`json:"min_score" validate:"min=0,max=1"`
+	Year			*int		`json:"year" validate:"min=0,max=9999"`
+	Author			*string		`json:"author" validate:"max=255"`
+	Format			*string		`json:"format" validate:"max=16"`
+	NotifyEmail		*bool		`json:"notify_email"`
+	WebhookURL		*string		`json:"webhook_url" validate:"max=2048"`
+}
+
+type SavedSearchHandler struct {
+	SearchRepo		repository.SavedSearchRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+}
+
+
+func NewSavedSearchHandler(searchRepo repository.SavedSearchRepository, workspaceRepo repository.WorkspaceRepository) *SavedSearchHandler {
+	log.Println("Initializing SavedSearchHandler...")
+	return &SavedSearchHandler{SearchRepo: searchRepo, WorkspaceRepo: workspaceRepo}
+}
+
+func (h *SavedSearchHandler) GetUserSavedSearches(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetUserSavedSearches request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetUserSavedSearches request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetUserSavedSearches request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.SavedSearch{}, jsonFieldNames(reflect.TypeOf(model.SavedSearch{})))
+	if err != nil {
+		log.Printf("GetUserSavedSearches request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	list, err := h.SearchRepo.GetByUserID(userID, page)
+	if err != nil {
+		log.Printf("GetUserSavedSearches request failed: Failed to fetch saved searches: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch saved searches", err))
+		return
+	}
+
+	log.Printf("Found %d saved searches for user_id=%d\n", len(list.Items), userID)
+	writeList(w, list.Items, list.NextCursor, fields, nil)
+}
+
+// SaveSearch saves a search. It applies to documents added from now on; the
+// documents already in the library can be searched with /search.
+func (h *SavedSearchHandler) SaveSearch(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting SaveSearch request")
+
+	var req SaveSearchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("SaveSearch request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("SaveSearch request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	search := model.SavedSearch{
+		UserID:			req.UserID,
+		WorkspaceID:	req.WorkspaceID,
+		Name:			strings.TrimSpace(req.Name),
+		Mode:			req.Mode,
+		Query:			strings.TrimSpace(req.Query),
+		MinScore:		req.MinScore,
+		Year:			req.Year,
+		Author:			strings.TrimSpace(req.Author),
+		Format:			req.Format,
+		NotifyEmail:	req.NotifyEmail,
+		WebhookURL:		strings.TrimSpace(req.WebhookURL),
+	}
+	if search.Mode == "" {
+		search.Mode = model.SearchModeKeyword
+	}
+	if search.Name == "" {
+		search.Name = truncateTitle(firstNonBlank(search.Query, search.Author, search.Format, "Saved search"))
+	}
+	if err := checkSavedSearch(&search); err != nil {
+		log.Printf("SaveSearch request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, search.UserID, search.WorkspaceID); err != nil {
+		log.Printf("SaveSearch request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	if err := h.SearchRepo.Create(&search); err != nil {
+		log.Printf("SaveSearch request failed: Failed to save search: %v\n", err)
+		writeError(w, r, repoErr("Failed to save search", err))
+		return
+	}
+
+	log.Printf("Saved search created with ID=%d for user ID=%d\n", search.ID, search.UserID)
+	writeJSON(w, http.StatusCreated, search)
+}
+
+func (h *SavedSearchHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetSavedSearch request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetSavedSearch request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing saved search ID"))
+		return
+	}
+
+	search, err := h.ownSearch(r, id)
+	if err != nil {
+		log.Printf("GetSavedSearch request failed: Failed to fetch saved search: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch saved search", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, search)
+}
+
+func (h *SavedSearchHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateSavedSearch request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("UpdateSavedSearch request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing saved search ID"))
+		return
+	}
+
+	userID, err := currentUser(r, 0)
+	if err != nil {
+		log.Printf("UpdateSavedSearch request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	var req UpdateSearchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("UpdateSavedSearch request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	search, err := h.ownSearch(r, id)
+	if err != nil {
+		log.Printf("UpdateSavedSearch request failed: Failed to fetch saved search: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch saved search", err))
+		return
+	}
+
+	if req.WorkspaceID != nil {
+		search.WorkspaceID = *req.WorkspaceID
+	}
+	if req.Name != nil {
+		search.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Mode != nil {
+		search.Mode = *req.Mode
+	}
+	if req.Query != nil {
+		search.Query = strings.TrimSpace(*req.Query)
+	}
+	if req.MinScore != nil {
+		search.MinScore = *req.MinScore
+	}
+	if req.Year != nil {
+		search.Year = *req.Year
+	}
+	if req.Author != nil {
+		search.Author = strings.TrimSpace(*req.Author)
+	}
+	if req.Format != nil {
+		search.Format = *req.Format
+	}
+	if req.NotifyEmail != nil {
+		search.NotifyEmail = *req.NotifyEmail
+	}
+	if req.WebhookURL != nil {
+		search.WebhookURL = strings.TrimSpace(*req.WebhookURL)
+	}
+	if err := checkSavedSearch(&search); err != nil {
+		log.Printf("UpdateSavedSearch request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if req.WorkspaceID != nil {
+		if err := checkWorkspace(h.WorkspaceRepo, userID, search.WorkspaceID); err != nil {
+			log.Printf("UpdateSavedSearch request failed: Failed to fetch workspace: %v\n", err)
+			writeError(w, r, repoErr("Failed to fetch workspace", err))
+			return
+		}
+	}
+
+	if err := h.SearchRepo.Update(&search); err != nil {
+		log.Printf("UpdateSavedSearch request failed: Failed to update saved search: %v\n", err)
+		writeError(w, r, repoErr("Failed to update saved search", err))
+		return
+	}
+
+	log.Printf("Updated saved search ID=%d\n", id)
+	writeJSON(w, http.StatusOK, search)
+}
+
+// DeleteSavedSearch stops a search. Its past notifications are kept.
+func (h *SavedSearchHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteSavedSearch request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteSavedSearch request failed: Invalid or missing saved search ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing saved search ID"))
+		return
+	}
+
+	if _, err := h.ownSearch(r, id); err != nil {
+		log.Printf("DeleteSavedSearch request failed: Failed to fetch saved search: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch saved search", err))
+		return
+	}
+
+	if err := h.SearchRepo.Delete(id); err != nil {
+		log.Printf("DeleteSavedSearch request failed: Failed to delete saved search: %v\n", err)
+		writeError(w, r, repoErr("Failed to delete saved search", err))
+		return
+	}
+
+	log.Printf("Successfully deleted saved search with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownSearch fetches a saved search of the authenticated user. Other users' searches
+// are reported as not found.
+func (h *SavedSearchHandler) ownSearch(r *http.Request, id uint) (model.SavedSearch, error) {
+	return own(r, "saved search", id, h.SearchRepo.GetByID, func(s model.SavedSearch) uint { return s.UserID })
+}
+
+// checkSavedSearch checks what the validate tags can't: that a search matches
+// something less than every document, and that its webhook is an http(s) URL.
+func checkSavedSearch(s *model.SavedSearch) error {
+	var fields []repository.FieldError
+	if s.Query == "" && s.Year == 0 && s.Author == "" && s.Format == "" {
+		fields = append(fields, repository.FieldError{Field: "query", Message: "a query or at least one filter is required"})
+	}
+	if s.Format != "" && s.Format != model.FormatPDF && s.Format != model.FormatHTML {
+		fields = append(fields, repository.FieldError{Field: "format", Message: "must be one of: pdf, html"})
+	}
+	if s.WebhookURL != "" {
+		u, err := url.Parse(s.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fields = append(fields, repository.FieldError{Field: "webhook_url", Message: "must be an http(s) URL"})
+		}
+	}
+	if len(fields) > 0 {
+		return &repository.ValidationError{Message: "Request validation failed", Fields: fields}
+	}
+	return nil
+}
+
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/model"
+)
+
+
+// GetDocumentOutline lists the sections of a document in reading order, with
+// their kind, heading, level and page but without their text.
+func (h *DocumentHandler) GetDocumentOutline(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocumentOutline request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetDocumentOutline request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("GetDocumentOutline request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	sections, err := h.DocRepo.GetOutline(id)
+	if err != nil {
+		log.Printf("GetDocumentOutline request failed: Failed to fetch sections: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch sections", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sections)
+}
+
+// GetDocumentSections returns the sections of a document with their text,
+// optionally only those of one kind, such as ?kind=methods.
+func (h *DocumentHandler) GetDocumentSections(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocumentSections request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetDocumentSections request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && !model.IsValidSectionKind(kind) {
+		log.Printf("GetDocumentSections request failed: Invalid kind %q\n", kind)
+		writeError(w, r, badRequest("Invalid kind"))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("GetDocumentSections request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	sections, err := h.DocRepo.GetSections(id, kind)
+	if err != nil {
+		log.Printf("GetDocumentSections request failed: Failed to fetch sections: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch sections", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sections)
+}
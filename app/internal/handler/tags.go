@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+type TagHandler struct {
+	TagRepo repository.TagRepository
+}
+
+type CreateTagRequest struct {
+	UserID			uint		`json:"user_id"`
+	ParentID		*uint		`json:"parent_id"`
+	Name			string		`json:"name" validate:"required,max=100"`
+}
+
+type BulkTagRequest struct {
+	UserID			uint		`json:"user_id"`
+	DocumentIDs		[]uint		`json:"document_ids" validate:"required,max=500"`
+	TagIDs			[]uint		`json:"tag_ids" validate:"required,max=100"`
+}
+
+
+func NewTagHandler(repo repository.TagRepository) *TagHandler {
+	log.Println("Initializing TagHandler...")
+	return &TagHandler{TagRepo: repo}
+}
+
+func (h *TagHandler) GetUserTags(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetUserTags request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetUserTags request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetUserTags request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.Tag{}, jsonFieldNames(reflect.TypeOf(model.Tag{})))
+	if err != nil {
+		log.Printf("GetUserTags request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	tags, err := h.TagRepo.GetByUserID(userID, page)
+	if err != nil {
+		log.Printf("GetUserTags request failed: Failed to fetch tags: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch tags", err))
+		return
+	}
+
+	log.Printf("Found %d tags for user_id=%d\n", len(tags.Items), userID)
+	writeList(w, tags.Items, tags.NextCursor, fields, nil)
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting CreateTag request")
+
+	var req CreateTagRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("CreateTag request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("CreateTag request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	tag := model.Tag{UserID: req.UserID, ParentID: req.ParentID, Name: strings.TrimSpace(req.Name)}
+	if tag.Name == "" || strings.Contains(tag.Name, "/") {
+		log.Println("CreateTag request failed: Invalid name")
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "name", Message: "must be non-blank and must not contain '/'"}},
+		})
+		return
+	}
+
+	if tag.ParentID != nil {
+		parent, err := h.TagRepo.GetByID(*tag.ParentID)
+		if err != nil || parent.UserID != tag.UserID {
+			log.Printf("CreateTag request failed: Invalid parent_id=%d: %v\n", *tag.ParentID, err)
+			writeError(w, r, &repository.ValidationError{
+				Message:	"Request validation failed",
+				Fields:		[]repository.FieldError{{Field: "parent_id", Message: "must be one of your tags"}},
+			})
+			return
+		}
+	}
+
+	if err := h.TagRepo.Create(&tag); err != nil {
+		log.Printf("CreateTag request failed: Failed to create tag in database: %v\n", err)
+		writeError(w, r, repoErr("Failed to create tag", err))
+		return
+	}
+
+	log.Printf("Tag created with ID=%d path=%q\n", tag.ID, tag.Path)
+	writeJSON(w, http.StatusCreated, tag)
+}
+
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteTag request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteTag request failed: Invalid or missing tag ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing tag ID"))
+		return
+	}
+
+	if _, err := own(r, "tag", id, h.TagRepo.GetByID, func(tag model.Tag) uint { return tag.UserID }); err != nil {
+		log.Printf("DeleteTag request failed: Failed to fetch tag: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch tag", err))
+		return
+	}
+
+	err = h.TagRepo.Delete(id)
+	if err != nil {
+		log.Printf("DeleteTag request failed: Failed to delete tag in database: %v\n", err)
+		writeError(w, r, repoErr("Failed to delete tag", err))
+		return
+	}
+
+	log.Printf("Successfully deleted tag with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TagHandler) TagDocuments(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting TagDocuments request")
+
+	p, ok := decodeBulkTagPayload(w, r, "TagDocuments")
+	if !ok {
+		return
+	}
+
+	added, err := h.TagRepo.TagDocuments(p.UserID, p.DocumentIDs, p.TagIDs)
+	if err != nil {
+		log.Printf("TagDocuments request failed: Failed to tag documents: %v\n", err)
+		writeError(w, r, repoErr("Failed to tag documents", err))
+		return
+	}
+
+	log.Printf("Added %d document tags\n", added)
+	writeJSON(w, http.StatusOK, map[string]int{"added": added})
+}
+
+func (h *TagHandler) UntagDocuments(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UntagDocuments request")
+
+	p, ok := decodeBulkTagPayload(w, r, "UntagDocuments")
+	if !ok {
+		return
+	}
+
+	removed, err := h.TagRepo.UntagDocuments(p.UserID, p.DocumentIDs, p.TagIDs)
+	if err != nil {
+		log.Printf("UntagDocuments request failed: Failed to untag documents: %v\n", err)
+		writeError(w, r, repoErr("Failed to untag documents", err))
+		return
+	}
+
+	log.Printf("Removed %d document tags\n", removed)
+	writeJSON(w, http.StatusOK, map[string]int{"removed": removed})
+}
+
+func decodeBulkTagPayload(w http.ResponseWriter, r *http.Request, op string) (BulkTagRequest, bool) {
+	var p BulkTagRequest
+	if err := decodeJSON(r, &p); err != nil {
+		log.Printf("%s request failed: Invalid payload: %v\n", op, err)
+		writeError(w, r, err)
+		return p, false
+	}
+	if err := bodyUser(r, &p.UserID); err != nil {
+		log.Printf("%s request failed: %v\n", op, err)
+		writeError(w, r, err)
+		return p, false
+	}
+	return p, true
+}
\ No newline at end of file
@@ -0,0 +1,449 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/ingest"
+	"backend/internal/middleware"
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/storage"
+	"backend/internal/util"
+)
+
+
+const (
+	// maxFormFields bounds the size of the non-file parts of an upload form.
+	maxFormFields	= 1 << 20
+	maxFieldSize	= 64 << 10
+
+	tusVersion		= "1.0.0"
+	tusExtensions	= "creation,termination,expiration"
+	// uploadTTL is how long an unfinished resumable upload may be resumed.
+	uploadTTL		= 7 * 24 * time.Hour
+)
+
+// UploadHandler serves resumable uploads following the tus 1.0 protocol
+// (https://tus.io/protocols/resumable-upload), for files too large to send in one
+// request. The document is created once the last byte has arrived.
+type UploadHandler struct {
+	Uploads			repository.UploadRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+	Importer		*ingest.Importer
+	Store			*storage.Store
+
+	// busy holds the uploads with a PATCH in progress; tus forbids concurrent ones.
+	busy			sync.Map
+}
+
+
+func NewUploadHandler(uploads repository.UploadRepository, workspaceRepo repository.WorkspaceRepository, importer *ingest.Importer, store *storage.Store) *UploadHandler {
+	log.Println("Initializing upload handler...")
+	return &UploadHandler{Uploads: uploads, WorkspaceRepo: workspaceRepo, Importer: importer, Store: store}
+}
+
+// receiveUpload reads a multipart upload without buffering it: the part named
+// fileField is streamed into storage as it arrives, and the other parts are decoded
+// into dst like decodeForm does. The stored file must be passed on to the importer
+// or discarded by the caller.
+func receiveUpload(w http.ResponseWriter, r *http.Request, im *ingest.Importer, fileField string, dst any) (f ingest.StoredFile, err error) {
+	if im.Limits.MaxFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, im.Limits.MaxFileSize+maxFormFields)
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return f, badRequest("Could not parse form")
+	}
+
+	defer func() {
+		if err != nil {
+			im.Discard(f)
+		}
+	}()
+
+	form := &multipart.Form{Value: map[string][]string{}, File: map[string][]*multipart.FileHeader{}}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return f, importErr("", err)
+			}
+			return f, badRequest("Could not parse form")
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+			if err != nil {
+				return f, badRequest("Could not parse form")
+			}
+			if len(b) > maxFieldSize {
+				return f, &repository.ValidationError{
+					Message:	"Request validation failed",
+					Fields:		[]repository.FieldError{{Field: name, Message: "is too long"}},
+				}
+			}
+			form.Value[name] = append(form.Value[name], string(b))
+			continue
+		}
+
+		if name != fileField || f.Hash != "" {
+			// Left for decodeForm to report, or a second copy of the file to ignore.
+			form.File[name] = append(form.File[name], &multipart.FileHeader{Filename: part.FileName()})
+			continue
+		}
+		if f, err = im.Stash(part, part.FileName()); err != nil {
+			return f, importErr("Failed to store file", err)
+		}
+		form.File[name] = []*multipart.FileHeader{{Filename: f.Filename, Size: f.Size}}
+	}
+
+	if f.Hash == "" {
+		return f, badRequest("PDF file not provided")
+	}
+	if err := decodeForm(form, dst, fileField); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// Options describes the server's tus support.
+func (h *UploadHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	if h.Importer.Limits.MaxFileSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.Importer.Limits.MaxFileSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload of Upload-Length bytes. Upload-Metadata
+// may carry filename, title, workspace_id, year and authors for the document.
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting CreateUpload request")
+
+	userID, ok := h.begin(w, r)
+	if !ok {
+		return
+	}
+	h.purgeExpired()
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		log.Printf("CreateUpload request failed: Invalid Upload-Length %q\n", r.Header.Get("Upload-Length"))
+		writeError(w, r, badRequest("Upload-Length must be a positive number of bytes"))
+		return
+	}
+	if max := h.Importer.Limits.MaxFileSize; max > 0 && length > max {
+		writeError(w, r, importErr("", ingest.ErrFileTooLarge))
+		return
+	}
+	if err := h.Importer.CheckQuota(userID, length); err != nil {
+		log.Printf("CreateUpload request failed: %v\n", err)
+		writeError(w, r, importErr("Failed to check storage quota", err))
+		return
+	}
+
+	upload, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		log.Printf("CreateUpload request failed: Invalid metadata: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := checkWorkspace(h.WorkspaceRepo, userID, upload.WorkspaceID); err != nil {
+		log.Printf("CreateUpload request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+	upload.UserID = userID
+	upload.Length = length
+	upload.ExpiresAt = time.Now().Add(uploadTTL)
+
+	if err := h.Uploads.Create(&upload); err != nil {
+		log.Printf("CreateUpload request failed: Failed to save upload: %v\n", err)
+		writeError(w, r, repoErr("Failed to create upload", err))
+		return
+	}
+	if err := h.Store.CreatePartial(upload.ID); err != nil {
+		log.Printf("CreateUpload request failed: Failed to create partial file: %v\n", err)
+		h.Uploads.Delete(upload.ID)
+		writeError(w, r, internalErr("Failed to create upload", err))
+		return
+	}
+
+	log.Printf("Created upload %s of %d bytes for user_id=%d\n", upload.ID, length, userID)
+	w.Header().Set("Location", r.URL.Path+"/"+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetUploadOffset reports how many bytes of an upload have arrived, so a client can
+// resume after an interruption.
+func (h *UploadHandler) GetUploadOffset(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.begin(w, r)
+	if !ok {
+		return
+	}
+
+	upload, err := h.Uploads.Get(r.PathValue("id"), userID)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to fetch upload", err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.DocumentID != 0 {
+		w.Header().Set("Document-Location", documentLocation(upload.DocumentID))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchUpload appends the request body to an upload at Upload-Offset. The request
+// that completes the upload creates the document and returns its location in the
+// Document-Location header; if the file is refused, the upload is dropped and the
+// error is returned instead.
+func (h *UploadHandler) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting PatchUpload request")
+
+	userID, ok := h.begin(w, r)
+	if !ok {
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		writeError(w, r, &statusError{http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+
+	id := r.PathValue("id")
+	if _, loaded := h.busy.LoadOrStore(id, true); loaded {
+		writeError(w, r, &repository.ConflictError{Resource: "upload", Message: "Upload is already being written to"})
+		return
+	}
+	defer h.busy.Delete(id)
+
+	upload, err := h.Uploads.Get(id, userID)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to fetch upload", err))
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		log.Printf("PatchUpload request failed: Offset %q doesn't match %d\n", r.Header.Get("Upload-Offset"), upload.Offset)
+		writeError(w, r, &repository.ConflictError{
+			Resource:	"upload",
+			Message:	"Upload-Offset doesn't match the upload",
+			Details:	map[string]int64{"offset": upload.Offset},
+		})
+		return
+	}
+	if upload.DocumentID != 0 || offset == upload.Length {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	n, err := h.Store.AppendPartial(id, offset, io.LimitReader(r.Body, upload.Length-offset))
+	if serr := h.Uploads.SetOffset(id, offset+n); serr != nil {
+		log.Printf("PatchUpload request failed: Failed to save offset: %v\n", serr)
+		writeError(w, r, internalErr("Failed to save upload", serr))
+		return
+	}
+	upload.Offset = offset + n
+	if err != nil {
+		// The bytes that did arrive are kept; the client resumes from the new offset.
+		log.Printf("PatchUpload of %s interrupted at %d bytes: %v\n", id, upload.Offset, err)
+		writeError(w, r, badRequest("Upload interrupted; resume from Upload-Offset"))
+		return
+	}
+
+	if upload.Offset == upload.Length {
+		doc, err := h.finish(upload)
+		if err != nil {
+			log.Printf("PatchUpload request failed: Failed to import upload %s: %v\n", id, err)
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Document-Location", documentLocation(doc.ID))
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUpload abandons an upload and removes the data received so far.
+func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteUpload request")
+
+	userID, ok := h.begin(w, r)
+	if !ok {
+		return
+	}
+
+	upload, err := h.Uploads.Get(r.PathValue("id"), userID)
+	if err != nil {
+		writeError(w, r, repoErr("Failed to fetch upload", err))
+		return
+	}
+	h.remove(upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// begin checks the tus version and authentication common to every request.
+func (h *UploadHandler) begin(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if v := r.Header.Get("Tus-Resumable"); v != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		writeError(w, r, &statusError{http.StatusPreconditionFailed, "unsupported_version", "Tus-Resumable must be " + tusVersion})
+		return 0, false
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, r, &repository.UnauthorizedError{Message: "Authentication required"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// finish turns a complete upload into a document. The upload is kept, pointing at
+// the document, until it expires, so a client that missed the response can find out
+// with a HEAD request.
+func (h *UploadHandler) finish(upload model.Upload) (*model.Document, error) {
+	f, err := h.Importer.StashFile(h.Store.PartialPath(upload.ID), upload.Filename)
+	if err != nil {
+		h.Uploads.Delete(upload.ID)
+		return nil, importErr("Failed to store file", err)
+	}
+
+	meta := ingest.Metadata{
+		UserID:			upload.UserID,
+		WorkspaceID:	upload.WorkspaceID,
+		Title:			upload.Title,
+		Year:			upload.Year,
+		Authors:		parseAuthors(upload.Authors),
+	}
+	doc, err := h.Importer.Create(f, meta, false)
+
+	var dup *ingest.DuplicateError
+	if errors.As(err, &dup) {
+		err = &repository.ConflictError{
+			Resource:	"document",
+			Message:	"This file is already in your library",
+			Details:	DuplicateDetails{DocumentID: dup.Existing.ID, Title: dup.Existing.Title},
+		}
+	}
+	if err != nil {
+		h.Uploads.Delete(upload.ID)
+		return nil, importErr("Failed to save document", err)
+	}
+
+	if err := h.Uploads.Complete(upload.ID, doc.ID); err != nil {
+		log.Printf("Failed to record document of upload %s: %v\n", upload.ID, err)
+	}
+	return doc, nil
+}
+
+func (h *UploadHandler) remove(upload model.Upload) {
+	if err := h.Store.RemovePartial(upload.ID); err != nil {
+		log.Printf("Failed to remove partial upload %s: %v\n", upload.ID, err)
+	}
+	if err := h.Uploads.Delete(upload.ID); err != nil {
+		log.Printf("Failed to delete upload %s: %v\n", upload.ID, err)
+	}
+}
+
+// purgeExpired drops uploads past their expiry along with their partial files.
+func (h *UploadHandler) purgeExpired() {
+	expired, err := h.Uploads.Expired(time.Now())
+	if err != nil {
+		log.Printf("Failed to list expired uploads: %v\n", err)
+		return
+	}
+	for _, upload := range expired {
+		h.remove(upload)
+	}
+}
+
+// parseUploadMetadata decodes the Upload-Metadata header: comma-separated pairs of
+// a key and a base64 value.
+func parseUploadMetadata(header string) (model.Upload, error) {
+	var upload model.Upload
+	var fields []repository.FieldError
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			fields = append(fields, repository.FieldError{Field: key, Message: "must be base64 encoded"})
+			continue
+		}
+		value := strings.TrimSpace(string(raw))
+
+		switch key {
+		case "filename":
+			upload.Filename = util.SanitizeFilename(value)
+		case "title":
+			upload.Title = value
+		case "authors":
+			upload.Authors = value
+		case "workspace_id":
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				fields = append(fields, repository.FieldError{Field: key, Message: "must be a positive integer"})
+			}
+			upload.WorkspaceID = uint(n)
+		case "year":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1000 || n > 2100 {
+				fields = append(fields, repository.FieldError{Field: key, Message: "must be a year between 1000 and 2100"})
+			}
+			upload.Year = n
+		}
+	}
+
+	if upload.Filename == "" {
+		upload.Filename = "upload.pdf"
+	}
+	if upload.Title == "" {
+		base := path.Base(upload.Filename)
+		upload.Title = strings.TrimSuffix(base, path.Ext(base))
+	}
+	if len(upload.Title) > 255 {
+		fields = append(fields, repository.FieldError{Field: "title", Message: "must be at most 255 characters"})
+	}
+	if len(upload.Authors) > 2000 {
+		fields = append(fields, repository.FieldError{Field: "authors", Message: "must be at most 2000 characters"})
+	}
+
+	if len(fields) > 0 {
+		return upload, &repository.ValidationError{Message: "Invalid Upload-Metadata", Fields: fields}
+	}
+	return upload, nil
+}
+
+func documentLocation(id uint) string {
+	return fmt.Sprintf("/api/v2/documents/%d", id)
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/util"
+)
+
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// ReplaceFileRequest holds the non-file fields of the replace-file form.
+type ReplaceFileRequest struct {
+	Password		string				`json:"password" validate:"max=1024"`
+}
+
+// DocumentDiff describes the text changes between two versions of a document.
+type DocumentDiff struct {
+	From			int					`json:"from"`
+	To				int					`json:"to"`
+	Added			int					`json:"added"`
+	Removed			int					`json:"removed"`
+	Hunks			[]util.DiffHunk		`json:"hunks"`
+}
+
+
+// ReplaceDocumentFile uploads a new file for an existing document. The previous
+// file and its extracted text are kept as an earlier version.
+func (h *DocumentHandler) ReplaceDocumentFile(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ReplaceDocumentFile request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("ReplaceDocumentFile request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	doc, err := h.ownDocument(r, id)
+	if err != nil {
+		log.Printf("ReplaceDocumentFile request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	var req ReplaceFileRequest
+	file, err := receiveUpload(w, r, h.Importer, "pdf", &req)
+	if err != nil {
+		log.Printf("ReplaceDocumentFile request failed: Invalid upload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.Importer.Replace(&doc, file, req.Password); err != nil {
+		log.Printf("ReplaceDocumentFile request failed: Failed to replace file: %v\n", err)
+		writeError(w, r, importErr("Failed to replace document file", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// GetDocumentVersions lists the earlier versions of a document, newest first. The
+// current version is the document itself.
+func (h *DocumentHandler) GetDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetDocumentVersions request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetDocumentVersions request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetDocumentVersions request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.DocumentVersion{}, jsonFieldNames(reflect.TypeOf(model.DocumentVersion{})))
+	if err != nil {
+		log.Printf("GetDocumentVersions request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("GetDocumentVersions request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	versions, err := h.DocRepo.ListVersions(id, page)
+	if err != nil {
+		log.Printf("GetDocumentVersions request failed: Failed to fetch versions: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch versions", err))
+		return
+	}
+
+	writeList(w, versions.Items, versions.NextCursor, fields, nil)
+}
+
+// ViewDocumentVersion serves the file of an earlier version of a document.
+func (h *DocumentHandler) ViewDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting ViewDocumentVersion request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("ViewDocumentVersion request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+	version, err := pathID(r, "version")
+	if err != nil {
+		log.Printf("ViewDocumentVersion request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing version"))
+		return
+	}
+
+	if _, err := h.ownDocument(r, id); err != nil {
+		log.Printf("ViewDocumentVersion request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	v, err := h.DocRepo.GetVersion(id, int(version))
+	if err != nil {
+		log.Printf("ViewDocumentVersion request failed: Failed to fetch version: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch version", err))
+		return
+	}
+
+	serveStored(w, r, v.FilePath, v.Format)
+	log.Printf("Serving version %d of document ID=%d from: %s\n", v.Version, id, v.FilePath)
+}
+
+// DiffDocumentVersions compares the extracted text of two versions of a document.
+// ?to= defaults to the current version and ?from= to the one before it. With
+// ?format=unified the diff is returned as plain text.
+func (h *DocumentHandler) DiffDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DiffDocumentVersions request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DiffDocumentVersions request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
+	}
+
+	doc, err := h.ownDocument(r, id)
+	if err != nil {
+		log.Printf("DiffDocumentVersions request failed: Failed to fetch document: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch document", err))
+		return
+	}
+
+	q := r.URL.Query()
+	to, err := parseVersion(q.Get("to"), doc.Version, doc.Version)
+	if err != nil {
+		log.Printf("DiffDocumentVersions request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	from, err := parseVersion(q.Get("from"), to-1, doc.Version)
+	if err == nil && from >= to {
+		err = badRequest("from must be an earlier version than to")
+	}
+	if err != nil {
+		log.Printf("DiffDocumentVersions request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	text := func(version int) (string, error) {
+		if version == doc.Version {
+			return doc.ExtractedText, nil
+		}
+		v, err := h.DocRepo.GetVersion(id, version)
+		return v.ExtractedText, err
+	}
+	fromText, err := text(from)
+	if err != nil {
+		log.Printf("DiffDocumentVersions request failed: Failed to fetch version %d: %v\n", from, err)
+		writeError(w, r, repoErr("Failed to fetch version", err))
+		return
+	}
+	toText, err := text(to)
+	if err != nil {
+		log.Printf("DiffDocumentVersions request failed: Failed to fetch version %d: %v\n", to, err)
+		writeError(w, r, repoErr("Failed to fetch version", err))
+		return
+	}
+
+	lines := util.DiffLines(strings.Split(fromText, "\n"), strings.Split(toText, "\n"))
+	diff := DocumentDiff{From: from, To: to, Hunks: util.DiffHunks(lines, diffContext)}
+	for _, l := range lines {
+		switch l.Op {
+		case util.DiffInsert:
+			diff.Added++
+		case util.DiffDelete:
+			diff.Removed++
+		}
+	}
+	log.Printf("Diffed versions %d and %d of document ID=%d: %d added, %d removed\n", from, to, id, diff.Added, diff.Removed)
+
+	if q.Get("format") == "unified" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, util.UnifiedDiff(fmt.Sprintf("version %d", from), fmt.Sprintf("version %d", to), diff.Hunks))
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// parseVersion reads a version number between 1 and latest, or def when s is empty.
+func parseVersion(s string, def, latest int) (int, error) {
+	if s == "" {
+		if def < 1 {
+			return 0, badRequest("Document has only one version")
+		}
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 || v > latest {
+		return 0, badRequest(fmt.Sprintf("Version must be between 1 and %d", latest))
+	}
+	return v, nil
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/webhooks"
+)
+
+
+// CreateWebhookRequest subscribes a URL to events of the authenticated user's
+// library, or with workspace_id of one workspace. Without events every event is
+// sent; without a secret one is generated. The secret is only returned in the
+// response.
+type CreateWebhookRequest struct {
+	UserID			uint		`json:"user_id"`
+	WorkspaceID		uint		`json:"workspace_id"`
+	URL				string		`json:"url" validate:"required,max=2048"`
+	Events			[]string	`json:"events"`
+	Secret			string		`json:"secret" validate:"omitempty,min=16,max=64"`
+}
+
+// UpdateWebhookRequest changes the fields that are present and leaves the rest.
+// rotate_secret replaces the secret with a new one, returned in the response.
+type UpdateWebhookRequest struct {
+	URL				*string		`json:"url" validate:"min=1,max=2048"`
+	Events			*[]string	`json:"events"`
+	Enabled			*bool		`json:"enabled"`
+	RotateSecret	bool		`json:"rotate_secret"`
+}
+
+type WebhookHandler struct {
+	WebhookRepo		repository.WebhookRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+	Dispatcher		*webhooks.Dispatcher
+}
+
+
+func NewWebhookHandler(webhookRepo repository.WebhookRepository, workspaceRepo repository.WorkspaceRepository, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	log.Println("Initializing WebhookHandler...")
+	return &WebhookHandler{WebhookRepo: webhookRepo, WorkspaceRepo: workspaceRepo, Dispatcher: dispatcher}
+}
+
+func (h *WebhookHandler) GetUserWebhooks(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetUserWebhooks request")
+
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetUserWebhooks request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetUserWebhooks request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.Webhook{}, jsonFieldNames(reflect.TypeOf(model.Webhook{})))
+	if err != nil {
+		log.Printf("GetUserWebhooks request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	list, err := h.WebhookRepo.GetByUserID(userID, page)
+	if err != nil {
+		log.Printf("GetUserWebhooks request failed: Failed to fetch webhooks: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhooks", err))
+		return
+	}
+	for i := range list.Items {
+		list.Items[i].Secret = ""
+	}
+
+	log.Printf("Found %d webhooks for user_id=%d\n", len(list.Items), userID)
+	writeList(w, list.Items, list.NextCursor, fields, nil)
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting CreateWebhook request")
+
+	var req CreateWebhookRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("CreateWebhook request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("CreateWebhook request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	hook := model.Webhook{
+		UserID:			req.UserID,
+		WorkspaceID:	req.WorkspaceID,
+		URL:			strings.TrimSpace(req.URL),
+		Secret:			req.Secret,
+		Enabled:		true,
+	}
+	if hook.Secret == "" {
+		hook.Secret = webhooks.NewSecret()
+	}
+	events, err := checkWebhook(hook.URL, req.Events)
+	if err != nil {
+		log.Printf("CreateWebhook request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	hook.Events = events
+
+	if err := checkWorkspace(h.WorkspaceRepo, hook.UserID, hook.WorkspaceID); err != nil {
+		log.Printf("CreateWebhook request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	if err := h.WebhookRepo.Create(&hook); err != nil {
+		log.Printf("CreateWebhook request failed: Failed to create webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to create webhook", err))
+		return
+	}
+
+	log.Printf("Webhook created with ID=%d for user ID=%d\n", hook.ID, hook.UserID)
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetWebhook request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetWebhook request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing webhook ID"))
+		return
+	}
+
+	hook, err := h.ownWebhook(r, id)
+	if err != nil {
+		log.Printf("GetWebhook request failed: Failed to fetch webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhook", err))
+		return
+	}
+
+	hook.Secret = ""
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateWebhook request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("UpdateWebhook request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing webhook ID"))
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("UpdateWebhook request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	hook, err := h.ownWebhook(r, id)
+	if err != nil {
+		log.Printf("UpdateWebhook request failed: Failed to fetch webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhook", err))
+		return
+	}
+
+	if req.URL != nil {
+		hook.URL = strings.TrimSpace(*req.URL)
+	}
+	events := strings.Split(hook.Events, ",")
+	if hook.Events == "" {
+		events = nil
+	}
+	if req.Events != nil {
+		events = *req.Events
+	}
+	if hook.Events, err = checkWebhook(hook.URL, events); err != nil {
+		log.Printf("UpdateWebhook request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if req.RotateSecret {
+		hook.Secret = webhooks.NewSecret()
+	}
+
+	if err := h.WebhookRepo.Update(&hook); err != nil {
+		log.Printf("UpdateWebhook request failed: Failed to update webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to update webhook", err))
+		return
+	}
+
+	if !req.RotateSecret {
+		hook.Secret = ""
+	}
+	log.Printf("Updated webhook ID=%d\n", id)
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhook deletes a webhook with its delivery log. Pending deliveries are
+// dropped.
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting DeleteWebhook request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteWebhook request failed: Invalid or missing webhook ID: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing webhook ID"))
+		return
+	}
+
+	if _, err := h.ownWebhook(r, id); err != nil {
+		log.Printf("DeleteWebhook request failed: Failed to fetch webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhook", err))
+		return
+	}
+
+	if err := h.WebhookRepo.Delete(id); err != nil {
+		log.Printf("DeleteWebhook request failed: Failed to delete webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to delete webhook", err))
+		return
+	}
+
+	log.Printf("Successfully deleted webhook with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists the delivery log of a webhook, newest first,
+// optionally only the deliveries with one status.
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetWebhookDeliveries request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetWebhookDeliveries request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing webhook ID"))
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !model.IsValidDeliveryStatus(status) {
+		writeError(w, r, badRequest("Invalid status"))
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetWebhookDeliveries request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(r, model.WebhookDelivery{}, jsonFieldNames(reflect.TypeOf(model.WebhookDelivery{})))
+	if err != nil {
+		log.Printf("GetWebhookDeliveries request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
+		return
+	}
+
+	if _, err := h.ownWebhook(r, id); err != nil {
+		log.Printf("GetWebhookDeliveries request failed: Failed to fetch webhook: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhook", err))
+		return
+	}
+
+	list, err := h.WebhookRepo.ListDeliveries(repository.DeliveryFilter{WebhookID: id, Status: status}, page)
+	if err != nil {
+		log.Printf("GetWebhookDeliveries request failed: Failed to fetch deliveries: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhook deliveries", err))
+		return
+	}
+
+	log.Printf("Found %d deliveries for webhook ID=%d\n", len(list.Items), id)
+	writeList(w, list.Items, list.NextCursor, fields, nil)
+}
+
+// RedeliverWebhookDelivery sends the event of a delivery again as a new delivery,
+// which is attempted within seconds.
+func (h *WebhookHandler) RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting RedeliverWebhookDelivery request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("RedeliverWebhookDelivery request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing delivery ID"))
+		return
+	}
+
+	previous, err := h.WebhookRepo.GetDelivery(id)
+	if err == nil {
+		_, err = h.ownWebhook(r, previous.WebhookID)
+	}
+	var notFound *repository.NotFoundError
+	if errors.As(err, &notFound) {
+		err = &repository.NotFoundError{Resource: "webhook delivery", ID: id}
+	}
+	if err != nil {
+		log.Printf("RedeliverWebhookDelivery request failed: Failed to fetch delivery: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch webhook delivery", err))
+		return
+	}
+
+	delivery, err := h.Dispatcher.Redeliver(id)
+	if err != nil {
+		log.Printf("RedeliverWebhookDelivery request failed: Failed to redeliver: %v\n", err)
+		writeError(w, r, repoErr("Failed to redeliver webhook delivery", err))
+		return
+	}
+
+	log.Printf("Delivery ID=%d redelivered as ID=%d\n", id, delivery.ID)
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// ownWebhook fetches a webhook of the authenticated user. Other users' webhooks are
+// reported as not found, so their IDs can't be probed.
+func (h *WebhookHandler) ownWebhook(r *http.Request, id uint) (model.Webhook, error) {
+	return own(r, "webhook", id, h.WebhookRepo.GetByID, func(hook model.Webhook) uint { return hook.UserID })
+}
+
+// checkWebhook checks the URL and events of a webhook and returns the events as
+// they are stored.
+func checkWebhook(rawURL string, events []string) (string, error) {
+	var fields []repository.FieldError
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields = append(fields, repository.FieldError{Field: "url", Message: "must be an http(s) URL"})
+	}
+	var list []string
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if !model.IsValidEvent(e) {
+			fields = append(fields, repository.FieldError{Field: "events", Message: fmt.Sprintf("unknown event %q", e)})
+			continue
+		}
+		if !slices.Contains(list, e) {
+			list = append(list, e)
+		}
+	}
+	if len(fields) > 0 {
+		return "", &repository.ValidationError{Message: "Request validation failed", Fields: fields}
+	}
+	return strings.Join(list, ","), nil
+}
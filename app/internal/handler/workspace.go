@@ -1,19 +1,35 @@
 package handler
 
 import (
-	"fmt"
 	"log"
-	"encoding/json"
 	"net/http"
-	"strconv"
+	"reflect"
+	"strings"
 
 	"backend/internal/model"
+	"backend/internal/ocr"
 	"backend/internal/repository"
+	"backend/internal/webhooks"
 )
 
 
+type CreateWorkspaceRequest struct {
+	UserID			uint		`json:"user_id"`
+	Title			string		`json:"title" validate:"required,max=255"`
+}
+
+// UpdateWorkspaceRequest changes the fields that are present and leaves the rest.
+// An empty ocr_language goes back to the server's default language.
+type UpdateWorkspaceRequest struct {
+	Title			*string		`json:"title" validate:"min=1,max=255"`
+	SkipOCR			*bool		`json:"skip_ocr"`
+	OCRLanguage		*string		`json:"ocr_language" validate:"max=64"`
+}
+
 type WorkspaceHandler struct {
-	WorkspaceRepo repository.WorkspaceRepository
+	WorkspaceRepo	repository.WorkspaceRepository
+	// Webhooks, if set, receives workspace.document_added.
+	Webhooks		*webhooks.Dispatcher
 }
 
 
@@ -25,134 +41,249 @@ func NewWorkspaceHandler(repo repository.WorkspaceRepository) *WorkspaceHandler
 func (h *WorkspaceHandler) GetUserWorkspaces(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting GetUserWorkspace request")
 
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		log.Println("Missing user_id parameter")
-		http.Error(w, "Missing user_id", http.StatusBadRequest)
+	userID, err := queryUser(r)
+	if err != nil {
+		log.Printf("GetUserWorkspace request failed: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		log.Printf("GetUserWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
 		return
 	}
 
-	userID64, err := strconv.ParseUint(userIDStr, 10, 64)
+	fields, err := parseFields(r, model.Workspace{}, jsonFieldNames(reflect.TypeOf(model.Workspace{})))
 	if err != nil {
-		log.Printf("GetUserWorkspace request failed: Invalid user_id: %v\n", err)
-		http.Error(w, "Invalid user_id", http.StatusBadRequest)
+		log.Printf("GetUserWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest(err.Error()))
 		return
 	}
-	userID := uint(userID64)
 
 	log.Printf("Fetching workspaces for user_id=%d\n", userID)
-	workspaces, err := h.WorkspaceRepo.GetByUserID(userID)
+	workspaces, err := h.WorkspaceRepo.GetByUserID(userID, page)
 	if err != nil {
 		log.Printf("GetUserWorkspace request failed: Failed to fetch workspaces: %v\n", err)
-		http.Error(w, "Failed to fetch workspaces", http.StatusInternalServerError)
+		writeError(w, r, repoErr("Failed to fetch workspaces", err))
 		return
 	}
 
-	log.Printf("Found %d workspaces for user_id=%d\n", len(workspaces), userID)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(workspaces)
+	log.Printf("Found %d workspaces for user_id=%d\n", len(workspaces.Items), userID)
+	writeList(w, workspaces.Items, workspaces.NextCursor, fields, nil)
 }
 
 func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting CreateWorkspace requeste")
 
-	var ws model.Workspace
-	if err := json.NewDecoder(r.Body).Decode(&ws); err != nil {
-		log.Printf("CreateWorkspace request failed: Failed to decode workspace: %v\n", err)
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	var req CreateWorkspaceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("CreateWorkspace request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
 		return
 	}
-
-	if ws.Title == "" || ws.UserID == 0 {
-		log.Println("Missing title or user_id")
-		http.Error(w, "Title and user_id are required", http.StatusBadRequest)
+	if err := bodyUser(r, &req.UserID); err != nil {
+		log.Printf("CreateWorkspace request failed: %v\n", err)
+		writeError(w, r, err)
 		return
 	}
 
+	ws := model.Workspace{UserID: req.UserID, Title: strings.TrimSpace(req.Title)}
+
 	log.Println("Creating workspace...")
 	if err := h.WorkspaceRepo.Create(&ws); err != nil {
 		log.Printf("CreateWorkspace request failed: Failed to create workspace in database: %v", err)
-		http.Error(w, "Failed to create workspace", http.StatusInternalServerError)
+		writeError(w, r, repoErr("Failed to create workspace", err))
 		return
 	}
 
 	log.Printf("Workspace created with ID=%d\n", ws.ID)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ws)
+	writeJSON(w, http.StatusCreated, ws)
+}
+
+func (h *WorkspaceHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting GetWorkspace request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("GetWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace ID"))
+		return
+	}
+
+	ws, err := ownWorkspace(r, h.WorkspaceRepo, id)
+	if err != nil {
+		log.Printf("GetWorkspace request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ws)
+}
+
+// UpdateWorkspace renames a workspace or changes its settings, such as whether
+// scanned documents in it go through OCR.
+func (h *WorkspaceHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	log.Println("Starting UpdateWorkspace request")
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("UpdateWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace ID"))
+		return
+	}
+
+	var req UpdateWorkspaceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		log.Printf("UpdateWorkspace request failed: Invalid payload: %v\n", err)
+		writeError(w, r, err)
+		return
+	}
+	if req.OCRLanguage != nil && *req.OCRLanguage != "" && !ocr.ValidLanguage(*req.OCRLanguage) {
+		writeError(w, r, &repository.ValidationError{
+			Message:	"Request validation failed",
+			Fields:		[]repository.FieldError{{Field: "ocr_language", Message: "must be Tesseract language codes such as eng or deu+eng"}},
+		})
+		return
+	}
+
+	ws, err := ownWorkspace(r, h.WorkspaceRepo, id)
+	if err != nil {
+		log.Printf("UpdateWorkspace request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
+		return
+	}
+
+	if req.Title != nil {
+		ws.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.SkipOCR != nil {
+		ws.SkipOCR = *req.SkipOCR
+	}
+	if req.OCRLanguage != nil {
+		ws.OCRLanguage = *req.OCRLanguage
+	}
+
+	if err := h.WorkspaceRepo.Update(&ws); err != nil {
+		log.Printf("UpdateWorkspace request failed: Failed to update workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to update workspace", err))
+		return
+	}
+
+	log.Printf("Updated workspace ID=%d\n", id)
+	writeJSON(w, http.StatusOK, ws)
 }
 
 func (h *WorkspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting DeleteWorkspace request")
 
-	var input struct {
-		ID uint `json:"id"`
+	id, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("DeleteWorkspace request failed: Invalid or missing workspace ID: %v", err)
+		writeError(w, r, badRequest("Invalid or missing workspace ID"))
+		return
 	}
 
-	if err:= json.NewDecoder(r.Body).Decode(&input); err != nil || input.ID == 0 {
-		log.Printf("DeleteWorkspace request failed: Invalid or missing workspace ID: %v", err)
-		http.Error(w, "Invalid or missing workspace ID", http.StatusBadRequest)
+	if _, err := ownWorkspace(r, h.WorkspaceRepo, id); err != nil {
+		log.Printf("DeleteWorkspace request failed: Failed to fetch workspace: %v\n", err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
 		return
 	}
 
 	log.Println("Deleting workspace...")
-	if err := h.WorkspaceRepo.Delete(input.ID); err != nil {
+	err = h.WorkspaceRepo.Delete(id)
+	if err != nil {
 		log.Printf("DeleteWorkspace request failed: Failed to delete workspace in database: %v", err)
-		http.Error(w, "Failed to delete workspace", http.StatusInternalServerError)
+		writeError(w, r, repoErr("Failed to delete workspace", err))
 		return
 	}
 
-	log.Printf("Successfully deleted workspace with ID=%d\n", input.ID)
-	w.WriteHeader(http.StatusOK)
+	log.Printf("Successfully deleted workspace with ID=%d\n", id)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *WorkspaceHandler) AddDocumentToWorkspace(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting AddDocumentToWorkspace request")
 
-	type Payload struct {
-		DocumentID		uint	`json:"document_id"`
-		WorkspaceID		uint	`json:"workspace_id"`
+	workspaceID, err := pathID(r, "id")
+	if err != nil {
+		log.Printf("AddDocumentToWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing workspace ID"))
+		return
+	}
+
+	documentID, err := pathID(r, "documentID")
+	if err != nil {
+		log.Printf("AddDocumentToWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
 	}
 
-	var p Payload
-	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		log.Printf("AddDocumentToWorkspace request failed: Invalid payload: %v\n", err)
-		http.Error(w, "Invalid payload", http.StatusBadRequest)
+	ws, err := ownWorkspace(r, h.WorkspaceRepo, workspaceID)
+	if err != nil {
+		log.Printf("AddDocumentToWorkspace request failed: Workspace ID=%d: %v\n", workspaceID, err)
+		writeError(w, r, repoErr("Failed to fetch workspace", err))
 		return
 	}
 
-	log.Printf("Adding document ID=%d to workspace ID=%d\n", p.DocumentID, p.WorkspaceID)
-	if err := h.WorkspaceRepo.AddDocumentToWorkspace(p.DocumentID, p.WorkspaceID); err != nil {
+	log.Printf("Adding document ID=%d to workspace ID=%d\n", documentID, workspaceID)
+	err = h.WorkspaceRepo.AddDocumentToWorkspace(ws.UserID, documentID, workspaceID)
+	if err != nil {
 		log.Printf("AddDocumentToWorkspace request failed: Failed to add document to workspace: %v\n", err)
-		http.Error(w, "Failed to add document to workspace", http.StatusInternalServerError)
+		writeError(w, r, repoErr("Failed to add document to workspace", err))
 		return
 	}
 
-	log.Printf("Successfully added document ID=%d to workspace ID=%d\n", p.DocumentID, p.WorkspaceID)
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, "Document added to workspace")
+	if h.Webhooks != nil {
+		h.Webhooks.Emit(model.EventWorkspaceDocumentAdded, ws.UserID, workspaceID, webhooks.WorkspaceDocument{WorkspaceID: workspaceID, DocumentID: documentID})
+	}
+
+	log.Printf("Successfully added document ID=%d to workspace ID=%d\n", documentID, workspaceID)
+	writeMessage(w, http.StatusOK, "Document added to workspace")
 }
 
+// RemoveDocumentFromWorkspace detaches a document. The legacy route only knows the
+// document, so a missing {id} detaches it from whatever workspace it is in.
 func (h *WorkspaceHandler) RemoveDocumentFromWorkspace(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting RemoveDocumentFromWorkspace request")
 
-	var p struct {
-		DocumentID		uint		`json:"document_id"`
+	documentID, err := pathID(r, "documentID")
+	if err != nil {
+		log.Printf("RemoveDocumentFromWorkspace request failed: %v\n", err)
+		writeError(w, r, badRequest("Invalid or missing document ID"))
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		log.Printf("RemoveDocumentFromWorkspace request failed: Invalid payload: %v\n", err)
-		http.Error(w, "Invalid payload", http.StatusBadRequest)
+	userID, err := currentUser(r, 0)
+	if err != nil {
+		log.Printf("RemoveDocumentFromWorkspace request failed: %v\n", err)
+		writeError(w, r, err)
 		return
 	}
 
-	log.Printf("Removing document ID=%d from workspace\n", p.DocumentID)
-	if err := h.WorkspaceRepo.RemoveDocumentFromWorkspace(p.DocumentID); err != nil {
+	var workspaceID uint
+	if r.PathValue("id") != "" {
+		if workspaceID, err = pathID(r, "id"); err != nil {
+			log.Printf("RemoveDocumentFromWorkspace request failed: %v\n", err)
+			writeError(w, r, badRequest("Invalid workspace ID"))
+			return
+		}
+		if _, err := ownWorkspace(r, h.WorkspaceRepo, workspaceID); err != nil {
+			log.Printf("RemoveDocumentFromWorkspace request failed: Workspace ID=%d: %v\n", workspaceID, err)
+			writeError(w, r, repoErr("Failed to fetch workspace", err))
+			return
+		}
+	}
+
+	log.Printf("Removing document ID=%d from workspace\n", documentID)
+	err = h.WorkspaceRepo.RemoveDocumentFromWorkspace(userID, documentID, workspaceID)
+	if err != nil {
 		log.Printf("RemoveDocumentFromWorkspace request failed: Failed to remove document from workspace: %v\n", err)
-		http.Error(w, "Failed to remove document", http.StatusInternalServerError)
+		writeError(w, r, repoErr("Failed to remove document", err))
 		return
 	}
 
-	log.Printf("Document ID=%d successfully removed from workspace", p.DocumentID)
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, "Document removed from workspace")
+	log.Printf("Document ID=%d successfully removed from workspace", documentID)
+	writeMessage(w, http.StatusOK, "Document removed from workspace")
 }
\ No newline at end of file
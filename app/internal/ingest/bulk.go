@@ -0,0 +1,175 @@
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/util"
+)
+
+
+const (
+	StatusImported	= "imported"
+	StatusSkipped	= "skipped"
+	StatusFailed	= "failed"
+)
+
+// Entry is one file found while walking an archive or directory. Open may only be
+// called during the visit, since tar entries can't be revisited.
+type Entry struct {
+	Name		string
+	Size		int64
+	Open		func() (io.ReadCloser, error)
+}
+
+// Walker calls visit for every regular file in a source.
+type Walker func(visit func(Entry) error) error
+
+type ImportedFile struct {
+	File			string		`json:"file"`
+	Status			string		`json:"status"`
+	DocumentID		uint		`json:"document_id,omitempty"`
+	Reason			string		`json:"reason,omitempty"`
+}
+
+type ImportReport struct {
+	Imported		int				`json:"imported"`
+	Skipped			int				`json:"skipped"`
+	Failed			int				`json:"failed"`
+	Files			[]ImportedFile	`json:"files"`
+}
+
+
+// ImportAll imports every PDF from walk into the library described by meta, using
+// each file's name as its title. Files already in the library, including earlier
+// files of the same import, are skipped. A failing file is recorded in the report
+// and doesn't stop the import; the error is only for a source that can't be read.
+func (im *Importer) ImportAll(walk Walker, meta Metadata) (ImportReport, error) {
+	var report ImportReport
+	add := func(res ImportedFile) {
+		report.Files = append(report.Files, res)
+		switch res.Status {
+		case StatusImported:
+			report.Imported++
+		case StatusSkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+
+	err := walk(func(e Entry) error {
+		res := ImportedFile{File: e.Name}
+		switch {
+		case !strings.EqualFold(path.Ext(e.Name), ".pdf"):
+			res.Status, res.Reason = StatusSkipped, "not a PDF"
+		case im.Limits.MaxFileSize > 0 && e.Size > im.Limits.MaxFileSize:
+			res.Status, res.Reason = StatusFailed, "file too large"
+		default:
+			res = im.importEntry(e, meta)
+		}
+		add(res)
+		return nil
+	})
+
+	return report, err
+}
+
+func (im *Importer) importEntry(e Entry, meta Metadata) ImportedFile {
+	res := ImportedFile{File: e.Name}
+
+	rc, err := e.Open()
+	if err != nil {
+		res.Status, res.Reason = StatusFailed, err.Error()
+		return res
+	}
+	defer rc.Close()
+
+	base := util.SanitizeFilename(path.Base(e.Name))
+	meta.Title = strings.TrimSuffix(base, path.Ext(base))
+	doc, err := im.Import(rc, base, meta, false)
+
+	var dup *DuplicateError
+	switch {
+	case errors.As(err, &dup):
+		res.Status, res.DocumentID, res.Reason = StatusSkipped, dup.Existing.ID, "already in library"
+	case err != nil:
+		res.Status, res.Reason = StatusFailed, err.Error()
+	default:
+		res.Status, res.DocumentID = StatusImported, doc.ID
+	}
+	return res
+}
+
+// Zip walks the files of a ZIP archive.
+func Zip(r io.ReaderAt, size int64) Walker {
+	return func(visit func(Entry) error) error {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			if err := visit(Entry{Name: f.Name, Size: int64(f.UncompressedSize64), Open: f.Open}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Tar walks the regular files of an uncompressed tar stream.
+func Tar(r io.Reader) Walker {
+	return func(visit func(Entry) error) error {
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+			if err := visit(Entry{Name: hdr.Name, Size: hdr.Size, Open: open}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Dir walks the regular files below root, reporting names relative to it.
+// Symbolic links are not followed.
+func Dir(root string) Walker {
+	return func(visit func(Entry) error) error {
+		return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			open := func() (io.ReadCloser, error) { return os.Open(p) }
+			return visit(Entry{Name: filepath.ToSlash(rel), Size: info.Size(), Open: open})
+		})
+	}
+}
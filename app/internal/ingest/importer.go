@@ -0,0 +1,561 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"backend/internal/clip"
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/storage"
+	"backend/internal/util"
+)
+
+
+// Importer turns files into documents. The single-file upload and the bulk import
+// share it so that every file is hashed, stored and extracted the same way.
+type Importer struct {
+	Docs		repository.DocumentRepository
+	Store		*storage.Store
+	Limits		Limits
+	// OCR receives documents with scanned pages. OCR is off when it is nil.
+	OCR			OCRQueue
+	// Enrich receives documents with a DOI or arXiv ID for a metadata lookup.
+	// Enrichment is off when it is nil.
+	Enrich		EnrichQueue
+	// Alerts receives new documents to run the saved searches on, once OCR and
+	// enrichment are over. Alerts are off when it is nil.
+	Alerts		AlertQueue
+	// Events receives the events of new and re-extracted documents for webhooks.
+	// No events are sent when it is nil.
+	Events		EventSink
+}
+
+// OCRQueue schedules OCR of a document whose OCR status is pending.
+type OCRQueue interface {
+	Enqueue(docID uint)
+}
+
+// EnrichQueue schedules the metadata lookup of a document whose enrichment status
+// is pending.
+type EnrichQueue interface {
+	Enqueue(docID uint)
+}
+
+// EventSink receives events about documents, named by the model's Event
+// constants.
+type EventSink interface {
+	DocumentEvent(event string, doc *model.Document)
+}
+
+// AlertQueue schedules the saved searches to run on a new document. It is
+// notified again when OCR or enrichment ends, and waits for both.
+type AlertQueue interface {
+	Enqueue(docID uint)
+}
+
+// Limits bounds what users may store. Zero means unlimited.
+type Limits struct {
+	MaxFileSize		int64
+	UserQuota		int64
+}
+
+// Metadata describes the document created for an imported file.
+type Metadata struct {
+	UserID			uint
+	WorkspaceID		uint
+	Title			string
+	Year			int
+	Authors			[]model.DocumentAuthor
+	// Password unlocks an encrypted PDF for text extraction. It isn't stored.
+	Password		string
+	// SourceURL, DOI and ArXivID describe a document imported from the web. The
+	// identifiers stand in for those that aren't found in the file.
+	SourceURL		string
+	DOI				string
+	ArXivID			string
+	// Format is the format of the stored file, model.FormatPDF when empty.
+	Format			string
+}
+
+// StoredFile is a file that has been stashed in storage but doesn't belong to a
+// document yet. It must be passed to Create or Replace, or else to Discard.
+type StoredFile struct {
+	Hash			string
+	Size			int64
+	Filename		string
+}
+
+// DuplicateError reports a file whose content is already in the user's library.
+type DuplicateError struct {
+	Existing	*model.Document
+}
+
+var (
+	ErrNotPDF			= errors.New("file is not a PDF document")
+	ErrFileTooLarge		= errors.New("file is too large")
+	ErrQuotaExceeded	= errors.New("storage quota exceeded")
+)
+
+// sniffLength is how far into a file the PDF header may start. Readers accept junk
+// before "%PDF-" within the first kilobyte, so the check does too.
+const sniffLength = 1024
+
+
+func NewImporter(docs repository.DocumentRepository, store *storage.Store, limits Limits) *Importer {
+	return &Importer{Docs: docs, Store: store, Limits: limits}
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate of document %d", e.Existing.ID)
+}
+
+// Import stores r and creates a document for it, the combination of Stash and Create.
+func (im *Importer) Import(r io.Reader, filename string, meta Metadata, allowDuplicates bool) (*model.Document, error) {
+	f, err := im.Stash(r, filename)
+	if err != nil {
+		return nil, err
+	}
+	return im.Create(f, meta, allowDuplicates)
+}
+
+// Stash streams r into storage after checking from its first bytes that it is a
+// PDF. Files over the size limit are rejected with ErrFileTooLarge once the limit
+// is crossed.
+func (im *Importer) Stash(r io.Reader, filename string) (StoredFile, error) {
+	f := StoredFile{Filename: util.SanitizeFilename(filename)}
+
+	if im.Limits.MaxFileSize > 0 {
+		r = &limitedReader{r: r, n: im.Limits.MaxFileSize}
+	}
+	br := bufio.NewReaderSize(r, sniffLength)
+	if err := sniff(br); err != nil {
+		return f, err
+	}
+
+	hash, size, err := im.Store.Put(br)
+	if errors.Is(err, ErrFileTooLarge) {
+		return f, err
+	}
+	if err != nil {
+		return f, fmt.Errorf("store file: %w", err)
+	}
+	f.Hash, f.Size = hash, size
+	return f, nil
+}
+
+// StashSnapshot stores the snapshot of a clipped web page. The snapshot is made
+// by the server rather than uploaded, so it isn't sniffed.
+func (im *Importer) StashSnapshot(snapshot []byte, filename string) (StoredFile, error) {
+	f := StoredFile{Filename: util.SanitizeFilename(filename)}
+	if im.Limits.MaxFileSize > 0 && int64(len(snapshot)) > im.Limits.MaxFileSize {
+		return f, ErrFileTooLarge
+	}
+
+	hash, size, err := im.Store.Put(bytes.NewReader(snapshot))
+	if err != nil {
+		return f, fmt.Errorf("store file: %w", err)
+	}
+	f.Hash, f.Size = hash, size
+	return f, nil
+}
+
+// StashFile moves a file that is already on disk, such as a finished resumable
+// upload, into storage. The file is removed whether or not it is accepted.
+func (im *Importer) StashFile(path, filename string) (StoredFile, error) {
+	f := StoredFile{Filename: util.SanitizeFilename(filename)}
+
+	err := func() error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if info, err := file.Stat(); err != nil {
+			return err
+		} else if im.Limits.MaxFileSize > 0 && info.Size() > im.Limits.MaxFileSize {
+			return ErrFileTooLarge
+		}
+		return sniff(bufio.NewReaderSize(file, sniffLength))
+	}()
+	if err != nil {
+		os.Remove(path)
+		return f, err
+	}
+
+	hash, size, err := im.Store.Adopt(path)
+	if err != nil {
+		return f, fmt.Errorf("store file: %w", err)
+	}
+	f.Hash, f.Size = hash, size
+	return f, nil
+}
+
+// Discard gives up a stashed file that won't become a document.
+func (im *Importer) Discard(f StoredFile) {
+	if f.Hash == "" {
+		return
+	}
+	if err := im.Store.Release(f.Hash); err != nil {
+		log.Printf("Failed to release blob %s: %v\n", f.Hash, err)
+	}
+}
+
+// Create makes a document of a stashed file. Unless allowDuplicates is set, a file
+// whose content already belongs to one of the user's documents is not imported
+// again and is reported as a *DuplicateError. Identical files of different users,
+// or duplicates that are allowed, become separate documents sharing one stored
+// file. The stashed file is discarded if no document is created.
+func (im *Importer) Create(f StoredFile, meta Metadata, allowDuplicates bool) (*model.Document, error) {
+	doc, err := im.create(f, meta, allowDuplicates)
+	if err != nil {
+		im.Discard(f)
+		return nil, err
+	}
+
+	log.Printf("Imported %s as document ID=%d\n", f.Filename, doc.ID)
+	return doc, nil
+}
+
+func (im *Importer) create(f StoredFile, meta Metadata, allowDuplicates bool) (*model.Document, error) {
+	if !allowDuplicates {
+		existing, err := im.Docs.GetByContentHash(meta.UserID, f.Hash)
+		if err == nil {
+			return nil, &DuplicateError{Existing: &existing}
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+	if err := im.CheckQuota(meta.UserID, f.Size); err != nil {
+		return nil, err
+	}
+
+	format := meta.Format
+	if format == "" {
+		format = model.FormatPDF
+	}
+	path := im.Store.Path(f.Hash)
+	doc := &model.Document{
+		Title:			meta.Title,
+		FilePath:		path,
+		ContentHash:	f.Hash,
+		Year:			meta.Year,
+		Format:			format,
+		ReadingStatus:	model.ReadingStatusUnread,
+		WorkspaceID:	meta.WorkspaceID,
+		UserID:			meta.UserID,
+		Authors:		meta.Authors,
+		SourceURL:		meta.SourceURL,
+	}
+	if err := im.extract(doc, f.Filename, meta.Password); err != nil {
+		return nil, err
+	}
+	if doc.DOI == "" && doc.ArXivID == "" && (meta.DOI != "" || meta.ArXivID != "") {
+		doc.DOI, doc.ArXivID = meta.DOI, meta.ArXivID
+		if im.Enrich != nil {
+			doc.EnrichmentStatus = model.EnrichmentPending
+		}
+	}
+	doc.AlertsPending = im.Alerts != nil
+	if err := im.Docs.Save(doc); err != nil {
+		return nil, err
+	}
+	im.linkReferences(doc)
+	im.queueOCR(doc)
+	im.queueEnrichment(doc)
+	im.queueAlerts(doc)
+	im.emit(model.EventDocumentCreated, doc)
+	im.emitExtracted(doc)
+	return doc, nil
+}
+
+// CheckQuota reports ErrQuotaExceeded if storing size more bytes would take the
+// user over their quota.
+func (im *Importer) CheckQuota(userID uint, size int64) error {
+	if im.Limits.UserQuota <= 0 {
+		return nil
+	}
+	used, err := im.Docs.StorageUsed(userID)
+	if err != nil {
+		return err
+	}
+	if used+size > im.Limits.UserQuota {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Replace makes a stashed file the new file of doc and re-extracts its text. The
+// previous file stays stored as an earlier version of the document. A file
+// identical to the current one leaves doc unchanged. The stashed file is discarded
+// if it isn't used.
+func (im *Importer) Replace(doc *model.Document, f StoredFile, password string) error {
+	if f.Hash == doc.ContentHash {
+		log.Printf("Replacement %s is identical to document ID=%d\n", f.Filename, doc.ID)
+		im.Discard(f)
+		return nil
+	}
+
+	err := im.CheckQuota(doc.UserID, f.Size)
+	if err == nil {
+		err = im.replace(doc, f, password)
+	}
+	if err != nil {
+		im.Discard(f)
+		return err
+	}
+
+	log.Printf("Replaced file of document ID=%d with %s (version %d)\n", doc.ID, f.Filename, doc.Version)
+	return nil
+}
+
+func (im *Importer) replace(doc *model.Document, f StoredFile, password string) error {
+	doc.FilePath = im.Store.Path(f.Hash)
+	doc.ContentHash = f.Hash
+	// Replacements are uploaded, which only PDFs may be.
+	doc.Format = model.FormatPDF
+	if err := im.extract(doc, f.Filename, password); err != nil {
+		return err
+	}
+	if err := im.Docs.ReplaceFile(doc); err != nil {
+		return err
+	}
+	im.linkReferences(doc)
+	im.queueOCR(doc)
+	im.queueEnrichment(doc)
+	im.emitExtracted(doc)
+	return nil
+}
+
+// Reextract extracts the text of a document's current file again, for example
+// with the password of an encrypted PDF. Scanned pages are queued for OCR again.
+func (im *Importer) Reextract(doc *model.Document, password string) error {
+	if err := im.extract(doc, doc.Title, password); err != nil {
+		return err
+	}
+	if err := im.Docs.UpdateExtraction(doc); err != nil {
+		return err
+	}
+	im.linkReferences(doc)
+	im.queueOCR(doc)
+	im.queueEnrichment(doc)
+	im.emitExtracted(doc)
+	return nil
+}
+
+// extract fills in the text and extraction outcome of doc from its file, which is
+// split into sections with the reference list parsed. A DOI or arXiv ID that
+// hasn't been looked up yet marks the document pending for enrichment.
+func (im *Importer) extract(doc *model.Document, filename, password string) error {
+	oldDOI, oldArXivID := doc.DOI, doc.ArXivID
+
+	var err error
+	if doc.Format == model.FormatHTML {
+		err = im.extractPage(doc, filename)
+	} else {
+		err = im.extractPDF(doc, filename, password)
+	}
+	if err != nil {
+		return err
+	}
+
+	found := doc.DOI != "" || doc.ArXivID != ""
+	changed := doc.DOI != oldDOI || doc.ArXivID != oldArXivID
+	if im.Enrich != nil && found && (changed || doc.EnrichmentStatus != model.EnrichmentDone) {
+		doc.EnrichmentStatus = model.EnrichmentPending
+	}
+	return nil
+}
+
+// extractPDF reads the text of a PDF. A PDF that is encrypted, scanned or
+// malformed still becomes a document, with the outcome recorded instead of the
+// text. Documents with pages that lack a text layer are marked pending for OCR
+// when it is enabled, except those that needed a password, which isn't kept for
+// the OCR worker. The identifiers are those on the first page.
+func (im *Importer) extractPDF(doc *model.Document, filename, password string) error {
+	pages, status, err := util.ExtractPDFPages(doc.FilePath, password)
+	if status == "" {
+		return fmt.Errorf("read %s: %w", filename, err)
+	}
+	if err != nil {
+		log.Printf("Text extraction of %s: %s: %v\n", filename, status, err)
+	}
+
+	text := strings.Join(pages, "")
+	doc.ExtractedText = text
+	doc.Fingerprint = util.Fingerprint(text)
+	doc.ExtractionStatus = status
+	doc.ExtractionError = ""
+	if err != nil {
+		doc.ExtractionError = truncateRunes(err.Error(), 255)
+	}
+
+	doc.Sections, doc.References, doc.DOI, doc.ArXivID = nil, nil, "", ""
+	if status == model.ExtractionOK {
+		doc.Sections = segment(doc.FilePath, password, pages)
+		doc.References = util.ReferencesFromSections(doc.Sections)
+		doc.DOI = util.FindDOI(pages[0])
+		doc.ArXivID = util.FindArXivID(pages[0])
+	}
+
+	doc.OCRStatus = ""
+	if im.OCR != nil && password == "" && len(util.PagesWithoutText(pages)) > 0 {
+		doc.OCRStatus = model.OCRPending
+	}
+	return nil
+}
+
+// extractPage reads the article of a web page snapshot as Markdown, split into
+// sections at its headings. The DOI is the one a scholarly page gives itself;
+// DOIs in the text of a blog post are mostly those of the papers it discusses.
+func (im *Importer) extractPage(doc *model.Document, filename string) error {
+	page, err := os.ReadFile(doc.FilePath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", filename, err)
+	}
+	a, err := clip.Extract(page, doc.SourceURL)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", filename, err)
+	}
+
+	doc.ExtractedText = a.Markdown
+	doc.Fingerprint = util.Fingerprint(a.Markdown)
+	doc.ExtractionStatus = model.ExtractionOK
+	doc.ExtractionError = ""
+	doc.Sections = util.Segment(clip.Lines(a.Markdown))
+	doc.References = util.ReferencesFromSections(doc.Sections)
+	doc.DOI, doc.ArXivID = a.DOI, ""
+	doc.OCRStatus = ""
+	return nil
+}
+
+// segment splits the text into sections, with font sizes when the PDF gives them
+// and from the plain text of the pages otherwise.
+func segment(path, password string, pages []string) []model.DocumentSection {
+	lines, err := util.ExtractPDFLines(path, password)
+	if err != nil || len(lines) == 0 {
+		if err != nil {
+			log.Printf("Falling back to plain text for sections of %s: %v\n", path, err)
+		}
+		return util.SegmentPages(pages)
+	}
+	return util.Segment(lines)
+}
+
+// linkReferences updates the citation graph for a new or re-extracted document.
+// The document is saved by then, so a failure only leaves links missing until the
+// next extraction.
+func (im *Importer) linkReferences(doc *model.Document) {
+	n, err := im.Docs.LinkReferences(doc)
+	if err != nil {
+		log.Printf("Failed to link references of document ID=%d: %v\n", doc.ID, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Linked %d references for document ID=%d\n", n, doc.ID)
+	}
+}
+
+func (im *Importer) queueOCR(doc *model.Document) {
+	if doc.OCRStatus == model.OCRPending {
+		im.OCR.Enqueue(doc.ID)
+	}
+}
+
+func (im *Importer) queueEnrichment(doc *model.Document) {
+	if doc.EnrichmentStatus == model.EnrichmentPending && im.Enrich != nil {
+		im.Enrich.Enqueue(doc.ID)
+	}
+}
+
+func (im *Importer) queueAlerts(doc *model.Document) {
+	if doc.AlertsPending && doc.OCRStatus != model.OCRPending && doc.EnrichmentStatus != model.EnrichmentPending {
+		im.Alerts.Enqueue(doc.ID)
+	}
+}
+
+func (im *Importer) emit(event string, doc *model.Document) {
+	if im.Events != nil {
+		im.Events.DocumentEvent(event, doc)
+	}
+}
+
+// emitExtracted reports the text of a document final, unless OCR will add to it.
+func (im *Importer) emitExtracted(doc *model.Document) {
+	if doc.OCRStatus != model.OCRPending {
+		im.emit(model.EventDocumentExtracted, doc)
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// Remove releases the stored file of a deleted document.
+func (im *Importer) Remove(doc model.Document) error {
+	return im.removeFile(doc.FilePath, doc.ContentHash)
+}
+
+// RemoveVersion releases the stored file of an earlier version of a deleted document.
+func (im *Importer) RemoveVersion(v model.DocumentVersion) error {
+	return im.removeFile(v.FilePath, v.ContentHash)
+}
+
+// removeFile releases a stored file. Files from before content-addressed storage
+// are owned by one document outright.
+func (im *Importer) removeFile(path, hash string) error {
+	if hash == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return im.Store.Release(hash)
+}
+
+// sniff checks the content type from the first bytes of a file rather than
+// trusting its name or the client's Content-Type.
+func sniff(br *bufio.Reader) error {
+	head, err := br.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if !bytes.Contains(head, []byte("%PDF-")) {
+		return ErrNotPDF
+	}
+	return nil
+}
+
+// limitedReader fails with ErrFileTooLarge once more than n bytes have been read,
+// where io.LimitReader would silently truncate the file.
+type limitedReader struct {
+	r		io.Reader
+	n		int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrFileTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+
+// Mailer sends plain-text email through an SMTP server. Any server will do,
+// including a local stand-in such as MailHog, which listens on localhost:1025 and
+// needs no login.
+type Mailer struct {
+	// Addr is the host:port of the SMTP server.
+	Addr			string
+	// From is the sender, e.g. "Research Assistant <noreply@example.org>".
+	From			string
+	// Username and Password log in to the server with PLAIN auth, which Go only
+	// sends over TLS or to localhost. Without a Username no login is attempted.
+	Username		string
+	Password		string
+}
+
+var ErrInvalidAddress = errors.New("invalid email address")
+
+
+func NewMailer(addr, from, username, password string) (*Mailer, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("SMTP address %q: %w", addr, err)
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("sender %q: %w", from, err)
+	}
+	return &Mailer{Addr: addr, From: from, Username: username, Password: password}, nil
+}
+
+// Send mails a plain-text message to one recipient.
+func (m *Mailer) Send(to, subject, body string) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return ErrInvalidAddress
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, from.Address, []string{rcpt.Address}, message(from, rcpt, subject, body))
+}
+
+// message builds the message with its headers. The subject is encoded, so it
+// can't add headers of its own.
+func message(from, to *mail.Address, subject, body string) []byte {
+	_, domain, _ := strings.Cut(from.Address, "@")
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("Message-ID: <" + hex.EncodeToString(id) + "@" + domain + ">\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	b.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
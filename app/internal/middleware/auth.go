@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+
+type userIDKey struct{}
+type tokenKey struct{}
+
+// TokenResolver returns the user a bearer token belongs to, or ErrUnauthenticated
+// for unknown and expired tokens. Other errors are logged and also rejected.
+type TokenResolver func(token string) (uint, error)
+
+var ErrUnauthenticated = errors.New("invalid or expired token")
+
+
+// Authenticate resolves an "Authorization: Bearer" token to its user. Requests
+// without a token pass through unchanged, for login, registration and the other
+// public routes; handlers that act for a user refuse them with 401. A token that
+// doesn't resolve is rejected here with 401 so a stale login fails loudly instead
+// of acting anonymously.
+func Authenticate(resolve TokenResolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			unauthorized(w, r, "Malformed Authorization header")
+			return
+		}
+
+		userID, err := resolve(token)
+		if err != nil {
+			if !errors.Is(err, ErrUnauthenticated) {
+				log.Printf("Authenticate failed: %v\n", err)
+			}
+			unauthorized(w, r, "Invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey{}, userID)
+		ctx = context.WithValue(ctx, tokenKey{}, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetUserID returns the user authenticated by a bearer token, if any.
+func GetUserID(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(userIDKey{}).(uint)
+	return id, ok
+}
+
+// GetToken returns the bearer token the request was authenticated with, if any.
+func GetToken(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeError(w, r, http.StatusUnauthorized, "unauthorized", message)
+}
+
+// writeError writes the same error envelope as the handlers, which this package
+// can't import.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":			code,
+		"message":		message,
+		"request_id":	GetRequestID(r.Context()),
+	})
+}
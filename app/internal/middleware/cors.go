@@ -9,10 +9,14 @@ func EnableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow requests from development frontend
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, HEAD, OPTIONS, PUT, PATCH, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, "+
+			"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+		w.Header().Set("Access-Control-Expose-Headers", "Deprecation, Link, X-Request-ID, Location, Document-Location, "+
+			"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Length, Upload-Offset, Upload-Expires")
 
-		if r.Method == http.MethodOptions {
+		// Only preflights are answered here; a plain OPTIONS request is tus discovery.
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
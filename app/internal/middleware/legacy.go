@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+
+// MaxJSONBody is the largest JSON request body that is read. It leaves room for
+// the longest note body after JSON escaping.
+const MaxJSONBody = 8 << 20
+
+
+// Deprecated marks a legacy route with the Deprecation header and points clients at
+// the /api/v2 route that replaces it.
+func Deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// QueryParamToPath exposes a legacy ?param= query value as the path wildcard name,
+// so handlers written for /api/v2 resource routes can serve the old verb-style routes.
+func QueryParamToPath(param, name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue(name, r.URL.Query().Get(param))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BodyFieldToPath moves a field of a legacy JSON request body into the path wildcard
+// name. The field is removed from the body that the handler sees.
+func BodyFieldToPath(field, name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxJSONBody))
+		r.Body.Close()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "too_large", "Request body is too large")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "validation_failed", "Invalid payload")
+			return
+		}
+
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &body); err == nil {
+			if v, ok := body[field]; ok {
+				r.SetPathValue(name, strings.Trim(string(v), `"`))
+				delete(body, field)
+				raw, _ = json.Marshal(body)
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(raw))
+		r.ContentLength = int64(len(raw))
+		next.ServeHTTP(w, r)
+	})
+}
\ No newline at end of file
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+
+type requestIDKey struct{}
+
+const RequestIDHeader = "X-Request-ID"
+
+
+// RequestID tags every request with an ID, reusing the caller's X-Request-ID when
+// present, and echoes it in the response so errors can be matched to server logs.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// GetRequestID returns the ID assigned by RequestID, or "" outside of a request.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
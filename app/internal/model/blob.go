@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Blob is a stored file, addressed by the SHA-256 hash of its content. Documents
+// that share a file reference the same blob; RefCount tracks how many do.
+type Blob struct {
+	Hash			string			`gorm:"primaryKey;size:64" json:"hash"`
+	Size			int64			`gorm:"not null" json:"size"`
+	RefCount		int				`gorm:"not null" json:"ref_count"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
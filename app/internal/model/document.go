@@ -6,16 +6,197 @@ import (
 
 
 type Document struct {
-	ID					uint			`gorm:"PrimaryKey" json:"id"`
-	Title				string			`gorm:"not null" json:"title"`
-	FilePath			string			`gorm:"not null" json:"file_path"`
-	ExtractedText		string			`gorm:"type:LONGTEXT" json:"extracted_text"`
-	UploadedAt			time.Time		`gorm:"autoCreateTime" json:"uploaded_at"`
+	ID					uint				`gorm:"PrimaryKey" json:"id"`
+	Title				string				`gorm:"not null" json:"title"`
+	FilePath			string				`gorm:"not null" json:"file_path"`
+	ContentHash			string				`gorm:"size:64;index" json:"content_hash,omitempty"`
+	ExtractedText		string				`gorm:"type:LONGTEXT" json:"extracted_text"`
+	// Fingerprint is a MinHash signature of ExtractedText for near-duplicate
+	// detection; NULL until computed, empty when the text is too short.
+	Fingerprint			[]byte				`gorm:"type:VARBINARY(256)" json:"-"`
+	ExtractionStatus	string				`gorm:"size:16;index;default:ok" json:"extraction_status"`
+	ExtractionError		string				`gorm:"size:255" json:"extraction_error,omitempty"`
+	// DOI is the first DOI on the document's first page, which is its own in
+	// published papers.
+	DOI					string				`gorm:"size:255;index" json:"doi,omitempty"`
+	ArXivID				string				`gorm:"size:32;index" json:"arxiv_id,omitempty"`
+	// SourceURL is where a document imported from the web was asked for: the URL
+	// given, or the doi.org or arXiv page of a DOI or arXiv ID.
+	SourceURL			string				`gorm:"size:2048" json:"source_url,omitempty"`
+	// OCRStatus tracks recognition of the pages that have no text layer. It is
+	// empty for documents that don't need OCR.
+	OCRStatus			string				`gorm:"size:16;index" json:"ocr_status,omitempty"`
+	UploadedAt			time.Time			`gorm:"autoCreateTime" json:"uploaded_at"`
 
-	WorkspaceID			uint			`json:"workspace_id"`
-	UserID				uint			`json:"user_id"`
+	Year				int					`gorm:"index" json:"year,omitempty"`
+	// Format is the format of the stored file, one of the Format constants.
+	Format				string				`gorm:"size:16;index" json:"format"`
+	ReadingStatus		string				`gorm:"size:16;index;default:unread" json:"reading_status"`
+	Version				int					`gorm:"not null;default:1" json:"version"`
 
-	User 				User			`gorm:"foreignKey:UserID" json:"-"`
+	// Metadata from scholarly APIs, looked up by DOI or arXiv ID. EnrichmentStatus
+	// is empty for documents without either.
+	Abstract			string				`gorm:"type:TEXT" json:"abstract,omitempty"`
+	Venue				string				`gorm:"size:255" json:"venue,omitempty"`
+	CitationCount		*int				`json:"citation_count,omitempty"`
+	OpenAccessURL		string				`gorm:"size:1024" json:"open_access_url,omitempty"`
+	EnrichmentStatus	string				`gorm:"size:16;index" json:"enrichment_status,omitempty"`
+	EnrichedAt			*time.Time			`json:"enriched_at,omitempty"`
+	// AlertsPending marks a new document that saved searches haven't been run on
+	// yet. They run once its OCR and enrichment are over.
+	AlertsPending		bool				`gorm:"index;not null;default:false" json:"-"`
+
+	WorkspaceID			uint				`json:"workspace_id"`
+	UserID				uint				`json:"user_id"`
+
+	User 				User				`gorm:"foreignKey:UserID" json:"-"`
+	Authors				[]DocumentAuthor	`gorm:"foreignKey:DocumentID" json:"authors,omitempty"`
+	Tags				[]Tag				`gorm:"many2many:document_tags" json:"tags,omitempty"`
+	Sections			[]DocumentSection	`gorm:"foreignKey:DocumentID" json:"-"`
+	References			[]DocumentReference	`gorm:"foreignKey:DocumentID" json:"-"`
+}
+
+type DocumentAuthor struct {
+	ID					uint				`gorm:"primaryKey" json:"-"`
+	DocumentID			uint				`gorm:"index;not null" json:"-"`
+	Name				string				`gorm:"size:255;index;not null" json:"name"`
+	Position			int					`json:"position"`
+}
+
+// DocumentVersion keeps an earlier file of a document, with its extracted text,
+// when the file is replaced. The document itself always holds the latest version.
+type DocumentVersion struct {
+	ID					uint				`gorm:"primaryKey" json:"id"`
+	DocumentID			uint				`gorm:"uniqueIndex:idx_document_version;not null" json:"document_id"`
+	Version				int					`gorm:"uniqueIndex:idx_document_version;not null" json:"version"`
+	FilePath			string				`gorm:"not null" json:"file_path"`
+	ContentHash			string				`gorm:"size:64;index" json:"content_hash,omitempty"`
+	// Format is empty for versions kept before documents had other formats than PDF.
+	Format				string				`gorm:"size:16" json:"format,omitempty"`
+	ExtractedText		string				`gorm:"type:LONGTEXT" json:"-"`
+	ReplacedAt			time.Time			`gorm:"autoCreateTime" json:"replaced_at"`
+}
+
+// DocumentPage is the text of one page of a document's current file. Pages are
+// stored once OCR has run, with the pages that had a text layer taken from it.
+type DocumentPage struct {
+	ID					uint				`gorm:"primaryKey" json:"-"`
+	DocumentID			uint				`gorm:"uniqueIndex:idx_document_page;not null" json:"-"`
+	Page				int					`gorm:"uniqueIndex:idx_document_page;not null" json:"page"`
+	Text				string				`gorm:"type:LONGTEXT" json:"text"`
+	Source				string				`gorm:"size:8;not null" json:"source"`
+	// Confidence is Tesseract's mean word confidence, 0 to 100, for OCR pages.
+	Confidence			*float64			`json:"confidence,omitempty"`
+}
+
+// DocumentSection is the part of a document's text under one heading, in reading
+// order. Kind places the heading among the usual parts of a paper.
+type DocumentSection struct {
+	ID					uint				`gorm:"primaryKey" json:"-"`
+	DocumentID			uint				`gorm:"uniqueIndex:idx_document_section;not null" json:"document_id"`
+	Position			int					`gorm:"uniqueIndex:idx_document_section;not null" json:"position"`
+	Kind				string				`gorm:"size:16;index;not null" json:"kind"`
+	Heading				string				`gorm:"size:255" json:"heading"`
+	Level				int					`json:"level"`
+	Page				int					`json:"page,omitempty"`
+	Text				string				`gorm:"type:LONGTEXT" json:"text,omitempty"`
+}
+
+// DocumentReference is an entry of a document's reference list. CitedDocumentID
+// is the document of the same library that the entry refers to, if any; those
+// links are the edges of the citation graph.
+type DocumentReference struct {
+	ID					uint				`gorm:"primaryKey" json:"id"`
+	DocumentID			uint				`gorm:"index;not null" json:"document_id"`
+	Position			int					`json:"position"`
+	Raw					string				`gorm:"type:TEXT" json:"raw"`
+	Authors				string				`gorm:"size:1024" json:"authors,omitempty"`
+	Title				string				`gorm:"size:512" json:"title,omitempty"`
+	Year				int					`json:"year,omitempty"`
+	DOI					string				`gorm:"size:255;index" json:"doi,omitempty"`
+	CitedDocumentID		*uint				`gorm:"index" json:"cited_document_id,omitempty"`
+}
+
+// Formats of stored files. An html document is the snapshot of a clipped web
+// page.
+const (
+	FormatPDF				= "pdf"
+	FormatHTML				= "html"
+)
+
+const (
+	ReadingStatusUnread		= "unread"
+	ReadingStatusReading	= "reading"
+	ReadingStatusRead		= "read"
+)
+
+func IsValidExtractionStatus(status string) bool {
+	switch status {
+	case ExtractionOK, ExtractionEncrypted, ExtractionNoText, ExtractionCorrupt:
+		return true
+	}
+	return false
+}
+
+func IsValidReadingStatus(status string) bool {
+	switch status {
+	case ReadingStatusUnread, ReadingStatusReading, ReadingStatusRead:
+		return true
+	}
+	return false
 }
 
+// Outcomes of extracting the text of a document. A document is kept whatever the
+// outcome; only ok documents have searchable text.
+const (
+	ExtractionOK			= "ok"
+	ExtractionEncrypted		= "encrypted"
+	ExtractionNoText		= "no_text"
+	ExtractionCorrupt		= "corrupt"
+)
+
+const (
+	OCRPending			= "pending"
+	OCRDone				= "done"
+	OCRFailed			= "failed"
+	OCRSkipped			= "skipped"
+)
 
+// Outcomes of looking up a document's metadata. not_found means no resolver
+// knew the identifier; failed means a resolver couldn't be reached.
+const (
+	EnrichmentPending	= "pending"
+	EnrichmentDone		= "done"
+	EnrichmentNotFound	= "not_found"
+	EnrichmentFailed	= "failed"
+)
+
+// Kinds of DocumentSection. Headings that aren't one of the standard parts, and
+// aren't under one, are other.
+const (
+	SectionTitle			= "title"
+	SectionAbstract			= "abstract"
+	SectionIntroduction		= "introduction"
+	SectionMethods			= "methods"
+	SectionResults			= "results"
+	SectionDiscussion		= "discussion"
+	SectionConclusion		= "conclusion"
+	SectionReferences		= "references"
+	SectionAppendix			= "appendix"
+	SectionOther			= "other"
+)
+
+func IsValidSectionKind(kind string) bool {
+	switch kind {
+	case SectionTitle, SectionAbstract, SectionIntroduction, SectionMethods, SectionResults,
+		SectionDiscussion, SectionConclusion, SectionReferences, SectionAppendix, SectionOther:
+		return true
+	}
+	return false
+}
+
+// Sources of the text of a DocumentPage.
+const (
+	PageSourceText		= "text"
+	PageSourceOCR		= "ocr"
+)
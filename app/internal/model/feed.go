@@ -0,0 +1,96 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Feed subscribes a workspace to an RSS or Atom feed, or to an arXiv search. New
+// entries are imported into the workspace or held in its inbox, depending on Mode.
+// The entries already seen are the FeedItems of the feed; ETag and LastModified
+// are the validators of the last response, so unchanged feeds aren't downloaded
+// again.
+type Feed struct {
+	ID					uint			`gorm:"primaryKey" json:"id"`
+	UserID				uint			`gorm:"index;not null" json:"user_id"`
+	WorkspaceID			uint			`gorm:"index;not null" json:"workspace_id"`
+	Kind				string			`gorm:"size:16;not null" json:"kind"`
+	// URL is the address of an RSS or Atom feed, Query an arXiv search query such
+	// as "cat:cs.LG AND abs:diffusion".
+	URL					string			`gorm:"size:2048" json:"url,omitempty"`
+	Query				string			`gorm:"size:1024" json:"query,omitempty"`
+	Title				string			`gorm:"size:255;not null" json:"title"`
+	Mode				string			`gorm:"size:16;not null" json:"mode"`
+	// Keywords, comma-separated, limit the entries taken to those that mention one
+	// of them in their title or summary. Empty takes every entry.
+	Keywords			string			`gorm:"size:1024" json:"keywords,omitempty"`
+	IntervalMinutes		int				`gorm:"not null" json:"interval_minutes"`
+	Enabled				bool			`gorm:"not null" json:"enabled"`
+	// Backfill takes the entries already in the feed on its first poll. Without it
+	// they are skipped, and only entries published later are taken.
+	Backfill			bool			`gorm:"not null" json:"backfill"`
+
+	ETag				string			`gorm:"size:255" json:"-"`
+	LastModified		string			`gorm:"size:64" json:"-"`
+	LastPolledAt		*time.Time		`json:"last_polled_at,omitempty"`
+	NextPollAt			time.Time		`gorm:"index;not null" json:"next_poll_at"`
+	LastError			string			`gorm:"size:255" json:"last_error,omitempty"`
+	CreatedAt			time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+// FeedItem is an entry seen in a feed, kept so that each entry is handled once.
+// Entries in the inbox wait to be accepted, which imports them, or dismissed.
+type FeedItem struct {
+	ID					uint			`gorm:"primaryKey" json:"id"`
+	FeedID				uint			`gorm:"uniqueIndex:idx_feed_item;not null" json:"feed_id"`
+	// GUID identifies the entry within its feed.
+	GUID				string			`gorm:"size:512;uniqueIndex:idx_feed_item;not null" json:"guid"`
+	UserID				uint			`gorm:"index;not null" json:"user_id"`
+	WorkspaceID			uint			`gorm:"index;not null" json:"workspace_id"`
+	Title				string			`gorm:"size:512" json:"title"`
+	Link				string			`gorm:"size:2048" json:"link,omitempty"`
+	PDFURL				string			`gorm:"size:2048" json:"pdf_url,omitempty"`
+	Summary				string			`gorm:"type:TEXT" json:"summary,omitempty"`
+	// Authors are separated by semicolons, as in upload forms.
+	Authors				string			`gorm:"size:2000" json:"authors,omitempty"`
+	DOI					string			`gorm:"size:255" json:"doi,omitempty"`
+	ArXivID				string			`gorm:"size:32" json:"arxiv_id,omitempty"`
+	PublishedAt			*time.Time		`json:"published_at,omitempty"`
+	Status				string			`gorm:"size:16;index;not null" json:"status"`
+	// DocumentID is the document the entry was imported as, or the one already in
+	// the library that it duplicates.
+	DocumentID			*uint			`gorm:"index" json:"document_id,omitempty"`
+	Error				string			`gorm:"size:255" json:"error,omitempty"`
+	CreatedAt			time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+
+const (
+	FeedKindRSS				= "rss"
+	FeedKindArXiv			= "arxiv"
+)
+
+const (
+	FeedModeImport			= "import"
+	FeedModeInbox			= "inbox"
+)
+
+// Statuses of FeedItem. Entries are skipped when they don't match the feed's
+// keywords or were in the feed before it was first polled, and are duplicates
+// when the library already has the paper.
+const (
+	FeedItemInbox			= "inbox"
+	FeedItemImported		= "imported"
+	FeedItemDuplicate		= "duplicate"
+	FeedItemDismissed		= "dismissed"
+	FeedItemSkipped			= "skipped"
+	FeedItemFailed			= "failed"
+)
+
+func IsValidFeedItemStatus(status string) bool {
+	switch status {
+	case FeedItemInbox, FeedItemImported, FeedItemDuplicate, FeedItemDismissed, FeedItemSkipped, FeedItemFailed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+
+type Note struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	WorkspaceID		uint			`gorm:"index;not null" json:"workspace_id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	Title			string			`gorm:"not null" json:"title"`
+	Body			string			`gorm:"type:LONGTEXT" json:"body"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt		time.Time		`gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// NoteRevision is an immutable snapshot of a note taken every time it is saved.
+type NoteRevision struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	NoteID			uint			`gorm:"index;not null" json:"note_id"`
+	Revision		int				`gorm:"not null" json:"revision"`
+	Title			string			`gorm:"not null" json:"title"`
+	Body			string			`gorm:"type:LONGTEXT" json:"body"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+// NoteLink is one [[doc:...]] or [[note:...]] reference found in a note body.
+// Links are rebuilt from the body on every save and back the backlink index.
+type NoteLink struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	NoteID			uint			`gorm:"index;not null" json:"note_id"`
+	TargetType		string			`gorm:"size:16;index:idx_note_link_target;not null" json:"target_type"`
+	TargetID		uint			`gorm:"index:idx_note_link_target;not null" json:"target_id"`
+	Page			int				`json:"page,omitempty"`
+}
+
+// NoteBacklink describes a note that cites a document or another note.
+type NoteBacklink struct {
+	NoteID			uint			`json:"note_id"`
+	WorkspaceID		uint			`json:"workspace_id"`
+	Title			string			`json:"title"`
+	Page			int				`json:"page,omitempty"`
+}
+
+const (
+	NoteLinkDocument	= "doc"
+	NoteLinkNote		= "note"
+)
\ No newline at end of file
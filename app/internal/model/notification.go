@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Notification tells a user that something happened in their library, such as a
+// new document matching one of their saved searches. Notifications are always
+// kept in the app, where they are unread until ReadAt is set; the user's
+// NotificationSettings decide where else they go.
+type Notification struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	Kind			string			`gorm:"size:32;not null" json:"kind"`
+	Title			string			`gorm:"size:255;not null" json:"title"`
+	Body			string			`gorm:"type:TEXT" json:"body,omitempty"`
+	DocumentID		*uint			`json:"document_id,omitempty"`
+	SavedSearchID	*uint			`gorm:"index" json:"saved_search_id,omitempty"`
+	FeedID			*uint			`json:"feed_id,omitempty"`
+	ReadAt			*time.Time		`gorm:"index" json:"read_at,omitempty"`
+	// EmailPending marks notifications waiting for the user's next email digest.
+	EmailPending	bool			`gorm:"index;not null;default:false" json:"-"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+// NotificationSettings are a user's choices of where notifications go besides
+// the app. Users who never saved any get the zero settings: no email, no webhook
+// and nothing muted.
+type NotificationSettings struct {
+	UserID			uint			`gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	// Email is one of the NotificationEmail constants.
+	Email			string			`gorm:"size:16;not null;default:off" json:"email"`
+	// WebhookURL receives every notification that isn't muted as a JSON post.
+	WebhookURL		string			`gorm:"size:2048" json:"webhook_url,omitempty"`
+	// Muted lists the kinds, comma-separated, that the user doesn't want at all,
+	// in the app included.
+	Muted			string			`gorm:"size:1024" json:"muted,omitempty"`
+	LastDigestAt	*time.Time		`json:"last_digest_at,omitempty"`
+	UpdatedAt		time.Time		`gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+
+// Kinds of Notification. A document is ready when the OCR or metadata lookup it
+// waited for is over; feed entries are the new entries a poll imported or put in
+// the inbox.
+const (
+	NotificationSearchMatch		= "search.match"
+	NotificationDocumentReady	= "document.ready"
+	NotificationFeedEntries		= "feed.entries"
+)
+
+// How notifications are emailed: not at all, one email each as they happen, or
+// gathered into an hourly or daily digest.
+const (
+	NotificationEmailOff		= "off"
+	NotificationEmailInstant	= "instant"
+	NotificationEmailHourly		= "hourly"
+	NotificationEmailDaily		= "daily"
+)
+
+func IsValidNotificationKind(kind string) bool {
+	switch kind {
+	case NotificationSearchMatch, NotificationDocumentReady, NotificationFeedEntries:
+		return true
+	}
+	return false
+}
+
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+
+// PasswordReset is a token mailed to a user who forgot their password. Like a
+// session token only its SHA-256 hash is stored; it works once, until ExpiresAt.
+type PasswordReset struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	TokenHash		string			`gorm:"size:64;uniqueIndex;not null" json:"-"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime;index" json:"created_at"`
+	ExpiresAt		time.Time		`gorm:"not null" json:"expires_at"`
+	UsedAt			*time.Time		`json:"used_at,omitempty"`
+}
\ No newline at end of file
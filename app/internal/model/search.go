@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+
+// SavedSearch is a search that is run again on every new document of its owner,
+// in one workspace or, with no workspace, the whole library. A document matches
+// when it passes the filters that are set and, with a query, matches the query.
+// Matches are recorded as notifications and can also be sent by email or posted
+// to a webhook.
+type SavedSearch struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	WorkspaceID		uint			`gorm:"index" json:"workspace_id"`
+	Name			string			`gorm:"size:255;not null" json:"name"`
+	Mode			string			`gorm:"size:16;not null" json:"mode"`
+	Query			string			`gorm:"size:1024" json:"query,omitempty"`
+	// MinScore is how alike a document must be to the query of a semantic
+	// search, from 0 to 1.
+	MinScore		float64			`json:"min_score,omitempty"`
+
+	Year			int				`json:"year,omitempty"`
+	Author			string			`gorm:"size:255" json:"author,omitempty"`
+	Format			string			`gorm:"size:16" json:"format,omitempty"`
+
+	NotifyEmail		bool			`gorm:"not null;default:false" json:"notify_email"`
+	WebhookURL		string			`gorm:"size:2048" json:"webhook_url,omitempty"`
+	LastMatchedAt	*time.Time		`json:"last_matched_at,omitempty"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+
+// Modes of SavedSearch. A keyword query matches documents whose title or text
+// contains it, as /search does. A semantic query matches documents about the same
+// topic by the words of their title and abstract, without needing the phrase.
+const (
+	SearchModeKeyword		= "keyword"
+	SearchModeSemantic		= "semantic"
+)
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Session is an API token issued at login. Only the SHA-256 hash of the token is stored.
+type Session struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	TokenHash		string			`gorm:"size:64;uniqueIndex;not null" json:"-"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt		time.Time		`gorm:"index;not null" json:"expires_at"`
+}
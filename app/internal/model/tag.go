@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Tag is a user-defined label. Tags nest through ParentID; Path holds the
+// slash-separated chain of names from the root so a subtree can be matched with a prefix.
+type Tag struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	UserID			uint			`gorm:"uniqueIndex:idx_tag_user_path;not null" json:"user_id"`
+	ParentID		*uint			`gorm:"index" json:"parent_id"`
+	Name			string			`gorm:"size:100;not null" json:"name"`
+	Path			string			`gorm:"size:500;uniqueIndex:idx_tag_user_path;not null" json:"path"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+type DocumentTag struct {
+	DocumentID		uint			`gorm:"primaryKey"`
+	TagID			uint			`gorm:"primaryKey;index"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Upload is a resumable upload in progress under /api/v2/uploads. The bytes received
+// so far are kept in a partial file; the rest describes the document to create
+// once all Length bytes have arrived.
+type Upload struct {
+	ID				string			`gorm:"primaryKey;size:64" json:"id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	Length			int64			`gorm:"not null" json:"length"`
+	Offset			int64			`gorm:"not null" json:"offset"`
+	Filename		string			`gorm:"size:255" json:"filename"`
+	Title			string			`gorm:"size:255" json:"title"`
+	WorkspaceID		uint			`json:"workspace_id"`
+	Year			int				`json:"year,omitempty"`
+	Authors			string			`gorm:"size:2000" json:"authors,omitempty"`
+	DocumentID		uint			`json:"document_id,omitempty"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt		time.Time		`gorm:"index;not null" json:"expires_at"`
+}
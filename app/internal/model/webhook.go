@@ -0,0 +1,76 @@
+package model
+
+import (
+	"time"
+)
+
+
+// Webhook subscribes a URL to events of a user's library, or of one workspace in
+// it. Every delivery is signed with Secret, which is only shown when the webhook
+// is created or its secret rotated.
+type Webhook struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	UserID			uint			`gorm:"index;not null" json:"user_id"`
+	// WorkspaceID limits the webhook to events in one workspace; 0 takes the
+	// events of the whole library.
+	WorkspaceID		uint			`gorm:"index" json:"workspace_id"`
+	URL				string			`gorm:"size:2048;not null" json:"url"`
+	Secret			string			`gorm:"size:64;not null" json:"secret,omitempty"`
+	// Events lists the events sent, comma-separated. Empty sends all of them.
+	Events			string			`gorm:"size:1024" json:"events,omitempty"`
+	Enabled			bool			`gorm:"not null" json:"enabled"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+// WebhookDelivery is one event sent to a webhook, with the outcome of its last
+// attempt. Failed attempts are retried with exponential backoff until one
+// succeeds or the attempts run out. A redelivery is a new delivery of the same
+// event, with the same EventID.
+type WebhookDelivery struct {
+	ID				uint			`gorm:"primaryKey" json:"id"`
+	WebhookID		uint			`gorm:"index;not null" json:"webhook_id"`
+	EventID			string			`gorm:"size:32;index;not null" json:"event_id"`
+	Event			string			`gorm:"size:64;not null" json:"event"`
+	Payload			string			`gorm:"type:TEXT;not null" json:"payload"`
+	Status			string			`gorm:"size:16;index;not null" json:"status"`
+	Attempts		int				`gorm:"not null" json:"attempts"`
+	NextAttemptAt	time.Time		`gorm:"index;not null" json:"next_attempt_at"`
+	ResponseStatus	int				`json:"response_status,omitempty"`
+	ResponseBody	string			`gorm:"size:1024" json:"response_body,omitempty"`
+	Error			string			`gorm:"size:255" json:"error,omitempty"`
+	DeliveredAt		*time.Time		`json:"delivered_at,omitempty"`
+	CreatedAt		time.Time		`gorm:"autoCreateTime" json:"created_at"`
+}
+
+
+// Events sent to webhooks. A document is created when it is imported, and
+// extracted when its text is final, OCR included. Notifications are sent to the
+// webhook URLs of the notification settings and saved searches.
+const (
+	EventDocumentCreated			= "document.created"
+	EventDocumentExtracted			= "document.extracted"
+	EventWorkspaceDocumentAdded		= "workspace.document_added"
+	EventNotificationCreated		= "notification.created"
+)
+
+const (
+	DeliveryPending			= "pending"
+	DeliveryDelivered		= "delivered"
+	DeliveryFailed			= "failed"
+)
+
+func IsValidEvent(event string) bool {
+	switch event {
+	case EventDocumentCreated, EventDocumentExtracted, EventWorkspaceDocumentAdded, EventNotificationCreated:
+		return true
+	}
+	return false
+}
+
+func IsValidDeliveryStatus(status string) bool {
+	switch status {
+	case DeliveryPending, DeliveryDelivered, DeliveryFailed:
+		return true
+	}
+	return false
+}
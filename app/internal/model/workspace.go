@@ -9,5 +9,9 @@ type Workspace struct {
 	ID			uint		`gorm:"primaryKey" json:"id"`
 	UserID		uint		`json:"user_id"`
 	Title		string		`gorm:"not null" json:"title"`
+	// SkipOCR turns off OCR of scanned documents in the workspace, and OCRLanguage
+	// overrides the server's Tesseract language, e.g. "deu+eng".
+	SkipOCR		bool		`gorm:"not null;default:false" json:"skip_ocr"`
+	OCRLanguage	string		`gorm:"size:64" json:"ocr_language,omitempty"`
 	CreatedAt	time.Time	`gorm:"autoCreateTime" json:"created_at"`
 }
\ No newline at end of file
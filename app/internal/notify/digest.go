@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+)
+
+
+// digestInterval is how often RunDigests looks for digests that are due.
+const digestInterval = time.Minute
+
+// maxDigestItems bounds the notifications listed in full in one digest; the rest
+// are only counted.
+const maxDigestItems = 50
+
+
+// RunDigests mails the digests that are due until ctx is cancelled. A user's
+// digest is due once a digest period has passed since the last one.
+func (n *Notifier) RunDigests(ctx context.Context) {
+	log.Println("Notification digests started")
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+
+	for {
+		n.sendDigests(time.Now())
+		select {
+		case <-ctx.Done():
+			log.Println("Notification digests stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Notifier) sendDigests(now time.Time) {
+	if n.Mailer == nil {
+		return
+	}
+	users, err := n.Notifications.PendingEmailUsers()
+	if err != nil {
+		log.Printf("Failed to list users with pending digests: %v\n", err)
+		return
+	}
+
+	for _, userID := range users {
+		settings, err := n.Notifications.GetSettings(userID)
+		if err != nil {
+			log.Printf("Digest for user ID=%d: Failed to fetch settings: %v\n", userID, err)
+			continue
+		}
+		// Users who switched away from digests get what was gathered at once.
+		period := digestPeriod(settings.Email)
+		if period > 0 && settings.LastDigestAt != nil && now.Sub(*settings.LastDigestAt) < period {
+			continue
+		}
+		if err := n.sendDigest(userID, now); err != nil {
+			log.Printf("Digest for user ID=%d failed: %v\n", userID, err)
+		}
+	}
+}
+
+func (n *Notifier) sendDigest(userID uint, now time.Time) error {
+	notes, err := n.Notifications.PendingEmail(userID)
+	if err != nil || len(notes) == 0 {
+		return err
+	}
+	user, err := n.Users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	subject, body := digest(notes)
+	if err := n.Mailer.Send(user.Email, subject, body); err != nil {
+		return err
+	}
+
+	ids := make([]uint, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+	if err := n.Notifications.ClearEmailPending(ids); err != nil {
+		return err
+	}
+	if err := n.Notifications.SetLastDigest(userID, now); err != nil {
+		return err
+	}
+	log.Printf("Sent digest of %d notifications to user ID=%d\n", len(notes), userID)
+	return nil
+}
+
+// digest writes the email of a digest, listing the notifications oldest first.
+func digest(notes []model.Notification) (string, string) {
+	subject := "1 new notification"
+	if len(notes) > 1 {
+		subject = fmt.Sprintf("%d new notifications", len(notes))
+	}
+
+	var b strings.Builder
+	for i, note := range notes {
+		if i == maxDigestItems {
+			fmt.Fprintf(&b, "... and %d more. See all of them in the app.\n", len(notes)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%s  %s\n", note.CreatedAt.Format("2006-01-02 15:04"), note.Title)
+		if body := strings.TrimSpace(note.Body); body != "" {
+			b.WriteString("    " + strings.ReplaceAll(body, "\n", "\n    ") + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return subject, b.String()
+}
+
+// digestPeriod is how long a digest gathers notifications, or 0 when the email
+// setting isn't a digest.
+func digestPeriod(email string) time.Duration {
+	switch email {
+	case model.NotificationEmailHourly:
+		return time.Hour
+	case model.NotificationEmailDaily:
+		return 24 * time.Hour
+	}
+	return 0
+}
@@ -0,0 +1,311 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/mail"
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// smtpStub is an SMTP server in the test process, a stand-in for MailHog. It
+// accepts every message without login or TLS and keeps them for the tests to
+// inspect.
+type smtpStub struct {
+	net.Listener
+	mu			sync.Mutex
+	messages	[]smtpMessage
+}
+
+// smtpMessage is a message as the stub received it: the envelope and the data.
+type smtpMessage struct {
+	From		string
+	To			[]string
+	Data		string
+}
+
+// fakeNotifications keeps notifications and settings in memory.
+type fakeNotifications struct {
+	repository.NotificationRepository
+	notes		[]model.Notification
+	settings	map[uint]model.NotificationSettings
+}
+
+type fakeUsers struct {
+	repository.UserRepository
+	users		map[uint]*model.User
+}
+
+const digestSender = "Research Assistant <noreply@example.org>"
+
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{Listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.session(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+// session speaks just enough SMTP for net/smtp.SendMail.
+func (s *smtpStub) session(conn net.Conn) {
+	defer conn.Close()
+	c := textproto.NewConn(conn)
+	c.PrintfLine("220 localhost SMTP stub")
+
+	var msg smtpMessage
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL":
+			msg = smtpMessage{From: envelopeAddress(arg)}
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, envelopeAddress(arg))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			c.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return
+		default:
+			c.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// Messages returns the messages received so far.
+func (s *smtpStub) Messages() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+// envelopeAddress takes the address out of "FROM:<a@b>" or "TO:<a@b>".
+func envelopeAddress(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(addr, " ")
+	return strings.Trim(addr, "<>")
+}
+
+func (f *fakeNotifications) Create(n *model.Notification) error {
+	n.ID = uint(len(f.notes) + 1)
+	n.CreatedAt = time.Date(2024, 3, 1, 9, len(f.notes), 0, 0, time.UTC)
+	f.notes = append(f.notes, *n)
+	return nil
+}
+
+func (f *fakeNotifications) GetSettings(userID uint) (model.NotificationSettings, error) {
+	settings, ok := f.settings[userID]
+	if !ok {
+		return model.NotificationSettings{UserID: userID, Email: model.NotificationEmailOff}, nil
+	}
+	return settings, nil
+}
+
+func (f *fakeNotifications) PendingEmailUsers() ([]uint, error) {
+	var users []uint
+	seen := make(map[uint]bool)
+	for _, n := range f.notes {
+		if n.EmailPending && !seen[n.UserID] {
+			seen[n.UserID] = true
+			users = append(users, n.UserID)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return users, nil
+}
+
+func (f *fakeNotifications) PendingEmail(userID uint) ([]model.Notification, error) {
+	var notes []model.Notification
+	for _, n := range f.notes {
+		if n.UserID == userID && n.EmailPending {
+			notes = append(notes, n)
+		}
+	}
+	return notes, nil
+}
+
+func (f *fakeNotifications) ClearEmailPending(ids []uint) error {
+	for _, id := range ids {
+		f.notes[id-1].EmailPending = false
+	}
+	return nil
+}
+
+func (f *fakeNotifications) SetLastDigest(userID uint, at time.Time) error {
+	settings := f.settings[userID]
+	settings.LastDigestAt = &at
+	f.settings[userID] = settings
+	return nil
+}
+
+// pending returns the IDs of the notifications still waiting for a digest.
+func (f *fakeNotifications) pending() []uint {
+	var ids []uint
+	for _, n := range f.notes {
+		if n.EmailPending {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+func (f *fakeUsers) GetByID(id uint) (*model.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, &repository.NotFoundError{Resource: "user", ID: id}
+	}
+	return user, nil
+}
+
+// Notifications of users with a digest are gathered and mailed together once the
+// digest is due, one message per user, and each is mailed only once.
+func TestDigestBatchesPendingNotifications(t *testing.T) {
+	srv := newSMTPStub(t)
+	mailer, err := mail.NewMailer(srv.Addr().String(), digestSender, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	hourAgo, twoHoursAgo := now.Add(-time.Hour), now.Add(-2*time.Hour)
+	notes := &fakeNotifications{settings: map[uint]model.NotificationSettings{
+		1:	{UserID: 1, Email: model.NotificationEmailDaily},
+		2:	{UserID: 2, Email: model.NotificationEmailDaily, LastDigestAt: &hourAgo},
+		3:	{UserID: 3, Email: model.NotificationEmailHourly, LastDigestAt: &twoHoursAgo},
+	}}
+	users := &fakeUsers{users: map[uint]*model.User{
+		1:	{ID: 1, Email: "ada@example.org"},
+		2:	{ID: 2, Email: "alan@example.org"},
+		3:	{ID: 3, Email: "grace@example.org"},
+	}}
+	n := NewNotifier(notes, users, mailer)
+	ctx := context.Background()
+
+	for i, userID := range []uint{1, 2, 1, 3, 1} {
+		note := &model.Notification{UserID: userID, Kind: model.NotificationFeedEntries, Title: fmt.Sprintf("Notification %d", i+1), Body: "First line\nsecond line"}
+		if err := n.Notify(ctx, note, Delivery{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if msgs := srv.Messages(); len(msgs) != 0 {
+		t.Fatalf("%d messages sent before the digest, want none", len(msgs))
+	}
+	if got, want := notes.pending(), []uint{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending %v, want %v", got, want)
+	}
+
+	n.sendDigests(now)
+
+	msgs := srv.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("%d messages, want 2: one for user 1 and one for user 3, whose digests are due", len(msgs))
+	}
+	checkDigest(t, msgs[0], "ada@example.org", "3 new notifications", []string{"Notification 1", "Notification 3", "Notification 5"})
+	checkDigest(t, msgs[1], "grace@example.org", "1 new notification", []string{"Notification 4"})
+
+	if got, want := notes.pending(), []uint{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pending after the digests %v, want %v, user 2's digest isn't due", got, want)
+	}
+	for _, userID := range []uint{1, 3} {
+		if at := notes.settings[userID].LastDigestAt; at == nil || !at.Equal(now) {
+			t.Errorf("user %d: last digest %v, want %v", userID, at, now)
+		}
+	}
+
+	n.sendDigests(now.Add(time.Minute))
+	if msgs := srv.Messages(); len(msgs) != 2 {
+		t.Errorf("%d messages after polling again, want still 2", len(msgs))
+	}
+}
+
+// checkDigest checks the envelope, headers and body of a digest.
+func checkDigest(t *testing.T, got smtpMessage, to, subject string, titles []string) {
+	t.Helper()
+	if got.From != "noreply@example.org" || !reflect.DeepEqual(got.To, []string{to}) {
+		t.Errorf("envelope from %q to %v, want from noreply@example.org to %s", got.From, got.To, to)
+	}
+
+	msg, err := netmail.ReadMessage(strings.NewReader(got.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := msg.Header
+	if from, err := h.AddressList("From"); err != nil || len(from) != 1 || from[0].String() != `"Research Assistant" <noreply@example.org>` {
+		t.Errorf("From %q", h.Get("From"))
+	}
+	if rcpt, err := h.AddressList("To"); err != nil || len(rcpt) != 1 || rcpt[0].Address != to {
+		t.Errorf("To %q, want %s", h.Get("To"), to)
+	}
+	if s, err := new(mime.WordDecoder).DecodeHeader(h.Get("Subject")); err != nil || s != subject {
+		t.Errorf("Subject %q, want %q", h.Get("Subject"), subject)
+	}
+	if _, err := h.Date(); err != nil {
+		t.Errorf("Date %q: %v", h.Get("Date"), err)
+	}
+	if id := h.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.org>") {
+		t.Errorf("Message-ID %q", id)
+	}
+	if ct := h.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type %q", ct)
+	}
+
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(body)
+	last := -1
+	for _, title := range titles {
+		i := strings.Index(text, title)
+		if i < 0 || i < last {
+			t.Errorf("body lists %q out of order, or not at all:\n%s", title, text)
+		}
+		last = i
+	}
+	if n := strings.Count(text, "    First line\n    second line\n"); n != len(titles) {
+		t.Errorf("body has %d indented notification bodies, want %d:\n%s", n, len(titles), text)
+	}
+}
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"backend/internal/mail"
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/webhooks"
+)
+
+
+// Notifier records notifications for their users and delivers them over the
+// channels of the users' settings: email, one at a time or as a digest, and a
+// webhook. Delivery is best effort: a notification that can't be mailed or posted
+// is still recorded.
+type Notifier struct {
+	Notifications	repository.NotificationRepository
+	Users			repository.UserRepository
+	// Mailer sends notifications by email. Email is off when it is nil.
+	Mailer			*mail.Mailer
+	// Webhooks, if set, delivers notifications to webhook URLs as
+	// notification.created events, signed and retried like the other events.
+	Webhooks		*webhooks.Dispatcher
+}
+
+// Delivery names channels that a notification goes out on besides those of the
+// user's settings, such as the ones set on a saved search. Email follows the
+// user's digest, or is sent at once if the user turned email off.
+type Delivery struct {
+	Email			bool
+	WebhookURL		string
+}
+
+
+func NewNotifier(notifications repository.NotificationRepository, users repository.UserRepository, mailer *mail.Mailer) *Notifier {
+	log.Println("Initializing notifier...")
+	return &Notifier{Notifications: notifications, Users: users, Mailer: mailer}
+}
+
+// Notify records the notification and delivers it, unless the user muted its
+// kind.
+func (n *Notifier) Notify(ctx context.Context, note *model.Notification, d Delivery) error {
+	settings, err := n.Notifications.GetSettings(note.UserID)
+	if err != nil {
+		return err
+	}
+	if muted(&settings, note.Kind) {
+		return nil
+	}
+
+	email := settings.Email
+	if d.Email && email == model.NotificationEmailOff {
+		email = model.NotificationEmailInstant
+	}
+	note.EmailPending = n.Mailer != nil && digestPeriod(email) > 0
+	if err := n.Notifications.Create(note); err != nil {
+		return err
+	}
+
+	if email == model.NotificationEmailInstant && n.Mailer != nil {
+		if err := n.email(note); err != nil {
+			log.Printf("Failed to email notification ID=%d: %v\n", note.ID, err)
+		}
+	}
+	if n.Webhooks != nil {
+		for _, url := range webhookURLs(settings.WebhookURL, d.WebhookURL) {
+			n.Webhooks.EmitTo(model.EventNotificationCreated, note.UserID, url, note)
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) email(note *model.Notification) error {
+	user, err := n.Users.GetByID(note.UserID)
+	if err != nil {
+		return err
+	}
+	return n.Mailer.Send(user.Email, note.Title, note.Body)
+}
+
+// muted reports whether the user turned off notifications of a kind.
+func muted(s *model.NotificationSettings, kind string) bool {
+	for _, k := range strings.Split(s.Muted, ",") {
+		if strings.TrimSpace(k) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookURLs lists the distinct, non-empty URLs.
+func webhookURLs(urls ...string) []string {
+	var list []string
+	for _, u := range urls {
+		if u != "" && !slices.Contains(list, u) {
+			list = append(list, u)
+		}
+	}
+	return list
+}
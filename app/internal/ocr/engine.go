@@ -0,0 +1,168 @@
+// Package ocr recognises the text of scanned PDF pages with a local Tesseract.
+// Pages are rasterised with pdftoppm from poppler-utils; nothing leaves the server.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+
+// Engine runs the external programs. The zero values of DPI and Timeout are
+// replaced by sensible defaults.
+type Engine struct {
+	Pdftoppm		string
+	Tesseract		string
+	DPI				int
+	// Timeout bounds the rasterisation and recognition of a single page.
+	Timeout			time.Duration
+}
+
+// PageText is the recognised text of a page with Tesseract's mean word
+// confidence, from 0 to 100.
+type PageText struct {
+	Text			string
+	Confidence		float64
+}
+
+var languagePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}(\+[A-Za-z0-9_]{3,32}){0,3}$`)
+
+const (
+	defaultDPI		= 300
+	defaultTimeout	= 2 * time.Minute
+)
+
+
+// NewEngine finds pdftoppm and tesseract, which may be given as names to look
+// up in PATH or as paths.
+func NewEngine(pdftoppm, tesseract string) (*Engine, error) {
+	var err error
+	e := &Engine{DPI: defaultDPI, Timeout: defaultTimeout}
+	if e.Pdftoppm, err = exec.LookPath(pdftoppm); err != nil {
+		return nil, err
+	}
+	if e.Tesseract, err = exec.LookPath(tesseract); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ValidLanguage reports whether lang looks like a Tesseract language, such as
+// "eng" or "deu+eng". It stops option-like values from reaching the command line;
+// whether the language is installed only shows when it is used.
+func ValidLanguage(lang string) bool {
+	return languagePattern.MatchString(lang)
+}
+
+// RecognizePage rasterises page (1-based) of the PDF at path and runs Tesseract
+// on the image.
+func (e *Engine) RecognizePage(ctx context.Context, path string, page int, lang string) (PageText, error) {
+	if !ValidLanguage(lang) {
+		return PageText{}, fmt.Errorf("invalid OCR language %q", lang)
+	}
+
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "ocr-")
+	if err != nil {
+		return PageText{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	dpi := e.DPI
+	if dpi <= 0 {
+		dpi = defaultDPI
+	}
+	n := strconv.Itoa(page)
+	image := filepath.Join(dir, "page")
+	if _, err := run(ctx, e.Pdftoppm, "-f", n, "-l", n, "-r", strconv.Itoa(dpi), "-gray", "-png", "-singlefile", path, image); err != nil {
+		return PageText{}, fmt.Errorf("rasterise page %d: %w", page, err)
+	}
+
+	tsv, err := run(ctx, e.Tesseract, image+".png", "stdout", "-l", lang, "tsv")
+	if err != nil {
+		return PageText{}, fmt.Errorf("recognise page %d: %w", page, err)
+	}
+	return parseTSV(tsv), nil
+}
+
+// run executes a program and returns its standard output. The error carries the
+// last line of standard error, which is where both programs explain themselves.
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+			msg = msg[i+1:]
+		}
+		if msg == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", err, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseTSV rebuilds the text of a page from Tesseract's TSV output, one row per
+// word, and averages the word confidences. Lines are kept and paragraphs are
+// separated by a blank line.
+func parseTSV(tsv []byte) PageText {
+	var (
+		b			strings.Builder
+		sum			float64
+		words		int
+		last		[3]string
+	)
+	for i, row := range strings.Split(string(tsv), "\n") {
+		cols := strings.Split(strings.TrimRight(row, "\r"), "\t")
+		// level page block paragraph line word left top width height conf text
+		if i == 0 || len(cols) < 12 || cols[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(cols[11])
+		conf, err := strconv.ParseFloat(cols[10], 64)
+		if text == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		pos := [3]string{cols[2], cols[3], cols[4]}
+		switch {
+		case words == 0:
+		case pos[0] != last[0] || pos[1] != last[1]:
+			b.WriteString("\n\n")
+		case pos[2] != last[2]:
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(' ')
+		}
+		last = pos
+
+		b.WriteString(text)
+		sum += conf
+		words++
+	}
+
+	if words == 0 {
+		return PageText{}
+	}
+	return PageText{Text: b.String(), Confidence: sum / float64(words)}
+}
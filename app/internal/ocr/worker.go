@@ -0,0 +1,233 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/util"
+)
+
+
+// Worker recognises the scanned pages of pending documents one document at a
+// time in the background. The queue lives in the database as the documents'
+// pending status, so work left over from a restart or a full channel is found
+// again with PendingOCR.
+type Worker struct {
+	Engine			*Engine
+	Docs			repository.DocumentRepository
+	Workspaces		repository.WorkspaceRepository
+	// Language is the Tesseract language of documents outside a workspace that
+	// sets its own.
+	Language		string
+	// Enrich, if set, receives documents whose DOI or arXiv ID was only found by
+	// OCR.
+	Enrich			ingest.EnrichQueue
+	// Alerts, if set, is told when OCR of a document ends, since saved searches
+	// wait for the recognised text.
+	Alerts			ingest.AlertQueue
+	// Events, if set, receives document.extracted when OCR of a document ends.
+	Events			ingest.EventSink
+
+	queue			chan uint
+	overflow		atomic.Bool
+}
+
+const queueSize = 256
+
+
+func NewWorker(engine *Engine, docs repository.DocumentRepository, workspaces repository.WorkspaceRepository, language string) *Worker {
+	log.Println("Initializing OCR worker...")
+	return &Worker{Engine: engine, Docs: docs, Workspaces: workspaces, Language: language, queue: make(chan uint, queueSize)}
+}
+
+// Enqueue schedules OCR of a document whose status is already pending. It never
+// blocks; when the channel is full the pending documents are looked up again
+// once it has drained.
+func (w *Worker) Enqueue(docID uint) {
+	select {
+	case w.queue <- docID:
+	default:
+		w.overflow.Store(true)
+		log.Printf("OCR queue is full; document ID=%d will be picked up later\n", docID)
+	}
+}
+
+// Run processes documents until ctx is cancelled, starting with those left
+// pending by a previous run.
+func (w *Worker) Run(ctx context.Context) {
+	log.Println("OCR worker started")
+	w.overflow.Store(true)
+
+	for {
+		if len(w.queue) == 0 && w.overflow.Swap(false) {
+			w.resume(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("OCR worker stopped")
+			return
+		case id := <-w.queue:
+			w.process(ctx, id)
+		}
+	}
+}
+
+func (w *Worker) resume(ctx context.Context) {
+	ids, err := w.Docs.PendingOCR()
+	if err != nil {
+		log.Printf("Failed to list documents pending OCR: %v\n", err)
+		return
+	}
+	if len(ids) > 0 {
+		log.Printf("Resuming OCR of %d pending documents\n", len(ids))
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		w.process(ctx, id)
+	}
+}
+
+// process runs OCR on the pages of a document that have no text layer and stores
+// every page, so that the document's text becomes the text layer with the gaps
+// filled in. Sections and references are found again in the combined text,
+// without font sizes.
+// A document that was deleted, re-extracted or given a new file since it was
+// queued is left alone.
+func (w *Worker) process(ctx context.Context, id uint) {
+	doc, err := w.Docs.GetByDocumentID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("OCR of document ID=%d: Failed to fetch document: %v\n", id, err)
+		return
+	}
+	if doc.OCRStatus != model.OCRPending {
+		return
+	}
+
+	lang := w.Language
+	if doc.WorkspaceID != 0 {
+		ws, err := w.Workspaces.GetByID(doc.WorkspaceID)
+		switch {
+		case err == nil && ws.SkipOCR:
+			log.Printf("Skipping OCR of document ID=%d: turned off in workspace ID=%d\n", id, ws.ID)
+			w.setStatus(id, model.OCRSkipped)
+			return
+		case err == nil && ws.OCRLanguage != "":
+			lang = ws.OCRLanguage
+		case err != nil && !errors.Is(err, repository.ErrNotFound):
+			log.Printf("OCR of document ID=%d: Failed to fetch workspace: %v\n", id, err)
+			return
+		}
+	}
+
+	start := time.Now()
+	pages, status, err := util.ExtractPDFPages(doc.FilePath, "")
+	if status != model.ExtractionOK && status != model.ExtractionNoText {
+		log.Printf("OCR of document ID=%d failed: Failed to read PDF: %v\n", id, err)
+		w.setStatus(id, model.OCRFailed)
+		return
+	}
+
+	stored := make([]model.DocumentPage, len(pages))
+	for i, text := range pages {
+		stored[i] = model.DocumentPage{Page: i + 1, Text: text, Source: model.PageSourceText}
+	}
+
+	missing := util.PagesWithoutText(pages)
+	recognized := 0
+	for _, n := range missing {
+		res, err := w.Engine.RecognizePage(ctx, doc.FilePath, n, lang)
+		if ctx.Err() != nil {
+			// Still pending, so the next run starts over.
+			return
+		}
+		if err != nil {
+			log.Printf("OCR of document ID=%d: %v\n", id, err)
+			continue
+		}
+		stored[n-1] = model.DocumentPage{Page: n, Text: res.Text, Source: model.PageSourceOCR, Confidence: &res.Confidence}
+		recognized++
+	}
+	if len(missing) > 0 && recognized == 0 {
+		log.Printf("OCR of document ID=%d failed: no page could be recognised\n", id)
+		w.setStatus(id, model.OCRFailed)
+		return
+	}
+
+	texts := make([]string, len(stored))
+	for i, p := range stored {
+		texts[i] = p.Text
+	}
+	doc.ExtractedText = strings.Join(texts, "\n")
+	doc.Fingerprint = util.Fingerprint(doc.ExtractedText)
+	doc.Sections = util.SegmentPages(texts)
+	doc.References = util.ReferencesFromSections(doc.Sections)
+	identified := doc.DOI != "" || doc.ArXivID != ""
+	if doc.DOI == "" && len(texts) > 0 {
+		doc.DOI = util.FindDOI(texts[0])
+	}
+	if doc.ArXivID == "" && len(texts) > 0 {
+		doc.ArXivID = util.FindArXivID(texts[0])
+	}
+	doc.OCRStatus = model.OCRDone
+
+	err = w.Docs.SaveOCR(&doc, stored)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Discarding OCR of document ID=%d: document changed meanwhile\n", id)
+		return
+	}
+	if err != nil {
+		log.Printf("OCR of document ID=%d: Failed to save pages: %v\n", id, err)
+		return
+	}
+
+	if _, err := w.Docs.LinkReferences(&doc); err != nil {
+		log.Printf("OCR of document ID=%d: Failed to link references: %v\n", id, err)
+	}
+	if w.Enrich != nil && !identified && (doc.DOI != "" || doc.ArXivID != "") {
+		if err := w.Docs.SetEnrichmentStatus(id, model.EnrichmentPending); err != nil {
+			log.Printf("OCR of document ID=%d: Failed to queue enrichment: %v\n", id, err)
+		} else {
+			w.Enrich.Enqueue(id)
+		}
+	}
+	w.finished(id)
+
+	log.Printf("OCR of document ID=%d done: %d of %d pages recognised in %s\n", id, recognized, len(missing), time.Since(start))
+}
+
+func (w *Worker) setStatus(id uint, status string) {
+	if err := w.Docs.SetOCRStatus(id, status); err != nil {
+		log.Printf("Failed to set OCR status of document ID=%d: %v\n", id, err)
+		return
+	}
+	w.finished(id)
+}
+
+// finished tells the alerts and the webhooks that OCR of a document is over,
+// whatever its outcome.
+func (w *Worker) finished(id uint) {
+	if w.Alerts != nil {
+		w.Alerts.Enqueue(id)
+	}
+	if w.Events != nil {
+		doc, err := w.Docs.GetByDocumentID(id)
+		if err != nil {
+			log.Printf("OCR of document ID=%d: Failed to fetch document for its events: %v\n", id, err)
+			return
+		}
+		w.Events.DocumentEvent(model.EventDocumentExtracted, &doc)
+	}
+}
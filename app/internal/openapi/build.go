@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+
+// Route describes one API operation. The router registers its handlers from the
+// same list, so the document can't drift from the routes that are actually served.
+type Route struct {
+	Method			string
+	Path			string
+	OperationID		string
+	Summary			string
+	Tag				string
+	Query			[]Param
+
+	// Body is a sample of the JSON request body, Form of the multipart form fields.
+	// Files names the file fields of a multipart form.
+	Body			any
+	Form			any
+	Files			[]string
+
+	// Status is the success status, 200 when zero. Response is a sample of the JSON
+	// response body; Items makes it a paginated list of that type instead, with
+	// Facets as an optional sample of the list's facet counts. ContentType marks a
+	// non-JSON response such as a file download.
+	Status			int
+	Response		any
+	Items			any
+	Facets			any
+	ContentType		string
+}
+
+// Param is a query parameter. Type is a sample value of the parameter's type.
+type Param struct {
+	Name			string
+	Type			any
+	Required		bool
+	Description		string
+}
+
+
+var pathParam = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// PageParams are the query parameters shared by every paginated list.
+var PageParams = []Param{
+	{Name: "limit", Type: 0, Description: "Page size, at most 200."},
+	{Name: "cursor", Type: "", Description: "The next_cursor of the previous page."},
+	{Name: "sort", Type: "", Description: "Sort key; prefix with '-' for descending order."},
+	{Name: "fields", Type: "", Description: "Comma separated list of fields to return."},
+}
+
+// Build assembles the OpenAPI document for the routes. errorType is a sample of
+// the error envelope returned by every operation.
+func Build(info Info, routes []Route, errorType any) *Document {
+	b := newSchemaBuilder()
+	doc := &Document{
+		OpenAPI:	"3.0.3",
+		Info:		info,
+		Paths:		map[string]*PathItem{},
+	}
+
+	errorSchema := b.ref(errorType)
+	for _, rt := range routes {
+		item := doc.Paths[rt.Path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[rt.Path] = item
+		}
+		item.setOperation(rt.Method, b.operation(rt, errorSchema))
+	}
+
+	doc.Components.Schemas = b.schemas
+	return doc
+}
+
+func (b *schemaBuilder) operation(rt Route, errorSchema *Schema) *Operation {
+	op := &Operation{
+		OperationID:	rt.OperationID,
+		Summary:		rt.Summary,
+		Responses:		map[string]*Response{},
+	}
+	if rt.Tag != "" {
+		op.Tags = []string{rt.Tag}
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(rt.Path, -1) {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:		m[1],
+			In:			"path",
+			Required:	true,
+			Schema:		&Schema{Type: "integer", Format: "int64", Minimum: float(1)},
+		})
+	}
+
+	query := rt.Query
+	if rt.Items != nil {
+		query = append(query[:len(query):len(query)], PageParams...)
+	}
+	for _, p := range query {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:			p.Name,
+			In:				"query",
+			Description:	p.Description,
+			Required:		p.Required,
+			Schema:			b.ref(p.Type),
+		})
+	}
+
+	switch {
+	case rt.Body != nil:
+		op.RequestBody = &RequestBody{
+			Required:	true,
+			Content:	map[string]*MediaType{"application/json": {Schema: b.ref(rt.Body)}},
+		}
+	case rt.Form != nil || len(rt.Files) > 0:
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		if rt.Form != nil {
+			b.addFields(form, reflect.TypeOf(rt.Form), true)
+		}
+		for _, name := range rt.Files {
+			form.Properties[name] = &Schema{Type: "string", Format: "binary"}
+			form.PropertyOrder = append(form.PropertyOrder, name)
+			form.Required = append(form.Required, name)
+		}
+		op.RequestBody = &RequestBody{
+			Required:	true,
+			Content:	map[string]*MediaType{"multipart/form-data": {Schema: form}},
+		}
+	}
+
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := &Response{Description: http.StatusText(status)}
+	switch {
+	case rt.Items != nil:
+		resp.Content = map[string]*MediaType{"application/json": {Schema: b.list(rt.Items, rt.Facets)}}
+	case rt.ContentType != "":
+		resp.Content = map[string]*MediaType{rt.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	case rt.Response != nil:
+		resp.Content = map[string]*MediaType{"application/json": {Schema: b.ref(rt.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+	op.Responses["default"] = &Response{
+		Description:	"Error",
+		Content:		map[string]*MediaType{"application/json": {Schema: errorSchema}},
+	}
+
+	return op
+}
+
+// list registers the list envelope for an item type as a <Item>List component.
+func (b *schemaBuilder) list(items, facets any) *Schema {
+	item := reflect.TypeOf(items)
+	name := item.Name() + "List"
+
+	if _, ok := b.schemas[name]; !ok {
+		s := &Schema{
+			Type:		"object",
+			Properties:	map[string]*Schema{
+				"items":		{Type: "array", Items: b.schemaFor(item)},
+				"next_cursor":	{Type: "string", Description: "Empty on the last page."},
+			},
+			PropertyOrder:	[]string{"items", "next_cursor"},
+			Required:		[]string{"items"},
+		}
+		if facets != nil {
+			s.Properties["facets"] = b.ref(facets)
+			s.PropertyOrder = append(s.PropertyOrder, "facets")
+		}
+		b.schemas[name] = s
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
\ No newline at end of file
@@ -0,0 +1,370 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+
+type generator struct {
+	doc			*Document
+	buf			bytes.Buffer
+	imports		map[string]bool
+}
+
+// GoClient generates the source of a typed Go client for the operations in doc:
+// a struct for every schema and a method for every operation, in package client.
+func GoClient(doc *Document) ([]byte, error) {
+	g := &generator{doc: doc, imports: map[string]bool{"context": true}}
+
+	var names []string
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.writeStruct(name, doc.Components.Schemas[name])
+	}
+
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		for _, method := range Methods {
+			if op := doc.Paths[path].Operation(method); op != nil {
+				g.writeOperation(method, path, op)
+			}
+		}
+	}
+
+	var head bytes.Buffer
+	head.WriteString("// Code generated by apigen from the OpenAPI document. DO NOT EDIT.\n\npackage client\n\nimport (\n")
+	var imports []string
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&head, "\t%q\n", imp)
+	}
+	head.WriteString(")\n")
+	head.Write(g.buf.Bytes())
+
+	return format.Source(head.Bytes())
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) writeStruct(name string, s *Schema) {
+	if s.Description != "" {
+		g.printf("\n// %s\n", s.Description)
+	}
+	g.printf("\ntype %s struct {\n", name)
+	for _, prop := range s.PropertyOrder {
+		required := contains(s.Required, prop)
+		typ := g.goType(s.Properties[prop])
+		if !required && s.Properties[prop].Ref != "" {
+			typ = "*" + typ
+		}
+		tag := prop
+		if !required {
+			tag += ",omitempty"
+		}
+		g.printf("\t%s %s `json:%q`\n", goName(prop), typ, tag)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) goType(s *Schema) string {
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+
+	var t string
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			t = "time.Time"
+		case "byte":
+			t = "[]byte"
+		default:
+			t = "string"
+		}
+	case "integer":
+		t = "int64"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		return "[]" + g.goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties)
+		}
+		return "map[string]any"
+	default:
+		return "any"
+	}
+
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+func (g *generator) writeOperation(method, path string, op *Operation) {
+	name := exported(op.OperationID)
+	args := []string{"ctx context.Context"}
+	var pathArgs []string
+	var query []*Parameter
+
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			args = append(args, p.Name+" int64")
+			pathArgs = append(pathArgs, p.Name)
+		case "query":
+			query = append(query, p)
+		}
+	}
+
+	if len(query) > 0 {
+		g.writeParams(name+"Params", query)
+		args = append(args, "params "+name+"Params")
+	}
+
+	var form *Schema
+	fileField := ""
+	if op.RequestBody != nil {
+		if mt := op.RequestBody.Content["application/json"]; mt != nil {
+			args = append(args, "body "+g.goType(mt.Schema))
+		}
+		if mt := op.RequestBody.Content["multipart/form-data"]; mt != nil {
+			form = &Schema{Type: "object", Properties: mt.Schema.Properties, Required: mt.Schema.Required}
+			for _, prop := range mt.Schema.PropertyOrder {
+				if mt.Schema.Properties[prop].Format == "binary" {
+					fileField = prop
+					continue
+				}
+				form.PropertyOrder = append(form.PropertyOrder, prop)
+			}
+			g.imports["io"] = true
+			if len(form.PropertyOrder) > 0 {
+				g.writeStruct(name+"Form", form)
+				args = append(args, "form "+name+"Form")
+			}
+			args = append(args, "filename string", "file io.Reader")
+		}
+	}
+
+	// Pick the success response and what the method returns for it.
+	var success *Response
+	for code, resp := range op.Responses {
+		if code != "default" {
+			success = resp
+		}
+	}
+	result, raw := "", false
+	for contentType, mt := range success.Content {
+		if contentType == "application/json" {
+			result = g.goType(mt.Schema)
+			if mt.Schema.Ref != "" {
+				result = "*" + result
+			}
+		} else {
+			g.imports["io"] = true
+			result, raw = "io.ReadCloser", true
+		}
+	}
+
+	g.printf("\n// %s calls %s %s: %s.\n", name, method, path, op.Summary)
+	if op.Deprecated {
+		g.printf("//\n// Deprecated: the server marks this operation as deprecated.\n")
+	}
+	if result == "" {
+		g.printf("func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+	} else {
+		g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+	}
+
+	if len(pathArgs) > 0 {
+		g.imports["fmt"] = true
+		g.printf("\tpath := fmt.Sprintf(%q, %s)\n", pathFormat(path), strings.Join(pathArgs, ", "))
+	} else {
+		g.printf("\tpath := %q\n", path)
+	}
+
+	queryArg := "nil"
+	if len(query) > 0 {
+		g.imports["net/url"] = true
+		queryArg = "q"
+		g.printf("\tq := url.Values{}\n")
+		for _, p := range query {
+			g.writeSet("q", "params."+goName(p.Name), p.Name, p.Schema, p.Required)
+		}
+	}
+
+	bodyArg := "nil"
+	if op.RequestBody != nil && op.RequestBody.Content["application/json"] != nil {
+		bodyArg = "body"
+	}
+
+	switch {
+	case form != nil:
+		g.printf("\tfields := map[string]string{}\n")
+		for _, prop := range form.PropertyOrder {
+			g.writeSet("fields", "form."+goName(prop), prop, form.Properties[prop], contains(form.Required, prop))
+		}
+		g.printf("\tvar out %s\n", strings.TrimPrefix(result, "*"))
+		g.printf("\tif err := c.doMultipart(ctx, %q, path, fields, %q, filename, file, &out); err != nil {\n\t\treturn nil, err\n\t}\n", method, fileField)
+		g.printf("\treturn &out, nil\n")
+	case raw:
+		g.printf("\treturn c.doRaw(ctx, %q, path, %s)\n", method, queryArg)
+	case result == "":
+		g.printf("\treturn c.do(ctx, %q, path, %s, %s, nil)\n", method, queryArg, bodyArg)
+	default:
+		g.printf("\tvar out %s\n", strings.TrimPrefix(result, "*"))
+		g.printf("\tif err := c.do(ctx, %q, path, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", method, queryArg, bodyArg)
+		if strings.HasPrefix(result, "*") {
+			g.printf("\treturn &out, nil\n")
+		} else {
+			g.printf("\treturn out, nil\n")
+		}
+	}
+	g.printf("}\n")
+}
+
+// writeParams declares the query parameters of an operation. Optional integers are
+// pointers so that zero can still be sent; booleans are only sent when true.
+func (g *generator) writeParams(name string, params []*Parameter) {
+	g.printf("\ntype %s struct {\n", name)
+	for _, p := range params {
+		if p.Description != "" {
+			g.printf("\t// %s\n", p.Description)
+		}
+		g.printf("\t%s %s\n", goName(p.Name), paramType(p.Schema, p.Required))
+	}
+	g.printf("}\n")
+}
+
+func paramType(s *Schema, required bool) string {
+	switch s.Type {
+	case "integer":
+		if required {
+			return "int64"
+		}
+		return "*int64"
+	case "number":
+		if required {
+			return "float64"
+		}
+		return "*float64"
+	case "boolean":
+		return "bool"
+	}
+	return "string"
+}
+
+// writeSet emits the code that copies one parameter into a url.Values or form map.
+func (g *generator) writeSet(dst, field, name string, s *Schema, required bool) {
+	put := func(value string) string {
+		if dst == "q" {
+			return fmt.Sprintf("q.Set(%q, %s)", name, value)
+		}
+		return fmt.Sprintf("%s[%q] = %s", dst, name, value)
+	}
+
+	typ := paramType(s, required)
+	if dst != "q" {
+		// Form fields mirror the request struct, whose integers are never pointers.
+		typ = strings.TrimPrefix(typ, "*")
+	}
+
+	switch typ {
+	case "int64":
+		g.imports["strconv"] = true
+		if required {
+			g.printf("\t%s\n", put("strconv.FormatInt("+field+", 10)"))
+		} else {
+			g.printf("\tif %s != 0 {\n\t\t%s\n\t}\n", field, put("strconv.FormatInt("+field+", 10)"))
+		}
+	case "*int64":
+		g.imports["strconv"] = true
+		g.printf("\tif %s != nil {\n\t\t%s\n\t}\n", field, put("strconv.FormatInt(*"+field+", 10)"))
+	case "float64":
+		g.imports["strconv"] = true
+		g.printf("\t%s\n", put("strconv.FormatFloat("+field+", 'g', -1, 64)"))
+	case "*float64":
+		g.imports["strconv"] = true
+		g.printf("\tif %s != nil {\n\t\t%s\n\t}\n", field, put("strconv.FormatFloat(*"+field+", 'g', -1, 64)"))
+	case "bool":
+		g.printf("\tif %s {\n\t\t%s\n\t}\n", field, put(`"true"`))
+	default:
+		if required {
+			g.printf("\t%s\n", put(field))
+		} else {
+			g.printf("\tif %s != \"\" {\n\t\t%s\n\t}\n", field, put(field))
+		}
+	}
+}
+
+// pathFormat turns "/notes/{id}" into "/notes/%d".
+func pathFormat(path string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(path, '{')
+		if i < 0 {
+			b.WriteString(path)
+			return b.String()
+		}
+		j := strings.IndexByte(path, '}')
+		b.WriteString(path[:i])
+		b.WriteString("%d")
+		path = path[j+1:]
+	}
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndexByte(ref, '/')+1:]
+}
+
+var initialisms = map[string]string{"id": "ID", "ids": "IDs", "url": "URL", "api": "API", "pdf": "PDF"}
+
+// goName turns a snake_case JSON name into an exported Go identifier.
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if v, ok := initialisms[part]; ok {
+			b.WriteString(v)
+			continue
+		}
+		b.WriteString(exported(part))
+	}
+	return b.String()
+}
+
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaBuilder reflects Go types into JSON schemas. Named structs become
+// components and are referenced with $ref so shared DTOs are described once.
+type schemaBuilder struct {
+	schemas		map[string]*Schema
+}
+
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: map[string]*Schema{}}
+}
+
+// ref returns a schema for the value's type, registering named structs as components.
+func (b *schemaBuilder) ref(v any) *Schema {
+	return b.schemaFor(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := b.schemaFor(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := t.Name()
+		if _, ok := b.schemas[name]; !ok {
+			// Register before descending so self-referencing types terminate.
+			b.schemas[name] = &Schema{}
+			*b.schemas[name] = *b.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// object describes a struct by its JSON field names. Request DTOs declare their
+// required fields through `validate` tags; for other types every field that is not
+// omitempty is always present and therefore required.
+func (b *schemaBuilder) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	validated := hasValidateTags(t)
+	b.addFields(s, t, validated)
+	return s
+}
+
+func (b *schemaBuilder) addFields(s *Schema, t reflect.Type, validated bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			b.addFields(s, sf.Type, validated)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		prop := b.schemaFor(sf.Type)
+		rules := sf.Tag.Get("validate")
+		applyRules(prop, rules)
+
+		s.Properties[name] = prop
+		s.PropertyOrder = append(s.PropertyOrder, name)
+
+		omitempty := strings.Contains(opts, "omitempty")
+		if hasRule(rules, "required") || (!validated && !omitempty) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasValidateTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("validate") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules mirrors the validate package's rules as schema constraints.
+func applyRules(s *Schema, rules string) {
+	if rules == "" || s.Ref != "" {
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			switch s.Type {
+			case "string":
+				if name == "min" {
+					s.MinLength = intPtr(int(n))
+				} else {
+					s.MaxLength = intPtr(int(n))
+				}
+			case "array":
+				if name == "max" {
+					s.MaxItems = intPtr(int(n))
+				}
+			default:
+				if name == "min" {
+					s.Minimum = float(n)
+				} else {
+					s.Maximum = float(n)
+				}
+			}
+		case "email":
+			s.Format = "email"
+		case "username":
+			s.Pattern = `^[A-Za-z0-9._-]+$`
+		case "password":
+			s.Format = "password"
+			s.MinLength, s.MaxLength = intPtr(8), intPtr(72)
+			s.Description = "At least one letter and one digit."
+		case "oneof":
+			s.Enum = strings.Split(arg, "|")
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func float(n float64) *float64 { return &n }
\ No newline at end of file
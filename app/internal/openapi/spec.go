@@ -0,0 +1,129 @@
+package openapi
+
+// The subset of the OpenAPI 3.0 document model that the API needs.
+
+type Document struct {
+	OpenAPI			string					`json:"openapi"`
+	Info			Info					`json:"info"`
+	Paths			map[string]*PathItem	`json:"paths"`
+	Components		Components				`json:"components"`
+}
+
+type Info struct {
+	Title			string		`json:"title"`
+	Version			string		`json:"version"`
+	Description		string		`json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas				map[string]*Schema			`json:"schemas"`
+	SecuritySchemes		map[string]*SecurityScheme	`json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type			string		`json:"type"`
+	Scheme			string		`json:"scheme,omitempty"`
+}
+
+type PathItem struct {
+	Get				*Operation		`json:"get,omitempty"`
+	Put				*Operation		`json:"put,omitempty"`
+	Post			*Operation		`json:"post,omitempty"`
+	Delete			*Operation		`json:"delete,omitempty"`
+	Patch			*Operation		`json:"patch,omitempty"`
+	Head			*Operation		`json:"head,omitempty"`
+}
+
+type Operation struct {
+	OperationID		string					`json:"operationId"`
+	Summary			string					`json:"summary,omitempty"`
+	Tags			[]string				`json:"tags,omitempty"`
+	Deprecated		bool					`json:"deprecated,omitempty"`
+	Parameters		[]*Parameter			`json:"parameters,omitempty"`
+	RequestBody		*RequestBody			`json:"requestBody,omitempty"`
+	Responses		map[string]*Response	`json:"responses"`
+}
+
+type Parameter struct {
+	Name			string		`json:"name"`
+	In				string		`json:"in"`
+	Description		string		`json:"description,omitempty"`
+	Required		bool		`json:"required,omitempty"`
+	Schema			*Schema		`json:"schema"`
+}
+
+type RequestBody struct {
+	Required		bool					`json:"required,omitempty"`
+	Content			map[string]*MediaType	`json:"content"`
+}
+
+type Response struct {
+	Description		string					`json:"description"`
+	Content			map[string]*MediaType	`json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema			*Schema		`json:"schema"`
+}
+
+type Schema struct {
+	Ref						string				`json:"$ref,omitempty"`
+	Type					string				`json:"type,omitempty"`
+	Format					string				`json:"format,omitempty"`
+	Description				string				`json:"description,omitempty"`
+	Nullable				bool				`json:"nullable,omitempty"`
+	Enum					[]string			`json:"enum,omitempty"`
+	MinLength				*int				`json:"minLength,omitempty"`
+	MaxLength				*int				`json:"maxLength,omitempty"`
+	Pattern					string				`json:"pattern,omitempty"`
+	Minimum					*float64			`json:"minimum,omitempty"`
+	Maximum					*float64			`json:"maximum,omitempty"`
+	MaxItems				*int				`json:"maxItems,omitempty"`
+	Items					*Schema				`json:"items,omitempty"`
+	Properties				map[string]*Schema	`json:"properties,omitempty"`
+	Required				[]string			`json:"required,omitempty"`
+	AdditionalProperties	*Schema				`json:"additionalProperties,omitempty"`
+
+	// PropertyOrder keeps struct field order so generated code is stable; it is not
+	// part of the serialised document.
+	PropertyOrder			[]string			`json:"-"`
+}
+
+// Operation returns the operation for an HTTP method, or nil.
+func (p *PathItem) Operation(method string) *Operation {
+	switch method {
+	case "GET":
+		return p.Get
+	case "PUT":
+		return p.Put
+	case "POST":
+		return p.Post
+	case "DELETE":
+		return p.Delete
+	case "PATCH":
+		return p.Patch
+	case "HEAD":
+		return p.Head
+	}
+	return nil
+}
+
+func (p *PathItem) setOperation(method string, op *Operation) {
+	switch method {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "PATCH":
+		p.Patch = op
+	case "HEAD":
+		p.Head = op
+	}
+}
+
+// Methods lists the HTTP methods in the order operations are emitted.
+var Methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}
\ No newline at end of file
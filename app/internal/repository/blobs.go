@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/model"
+)
+
+
+type BlobRepository interface {
+	Acquire(hash string, size int64) error
+	Release(hash string) (int, error)
+}
+
+type blobRepo struct {
+	db *gorm.DB
+}
+
+
+func NewBlobRepository(db *gorm.DB) BlobRepository {
+	return &blobRepo{db}
+}
+
+// Acquire adds a reference to a blob, creating its record on first use.
+func (r *blobRepo) Acquire(hash string, size int64) error {
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates:	clause.Assignments(map[string]any{"ref_count": gorm.Expr("ref_count + 1")}),
+	}).Create(&model.Blob{Hash: hash, Size: size, RefCount: 1}).Error
+}
+
+// Release drops a reference and returns how many remain. The record is deleted
+// with its last reference.
+func (r *blobRepo) Release(hash string) (int, error) {
+	remaining := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var blob model.Blob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("hash = ?", hash).First(&blob).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		remaining = blob.RefCount - 1
+		if remaining <= 0 {
+			remaining = 0
+			return tx.Delete(&blob).Error
+		}
+		return tx.Model(&blob).Update("ref_count", remaining).Error
+	})
+	return remaining, err
+}
@@ -1,18 +1,109 @@
 package repository
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"backend/internal/model"
+	"backend/internal/util"
 )
 
 
 type DocumentRepository interface {
 	GetByUserID(userID uint) ([]model.Document, error)
 	GetByDocumentID(docID uint) (model.Document, error)
+	GetByContentHash(userID uint, hash string) (model.Document, error)
+	FindBySource(userID uint, doi, arXivID, sourceURL string) (model.Document, error)
 	Save(doc *model.Document) error
+	Delete(id uint) error
+	Search(userID uint, query string, scope SearchScope) ([]model.Document, error)
+	SearchSections(userID uint, query string, scope SearchScope) ([]model.DocumentSection, error)
+	List(filter DocumentFilter, page PageRequest) (Page[model.Document], error)
+	Facets(filter DocumentFilter) (DocumentFacets, error)
+	UpdateReadingStatus(userID uint, documentIDs []uint, status string) (int, error)
+	GetByIDs(userID uint, ids []uint) ([]model.Document, error)
+	Fingerprints(userID uint) ([]util.FingerprintedDocument, error)
+	BackfillFingerprints(userID uint) (int, error)
+	Merge(userID, canonicalID uint, duplicateIDs []uint) ([]model.Document, error)
+	ReplaceFile(doc *model.Document) error
+	GetVersions(docID uint) ([]model.DocumentVersion, error)
+	ListVersions(docID uint, page PageRequest) (Page[model.DocumentVersion], error)
+	GetVersion(docID uint, version int) (model.DocumentVersion, error)
+	StorageUsed(userID uint) (int64, error)
+	UpdateExtraction(doc *model.Document) error
+	GetPages(docID uint) ([]model.DocumentPage, error)
+	PendingOCR() ([]uint, error)
+	SetOCRStatus(docID uint, status string) error
+	SaveOCR(doc *model.Document, pages []model.DocumentPage) error
+	GetOutline(docID uint) ([]model.DocumentSection, error)
+	GetSections(docID uint, kind string) ([]model.DocumentSection, error)
+	GetReferences(docID uint) ([]model.DocumentReference, error)
+	LinkReferences(doc *model.Document) (int, error)
+	CitedDocuments(docID uint) ([]model.Document, error)
+	CitingDocuments(docID uint) ([]model.Document, error)
+	CitationGraph(workspaceID uint) ([]model.Document, []CitationEdge, error)
+	PendingEnrichment() ([]uint, error)
+	SetEnrichmentStatus(docID uint, status string) error
+	SaveEnrichment(doc *model.Document) error
+	PendingAlerts() ([]uint, error)
+	SetAlertsPending(docID uint, pending bool) error
+}
+
+// SearchScope narrows a search. Zero values search everywhere. With a Section,
+// only the text of sections of that kind is searched.
+type SearchScope struct {
+	WorkspaceID		uint
+	Section			string
+}
+
+// DocumentFilter narrows a user's library. Zero values mean "don't filter on this field".
+type DocumentFilter struct {
+	UserID			uint
+	TagID			uint
+	WorkspaceID		*uint
+	Year			int
+	Author			string
+	Format			string
+	ReadingStatus	string
+	Extraction		string
+	UploadedFrom	*time.Time
+	UploadedTo		*time.Time
+}
+
+// CitationEdge says that document From cites document To.
+type CitationEdge struct {
+	From			uint		`json:"from"`
+	To				uint		`json:"to"`
+}
+
+type FacetCount struct {
+	Value			string		`json:"value"`
+	Label			string		`json:"label,omitempty"`
+	Count			int64		`json:"count"`
+}
+
+type DocumentFacets struct {
+	Tags			[]FacetCount	`json:"tags"`
+	Workspaces		[]FacetCount	`json:"workspaces"`
+	Years			[]FacetCount	`json:"years"`
+	Authors			[]FacetCount	`json:"authors"`
+	Formats			[]FacetCount	`json:"formats"`
+	ReadingStatuses	[]FacetCount	`json:"reading_statuses"`
+	Extraction		[]FacetCount	`json:"extraction"`
+}
+
+var documentSortKeys = map[string]sortKey{
+	"title":		{"documents.title", sortString},
+	"uploaded_at":	{"documents.uploaded_at", sortTime},
+	"year":			{"documents.year", sortInt},
+}
+
+var versionSortKeys = map[string]sortKey{
+	"version":		{"document_versions.version", sortInt},
+	"replaced_at":	{"document_versions.replaced_at", sortTime},
 }
 
 type documentRepo struct {
@@ -33,11 +124,771 @@ func (r *documentRepo) GetByUserID(userID uint) ([]model.Document, error) {
 
 func (r *documentRepo) GetByDocumentID(docID uint) (model.Document, error) {
 	var doc model.Document
-	err := r.db.Where("id = ?", docID).Find(&doc).Error
-	return doc, err
+	err := r.db.Where("id = ?", docID).Preload("Authors").Preload("Tags").First(&doc).Error
+	return doc, translate(err, "document", docID)
+}
+
+// GetByContentHash finds the user's document with the given SHA-256 file hash.
+func (r *documentRepo) GetByContentHash(userID uint, hash string) (model.Document, error) {
+	var doc model.Document
+	err := r.db.Where("user_id = ? AND content_hash = ?", userID, hash).First(&doc).Error
+	return doc, translate(err, "document", 0)
+}
+
+// FindBySource finds a document of the user with the DOI, the arXiv ID or the
+// source URL, whichever are given, for recognising papers before downloading them.
+func (r *documentRepo) FindBySource(userID uint, doi, arXivID, sourceURL string) (model.Document, error) {
+	var (
+		doc		model.Document
+		conds	[]string
+		args	[]any
+	)
+	if doi != "" {
+		conds, args = append(conds, "doi = ?"), append(args, strings.ToLower(doi))
+	}
+	if arXivID != "" {
+		conds, args = append(conds, "arxiv_id = ?"), append(args, arXivID)
+	}
+	if sourceURL != "" {
+		conds, args = append(conds, "source_url = ?"), append(args, sourceURL)
+	}
+	if len(conds) == 0 {
+		return doc, &NotFoundError{Resource: "document"}
+	}
+
+	err := r.db.Where("user_id = ?", userID).Where("("+strings.Join(conds, " OR ")+")", args...).Order("id").First(&doc).Error
+	return doc, translate(err, "document", 0)
 }
 
 func (r *documentRepo) Save(doc *model.Document) error {
 	doc.UploadedAt = time.Now()
 	return r.db.Create(doc).Error
-}
\ No newline at end of file
+}
+
+// Delete removes the document record along with its authors, tag associations,
+// earlier versions, pages, sections and references. References to it from other
+// documents, feed entries and notifications are unlinked.
+func (r *documentRepo) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", id).Delete(&model.DocumentTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&model.DocumentVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&model.DocumentPage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&model.DocumentSection{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&model.DocumentReference{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&model.DocumentReference{}).Where("cited_document_id = ?", id).
+			Update("cited_document_id", nil).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.FeedItem{}).Where("document_id = ?", id).Update("document_id", nil).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.Notification{}).Where("document_id = ?", id).Update("document_id", nil).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&model.DocumentAuthor{}).Error; err != nil {
+			return err
+		}
+		return affected(tx.Delete(&model.Document{}, id), "document", id)
+	})
+}
+
+func (r *documentRepo) Search(userID uint, query string, scope SearchScope) ([]model.Document, error) {
+	var docs []model.Document
+	like := containing(query)
+	db := r.db.Where("user_id = ?", userID)
+	if scope.WorkspaceID != 0 {
+		db = db.Where("workspace_id = ?", scope.WorkspaceID)
+	}
+	if scope.Section != "" {
+		db = db.Where("id IN (?)", r.db.Model(&model.DocumentSection{}).Select("document_id").
+			Where(`kind = ? AND text LIKE ? ESCAPE '\\'`, scope.Section, like))
+	} else {
+		db = db.Where(`(title LIKE ? ESCAPE '\\' OR extracted_text LIKE ? ESCAPE '\\')`, like, like)
+	}
+	err := db.Find(&docs).Error
+	return docs, err
+}
+
+// SearchSections finds the sections whose text matches, without the text itself.
+func (r *documentRepo) SearchSections(userID uint, query string, scope SearchScope) ([]model.DocumentSection, error) {
+	var sections []model.DocumentSection
+	db := r.db.Omit("text").Joins("JOIN documents ON documents.id = document_sections.document_id").
+		Where(`documents.user_id = ? AND document_sections.text LIKE ? ESCAPE '\\'`, userID, containing(query))
+	if scope.WorkspaceID != 0 {
+		db = db.Where("documents.workspace_id = ?", scope.WorkspaceID)
+	}
+	if scope.Section != "" {
+		db = db.Where("document_sections.kind = ?", scope.Section)
+	}
+	err := db.Order("document_sections.document_id, document_sections.position").Find(&sections).Error
+	return sections, err
+}
+
+func (r *documentRepo) List(filter DocumentFilter, page PageRequest) (Page[model.Document], error) {
+	db := r.db.Model(&model.Document{}).Scopes(filter.apply).Preload("Authors").Preload("Tags")
+	return paginate(db, page, "documents", documentSortKeys, "-uploaded_at", func(d *model.Document) (any, uint) {
+		switch strings.TrimPrefix(page.Sort, "-") {
+		case "title":
+			return d.Title, d.ID
+		case "year":
+			return d.Year, d.ID
+		}
+		return d.UploadedAt, d.ID
+	})
+}
+
+// Facets counts the documents matching filter per tag, workspace, year, author,
+// format, reading status and extraction outcome so the client can render a filter sidebar.
+func (r *documentRepo) Facets(filter DocumentFilter) (DocumentFacets, error) {
+	var f DocumentFacets
+	matching := r.db.Model(&model.Document{}).Scopes(filter.apply).Select("documents.id")
+
+	columns := []struct {
+		column	string
+		dest	*[]FacetCount
+	}{
+		{"workspace_id", &f.Workspaces},
+		{"year", &f.Years},
+		{"format", &f.Formats},
+		{"reading_status", &f.ReadingStatuses},
+		{"extraction_status", &f.Extraction},
+	}
+	for _, c := range columns {
+		err := r.db.Model(&model.Document{}).Scopes(filter.apply).
+			Select(c.column + " AS value, COUNT(*) AS count").
+			Group(c.column).Order("count DESC").Scan(c.dest).Error
+		if err != nil {
+			return f, err
+		}
+	}
+
+	err := r.db.Table("document_tags").
+		Select("tags.id AS value, tags.path AS label, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = document_tags.tag_id").
+		Where("document_tags.document_id IN (?)", matching).
+		Group("tags.id, tags.path").Order("tags.path").Scan(&f.Tags).Error
+	if err != nil {
+		return f, err
+	}
+
+	err = r.db.Model(&model.DocumentAuthor{}).
+		Select("name AS value, COUNT(DISTINCT document_id) AS count").
+		Where("document_id IN (?)", matching).
+		Group("name").Order("count DESC").Scan(&f.Authors).Error
+	return f, err
+}
+
+// UpdateReadingStatus sets the status on the given documents owned by userID.
+func (r *documentRepo) UpdateReadingStatus(userID uint, documentIDs []uint, status string) (int, error) {
+	res := r.db.Model(&model.Document{}).Where("user_id = ? AND id IN ?", userID, documentIDs).
+		Update("reading_status", status)
+	return int(res.RowsAffected), res.Error
+}
+
+// GetByIDs loads the user's documents with the given IDs, without their extracted
+// text, in ascending ID order.
+func (r *documentRepo) GetByIDs(userID uint, ids []uint) ([]model.Document, error) {
+	var docs []model.Document
+	err := r.db.Omit("extracted_text").Where("user_id = ? AND id IN ?", userID, ids).
+		Preload("Authors").Preload("Tags").Order("id").Find(&docs).Error
+	return docs, err
+}
+
+// Fingerprints returns the text fingerprints of the user's documents.
+func (r *documentRepo) Fingerprints(userID uint) ([]util.FingerprintedDocument, error) {
+	var fps []util.FingerprintedDocument
+	err := r.db.Model(&model.Document{}).Select("id, fingerprint").
+		Where("user_id = ? AND fingerprint IS NOT NULL AND fingerprint <> ''", userID).
+		Order("id").Scan(&fps).Error
+	return fps, err
+}
+
+// BackfillFingerprints fingerprints the user's documents imported before
+// fingerprints were computed at ingest, and reports how many it updated.
+func (r *documentRepo) BackfillFingerprints(userID uint) (int, error) {
+	var docs []model.Document
+	err := r.db.Select("id, extracted_text").Where("user_id = ? AND fingerprint IS NULL", userID).Find(&docs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range docs {
+		err := r.db.Model(&model.Document{}).Where("id = ?", d.ID).
+			Update("fingerprint", util.Fingerprint(d.ExtractedText)).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(docs), nil
+}
+
+// Merge folds the duplicate documents into the canonical one: their tags are added
+// to it, note links, feed entries and notifications pointing at them are pointed
+// at it, and metadata the canonical document lacks is taken from the first
+// duplicate that has it. The duplicates' records are then deleted and returned so
+// the caller can release their stored files.
+func (r *documentRepo) Merge(userID, canonicalID uint, duplicateIDs []uint) ([]model.Document, error) {
+	var removed []model.Document
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var canonical model.Document
+		err := tx.Omit("extracted_text").Where("id = ? AND user_id = ?", canonicalID, userID).
+			Preload("Authors").First(&canonical).Error
+		if err != nil {
+			return translate(err, "document", canonicalID)
+		}
+
+		err = tx.Omit("extracted_text").Where("id IN ? AND user_id = ?", duplicateIDs, userID).
+			Preload("Authors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+			Order("id").Find(&removed).Error
+		if err != nil {
+			return err
+		}
+		if len(removed) != len(duplicateIDs) {
+			return &NotFoundError{Resource: "document", ID: 0}
+		}
+
+		var tags []model.DocumentTag
+		if err := tx.Where("document_id IN ?", duplicateIDs).Find(&tags).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			for i := range tags {
+				tags[i].DocumentID = canonicalID
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("document_id IN ?", duplicateIDs).Delete(&model.DocumentTag{}).Error; err != nil {
+			return err
+		}
+
+		if err := retargetNoteLinks(tx, canonicalID, duplicateIDs); err != nil {
+			return err
+		}
+
+		updates := map[string]any{}
+		for _, d := range removed {
+			if canonical.WorkspaceID == 0 && d.WorkspaceID != 0 {
+				canonical.WorkspaceID = d.WorkspaceID
+				updates["workspace_id"] = d.WorkspaceID
+			}
+			if canonical.Year == 0 && d.Year != 0 {
+				canonical.Year = d.Year
+				updates["year"] = d.Year
+			}
+			if len(canonical.Authors) == 0 && len(d.Authors) > 0 {
+				canonical.Authors = d.Authors
+				err := tx.Model(&model.DocumentAuthor{}).Where("document_id = ?", d.ID).
+					Update("document_id", canonicalID).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&model.Document{}).Where("id = ?", canonicalID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("document_id IN ?", duplicateIDs).Delete(&model.DocumentAuthor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id IN ?", duplicateIDs).Delete(&model.DocumentVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id IN ?", duplicateIDs).Delete(&model.DocumentPage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id IN ?", duplicateIDs).Delete(&model.DocumentSection{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id IN ?", duplicateIDs).Delete(&model.DocumentReference{}).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&model.DocumentReference{}).Where("cited_document_id IN ?", duplicateIDs).
+			Update("cited_document_id", canonicalID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.FeedItem{}).Where("document_id IN ?", duplicateIDs).
+			Update("document_id", canonicalID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.Notification{}).Where("document_id IN ?", duplicateIDs).
+			Update("document_id", canonicalID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&model.Document{}, duplicateIDs).Error
+	})
+	return removed, err
+}
+
+// ReplaceFile makes the file fields of doc the document's new current version. The
+// file it replaces is kept, with its extracted text, as a DocumentVersion.
+func (r *documentRepo) ReplaceFile(doc *model.Document) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.Document
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id, version, file_path, content_hash, format, extracted_text").
+			Where("id = ?", doc.ID).First(&current).Error
+		if err != nil {
+			return translate(err, "document", doc.ID)
+		}
+
+		previous := model.DocumentVersion{
+			DocumentID:		current.ID,
+			Version:		current.Version,
+			FilePath:		current.FilePath,
+			ContentHash:	current.ContentHash,
+			Format:			current.Format,
+			ExtractedText:	current.ExtractedText,
+		}
+		if err := tx.Create(&previous).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.DocumentPage{}).Error; err != nil {
+			return err
+		}
+		if err := replaceSections(tx, doc.ID, doc.Sections); err != nil {
+			return err
+		}
+		if err := replaceReferences(tx, doc.ID, doc.References); err != nil {
+			return err
+		}
+
+		doc.Version = current.Version + 1
+		return tx.Model(&model.Document{}).Where("id = ?", doc.ID).Updates(map[string]any{
+			"file_path":			doc.FilePath,
+			"content_hash":			doc.ContentHash,
+			"format":				doc.Format,
+			"extracted_text":		doc.ExtractedText,
+			"fingerprint":			doc.Fingerprint,
+			"extraction_status":	doc.ExtractionStatus,
+			"extraction_error":		doc.ExtractionError,
+			"doi":					doc.DOI,
+			"arxiv_id":				doc.ArXivID,
+			"ocr_status":			doc.OCRStatus,
+			"enrichment_status":	doc.EnrichmentStatus,
+			"version":				doc.Version,
+		}).Error
+	})
+}
+
+// UpdateExtraction saves a new extraction of the document's current file with its
+// sections and references. Pages recognised from the previous extraction are dropped.
+func (r *documentRepo) UpdateExtraction(doc *model.Document) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := affected(tx.Model(&model.Document{}).Where("id = ?", doc.ID).Updates(map[string]any{
+			"extracted_text":		doc.ExtractedText,
+			"fingerprint":			doc.Fingerprint,
+			"extraction_status":	doc.ExtractionStatus,
+			"extraction_error":		doc.ExtractionError,
+			"doi":					doc.DOI,
+			"arxiv_id":				doc.ArXivID,
+			"ocr_status":			doc.OCRStatus,
+			"enrichment_status":	doc.EnrichmentStatus,
+		}), "document", doc.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.DocumentPage{}).Error; err != nil {
+			return err
+		}
+		if err := replaceSections(tx, doc.ID, doc.Sections); err != nil {
+			return err
+		}
+		return replaceReferences(tx, doc.ID, doc.References)
+	})
+}
+
+func (r *documentRepo) GetPages(docID uint) ([]model.DocumentPage, error) {
+	var pages []model.DocumentPage
+	err := r.db.Where("document_id = ?", docID).Order("page").Find(&pages).Error
+	return pages, err
+}
+
+// PendingOCR lists the documents waiting for OCR, oldest first.
+func (r *documentRepo) PendingOCR() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Document{}).Where("ocr_status = ?", model.OCRPending).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *documentRepo) SetOCRStatus(docID uint, status string) error {
+	return affected(r.db.Model(&model.Document{}).Where("id = ?", docID).Update("ocr_status", status), "document", docID)
+}
+
+// SaveOCR stores the pages of a document along with the text, sections,
+// references and status they produce. It only applies while the document is still
+// pending with the same file; a document whose file was replaced or re-extracted
+// meanwhile is reported as not found.
+func (r *documentRepo) SaveOCR(doc *model.Document, pages []model.DocumentPage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Document{}).
+			Where("id = ? AND content_hash = ? AND ocr_status = ?", doc.ID, doc.ContentHash, model.OCRPending).
+			Updates(map[string]any{
+				"extracted_text":	doc.ExtractedText,
+				"fingerprint":		doc.Fingerprint,
+				"doi":				doc.DOI,
+				"arxiv_id":			doc.ArXivID,
+				"ocr_status":		doc.OCRStatus,
+			})
+		if err := affected(res, "document", doc.ID); err != nil {
+			return err
+		}
+
+		if err := replaceSections(tx, doc.ID, doc.Sections); err != nil {
+			return err
+		}
+		if err := replaceReferences(tx, doc.ID, doc.References); err != nil {
+			return err
+		}
+
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.DocumentPage{}).Error; err != nil {
+			return err
+		}
+		if len(pages) == 0 {
+			return nil
+		}
+		for i := range pages {
+			pages[i].DocumentID = doc.ID
+		}
+		return tx.CreateInBatches(pages, 100).Error
+	})
+}
+
+// PendingEnrichment lists the documents waiting for a metadata lookup, oldest first.
+func (r *documentRepo) PendingEnrichment() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Document{}).Where("enrichment_status = ?", model.EnrichmentPending).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *documentRepo) SetEnrichmentStatus(docID uint, status string) error {
+	return affected(r.db.Model(&model.Document{}).Where("id = ?", docID).Update("enrichment_status", status), "document", docID)
+}
+
+// SaveEnrichment stores the metadata looked up for a document, replacing its
+// authors when doc has any. Like SaveOCR it only applies while the document is
+// still pending with the same file, and reports not found otherwise.
+func (r *documentRepo) SaveEnrichment(doc *model.Document) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Document{}).
+			Where("id = ? AND content_hash = ? AND enrichment_status = ?", doc.ID, doc.ContentHash, model.EnrichmentPending).
+			Updates(map[string]any{
+				"title":				doc.Title,
+				"year":					doc.Year,
+				"venue":				doc.Venue,
+				"abstract":				doc.Abstract,
+				"citation_count":		doc.CitationCount,
+				"open_access_url":		doc.OpenAccessURL,
+				"doi":					doc.DOI,
+				"arxiv_id":				doc.ArXivID,
+				"enrichment_status":	doc.EnrichmentStatus,
+				"enriched_at":			doc.EnrichedAt,
+			})
+		if err := affected(res, "document", doc.ID); err != nil {
+			return err
+		}
+		if len(doc.Authors) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.DocumentAuthor{}).Error; err != nil {
+			return err
+		}
+		for i := range doc.Authors {
+			doc.Authors[i].ID = 0
+			doc.Authors[i].DocumentID = doc.ID
+		}
+		return tx.Create(&doc.Authors).Error
+	})
+}
+
+// GetOutline lists the sections of a document without their text.
+func (r *documentRepo) GetOutline(docID uint) ([]model.DocumentSection, error) {
+	var sections []model.DocumentSection
+	err := r.db.Omit("text").Where("document_id = ?", docID).Order("position").Find(&sections).Error
+	return sections, err
+}
+
+// GetSections returns the sections of a document with their text, only those of
+// the given kind unless it is empty.
+func (r *documentRepo) GetSections(docID uint, kind string) ([]model.DocumentSection, error) {
+	var sections []model.DocumentSection
+	db := r.db.Where("document_id = ?", docID)
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	err := db.Order("position").Find(&sections).Error
+	return sections, err
+}
+
+// replaceSections swaps the stored sections of a document for new ones.
+func replaceSections(tx *gorm.DB, docID uint, sections []model.DocumentSection) error {
+	if err := tx.Where("document_id = ?", docID).Delete(&model.DocumentSection{}).Error; err != nil {
+		return err
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+	for i := range sections {
+		sections[i].DocumentID = docID
+	}
+	return tx.CreateInBatches(sections, 100).Error
+}
+
+// replaceReferences swaps the stored reference list of a document for a new one.
+// Its links to other documents are made again by LinkReferences.
+func replaceReferences(tx *gorm.DB, docID uint, refs []model.DocumentReference) error {
+	if err := tx.Where("document_id = ?", docID).Delete(&model.DocumentReference{}).Error; err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	for i := range refs {
+		refs[i].DocumentID = docID
+		refs[i].CitedDocumentID = nil
+	}
+	return tx.CreateInBatches(refs, 100).Error
+}
+
+func (r *documentRepo) GetReferences(docID uint) ([]model.DocumentReference, error) {
+	var refs []model.DocumentReference
+	err := r.db.Where("document_id = ?", docID).Order("position").Find(&refs).Error
+	return refs, err
+}
+
+// LinkReferences matches the unlinked references of doc against the rest of
+// its owner's library, and the unlinked references of the rest of the library
+// against doc. It returns the number of references linked.
+func (r *documentRepo) LinkReferences(doc *model.Document) (int, error) {
+	var library []model.Document
+	err := r.db.Select("id, title, year, doi").Where("user_id = ? AND id <> ?", doc.UserID, doc.ID).Find(&library).Error
+	if err != nil {
+		return 0, err
+	}
+	citable := make([]util.CitableDocument, len(library))
+	for i, d := range library {
+		citable[i] = util.CitableDocument{ID: d.ID, Title: d.Title, Year: d.Year, DOI: d.DOI}
+	}
+
+	var own, others []model.DocumentReference
+	err = r.db.Where("document_id = ? AND cited_document_id IS NULL", doc.ID).Find(&own).Error
+	if err != nil {
+		return 0, err
+	}
+	err = r.db.Joins("JOIN documents ON documents.id = document_references.document_id").
+		Where("documents.user_id = ? AND documents.id <> ? AND document_references.cited_document_id IS NULL", doc.UserID, doc.ID).
+		Find(&others).Error
+	if err != nil {
+		return 0, err
+	}
+
+	links := map[uint]uint{}
+	toLibrary := util.NewReferenceMatcher(citable)
+	for _, ref := range own {
+		if id := toLibrary.Match(ref); id != 0 {
+			links[ref.ID] = id
+		}
+	}
+	toDoc := util.NewReferenceMatcher([]util.CitableDocument{{ID: doc.ID, Title: doc.Title, Year: doc.Year, DOI: doc.DOI}})
+	for _, ref := range others {
+		if id := toDoc.Match(ref); id != 0 {
+			links[ref.ID] = id
+		}
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		for refID, cited := range links {
+			err := tx.Model(&model.DocumentReference{}).Where("id = ?", refID).Update("cited_document_id", cited).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(links), err
+}
+
+// CitedDocuments lists the documents of the library that a document cites.
+func (r *documentRepo) CitedDocuments(docID uint) ([]model.Document, error) {
+	var docs []model.Document
+	cited := r.db.Model(&model.DocumentReference{}).Select("cited_document_id").Where("document_id = ?", docID)
+	err := r.db.Omit("extracted_text").Where("id IN (?)", cited).
+		Preload("Authors").Order("year, id").Find(&docs).Error
+	return docs, err
+}
+
+// CitingDocuments lists the documents of the library that cite a document.
+func (r *documentRepo) CitingDocuments(docID uint) ([]model.Document, error) {
+	var docs []model.Document
+	citing := r.db.Model(&model.DocumentReference{}).Select("document_id").Where("cited_document_id = ?", docID)
+	err := r.db.Omit("extracted_text").Where("id IN (?)", citing).
+		Preload("Authors").Order("year, id").Find(&docs).Error
+	return docs, err
+}
+
+// CitationGraph returns the documents of a workspace and the citations between
+// them. Citations of documents outside the workspace are left out.
+func (r *documentRepo) CitationGraph(workspaceID uint) ([]model.Document, []CitationEdge, error) {
+	var docs []model.Document
+	err := r.db.Select("id, title, year, doi").Where("workspace_id = ?", workspaceID).Order("id").Find(&docs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var edges []CitationEdge
+	err = r.db.Model(&model.DocumentReference{}).
+		Select("DISTINCT document_references.document_id AS `from`, document_references.cited_document_id AS `to`").
+		Joins("JOIN documents citing ON citing.id = document_references.document_id").
+		Joins("JOIN documents cited ON cited.id = document_references.cited_document_id").
+		Where("citing.workspace_id = ? AND cited.workspace_id = ?", workspaceID, workspaceID).
+		Order("`from`, `to`").Scan(&edges).Error
+	return docs, edges, err
+}
+
+// GetVersions lists the earlier versions of a document, newest first, without
+// their extracted text.
+func (r *documentRepo) GetVersions(docID uint) ([]model.DocumentVersion, error) {
+	var versions []model.DocumentVersion
+	err := r.db.Omit("extracted_text").Where("document_id = ?", docID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// ListVersions is GetVersions a page at a time.
+func (r *documentRepo) ListVersions(docID uint, page PageRequest) (Page[model.DocumentVersion], error) {
+	db := r.db.Model(&model.DocumentVersion{}).Where("document_id = ?", docID)
+	page.Omit = append(page.Omit, "extracted_text")
+	return paginate(db, page, "document_versions", versionSortKeys, "-version", func(v *model.DocumentVersion) (any, uint) {
+		if strings.TrimPrefix(page.Sort, "-") == "replaced_at" {
+			return v.ReplacedAt, v.ID
+		}
+		return v.Version, v.ID
+	})
+}
+
+func (r *documentRepo) GetVersion(docID uint, version int) (model.DocumentVersion, error) {
+	var v model.DocumentVersion
+	err := r.db.Where("document_id = ? AND version = ?", docID, version).First(&v).Error
+	return v, translate(err, "document version", uint(version))
+}
+
+// StorageUsed sums the sizes of the files of the user's documents and their earlier
+// versions. A file counts once per document holding it, even though it is stored once.
+func (r *documentRepo) StorageUsed(userID uint) (int64, error) {
+	var used int64
+	err := r.db.Raw(`SELECT COALESCE(SUM(blobs.size), 0) FROM blobs JOIN (
+			SELECT content_hash FROM documents WHERE user_id = ?
+			UNION ALL
+			SELECT v.content_hash FROM document_versions v JOIN documents d ON d.id = v.document_id WHERE d.user_id = ?
+		) AS held ON held.content_hash = blobs.hash`, userID, userID).Scan(&used).Error
+	return used, err
+}
+
+// retargetNoteLinks rewrites [[doc:...]] links to the duplicates in note bodies and
+// in the backlink index so that they cite the canonical document.
+func retargetNoteLinks(tx *gorm.DB, canonicalID uint, duplicateIDs []uint) error {
+	linking := tx.Session(&gorm.Session{NewDB: true}).Model(&model.NoteLink{}).Select("note_id").
+		Where("target_type = ? AND target_id IN ?", model.NoteLinkDocument, duplicateIDs)
+
+	var notes []model.Note
+	if err := tx.Select("id, body").Where("id IN (?)", linking).Find(&notes).Error; err != nil {
+		return err
+	}
+
+	moved := make(map[uint]uint, len(duplicateIDs))
+	for _, id := range duplicateIDs {
+		moved[id] = canonicalID
+	}
+	for _, n := range notes {
+		err := tx.Model(&model.Note{}).Where("id = ?", n.ID).
+			UpdateColumn("body", util.RetargetDocLinks(n.Body, moved)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&model.NoteLink{}).Where("target_type = ? AND target_id IN ?", model.NoteLinkDocument, duplicateIDs).
+		Update("target_id", canonicalID).Error
+}
+
+func (f DocumentFilter) apply(db *gorm.DB) *gorm.DB {
+	db = db.Where("documents.user_id = ?", f.UserID)
+
+	if f.TagID != 0 {
+		// A tag also matches documents tagged with any of its descendants. The root
+		// path is escaped in SQL as under does in Go, so '%' and '_' in it match
+		// only themselves.
+		subtree := db.Session(&gorm.Session{NewDB: true}).Table("tags AS t").Select("t.id").
+			Joins("JOIN tags AS root ON root.id = ?", f.TagID).
+			Where(`t.user_id = root.user_id AND (t.path = root.path OR t.path LIKE CONCAT(` +
+				`REPLACE(REPLACE(REPLACE(root.path, '\\', '\\\\'), '%', '\\%'), '_', '\\_'), '/%') ESCAPE '\\')`)
+		tagged := db.Session(&gorm.Session{NewDB: true}).Model(&model.DocumentTag{}).
+			Select("document_id").Where("tag_id IN (?)", subtree)
+		db = db.Where("documents.id IN (?)", tagged)
+	}
+	if f.WorkspaceID != nil {
+		db = db.Where("documents.workspace_id = ?", *f.WorkspaceID)
+	}
+	if f.Year != 0 {
+		db = db.Where("documents.year = ?", f.Year)
+	}
+	if f.Author != "" {
+		authored := db.Session(&gorm.Session{NewDB: true}).Model(&model.DocumentAuthor{}).
+			Select("document_id").Where("name = ?", f.Author)
+		db = db.Where("documents.id IN (?)", authored)
+	}
+	if f.Format != "" {
+		db = db.Where("documents.format = ?", f.Format)
+	}
+	if f.ReadingStatus != "" {
+		db = db.Where("documents.reading_status = ?", f.ReadingStatus)
+	}
+	if f.Extraction != "" {
+		db = db.Where("documents.extraction_status = ?", f.Extraction)
+	}
+	if f.UploadedFrom != nil {
+		db = db.Where("documents.uploaded_at >= ?", *f.UploadedFrom)
+	}
+	if f.UploadedTo != nil {
+		db = db.Where("documents.uploaded_at < ?", *f.UploadedTo)
+	}
+	return db
+}
+
+// PendingAlerts lists the new documents that saved searches should be run on,
+// oldest first. Documents still waiting for OCR or enrichment aren't ready yet.
+func (r *documentRepo) PendingAlerts() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Document{}).Where("alerts_pending = ?", true).
+		Where("COALESCE(ocr_status, '') <> ? AND COALESCE(enrichment_status, '') <> ?", model.OCRPending, model.EnrichmentPending).
+		Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *documentRepo) SetAlertsPending(docID uint, pending bool) error {
+	return affected(r.db.Model(&model.Document{}).Where("id = ?", docID).Update("alerts_pending", pending), "document", docID)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+
+// Sentinel errors so callers can test the category of a domain error with errors.Is.
+var (
+	ErrNotFound			= errors.New("not found")
+	ErrConflict			= errors.New("conflict")
+	ErrForbidden		= errors.New("forbidden")
+	ErrUnauthorized		= errors.New("unauthorized")
+	ErrValidation		= errors.New("validation failed")
+)
+
+type NotFoundError struct {
+	Resource	string
+	ID			uint
+}
+
+type ConflictError struct {
+	Resource	string
+	Message		string
+	Details		any
+}
+
+type ForbiddenError struct {
+	Message		string
+}
+
+type UnauthorizedError struct {
+	Message		string
+}
+
+type FieldError struct {
+	Field		string		`json:"field"`
+	Message		string		`json:"message"`
+}
+
+type ValidationError struct {
+	Message		string
+	Fields		[]FieldError
+}
+
+
+func (e *NotFoundError) Error() string {
+	if e.ID == 0 {
+		return e.Resource + " not found"
+	}
+	return fmt.Sprintf("%s %d not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+func (e *ConflictError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Resource + " already exists"
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+func (e *ForbiddenError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "forbidden"
+}
+
+func (e *ForbiddenError) Is(target error) bool { return target == ErrForbidden }
+
+func (e *UnauthorizedError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "unauthorized"
+}
+
+func (e *UnauthorizedError) Is(target error) bool { return target == ErrUnauthorized }
+
+func (e *ValidationError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "validation failed"
+}
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// NewValidationError is shorthand for a validation error without field details.
+func NewValidationError(format string, args ...any) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// translate turns GORM errors into domain errors for the given resource.
+func translate(err error, resource string, id uint) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &NotFoundError{Resource: resource, ID: id}
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &ConflictError{Resource: resource}
+	}
+	return err
+}
+
+// affected reports a NotFoundError when a write matched no rows.
+func affected(res *gorm.DB, resource string, id uint) error {
+	if res.Error != nil {
+		return translate(res.Error, resource, id)
+	}
+	if res.RowsAffected == 0 {
+		return &NotFoundError{Resource: resource, ID: id}
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+)
+
+
+type FeedRepository interface {
+	Create(feed *model.Feed) error
+	GetByID(id uint) (model.Feed, error)
+	GetByWorkspaceID(workspaceID uint, page PageRequest) (Page[model.Feed], error)
+	Update(feed *model.Feed) error
+	Delete(id uint) error
+	Due(now time.Time) ([]uint, error)
+	SavePollState(feed *model.Feed) error
+	SeenGUIDs(feedID uint, guids []string) (map[string]bool, error)
+	CreateItem(item *model.FeedItem) error
+	GetItem(id uint) (model.FeedItem, error)
+	ListItems(filter FeedItemFilter, page PageRequest) (Page[model.FeedItem], error)
+	UpdateItem(item *model.FeedItem) error
+}
+
+// FeedItemFilter narrows the entries listed. Zero values mean "don't filter on
+// this field".
+type FeedItemFilter struct {
+	FeedID			uint
+	WorkspaceID		uint
+	Status			string
+}
+
+var feedSortKeys = map[string]sortKey{
+	"title":		{"feeds.title", sortString},
+	"created_at":	{"feeds.created_at", sortTime},
+}
+
+var feedItemSortKeys = map[string]sortKey{
+	"title":		{"feed_items.title", sortString},
+	"created_at":	{"feed_items.created_at", sortTime},
+}
+
+type feedRepo struct {
+	db *gorm.DB
+}
+
+
+func NewFeedRepository(db *gorm.DB) FeedRepository {
+	return &feedRepo{db}
+}
+
+func (r *feedRepo) Create(feed *model.Feed) error {
+	return r.db.Create(feed).Error
+}
+
+func (r *feedRepo) GetByID(id uint) (model.Feed, error) {
+	var feed model.Feed
+	err := r.db.Where("id = ?", id).First(&feed).Error
+	return feed, translate(err, "feed", id)
+}
+
+func (r *feedRepo) GetByWorkspaceID(workspaceID uint, page PageRequest) (Page[model.Feed], error) {
+	db := r.db.Model(&model.Feed{}).Where("workspace_id = ?", workspaceID)
+	return paginate(db, page, "feeds", feedSortKeys, "created_at", func(feed *model.Feed) (any, uint) {
+		if strings.TrimPrefix(page.Sort, "-") == "title" {
+			return feed.Title, feed.ID
+		}
+		return feed.CreatedAt, feed.ID
+	})
+}
+
+// Update saves the title and settings of the feed.
+func (r *feedRepo) Update(feed *model.Feed) error {
+	res := r.db.Model(feed).Select("title", "mode", "keywords", "interval_minutes", "enabled", "next_poll_at").Updates(feed)
+	return affected(res, "feed", feed.ID)
+}
+
+// Delete removes the feed with the entries it has seen. Documents imported from
+// it stay in the library.
+func (r *feedRepo) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("feed_id = ?", id).Delete(&model.FeedItem{}).Error; err != nil {
+			return err
+		}
+		return affected(tx.Delete(&model.Feed{}, id), "feed", id)
+	})
+}
+
+// Due lists the enabled feeds whose next poll is at or before now, the most
+// overdue first.
+func (r *feedRepo) Due(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Feed{}).Where("enabled = ? AND next_poll_at <= ?", true, now).
+		Order("next_poll_at").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SavePollState records the outcome of a poll and when the next one is due.
+func (r *feedRepo) SavePollState(feed *model.Feed) error {
+	res := r.db.Model(feed).Select("etag", "last_modified", "last_polled_at", "next_poll_at", "last_error").Updates(feed)
+	return affected(res, "feed", feed.ID)
+}
+
+// SeenGUIDs returns which of the GUIDs the feed has already recorded.
+func (r *feedRepo) SeenGUIDs(feedID uint, guids []string) (map[string]bool, error) {
+	seen := make(map[string]bool, len(guids))
+	if len(guids) == 0 {
+		return seen, nil
+	}
+
+	var found []string
+	err := r.db.Model(&model.FeedItem{}).Where("feed_id = ? AND guid IN ?", feedID, guids).Pluck("guid", &found).Error
+	for _, g := range found {
+		seen[g] = true
+	}
+	return seen, err
+}
+
+func (r *feedRepo) CreateItem(item *model.FeedItem) error {
+	return translate(r.db.Create(item).Error, "feed item", 0)
+}
+
+func (r *feedRepo) GetItem(id uint) (model.FeedItem, error) {
+	var item model.FeedItem
+	err := r.db.Where("id = ?", id).First(&item).Error
+	return item, translate(err, "feed item", id)
+}
+
+func (r *feedRepo) ListItems(filter FeedItemFilter, page PageRequest) (Page[model.FeedItem], error) {
+	db := r.db.Model(&model.FeedItem{})
+	if filter.FeedID != 0 {
+		db = db.Where("feed_id = ?", filter.FeedID)
+	}
+	if filter.WorkspaceID != 0 {
+		db = db.Where("workspace_id = ?", filter.WorkspaceID)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	return paginate(db, page, "feed_items", feedItemSortKeys, "-created_at", func(item *model.FeedItem) (any, uint) {
+		if strings.TrimPrefix(page.Sort, "-") == "title" {
+			return item.Title, item.ID
+		}
+		return item.CreatedAt, item.ID
+	})
+}
+
+// UpdateItem saves what became of the entry.
+func (r *feedRepo) UpdateItem(item *model.FeedItem) error {
+	res := r.db.Model(item).Select("status", "document_id", "error").Updates(item)
+	return affected(res, "feed item", item.ID)
+}
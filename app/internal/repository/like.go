@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"strings"
+)
+
+
+// likeEscaper escapes the characters that LIKE treats specially, so that user
+// input only ever matches itself. Patterns built with it need ESCAPE '\\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+
+// containing is a LIKE pattern matching text that contains s.
+func containing(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// under is a LIKE pattern matching the paths nested beneath path.
+func under(path string) string {
+	return likeEscaper.Replace(path) + "/%"
+}
\ No newline at end of file
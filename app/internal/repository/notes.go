@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+	"backend/internal/util"
+)
+
+
+type NoteRepository interface {
+	Create(note *model.Note) error
+	Update(note *model.Note) error
+	Delete(id uint) error
+	GetByID(id uint) (model.Note, error)
+	GetByWorkspaceID(workspaceID uint, page PageRequest) (Page[model.Note], error)
+	GetRevisions(noteID uint, page PageRequest) (Page[model.NoteRevision], error)
+	GetDocumentBacklinks(userID, docID uint) ([]model.NoteBacklink, error)
+	GetNoteBacklinks(userID, noteID uint) ([]model.NoteBacklink, error)
+	Search(userID uint, query string, workspaceID uint) ([]model.Note, error)
+}
+
+var noteSortKeys = map[string]sortKey{
+	"title":		{"notes.title", sortString},
+	"created_at":	{"notes.created_at", sortTime},
+	"updated_at":	{"notes.updated_at", sortTime},
+}
+
+var revisionSortKeys = map[string]sortKey{
+	"revision":		{"note_revisions.revision", sortInt},
+}
+
+type noteRepo struct {
+	db *gorm.DB
+}
+
+
+func NewNoteRepository(db *gorm.DB) NoteRepository {
+	return &noteRepo{db}
+}
+
+func (r *noteRepo) Create(note *model.Note) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(note).Error; err != nil {
+			return err
+		}
+		return saveRevisionAndLinks(tx, note)
+	})
+}
+
+func (r *noteRepo) Update(note *model.Note) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(note).Select("title", "body").Updates(note).Error; err != nil {
+			return err
+		}
+		return saveRevisionAndLinks(tx, note)
+	})
+}
+
+func (r *noteRepo) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id = ?", id).Delete(&model.NoteLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Delete(&model.NoteRevision{}).Error; err != nil {
+			return err
+		}
+		return affected(tx.Delete(&model.Note{}, id), "note", id)
+	})
+}
+
+func (r *noteRepo) GetByID(id uint) (model.Note, error) {
+	var note model.Note
+	err := r.db.Where("id = ?", id).First(&note).Error
+	return note, translate(err, "note", id)
+}
+
+func (r *noteRepo) GetByWorkspaceID(workspaceID uint, page PageRequest) (Page[model.Note], error) {
+	db := r.db.Model(&model.Note{}).Where("workspace_id = ?", workspaceID)
+	return paginate(db, page, "notes", noteSortKeys, "-updated_at", func(n *model.Note) (any, uint) {
+		switch strings.TrimPrefix(page.Sort, "-") {
+		case "title":
+			return n.Title, n.ID
+		case "created_at":
+			return n.CreatedAt, n.ID
+		}
+		return n.UpdatedAt, n.ID
+	})
+}
+
+// GetRevisions lists the revisions of a note, newest first unless page asks for
+// another order.
+func (r *noteRepo) GetRevisions(noteID uint, page PageRequest) (Page[model.NoteRevision], error) {
+	db := r.db.Model(&model.NoteRevision{}).Where("note_id = ?", noteID)
+	return paginate(db, page, "note_revisions", revisionSortKeys, "-revision", func(rev *model.NoteRevision) (any, uint) {
+		return rev.Revision, rev.ID
+	})
+}
+
+func (r *noteRepo) GetDocumentBacklinks(userID, docID uint) ([]model.NoteBacklink, error) {
+	return r.backlinks(userID, model.NoteLinkDocument, docID)
+}
+
+func (r *noteRepo) GetNoteBacklinks(userID, noteID uint) ([]model.NoteBacklink, error) {
+	return r.backlinks(userID, model.NoteLinkNote, noteID)
+}
+
+// Search finds the user's notes by title or body, in one workspace unless
+// workspaceID is 0.
+func (r *noteRepo) Search(userID uint, query string, workspaceID uint) ([]model.Note, error) {
+	var notes []model.Note
+	like := containing(query)
+	db := r.db.Where(`user_id = ? AND (title LIKE ? ESCAPE '\\' OR body LIKE ? ESCAPE '\\')`, userID, like, like)
+	if workspaceID != 0 {
+		db = db.Where("workspace_id = ?", workspaceID)
+	}
+	err := db.Order("updated_at DESC").Find(&notes).Error
+	return notes, err
+}
+
+// backlinks lists the user's notes that link to the target. Links from other
+// users' notes are left out.
+func (r *noteRepo) backlinks(userID uint, targetType string, targetID uint) ([]model.NoteBacklink, error) {
+	var links []model.NoteBacklink
+	err := r.db.Table("note_links").
+		Select("notes.id AS note_id, notes.workspace_id, notes.title, note_links.page").
+		Joins("JOIN notes ON notes.id = note_links.note_id").
+		Where("note_links.target_type = ? AND note_links.target_id = ? AND notes.user_id = ?", targetType, targetID, userID).
+		Order("notes.updated_at DESC").
+		Scan(&links).Error
+	return links, err
+}
+
+// saveRevisionAndLinks snapshots the note as its next revision and rebuilds its outgoing links.
+func saveRevisionAndLinks(tx *gorm.DB, note *model.Note) error {
+	var latest int
+	if err := tx.Model(&model.NoteRevision{}).Where("note_id = ?", note.ID).
+		Select("COALESCE(MAX(revision), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	revision := model.NoteRevision{
+		NoteID:		note.ID,
+		Revision:	latest + 1,
+		Title:		note.Title,
+		Body:		note.Body,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("note_id = ?", note.ID).Delete(&model.NoteLink{}).Error; err != nil {
+		return err
+	}
+
+	links := util.ParseNoteLinks(note.Body)
+	if len(links) == 0 {
+		return nil
+	}
+	for i := range links {
+		links[i].NoteID = note.ID
+	}
+	return tx.Create(&links).Error
+}
\ No newline at end of file
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/model"
+)
+
+
+type NotificationRepository interface {
+	Create(n *model.Notification) error
+	GetByID(id uint) (model.Notification, error)
+	List(filter NotificationFilter, page PageRequest) (Page[model.Notification], error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(userID uint, ids []uint) (int, error)
+	PendingEmailUsers() ([]uint, error)
+	PendingEmail(userID uint) ([]model.Notification, error)
+	ClearEmailPending(ids []uint) error
+
+	GetSettings(userID uint) (model.NotificationSettings, error)
+	SaveSettings(settings *model.NotificationSettings) error
+	SetLastDigest(userID uint, at time.Time) error
+}
+
+// NotificationFilter narrows a user's notifications. Zero values mean "don't
+// filter on this field".
+type NotificationFilter struct {
+	UserID			uint
+	SavedSearchID	uint
+	Kind			string
+	Unread			bool
+}
+
+var notificationSortKeys = map[string]sortKey{
+	"created_at":	{"notifications.created_at", sortTime},
+}
+
+type notificationRepo struct {
+	db *gorm.DB
+}
+
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepo{db}
+}
+
+func (r *notificationRepo) Create(n *model.Notification) error {
+	return r.db.Create(n).Error
+}
+
+func (r *notificationRepo) GetByID(id uint) (model.Notification, error) {
+	var n model.Notification
+	err := r.db.Where("id = ?", id).First(&n).Error
+	return n, translate(err, "notification", id)
+}
+
+func (r *notificationRepo) List(filter NotificationFilter, page PageRequest) (Page[model.Notification], error) {
+	db := r.db.Model(&model.Notification{}).Where("user_id = ?", filter.UserID)
+	if filter.SavedSearchID != 0 {
+		db = db.Where("saved_search_id = ?", filter.SavedSearchID)
+	}
+	if filter.Kind != "" {
+		db = db.Where("kind = ?", filter.Kind)
+	}
+	if filter.Unread {
+		db = db.Where("read_at IS NULL")
+	}
+	return paginate(db, page, "notifications", notificationSortKeys, "-created_at", func(n *model.Notification) (any, uint) {
+		return n.CreatedAt, n.ID
+	})
+}
+
+func (r *notificationRepo) CountUnread(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+// MarkRead marks the user's notifications with the given IDs read, or all of
+// them when ids is empty, and returns how many were unread. IDs of other users'
+// notifications are ignored.
+func (r *notificationRepo) MarkRead(userID uint, ids []uint) (int, error) {
+	db := r.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	res := db.Update("read_at", time.Now())
+	return int(res.RowsAffected), res.Error
+}
+
+// PendingEmailUsers lists the users with notifications waiting for a digest.
+func (r *notificationRepo) PendingEmailUsers() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Notification{}).Where("email_pending").Distinct().Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// PendingEmail returns the user's notifications waiting for a digest, oldest
+// first.
+func (r *notificationRepo) PendingEmail(userID uint) ([]model.Notification, error) {
+	var list []model.Notification
+	err := r.db.Where("user_id = ? AND email_pending", userID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *notificationRepo) ClearEmailPending(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.Notification{}).Where("id IN ?", ids).Update("email_pending", false).Error
+}
+
+// GetSettings returns the user's notification settings, or the defaults if they
+// never saved any.
+func (r *notificationRepo) GetSettings(userID uint) (model.NotificationSettings, error) {
+	var s model.NotificationSettings
+	err := r.db.Where("user_id = ?", userID).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.NotificationSettings{UserID: userID, Email: model.NotificationEmailOff}, nil
+	}
+	return s, err
+}
+
+// SaveSettings creates or replaces the user's notification settings. The time
+// of the last digest is kept.
+func (r *notificationRepo) SaveSettings(settings *model.NotificationSettings) error {
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates:	clause.AssignmentColumns([]string{"email", "webhook_url", "muted", "updated_at"}),
+	}).Create(settings).Error
+}
+
+func (r *notificationRepo) SetLastDigest(userID uint, at time.Time) error {
+	return r.db.Model(&model.NotificationSettings{}).Where("user_id = ?", userID).Update("last_digest_at", at).Error
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+
+const (
+	DefaultPageLimit	= 50
+	MaxPageLimit		= 200
+)
+
+var (
+	ErrInvalidCursor	error = &ValidationError{Message: "invalid cursor"}
+	ErrInvalidSort		error = &ValidationError{Message: "invalid sort key"}
+)
+
+// PageRequest asks for one page of a list. Sort is a sort key name, optionally
+// prefixed with "-" for descending order; an empty Sort uses the list's default.
+// Omit names columns that should not be loaded, such as large text bodies.
+type PageRequest struct {
+	Limit		int
+	Cursor		string
+	Sort		string
+	Omit		[]string
+}
+
+// Page is one slice of a keyset-paginated list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items		[]T
+	NextCursor	string
+}
+
+type sortKind int
+
+const (
+	sortString sortKind = iota
+	sortInt
+	sortTime
+)
+
+type sortKey struct {
+	column	string
+	kind	sortKind
+}
+
+// cursor pins the position after the last item of a page. It records the sort it
+// was issued for so it can't be replayed against a different ordering.
+type cursor struct {
+	Sort		string				`json:"s"`
+	Value		json.RawMessage		`json:"v"`
+	ID			uint				`json:"i"`
+}
+
+
+// paginate runs a keyset query ordered by the requested sort key and the table's id
+// column as a tie-breaker. keyOf returns the sort value and id of an item.
+func paginate[T any](db *gorm.DB, req PageRequest, table string, keys map[string]sortKey, defaultSort string, keyOf func(*T) (any, uint)) (Page[T], error) {
+	var page Page[T]
+
+	sort := req.Sort
+	if sort == "" {
+		sort = defaultSort
+	}
+	desc := strings.HasPrefix(sort, "-")
+	key, ok := keys[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return page, ErrInvalidSort
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+	idColumn := table + ".id"
+
+	if req.Cursor != "" {
+		c, value, err := decodeCursor(req.Cursor, key.kind)
+		if err != nil || c.Sort != sort {
+			return page, ErrInvalidCursor
+		}
+		db = db.Where("("+key.column+" "+op+" ? OR ("+key.column+" = ? AND "+idColumn+" "+op+" ?))", value, value, c.ID)
+	}
+
+	if len(req.Omit) > 0 {
+		db = db.Omit(req.Omit...)
+	}
+
+	var items []T
+	err := db.Order(key.column + " " + dir).Order(idColumn + " " + dir).Limit(limit + 1).Find(&items).Error
+	if err != nil {
+		return page, err
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+		value, id := keyOf(&items[limit-1])
+		page.NextCursor, err = encodeCursor(sort, value, id)
+		if err != nil {
+			return page, err
+		}
+	}
+
+	page.Items = items
+	return page, nil
+}
+
+func encodeCursor(sort string, value any, id uint) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(cursor{Sort: sort, Value: raw, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string, kind sortKind) (cursor, any, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, nil, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, nil, err
+	}
+
+	switch kind {
+	case sortInt:
+		var v int64
+		err = json.Unmarshal(c.Value, &v)
+		return c, v, err
+	case sortTime:
+		var v time.Time
+		err = json.Unmarshal(c.Value, &v)
+		return c, v, err
+	default:
+		var v string
+		err = json.Unmarshal(c.Value, &v)
+		return c, v, err
+	}
+}
\ No newline at end of file
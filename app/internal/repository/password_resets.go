@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+	"backend/internal/util"
+)
+
+
+type PasswordResetRepository interface {
+	Create(userID uint, ttl time.Duration) (string, *model.PasswordReset, error)
+	CountSince(userID uint, since time.Time) (int64, error)
+	GetByToken(token string) (*model.PasswordReset, error)
+	Consume(token string) (*model.PasswordReset, error)
+	RevokeByUserID(userID uint) error
+}
+
+type passwordResetRepo struct {
+	db *gorm.DB
+}
+
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepo{db}
+}
+
+// Create issues a reset token for the user and returns it with its record. As with
+// sessions, the token itself is not stored.
+func (r *passwordResetRepo) Create(userID uint, ttl time.Duration) (string, *model.PasswordReset, error) {
+	token, err := util.NewToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	reset := model.PasswordReset{
+		UserID:		userID,
+		TokenHash:	util.HashToken(token),
+		ExpiresAt:	time.Now().Add(ttl),
+	}
+	if err := r.db.Create(&reset).Error; err != nil {
+		return "", nil, err
+	}
+	return token, &reset, nil
+}
+
+// CountSince counts the tokens issued to a user since a time, used or not.
+func (r *passwordResetRepo) CountSince(userID uint, since time.Time) (int64, error) {
+	var n int64
+	err := r.db.Model(&model.PasswordReset{}).Where("user_id = ? AND created_at >= ?", userID, since).Count(&n).Error
+	return n, err
+}
+
+// GetByToken returns the reset for an unused, unexpired token, or ErrInvalidToken.
+func (r *passwordResetRepo) GetByToken(token string) (*model.PasswordReset, error) {
+	var reset model.PasswordReset
+	err := r.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", util.HashToken(token), time.Now()).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// Consume marks an unused, unexpired token used and returns it, or returns
+// ErrInvalidToken. Of two requests racing with the same token only one succeeds.
+func (r *passwordResetRepo) Consume(token string) (*model.PasswordReset, error) {
+	reset, err := r.GetByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	res := r.db.Model(&model.PasswordReset{}).Where("id = ? AND used_at IS NULL", reset.ID).Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalidToken
+	}
+	reset.UsedAt = &now
+	return reset, nil
+}
+
+// RevokeByUserID marks a user's unused tokens used, so that none still works once
+// the password has changed. The records are kept for CountSince and as a trail of
+// the resets requested.
+func (r *passwordResetRepo) RevokeByUserID(userID uint) error {
+	return r.db.Model(&model.PasswordReset{}).Where("user_id = ? AND used_at IS NULL", userID).Update("used_at", time.Now()).Error
+}
\ No newline at end of file
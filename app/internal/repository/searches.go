@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+)
+
+
+type SavedSearchRepository interface {
+	Create(search *model.SavedSearch) error
+	GetByID(id uint) (model.SavedSearch, error)
+	GetByUserID(userID uint, page PageRequest) (Page[model.SavedSearch], error)
+	Update(search *model.SavedSearch) error
+	Delete(id uint) error
+	ForDocument(userID, workspaceID uint) ([]model.SavedSearch, error)
+	SetLastMatched(id uint, at time.Time) error
+}
+
+var savedSearchSortKeys = map[string]sortKey{
+	"name":			{"saved_searches.name", sortString},
+	"created_at":	{"saved_searches.created_at", sortTime},
+}
+
+type savedSearchRepo struct {
+	db *gorm.DB
+}
+
+
+func NewSavedSearchRepository(db *gorm.DB) SavedSearchRepository {
+	return &savedSearchRepo{db}
+}
+
+func (r *savedSearchRepo) Create(search *model.SavedSearch) error {
+	return r.db.Create(search).Error
+}
+
+func (r *savedSearchRepo) GetByID(id uint) (model.SavedSearch, error) {
+	var search model.SavedSearch
+	err := r.db.Where("id = ?", id).First(&search).Error
+	return search, translate(err, "saved search", id)
+}
+
+func (r *savedSearchRepo) GetByUserID(userID uint, page PageRequest) (Page[model.SavedSearch], error) {
+	db := r.db.Model(&model.SavedSearch{}).Where("user_id = ?", userID)
+	return paginate(db, page, "saved_searches", savedSearchSortKeys, "created_at", func(s *model.SavedSearch) (any, uint) {
+		if strings.TrimPrefix(page.Sort, "-") == "name" {
+			return s.Name, s.ID
+		}
+		return s.CreatedAt, s.ID
+	})
+}
+
+// Update saves the name, query, filters and delivery settings of the search.
+func (r *savedSearchRepo) Update(search *model.SavedSearch) error {
+	res := r.db.Model(search).Select("name", "workspace_id", "mode", "query", "min_score", "year", "author", "format",
+		"notify_email", "webhook_url").Updates(search)
+	return affected(res, "saved search", search.ID)
+}
+
+func (r *savedSearchRepo) Delete(id uint) error {
+	return affected(r.db.Delete(&model.SavedSearch{}, id), "saved search", id)
+}
+
+// ForDocument lists the user's searches that cover a document in the workspace:
+// those on the whole library and those on that workspace.
+func (r *savedSearchRepo) ForDocument(userID, workspaceID uint) ([]model.SavedSearch, error) {
+	var searches []model.SavedSearch
+	db := r.db.Where("user_id = ?", userID)
+	if workspaceID != 0 {
+		db = db.Where("workspace_id IN ?", []uint{0, workspaceID})
+	} else {
+		db = db.Where("workspace_id = ?", 0)
+	}
+	err := db.Order("id").Find(&searches).Error
+	return searches, err
+}
+
+func (r *savedSearchRepo) SetLastMatched(id uint, at time.Time) error {
+	return affected(r.db.Model(&model.SavedSearch{}).Where("id = ?", id).Update("last_matched_at", at), "saved search", id)
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+	"backend/internal/util"
+)
+
+
+var ErrInvalidToken error = &UnauthorizedError{Message: "Invalid or expired token"}
+
+type SessionRepository interface {
+	Create(userID uint, ttl time.Duration) (string, *model.Session, error)
+	GetByToken(token string) (*model.Session, error)
+	Delete(token string) error
+	DeleteByUserID(userID uint) error
+}
+
+type sessionRepo struct {
+	db *gorm.DB
+}
+
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepo{db}
+}
+
+// Create issues a new token for the user and returns it with its session. The token
+// itself is not stored and can't be recovered later.
+func (r *sessionRepo) Create(userID uint, ttl time.Duration) (string, *model.Session, error) {
+	token, err := util.NewToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	session := model.Session{
+		UserID:		userID,
+		TokenHash:	util.HashToken(token),
+		ExpiresAt:	time.Now().Add(ttl),
+	}
+	if err := r.db.Create(&session).Error; err != nil {
+		return "", nil, err
+	}
+	return token, &session, nil
+}
+
+// GetByToken returns the session for an unexpired token, or ErrInvalidToken.
+func (r *sessionRepo) GetByToken(token string) (*model.Session, error) {
+	var session model.Session
+	err := r.db.Where("token_hash = ? AND expires_at > ?", util.HashToken(token), time.Now()).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) Delete(token string) error {
+	return r.db.Where("token_hash = ?", util.HashToken(token)).Delete(&model.Session{}).Error
+}
+
+// DeleteByUserID signs a user out everywhere.
+func (r *sessionRepo) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.Session{}).Error
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/model"
+)
+
+
+type TagRepository interface {
+	Create(tag *model.Tag) error
+	GetByID(id uint) (model.Tag, error)
+	GetByUserID(userID uint, page PageRequest) (Page[model.Tag], error)
+	Delete(id uint) error
+	TagDocuments(userID uint, documentIDs, tagIDs []uint) (int, error)
+	UntagDocuments(userID uint, documentIDs, tagIDs []uint) (int, error)
+}
+
+var tagSortKeys = map[string]sortKey{
+	"path":			{"tags.path", sortString},
+	"name":			{"tags.name", sortString},
+	"created_at":	{"tags.created_at", sortTime},
+}
+
+type tagRepo struct {
+	db *gorm.DB
+}
+
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepo{db}
+}
+
+func (r *tagRepo) Create(tag *model.Tag) error {
+	tag.Path = tag.Name
+	if tag.ParentID != nil {
+		parent, err := r.GetByID(*tag.ParentID)
+		if err != nil {
+			return err
+		}
+		tag.Path = parent.Path + "/" + tag.Name
+	}
+
+	if err := r.db.Create(tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return &ConflictError{Resource: "tag", Message: "tag " + tag.Path + " already exists"}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *tagRepo) GetByID(id uint) (model.Tag, error) {
+	var tag model.Tag
+	err := r.db.Where("id = ?", id).First(&tag).Error
+	return tag, translate(err, "tag", id)
+}
+
+func (r *tagRepo) GetByUserID(userID uint, page PageRequest) (Page[model.Tag], error) {
+	db := r.db.Model(&model.Tag{}).Where("user_id = ?", userID)
+	return paginate(db, page, "tags", tagSortKeys, "path", func(t *model.Tag) (any, uint) {
+		switch strings.TrimPrefix(page.Sort, "-") {
+		case "name":
+			return t.Name, t.ID
+		case "created_at":
+			return t.CreatedAt, t.ID
+		}
+		return t.Path, t.ID
+	})
+}
+
+// Delete removes the tag together with every tag nested beneath it.
+func (r *tagRepo) Delete(id uint) error {
+	tag, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		subtree := tx.Model(&model.Tag{}).Select("id").
+			Where(`user_id = ? AND (path = ? OR path LIKE ? ESCAPE '\\')`, tag.UserID, tag.Path, under(tag.Path))
+
+		var ids []uint
+		if err := subtree.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id IN ?", ids).Delete(&model.DocumentTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Tag{}, ids).Error
+	})
+}
+
+// TagDocuments attaches every tag to every document, ignoring documents and tags
+// that do not belong to the user. It returns the number of new associations.
+func (r *tagRepo) TagDocuments(userID uint, documentIDs, tagIDs []uint) (int, error) {
+	docs, tags, err := r.ownedIDs(userID, documentIDs, tagIDs)
+	if err != nil || len(docs) == 0 || len(tags) == 0 {
+		return 0, err
+	}
+
+	rows := make([]model.DocumentTag, 0, len(docs)*len(tags))
+	for _, d := range docs {
+		for _, t := range tags {
+			rows = append(rows, model.DocumentTag{DocumentID: d, TagID: t})
+		}
+	}
+
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows)
+	return int(res.RowsAffected), res.Error
+}
+
+func (r *tagRepo) UntagDocuments(userID uint, documentIDs, tagIDs []uint) (int, error) {
+	docs, tags, err := r.ownedIDs(userID, documentIDs, tagIDs)
+	if err != nil || len(docs) == 0 || len(tags) == 0 {
+		return 0, err
+	}
+
+	res := r.db.Where("document_id IN ? AND tag_id IN ?", docs, tags).Delete(&model.DocumentTag{})
+	return int(res.RowsAffected), res.Error
+}
+
+func (r *tagRepo) ownedIDs(userID uint, documentIDs, tagIDs []uint) ([]uint, []uint, error) {
+	var docs, tags []uint
+	if len(documentIDs) == 0 || len(tagIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	if err := r.db.Model(&model.Document{}).Where("user_id = ? AND id IN ?", userID, documentIDs).
+		Pluck("id", &docs).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := r.db.Model(&model.Tag{}).Where("user_id = ? AND id IN ?", userID, tagIDs).
+		Pluck("id", &tags).Error; err != nil {
+		return nil, nil, err
+	}
+	return docs, tags, nil
+}
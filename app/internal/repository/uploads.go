@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+	"backend/internal/util"
+)
+
+
+type UploadRepository interface {
+	Create(upload *model.Upload) error
+	Get(id string, userID uint) (model.Upload, error)
+	SetOffset(id string, offset int64) error
+	Complete(id string, documentID uint) error
+	Delete(id string) error
+	Expired(now time.Time) ([]model.Upload, error)
+}
+
+type uploadRepo struct {
+	db *gorm.DB
+}
+
+
+func NewUploadRepository(db *gorm.DB) UploadRepository {
+	return &uploadRepo{db}
+}
+
+// Create assigns the upload a random, unguessable ID and saves it.
+func (r *uploadRepo) Create(upload *model.Upload) error {
+	id, err := util.NewToken()
+	if err != nil {
+		return err
+	}
+	upload.ID = id
+	return r.db.Create(upload).Error
+}
+
+// Get returns the user's upload with the given ID. Uploads of other users are
+// reported as not found.
+func (r *uploadRepo) Get(id string, userID uint) (model.Upload, error) {
+	var upload model.Upload
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return upload, &NotFoundError{Resource: "upload"}
+	}
+	return upload, err
+}
+
+func (r *uploadRepo) SetOffset(id string, offset int64) error {
+	return r.db.Model(&model.Upload{}).Where("id = ?", id).Update("offset", offset).Error
+}
+
+// Complete records the document created from a finished upload.
+func (r *uploadRepo) Complete(id string, documentID uint) error {
+	return r.db.Model(&model.Upload{}).Where("id = ?", id).Update("document_id", documentID).Error
+}
+
+func (r *uploadRepo) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.Upload{}).Error
+}
+
+// Expired lists the uploads that expired before now, finished or not.
+func (r *uploadRepo) Expired(now time.Time) ([]model.Upload, error) {
+	var uploads []model.Upload
+	err := r.db.Where("expires_at <= ?", now).Find(&uploads).Error
+	return uploads, err
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,8 +12,11 @@ import (
 
 type UserRepository interface {
 	Create(user *model.User) error
+	GetByID(id uint) (*model.User, error)
 	GetByUsername(username string) (*model.User, error)
+	GetByEmail(email string) (*model.User, error)
 	UpdateLastLogin(user *model.User) error
+	UpdatePassword(id uint, hash string) error
 }
 
 type userRepo struct {
@@ -26,20 +30,52 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 func (r *userRepo) Create(user *model.User) error {
 	user.CreatedAt = time.Now()
-	return r.db.Create(user).Error
+	if err := r.db.Create(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return &ConflictError{Resource: "user", Message: "Username or email is already taken"}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *userRepo) GetByID(id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, translate(err, "user", id)
+	}
+
+	return &user, nil
 }
 
 func (r *userRepo) GetByUsername(username string) (*model.User, error) {
 	var user model.User
 	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
-		return nil, err
+		return nil, translate(err, "user", 0)
 	}
 
 	return &user, nil
 }
 
+func (r *userRepo) GetByEmail(email string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err, "user", 0)
+	}
+
+	return &user, nil
+}
+
+// UpdateLastLogin stores the time of the user's login. Only that column is
+// written, so a password changed since the user was loaded is kept.
 func (r *userRepo) UpdateLastLogin(user *model.User) error {
 	now := time.Now()
 	user.LastLoginAt = &now
-	return r.db.Save(user).Error
+	return r.db.Model(&model.User{}).Where("id = ?", user.ID).Update("last_login_at", now).Error
+}
+
+// UpdatePassword stores a new password hash for the user.
+func (r *userRepo) UpdatePassword(id uint, hash string) error {
+	res := r.db.Model(&model.User{}).Where("id = ?", id).Update("password", hash)
+	return affected(res, "user", id)
 }
\ No newline at end of file
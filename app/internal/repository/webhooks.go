@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/model"
+)
+
+
+type WebhookRepository interface {
+	Create(hook *model.Webhook) error
+	GetByID(id uint) (model.Webhook, error)
+	GetByUserID(userID uint, page PageRequest) (Page[model.Webhook], error)
+	GetByURL(userID uint, url string) (model.Webhook, error)
+	Update(hook *model.Webhook) error
+	Delete(id uint) error
+	ForEvent(userID, workspaceID uint) ([]model.Webhook, error)
+	CreateDelivery(d *model.WebhookDelivery) error
+	GetDelivery(id uint) (model.WebhookDelivery, error)
+	ListDeliveries(filter DeliveryFilter, page PageRequest) (Page[model.WebhookDelivery], error)
+	DueDeliveries(now time.Time, limit int) ([]uint, error)
+	SaveAttempt(d *model.WebhookDelivery) error
+}
+
+// DeliveryFilter narrows the deliveries of a webhook. A zero Status means "don't
+// filter on the status".
+type DeliveryFilter struct {
+	WebhookID		uint
+	Status			string
+}
+
+var webhookSortKeys = map[string]sortKey{
+	"url":			{"webhooks.url", sortString},
+	"created_at":	{"webhooks.created_at", sortTime},
+}
+
+var deliverySortKeys = map[string]sortKey{
+	"created_at":	{"webhook_deliveries.created_at", sortTime},
+}
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepo{db}
+}
+
+func (r *webhookRepo) Create(hook *model.Webhook) error {
+	return r.db.Create(hook).Error
+}
+
+func (r *webhookRepo) GetByID(id uint) (model.Webhook, error) {
+	var hook model.Webhook
+	err := r.db.Where("id = ?", id).First(&hook).Error
+	return hook, translate(err, "webhook", id)
+}
+
+func (r *webhookRepo) GetByUserID(userID uint, page PageRequest) (Page[model.Webhook], error) {
+	db := r.db.Model(&model.Webhook{}).Where("user_id = ?", userID)
+	return paginate(db, page, "webhooks", webhookSortKeys, "created_at", func(hook *model.Webhook) (any, uint) {
+		if strings.TrimPrefix(page.Sort, "-") == "url" {
+			return hook.URL, hook.ID
+		}
+		return hook.CreatedAt, hook.ID
+	})
+}
+
+// GetByURL returns the user's first webhook with the URL.
+func (r *webhookRepo) GetByURL(userID uint, url string) (model.Webhook, error) {
+	var hook model.Webhook
+	err := r.db.Where("user_id = ? AND url = ?", userID, url).Order("id").First(&hook).Error
+	return hook, translate(err, "webhook", 0)
+}
+
+// Update saves the URL, events, state and secret of the webhook.
+func (r *webhookRepo) Update(hook *model.Webhook) error {
+	res := r.db.Model(hook).Select("url", "events", "enabled", "secret").Updates(hook)
+	return affected(res, "webhook", hook.ID)
+}
+
+// Delete deletes the webhook with its delivery log.
+func (r *webhookRepo) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return affected(tx.Delete(&model.Webhook{}, id), "webhook", id)
+	})
+}
+
+// ForEvent lists the user's enabled webhooks that take events in the workspace:
+// those on the whole library and those on that workspace.
+func (r *webhookRepo) ForEvent(userID, workspaceID uint) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	db := r.db.Where("user_id = ? AND enabled = ?", userID, true)
+	if workspaceID != 0 {
+		db = db.Where("workspace_id IN ?", []uint{0, workspaceID})
+	} else {
+		db = db.Where("workspace_id = ?", 0)
+	}
+	err := db.Order("id").Find(&hooks).Error
+	return hooks, err
+}
+
+func (r *webhookRepo) CreateDelivery(d *model.WebhookDelivery) error {
+	return r.db.Create(d).Error
+}
+
+func (r *webhookRepo) GetDelivery(id uint) (model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	err := r.db.Where("id = ?", id).First(&d).Error
+	return d, translate(err, "webhook delivery", id)
+}
+
+func (r *webhookRepo) ListDeliveries(filter DeliveryFilter, page PageRequest) (Page[model.WebhookDelivery], error) {
+	db := r.db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", filter.WebhookID)
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	return paginate(db, page, "webhook_deliveries", deliverySortKeys, "-created_at", func(d *model.WebhookDelivery) (any, uint) {
+		return d.CreatedAt, d.ID
+	})
+}
+
+// DueDeliveries lists the pending deliveries whose next attempt is at or before
+// now, the most overdue first.
+func (r *webhookRepo) DueDeliveries(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.WebhookDelivery{}).Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SaveAttempt records the outcome of an attempt and when the next one is due.
+func (r *webhookRepo) SaveAttempt(d *model.WebhookDelivery) error {
+	res := r.db.Model(d).Select("status", "attempts", "next_attempt_at", "response_status", "response_body", "error",
+		"delivered_at").Updates(d)
+	return affected(res, "webhook delivery", d.ID)
+}
@@ -1,6 +1,8 @@
 package repository
 
 import  (
+	"strings"
+
 	"gorm.io/gorm"
 	
 	"backend/internal/model"
@@ -8,11 +10,18 @@ import  (
 
 
 type WorkspaceRepository interface {
-	GetByUserID(userID uint) ([]model.Workspace, error)
+	GetByID(id uint) (model.Workspace, error)
+	GetByUserID(userID uint, page PageRequest) (Page[model.Workspace], error)
 	Create(workspace *model.Workspace) error
+	Update(workspace *model.Workspace) error
 	Delete(id uint) error
-	AddDocumentToWorkspace(documentID, workspaceID uint) error
-	RemoveDocumentFromWorkspace(documentID uint) error
+	AddDocumentToWorkspace(userID, documentID, workspaceID uint) error
+	RemoveDocumentFromWorkspace(userID, documentID, workspaceID uint) error
+}
+
+var workspaceSortKeys = map[string]sortKey{
+	"title":		{"workspaces.title", sortString},
+	"created_at":	{"workspaces.created_at", sortTime},
 }
 
 type workspaceRepo struct {
@@ -24,24 +33,48 @@ func NewWorkspaceRepository(db *gorm.DB) WorkspaceRepository {
 	return &workspaceRepo{db}
 }
 
-func (r *workspaceRepo) GetByUserID(userID uint) ([]model.Workspace, error) {
-	var workspaces []model.Workspace
-	err := r.db.Where("user_id = ?", userID).Find(&workspaces).Error
-	return workspaces, err
+func (r *workspaceRepo) GetByID(id uint) (model.Workspace, error) {
+	var ws model.Workspace
+	err := r.db.Where("id = ?", id).First(&ws).Error
+	return ws, translate(err, "workspace", id)
+}
+
+func (r *workspaceRepo) GetByUserID(userID uint, page PageRequest) (Page[model.Workspace], error) {
+	db := r.db.Model(&model.Workspace{}).Where("user_id = ?", userID)
+	return paginate(db, page, "workspaces", workspaceSortKeys, "created_at", func(ws *model.Workspace) (any, uint) {
+		if strings.TrimPrefix(page.Sort, "-") == "title" {
+			return ws.Title, ws.ID
+		}
+		return ws.CreatedAt, ws.ID
+	})
 }
 
 func (r *workspaceRepo) Create(ws *model.Workspace) error {
 	return r.db.Create(ws).Error
 }
 
+// Update saves the title and settings of the workspace.
+func (r *workspaceRepo) Update(ws *model.Workspace) error {
+	res := r.db.Model(ws).Select("title", "skip_ocr", "ocr_language").Updates(ws)
+	return affected(res, "workspace", ws.ID)
+}
+
 func (r *workspaceRepo) Delete(id uint) error {
-	return r.db.Delete(&model.Workspace{}, id).Error
+	return affected(r.db.Delete(&model.Workspace{}, id), "workspace", id)
 }
 
-func (r *workspaceRepo) AddDocumentToWorkspace(documentID, workspaceID uint) error {
-	return r.db.Model(&model.Document{}).Where("id = ?", documentID).Update("workspace_id", workspaceID).Error
+func (r *workspaceRepo) AddDocumentToWorkspace(userID, documentID, workspaceID uint) error {
+	res := r.db.Model(&model.Document{}).Where("id = ? AND user_id = ?", documentID, userID).Update("workspace_id", workspaceID)
+	return affected(res, "document", documentID)
 }
 
-func (r *workspaceRepo) RemoveDocumentFromWorkspace(documentID uint) error {
-	return r.db.Model(&model.Document{}).Where("id = ?", documentID).Update("workspace_id", nil).Error
+// RemoveDocumentFromWorkspace detaches the user's document. A workspaceID of 0
+// detaches it from whichever workspace it is in.
+func (r *workspaceRepo) RemoveDocumentFromWorkspace(userID, documentID, workspaceID uint) error {
+	db := r.db.Model(&model.Document{}).Where("id = ? AND user_id = ?", documentID, userID)
+	if workspaceID != 0 {
+		db = db.Where("workspace_id = ?", workspaceID)
+	}
+
+	return affected(db.Update("workspace_id", 0), "document", documentID)
 }
\ No newline at end of file
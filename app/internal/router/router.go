@@ -0,0 +1,180 @@
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/openapi"
+)
+
+
+type Handlers struct {
+	Auth			*handler.AuthHandler
+	Documents		*handler.DocumentHandler
+	Workspaces		*handler.WorkspaceHandler
+	Notes			*handler.NoteHandler
+	Tags			*handler.TagHandler
+	Search			*handler.SearchHandler
+	Uploads			*handler.UploadHandler
+	Feeds			*handler.FeedHandler
+	SavedSearches	*handler.SavedSearchHandler
+	Notifications	*handler.NotificationHandler
+	Webhooks		*handler.WebhookHandler
+}
+
+// route pairs an operation's OpenAPI description with the handler that serves it.
+type route struct {
+	openapi.Route
+	handler		http.HandlerFunc
+}
+
+// probe stands in for the ResponseWriter of ServeMux's own error handlers, to
+// learn the status and Allow header they would answer with.
+type probe struct {
+	header		http.Header
+	status		int
+}
+
+const APIV2 = "/api/v2"
+
+
+// New builds the mux serving the v2 routes, the legacy routes and /openapi.json.
+// Requests no route matches, or with a method their route doesn't take, are
+// answered with the JSON error envelope like every other error.
+func New(h Handlers) http.Handler {
+	mux := http.NewServeMux()
+	registerV2Routes(mux, h)
+	registerUploadRoutes(mux, h)
+	registerLegacyRoutes(mux, h)
+
+	spec, err := json.Marshal(Spec())
+	if err != nil {
+		log.Fatalf("Failed to encode OpenAPI document: %v", err)
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+
+	return withErrorEnvelope(mux)
+}
+
+// withErrorEnvelope replaces the plain-text 404 and 405 answers of the mux with the
+// JSON error envelope.
+func withErrorEnvelope(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		p := &probe{header: http.Header{}}
+		h.ServeHTTP(p, r)
+		if p.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", p.header.Get("Allow"))
+			handler.MethodNotAllowed(w, r)
+			return
+		}
+		handler.NotFound(w, r)
+	})
+}
+
+func (p *probe) Header() http.Header { return p.header }
+
+func (p *probe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (p *probe) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+// Spec returns the OpenAPI document describing the v2 routes.
+func Spec() *openapi.Document {
+	var routes []openapi.Route
+	for _, rt := range v2Routes(Handlers{}) {
+		routes = append(routes, rt.Route)
+	}
+
+	info := openapi.Info{
+		Title:			"ResearchAssistant API",
+		Version:		"2.0.0",
+		Description:	"The verb-style routes from before /api/v2 still work but are deprecated and not described here.",
+	}
+	doc := openapi.Build(info, routes, handler.ErrorResponse{})
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"bearerAuth": {Type: "http", Scheme: "bearer"},
+	}
+	return doc
+}
+
+// registerV2Routes registers the versioned resource routes. The method patterns let
+// ServeMux answer 405 Method Not Allowed (with an Allow header) on its own.
+func registerV2Routes(mux *http.ServeMux, h Handlers) {
+	for _, rt := range v2Routes(h) {
+		mux.HandleFunc(rt.Method+" "+rt.Path, rt.handler)
+	}
+}
+
+// registerUploadRoutes serves resumable uploads. They speak the tus protocol through
+// headers rather than JSON, so they aren't described in the OpenAPI document.
+func registerUploadRoutes(mux *http.ServeMux, h Handlers) {
+	mux.HandleFunc("OPTIONS "+APIV2+"/uploads", h.Uploads.Options)
+	mux.HandleFunc("POST "+APIV2+"/uploads", h.Uploads.CreateUpload)
+	mux.HandleFunc("HEAD "+APIV2+"/uploads/{id}", h.Uploads.GetUploadOffset)
+	mux.HandleFunc("PATCH "+APIV2+"/uploads/{id}", h.Uploads.PatchUpload)
+	mux.HandleFunc("DELETE "+APIV2+"/uploads/{id}", h.Uploads.DeleteUpload)
+}
+
+// registerLegacyRoutes keeps the original verb-style routes working while clients
+// migrate. Every response carries a Deprecation header pointing at its v2 successor.
+func registerLegacyRoutes(mux *http.ServeMux, h Handlers) {
+	legacy := func(path, successor string, next http.Handler) {
+		mux.Handle(path, middleware.Deprecated(APIV2+successor, next))
+	}
+	fn := func(f http.HandlerFunc) http.Handler { return f }
+	queryID := func(param string, next http.HandlerFunc) http.Handler {
+		return middleware.QueryParamToPath(param, "id", next)
+	}
+	bodyID := func(field string, next http.Handler) http.Handler {
+		return middleware.BodyFieldToPath(field, "id", next)
+	}
+
+	legacy("/health", "/health", fn(handler.HealthCheck))
+	legacy("/register", "/auth/register", fn(h.Auth.Register))
+	legacy("/login", "/auth/login", fn(h.Auth.Login))
+	legacy("/documents/get", "/documents", fn(h.Documents.GetDocuments))
+	legacy("/documents/upload", "/documents", fn(h.Documents.UploadDocuments))
+	legacy("/documents/view", "/documents/{id}/file", queryID("id", h.Documents.ViewDocument))
+	legacy("/documents/backlinks", "/documents/{id}/backlinks", queryID("id", h.Notes.GetDocumentBacklinks))
+	legacy("/documents/status", "/documents/reading-status", fn(h.Documents.UpdateReadingStatus))
+	legacy("/documents/tag", "/documents/tags", fn(h.Tags.TagDocuments))
+	legacy("/documents/untag", "/documents/tags", fn(h.Tags.UntagDocuments))
+	legacy("/workspace/create", "/workspaces", fn(h.Workspaces.CreateWorkspace))
+	legacy("/workspace/get", "/workspaces", fn(h.Workspaces.GetUserWorkspaces))
+	legacy("/workspace/delete", "/workspaces/{id}", bodyID("id", fn(h.Workspaces.DeleteWorkspace)))
+	legacy("/workspace/add-document", "/workspaces/{id}/documents/{documentID}",
+		bodyID("workspace_id", middleware.BodyFieldToPath("document_id", "documentID", fn(h.Workspaces.AddDocumentToWorkspace))))
+	legacy("/workspace/remove-document", "/workspaces/{id}/documents/{documentID}",
+		middleware.BodyFieldToPath("document_id", "documentID", fn(h.Workspaces.RemoveDocumentFromWorkspace)))
+	legacy("/notes/create", "/notes", fn(h.Notes.CreateNote))
+	legacy("/notes/get", "/workspaces/{id}/notes", queryID("workspace_id", h.Notes.GetWorkspaceNotes))
+	legacy("/notes/view", "/notes/{id}", queryID("id", h.Notes.ViewNote))
+	legacy("/notes/update", "/notes/{id}", bodyID("id", fn(h.Notes.UpdateNote)))
+	legacy("/notes/delete", "/notes/{id}", bodyID("id", fn(h.Notes.DeleteNote)))
+	legacy("/notes/revisions", "/notes/{id}/revisions", queryID("id", h.Notes.GetNoteRevisions))
+	legacy("/notes/backlinks", "/notes/{id}/backlinks", queryID("id", h.Notes.GetNoteBacklinks))
+	legacy("/tags/create", "/tags", fn(h.Tags.CreateTag))
+	legacy("/tags/get", "/tags", fn(h.Tags.GetUserTags))
+	legacy("/tags/delete", "/tags/{id}", bodyID("id", fn(h.Tags.DeleteTag)))
+	legacy("/search", "/search", fn(h.Search.Search))
+}
\ No newline at end of file
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	"backend/internal/handler"
+	"backend/internal/openapi"
+)
+
+
+// wildcard matches the {name} segments of a route pattern.
+var wildcard = regexp.MustCompile(`\{[^}]+\}`)
+
+
+// TestSpecCoversRoutes checks that every v2 route is in the OpenAPI document, and
+// that every operation of the document is served by the mux under its own pattern.
+func TestSpecCoversRoutes(t *testing.T) {
+	doc := Spec()
+	for _, rt := range v2Routes(Handlers{}) {
+		item, ok := doc.Paths[rt.Path]
+		if !ok || item.Operation(rt.Method) == nil {
+			t.Errorf("%s %s is registered but not in the spec", rt.Method, rt.Path)
+		}
+	}
+
+	mux := http.NewServeMux()
+	registerV2Routes(mux, Handlers{})
+	for path, item := range doc.Paths {
+		for _, method := range openapi.Methods {
+			if item.Operation(method) == nil {
+				continue
+			}
+			req := httptest.NewRequest(method, wildcard.ReplaceAllString(path, "1"), nil)
+			if _, pattern := mux.Handler(req); pattern != method+" "+path {
+				t.Errorf("%s %s is in the spec but served by %q", method, path, pattern)
+			}
+		}
+	}
+}
+
+// TestClientUpToDate checks that pkg/client/client_gen.go is what the spec
+// generates; run go generate ./pkg/client after changing a route or DTO.
+func TestClientUpToDate(t *testing.T) {
+	want, err := openapi.GoClient(Spec())
+	if err != nil {
+		t.Fatalf("generating the client: %v", err)
+	}
+	got, err := os.ReadFile("../../pkg/client/client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("pkg/client/client_gen.go is out of date; run go generate ./pkg/client")
+	}
+}
+
+// TestUnmatchedRoutesUseErrorEnvelope checks that the 404 and 405 answers of the
+// mux are JSON error envelopes, and that a 405 still lists the allowed methods.
+func TestUnmatchedRoutesUseErrorEnvelope(t *testing.T) {
+	mux := New(Handlers{})
+	for _, tc := range []struct {
+		method, path	string
+		status			int
+		code, allow		string
+	}{
+		{"GET", "/no/such/route", http.StatusNotFound, "not_found", ""},
+		{"GET", APIV2 + "/documents/1/nothing", http.StatusNotFound, "not_found", ""},
+		{"DELETE", "/openapi.json", http.StatusMethodNotAllowed, "method_not_allowed", "GET, HEAD"},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: Content-Type %q, want application/json", tc.method, tc.path, ct)
+		}
+		if allow := rec.Header().Get("Allow"); allow != tc.allow {
+			t.Errorf("%s %s: Allow %q, want %q", tc.method, tc.path, allow, tc.allow)
+		}
+		var resp handler.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s %s: body %q is not an error envelope: %v", tc.method, tc.path, rec.Body, err)
+			continue
+		}
+		if resp.Code != tc.code || resp.Message == "" {
+			t.Errorf("%s %s: %+v, want code %s and a message", tc.method, tc.path, resp, tc.code)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package router
+
+import (
+	"net/http"
+
+	"backend/internal/feeds"
+	"backend/internal/handler"
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/openapi"
+	"backend/internal/repository"
+)
+
+
+var userIDParam = openapi.Param{Name: "user_id", Type: uint(0), Description: "Owner of the listed resources; defaults to, and must be, the authenticated user."}
+
+const sectionKinds = "Kinds: title, abstract, introduction, methods, results, discussion, conclusion, references, appendix, other."
+
+// v2Routes lists every /api/v2 operation. Spec is built from the same list with
+// zero Handlers, so the handler method values must not be called there.
+func v2Routes(h Handlers) []route {
+	op := func(method, path, id, tag, summary string, fn http.HandlerFunc, meta openapi.Route) route {
+		meta.Method, meta.Path, meta.OperationID, meta.Tag, meta.Summary = method, APIV2+path, id, tag, summary
+		return route{Route: meta, handler: fn}
+	}
+	counts := map[string]int{}
+
+	return []route{
+		op("GET", "/health", "healthCheck", "system", "Report that the server is up",
+			handler.HealthCheck, openapi.Route{ContentType: "text/plain"}),
+		op("POST", "/auth/register", "register", "auth", "Create a user account",
+			h.Auth.Register, openapi.Route{Body: handler.AuthRequest{}, Status: http.StatusCreated, Response: handler.MessageResponse{}}),
+		op("POST", "/auth/login", "login", "auth", "Check a username and password",
+			h.Auth.Login, openapi.Route{Body: handler.LoginRequest{}, Response: handler.LoginResponse{}}),
+		op("POST", "/auth/logout", "logout", "auth", "Revoke the bearer token",
+			h.Auth.Logout, openapi.Route{Status: http.StatusNoContent}),
+		op("GET", "/auth/me", "me", "auth", "Get the user the bearer token belongs to",
+			h.Auth.Me, openapi.Route{Response: handler.UserResponse{}}),
+		op("POST", "/auth/password", "changePassword", "auth", "Change the password, revoking every session; the response has a new token",
+			h.Auth.ChangePassword, openapi.Route{Body: handler.ChangePasswordRequest{}, Response: handler.LoginResponse{}}),
+		op("POST", "/auth/password/forgot", "forgotPassword", "auth", "Mail a password reset link to an account's email",
+			h.Auth.ForgotPassword, openapi.Route{Body: handler.ForgotPasswordRequest{}, Status: http.StatusAccepted, Response: handler.MessageResponse{}}),
+		op("POST", "/auth/password/reset", "resetPassword", "auth", "Set a new password with the token of a reset link",
+			h.Auth.ResetPassword, openapi.Route{Body: handler.ResetPasswordRequest{}, Response: handler.MessageResponse{}}),
+
+		op("GET", "/documents", "listDocuments", "documents", "List and filter a user's documents",
+			h.Documents.GetDocuments, openapi.Route{
+				Query: []openapi.Param{
+					userIDParam,
+					{Name: "tag", Type: uint(0), Description: "Tag ID; includes documents tagged with its descendants."},
+					{Name: "workspace_id", Type: uint(0), Description: "Workspace ID; 0 selects documents in no workspace."},
+					{Name: "year", Type: 0},
+					{Name: "author", Type: ""},
+					{Name: "format", Type: ""},
+					{Name: "status", Type: "", Description: "Reading status: unread, reading or read."},
+					{Name: "extraction", Type: "", Description: "Extraction outcome: ok, encrypted, no_text or corrupt."},
+					{Name: "uploaded_from", Type: "", Description: "RFC 3339 timestamp or YYYY-MM-DD date."},
+					{Name: "uploaded_to", Type: "", Description: "RFC 3339 timestamp or YYYY-MM-DD date, inclusive."},
+				},
+				Items:	model.Document{},
+				Facets:	repository.DocumentFacets{},
+			}),
+		op("POST", "/documents", "uploadDocument", "documents", "Upload a PDF",
+			h.Documents.UploadDocuments, openapi.Route{
+				Form:		handler.UploadRequest{},
+				Files:		[]string{"pdf"},
+				Status:		http.StatusCreated,
+				Response:	model.Document{},
+			}),
+		op("POST", "/documents/import", "importArchive", "documents", "Import every PDF in a ZIP or tar archive",
+			h.Documents.ImportArchive, openapi.Route{
+				Form:		handler.ImportArchiveRequest{},
+				Files:		[]string{"archive"},
+				Response:	ingest.ImportReport{},
+			}),
+		op("POST", "/documents/import/directory", "importDirectory", "documents", "Import every PDF below a server directory",
+			h.Documents.ImportDirectory, openapi.Route{Body: handler.ImportDirectoryRequest{}, Response: ingest.ImportReport{}}),
+		op("POST", "/documents/import-url", "importURL", "documents", "Import a PDF from a URL, DOI or arXiv ID",
+			h.Documents.ImportURL, openapi.Route{Body: handler.ImportURLRequest{}, Status: http.StatusCreated, Response: model.Document{}}),
+		op("POST", "/documents/clip", "clipPage", "documents", "Save the article of a web page as a document",
+			h.Documents.ClipPage, openapi.Route{Body: handler.ClipRequest{}, Status: http.StatusCreated, Response: model.Document{}}),
+		op("GET", "/documents/duplicates", "listDuplicateDocuments", "documents", "Group documents whose text is nearly the same",
+			h.Documents.GetDuplicates, openapi.Route{
+				Query: []openapi.Param{
+					userIDParam,
+					{Name: "threshold", Type: 0.0, Description: "Minimum similarity from 0.5 to 1; defaults to 0.8."},
+				},
+				Response:	[]handler.DuplicateGroup{},
+			}),
+		op("POST", "/documents/{id}/merge", "mergeDocuments", "documents", "Merge duplicate documents into this one",
+			h.Documents.MergeDocuments, openapi.Route{Body: handler.MergeRequest{}, Response: model.Document{}}),
+		op("GET", "/documents/{id}", "getDocument", "documents", "Get a document",
+			h.Documents.GetDocument, openapi.Route{Response: model.Document{}}),
+		op("DELETE", "/documents/{id}", "deleteDocument", "documents", "Delete a document and its file",
+			h.Documents.DeleteDocument, openapi.Route{Status: http.StatusNoContent}),
+		op("GET", "/documents/{id}/file", "getDocumentFile", "documents", "Download the original file",
+			h.Documents.ViewDocument, openapi.Route{ContentType: "application/pdf"}),
+		op("PUT", "/documents/{id}/file", "replaceDocumentFile", "documents", "Replace the file, keeping the old one as a version",
+			h.Documents.ReplaceDocumentFile, openapi.Route{Form: handler.ReplaceFileRequest{}, Files: []string{"pdf"}, Response: model.Document{}}),
+		op("POST", "/documents/{id}/extract", "extractDocument", "documents", "Extract the text again, e.g. with a PDF password",
+			h.Documents.ExtractDocument, openapi.Route{Body: handler.ExtractRequest{}, Response: model.Document{}}),
+		op("GET", "/documents/{id}/pages", "listDocumentPages", "documents", "List the text of each page, with OCR confidence",
+			h.Documents.GetDocumentPages, openapi.Route{Response: []model.DocumentPage{}}),
+		op("GET", "/documents/{id}/outline", "getDocumentOutline", "documents", "List the sections of a document without their text",
+			h.Documents.GetDocumentOutline, openapi.Route{Response: []model.DocumentSection{}}),
+		op("GET", "/documents/{id}/sections", "listDocumentSections", "documents", "Get the text of a document's sections",
+			h.Documents.GetDocumentSections, openapi.Route{
+				Query:		[]openapi.Param{{Name: "kind", Type: "", Description: sectionKinds}},
+				Response:	[]model.DocumentSection{},
+			}),
+		op("GET", "/documents/{id}/references", "listDocumentReferences", "citations", "List the parsed reference list of a document",
+			h.Documents.GetDocumentReferences, openapi.Route{Response: []model.DocumentReference{}}),
+		op("GET", "/documents/{id}/cites", "listCitedDocuments", "citations", "List the documents in the library that a document cites",
+			h.Documents.GetDocumentCites, openapi.Route{Response: []model.Document{}}),
+		op("GET", "/documents/{id}/cited-by", "listCitingDocuments", "citations", "List the documents in the library that cite a document",
+			h.Documents.GetDocumentCitedBy, openapi.Route{Response: []model.Document{}}),
+		op("POST", "/documents/{id}/ocr", "recognizeDocument", "documents", "Queue the scanned pages of a document for OCR",
+			h.Documents.RecognizeDocument, openapi.Route{Status: http.StatusAccepted, Response: model.Document{}}),
+		op("POST", "/documents/{id}/enrich", "enrichDocument", "documents", "Queue a metadata lookup by DOI or arXiv ID",
+			h.Documents.EnrichDocument, openapi.Route{Status: http.StatusAccepted, Response: model.Document{}}),
+		op("GET", "/documents/{id}/versions", "listDocumentVersions", "documents", "List the earlier versions of a document",
+			h.Documents.GetDocumentVersions, openapi.Route{Items: model.DocumentVersion{}}),
+		op("GET", "/documents/{id}/versions/{version}/file", "getDocumentVersionFile", "documents", "Download the file of an earlier version",
+			h.Documents.ViewDocumentVersion, openapi.Route{ContentType: "application/pdf"}),
+		op("GET", "/documents/{id}/diff", "diffDocumentVersions", "documents", "Compare the text of two versions",
+			h.Documents.DiffDocumentVersions, openapi.Route{
+				Query: []openapi.Param{
+					{Name: "from", Type: 0, Description: "Earlier version; defaults to the one before to."},
+					{Name: "to", Type: 0, Description: "Later version; defaults to the current version."},
+					{Name: "format", Type: "", Description: "unified returns the diff as plain text."},
+				},
+				Response:	handler.DocumentDiff{},
+			}),
+		op("GET", "/documents/{id}/backlinks", "getDocumentBacklinks", "notes", "List notes that link to a document",
+			h.Notes.GetDocumentBacklinks, openapi.Route{Response: []model.NoteBacklink{}}),
+		op("POST", "/documents/reading-status", "updateReadingStatus", "documents", "Set the reading status of documents",
+			h.Documents.UpdateReadingStatus, openapi.Route{Body: handler.ReadingStatusRequest{}, Response: counts}),
+		op("POST", "/documents/tags", "tagDocuments", "tags", "Add tags to documents",
+			h.Tags.TagDocuments, openapi.Route{Body: handler.BulkTagRequest{}, Response: counts}),
+		op("DELETE", "/documents/tags", "untagDocuments", "tags", "Remove tags from documents",
+			h.Tags.UntagDocuments, openapi.Route{Body: handler.BulkTagRequest{}, Response: counts}),
+
+		op("GET", "/workspaces", "listWorkspaces", "workspaces", "List a user's workspaces",
+			h.Workspaces.GetUserWorkspaces, openapi.Route{Query: []openapi.Param{userIDParam}, Items: model.Workspace{}}),
+		op("POST", "/workspaces", "createWorkspace", "workspaces", "Create a workspace",
+			h.Workspaces.CreateWorkspace, openapi.Route{Body: handler.CreateWorkspaceRequest{}, Status: http.StatusCreated, Response: model.Workspace{}}),
+		op("GET", "/workspaces/{id}", "getWorkspace", "workspaces", "Get a workspace",
+			h.Workspaces.GetWorkspace, openapi.Route{Response: model.Workspace{}}),
+		op("PATCH", "/workspaces/{id}", "updateWorkspace", "workspaces", "Rename a workspace or change its OCR settings",
+			h.Workspaces.UpdateWorkspace, openapi.Route{Body: handler.UpdateWorkspaceRequest{}, Response: model.Workspace{}}),
+		op("DELETE", "/workspaces/{id}", "deleteWorkspace", "workspaces", "Delete a workspace",
+			h.Workspaces.DeleteWorkspace, openapi.Route{Status: http.StatusNoContent}),
+		op("PUT", "/workspaces/{id}/documents/{documentID}", "addDocumentToWorkspace", "workspaces", "Move a document into a workspace",
+			h.Workspaces.AddDocumentToWorkspace, openapi.Route{Response: handler.MessageResponse{}}),
+		op("DELETE", "/workspaces/{id}/documents/{documentID}", "removeDocumentFromWorkspace", "workspaces", "Take a document out of a workspace",
+			h.Workspaces.RemoveDocumentFromWorkspace, openapi.Route{Response: handler.MessageResponse{}}),
+		op("GET", "/workspaces/{id}/citations", "getCitationGraph", "citations", "Export the citation graph of a workspace",
+			h.Documents.GetCitationGraph, openapi.Route{
+				Query:		[]openapi.Param{{Name: "format", Type: "", Description: "graphml returns the graph as GraphML XML."}},
+				Response:	handler.CitationGraph{},
+			}),
+		op("GET", "/workspaces/{id}/notes", "listWorkspaceNotes", "notes", "List the notes in a workspace",
+			h.Notes.GetWorkspaceNotes, openapi.Route{Items: model.Note{}}),
+		op("GET", "/workspaces/{id}/feeds", "listWorkspaceFeeds", "feeds", "List the feeds a workspace subscribes to",
+			h.Feeds.GetWorkspaceFeeds, openapi.Route{Items: model.Feed{}}),
+		op("GET", "/workspaces/{id}/inbox", "listWorkspaceInbox", "feeds", "List the feed entries waiting to be accepted or dismissed",
+			h.Feeds.GetWorkspaceInbox, openapi.Route{Items: model.FeedItem{}}),
+
+		op("POST", "/feeds", "createFeed", "feeds", "Subscribe a workspace to an RSS or Atom feed or an arXiv search",
+			h.Feeds.CreateFeed, openapi.Route{Body: handler.CreateFeedRequest{}, Status: http.StatusCreated, Response: model.Feed{}}),
+		op("GET", "/feeds/{id}", "getFeed", "feeds", "Get a feed",
+			h.Feeds.GetFeed, openapi.Route{Response: model.Feed{}}),
+		op("PATCH", "/feeds/{id}", "updateFeed", "feeds", "Rename a feed or change its settings",
+			h.Feeds.UpdateFeed, openapi.Route{Body: handler.UpdateFeedRequest{}, Response: model.Feed{}}),
+		op("DELETE", "/feeds/{id}", "deleteFeed", "feeds", "Unsubscribe from a feed, keeping the documents imported from it",
+			h.Feeds.DeleteFeed, openapi.Route{Status: http.StatusNoContent}),
+		op("POST", "/feeds/{id}/poll", "pollFeed", "feeds", "Poll a feed now",
+			h.Feeds.PollFeed, openapi.Route{Response: feeds.PollResult{}}),
+		op("GET", "/feeds/{id}/items", "listFeedItems", "feeds", "List the entries seen in a feed",
+			h.Feeds.GetFeedItems, openapi.Route{
+				Query:	[]openapi.Param{{Name: "status", Type: "", Description: "inbox, imported, duplicate, dismissed, skipped or failed."}},
+				Items:	model.FeedItem{},
+			}),
+		op("POST", "/feed-items/{id}/accept", "acceptFeedItem", "feeds", "Import a feed entry from the inbox",
+			h.Feeds.AcceptFeedItem, openapi.Route{Response: model.FeedItem{}}),
+		op("POST", "/feed-items/{id}/dismiss", "dismissFeedItem", "feeds", "Dismiss a feed entry from the inbox",
+			h.Feeds.DismissFeedItem, openapi.Route{Response: model.FeedItem{}}),
+
+		op("POST", "/notes", "createNote", "notes", "Create a note",
+			h.Notes.CreateNote, openapi.Route{Body: handler.CreateNoteRequest{}, Status: http.StatusCreated, Response: model.Note{}}),
+		op("GET", "/notes/{id}", "getNote", "notes", "Get a note",
+			h.Notes.ViewNote, openapi.Route{Response: model.Note{}}),
+		op("PUT", "/notes/{id}", "updateNote", "notes", "Update a note, recording a revision",
+			h.Notes.UpdateNote, openapi.Route{Body: handler.UpdateNoteRequest{}, Response: model.Note{}}),
+		op("DELETE", "/notes/{id}", "deleteNote", "notes", "Delete a note",
+			h.Notes.DeleteNote, openapi.Route{Status: http.StatusNoContent}),
+		op("GET", "/notes/{id}/revisions", "listNoteRevisions", "notes", "List the revisions of a note",
+			h.Notes.GetNoteRevisions, openapi.Route{Items: model.NoteRevision{}}),
+		op("GET", "/notes/{id}/backlinks", "getNoteBacklinks", "notes", "List notes that link to a note",
+			h.Notes.GetNoteBacklinks, openapi.Route{Response: []model.NoteBacklink{}}),
+
+		op("GET", "/tags", "listTags", "tags", "List a user's tags",
+			h.Tags.GetUserTags, openapi.Route{Query: []openapi.Param{userIDParam}, Items: model.Tag{}}),
+		op("POST", "/tags", "createTag", "tags", "Create a tag",
+			h.Tags.CreateTag, openapi.Route{Body: handler.CreateTagRequest{}, Status: http.StatusCreated, Response: model.Tag{}}),
+		op("DELETE", "/tags/{id}", "deleteTag", "tags", "Delete a tag and its descendants",
+			h.Tags.DeleteTag, openapi.Route{Status: http.StatusNoContent}),
+
+		op("GET", "/search", "search", "search", "Search documents and notes",
+			h.Search.Search, openapi.Route{
+				Query:		[]openapi.Param{
+					userIDParam,
+					{Name: "q", Type: "", Required: true},
+					{Name: "workspace_id", Type: uint(0), Description: "Only search this workspace."},
+					{Name: "section", Type: "", Description: "Only search sections of this kind; notes are left out. " + sectionKinds},
+				},
+				Response:	handler.SearchResponse{},
+			}),
+		op("GET", "/saved-searches", "listSavedSearches", "search", "List a user's saved searches",
+			h.SavedSearches.GetUserSavedSearches, openapi.Route{Query: []openapi.Param{userIDParam}, Items: model.SavedSearch{}}),
+		op("POST", "/saved-searches", "saveSearch", "search", "Save a search to be alerted of new matching documents",
+			h.SavedSearches.SaveSearch, openapi.Route{Body: handler.SaveSearchRequest{}, Status: http.StatusCreated, Response: model.SavedSearch{}}),
+		op("GET", "/saved-searches/{id}", "getSavedSearch", "search", "Get a saved search",
+			h.SavedSearches.GetSavedSearch, openapi.Route{Response: model.SavedSearch{}}),
+		op("PATCH", "/saved-searches/{id}", "updateSavedSearch", "search", "Change a saved search",
+			h.SavedSearches.UpdateSavedSearch, openapi.Route{Body: handler.UpdateSearchRequest{}, Response: model.SavedSearch{}}),
+		op("DELETE", "/saved-searches/{id}", "deleteSavedSearch", "search", "Delete a saved search, keeping its notifications",
+			h.SavedSearches.DeleteSavedSearch, openapi.Route{Status: http.StatusNoContent}),
+
+		op("GET", "/notifications", "listNotifications", "notifications", "List a user's notifications",
+			h.Notifications.GetNotifications, openapi.Route{
+				Query:	[]openapi.Param{
+					userIDParam,
+					{Name: "unread", Type: false, Description: "Only the unread notifications."},
+					{Name: "kind", Type: "", Description: "search.match, document.ready or feed.entries."},
+					{Name: "saved_search_id", Type: uint(0), Description: "Only the matches of this saved search."},
+				},
+				Items:	model.Notification{},
+			}),
+		op("GET", "/notifications/unread-count", "countUnreadNotifications", "notifications", "Count a user's unread notifications",
+			h.Notifications.GetUnreadCount, openapi.Route{Query: []openapi.Param{userIDParam}, Response: handler.UnreadCountResponse{}}),
+		op("POST", "/notifications/read", "readNotifications", "notifications", "Mark notifications read",
+			h.Notifications.ReadNotifications, openapi.Route{Body: handler.MarkReadRequest{}, Response: counts}),
+		op("POST", "/notifications/{id}/read", "readNotification", "notifications", "Mark a notification read",
+			h.Notifications.ReadNotification, openapi.Route{Response: model.Notification{}}),
+		op("GET", "/notification-settings", "getNotificationSettings", "notifications", "Get how a user is notified",
+			h.Notifications.GetNotificationSettings, openapi.Route{Query: []openapi.Param{userIDParam}, Response: model.NotificationSettings{}}),
+		op("PUT", "/notification-settings", "updateNotificationSettings", "notifications", "Choose the email, digest and webhook delivery of notifications",
+			h.Notifications.UpdateNotificationSettings, openapi.Route{Body: handler.NotificationSettingsRequest{}, Response: model.NotificationSettings{}}),
+
+		op("GET", "/webhooks", "listWebhooks", "webhooks", "List a user's webhooks",
+			h.Webhooks.GetUserWebhooks, openapi.Route{Query: []openapi.Param{userIDParam}, Items: model.Webhook{}}),
+		op("POST", "/webhooks", "createWebhook", "webhooks", "Subscribe a URL to library events; the response has the signing secret",
+			h.Webhooks.CreateWebhook, openapi.Route{Body: handler.CreateWebhookRequest{}, Status: http.StatusCreated, Response: model.Webhook{}}),
+		op("GET", "/webhooks/{id}", "getWebhook", "webhooks", "Get a webhook",
+			h.Webhooks.GetWebhook, openapi.Route{Response: model.Webhook{}}),
+		op("PATCH", "/webhooks/{id}", "updateWebhook", "webhooks", "Change a webhook or rotate its secret",
+			h.Webhooks.UpdateWebhook, openapi.Route{Body: handler.UpdateWebhookRequest{}, Response: model.Webhook{}}),
+		op("DELETE", "/webhooks/{id}", "deleteWebhook", "webhooks", "Delete a webhook and its delivery log",
+			h.Webhooks.DeleteWebhook, openapi.Route{Status: http.StatusNoContent}),
+		op("GET", "/webhooks/{id}/deliveries", "listWebhookDeliveries", "webhooks", "List the deliveries of a webhook",
+			h.Webhooks.GetWebhookDeliveries, openapi.Route{
+				Query:	[]openapi.Param{{Name: "status", Type: "", Description: "pending, delivered or failed."}},
+				Items:	model.WebhookDelivery{},
+			}),
+		op("POST", "/webhook-deliveries/{id}/redeliver", "redeliverWebhookDelivery", "webhooks", "Send the event of a delivery again",
+			h.Webhooks.RedeliverWebhookDelivery, openapi.Route{Status: http.StatusAccepted, Response: model.WebhookDelivery{}}),
+	}
+}
\ No newline at end of file
@@ -0,0 +1,93 @@
+package scholar
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+
+// ArXiv resolves arXiv IDs with the arXiv API, which returns an Atom feed. Every
+// arXiv paper is open access, so the PDF link is its open-access URL.
+type ArXiv struct {
+	Client		*Client
+	BaseURL		string
+	limiter		*limiter
+}
+
+type arXivFeed struct {
+	Entries		[]struct {
+		ID				string		`xml:"id"`
+		Title			string		`xml:"title"`
+		Summary			string		`xml:"summary"`
+		Published		string		`xml:"published"`
+		DOI				string		`xml:"doi"`
+		JournalRef		string		`xml:"journal_ref"`
+		Authors			[]struct {
+			Name			string		`xml:"name"`
+		}	`xml:"author"`
+		Links			[]struct {
+			Href			string		`xml:"href,attr"`
+			Title			string		`xml:"title,attr"`
+		}	`xml:"link"`
+	}	`xml:"entry"`
+}
+
+// arXivInterval is the pause between requests that the arXiv API terms ask for.
+const arXivInterval = 3 * time.Second
+
+
+func NewArXiv(client *Client, baseURL string) *ArXiv {
+	return &ArXiv{Client: client, BaseURL: strings.TrimRight(baseURL, "/"), limiter: newLimiter(arXivInterval)}
+}
+
+func (a *ArXiv) Name() string {
+	return "arxiv"
+}
+
+func (a *ArXiv) Resolve(ctx context.Context, id Identifier) (*Metadata, error) {
+	arXivID := id.arXivID()
+	if arXivID == "" {
+		return nil, ErrNotFound
+	}
+
+	q := url.Values{"id_list": {arXivID}, "max_results": {"1"}}
+	body, err := a.Client.get(ctx, a.limiter, a.BaseURL+"/query?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var feed arXivFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	// Unknown IDs give an empty feed, malformed ones an entry describing the error.
+	if len(feed.Entries) == 0 || strings.Contains(feed.Entries[0].ID, "/api/errors") {
+		return nil, ErrNotFound
+	}
+	e := feed.Entries[0]
+	md := &Metadata{
+		Title:		cleanText(e.Title),
+		Abstract:	cleanText(e.Summary),
+		Venue:		cleanText(e.JournalRef),
+		DOI:		strings.ToLower(e.DOI),
+		ArXivID:	arXivID,
+	}
+	if t, err := time.Parse(time.RFC3339, e.Published); err == nil {
+		md.Year = t.Year()
+	}
+	for _, au := range e.Authors {
+		if name := cleanText(au.Name); name != "" {
+			md.Authors = append(md.Authors, name)
+		}
+	}
+	for _, l := range e.Links {
+		if l.Title == "pdf" {
+			md.OpenAccessURL = l.Href
+		}
+	}
+	return md, nil
+}
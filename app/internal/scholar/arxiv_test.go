@@ -0,0 +1,91 @@
+package scholar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+
+func TestArXivResolve(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/api/query": "arxiv_query.atom"})
+	a := NewArXiv(NewClient(nil, ""), api.URL+"/api")
+
+	md, err := a.Resolve(context.Background(), Identifier{ArXivID: "1706.03762"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Metadata{
+		Title:			"Attention Is All You Need",
+		Authors:		[]string{"Ashish Vaswani", "Noam Shazeer", "Niki Parmar"},
+		Year:			2017,
+		Venue:			"Advances in Neural Information Processing Systems 30 (2017)",
+		Abstract:		"The dominant sequence transduction models are based on complex recurrent or convolutional neural networks in an encoder-decoder configuration.",
+		DOI:			"10.48550/arxiv.1706.03762",
+		ArXivID:		"1706.03762",
+		OpenAccessURL:	"http://arxiv.org/pdf/1706.03762v7",
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("got  %+v\nwant %+v", md, want)
+	}
+
+	q := api.Requests()[0].URL.Query()
+	if q.Get("id_list") != "1706.03762" || q.Get("max_results") != "1" {
+		t.Errorf("query %s", q.Encode())
+	}
+}
+
+func TestArXivResolvesArXivDOIs(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/query": "arxiv_query.atom"})
+	a := NewArXiv(NewClient(nil, ""), api.URL)
+
+	md, err := a.Resolve(context.Background(), Identifier{DOI: "10.48550/arXiv.1706.03762"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.ArXivID != "1706.03762" {
+		t.Errorf("arXiv ID %q", md.ArXivID)
+	}
+	if got := api.Requests()[0].URL.Query().Get("id_list"); got != "1706.03762" {
+		t.Errorf("id_list %q", got)
+	}
+}
+
+func TestArXivSkipsOtherDOIs(t *testing.T) {
+	api := newStubAPI(t, nil)
+	a := NewArXiv(NewClient(nil, ""), api.URL)
+
+	if _, err := a.Resolve(context.Background(), Identifier{DOI: "10.1038/nature14539"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err %v, want ErrNotFound", err)
+	}
+	if n := len(api.Requests()); n != 0 {
+		t.Errorf("API got %d requests, want none", n)
+	}
+}
+
+// The arXiv API answers unknown IDs with an empty feed and malformed ones with an
+// entry describing the error, both with status 200.
+func TestArXivNotFound(t *testing.T) {
+	for _, fixture := range []string{"arxiv_empty.atom", "arxiv_error.atom"} {
+		body, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/atom+xml; charset=UTF-8")
+			w.Write(body)
+		}))
+		a := NewArXiv(NewClient(nil, ""), srv.URL)
+
+		if _, err := a.Resolve(context.Background(), Identifier{ArXivID: "2399.99999"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err %v, want ErrNotFound", fixture, err)
+		}
+		srv.Close()
+	}
+}
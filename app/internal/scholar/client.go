@@ -0,0 +1,203 @@
+package scholar
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+
+// Client makes the HTTP requests of the resolvers. Responses, including "not
+// found", are cached, and every resolver waits its own interval between requests
+// so that the APIs' rate limits are kept.
+type Client struct {
+	HTTP			*http.Client
+	Cache			*Cache
+	// UserAgent identifies the server to the APIs. Crossref serves requests with
+	// a contact address in it from a faster pool.
+	UserAgent		string
+}
+
+// APIError is an unexpected response from an API. Temporary errors, rate limits
+// and server errors, may go away when the lookup is tried again later.
+type APIError struct {
+	Status			int
+	RetryAfter		time.Duration
+}
+
+// limiter spaces requests at least interval apart.
+type limiter struct {
+	mu				sync.Mutex
+	interval		time.Duration
+	next			time.Time
+}
+
+// Cache keeps API responses for a while, bounded in number with the least
+// recently used evicted first. It is safe for concurrent use.
+type Cache struct {
+	mu				sync.Mutex
+	ttl				time.Duration
+	max				int
+	entries			map[string]*list.Element
+	order			*list.List
+}
+
+type cacheEntry struct {
+	key				string
+	body			[]byte
+	found			bool
+	expires			time.Time
+}
+
+// maxResponseSize bounds the responses read from an API.
+const maxResponseSize = 4 << 20
+
+
+func NewClient(cache *Cache, userAgent string) *Client {
+	return &Client{HTTP: &http.Client{Timeout: 30 * time.Second}, Cache: cache, UserAgent: userAgent}
+}
+
+func NewCache(ttl time.Duration, max int) *Cache {
+	return &Cache{ttl: ttl, max: max, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{interval: interval}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected response status %d", e.Status)
+}
+
+func (e *APIError) Temporary() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// get fetches url, from the cache if possible. A 404 is ErrNotFound.
+func (c *Client) get(ctx context.Context, lim *limiter, url string, header http.Header) ([]byte, error) {
+	if body, found, ok := c.Cache.get(url); ok {
+		if !found {
+			return nil, ErrNotFound
+		}
+		return body, nil
+	}
+
+	if err := lim.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		c.Cache.put(url, nil, false)
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		apiErr := &APIError{Status: resp.StatusCode}
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(s) * time.Second
+			lim.delay(apiErr.RetryAfter)
+		}
+		return nil, apiErr
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.put(url, body, true)
+	return body, nil
+}
+
+// wait blocks until the next request may be made, or ctx is done.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := time.Until(at); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return nil
+}
+
+// delay holds off further requests for d, as asked by a Retry-After header.
+func (l *limiter) delay(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if at := time.Now().Add(d); at.After(l.next) {
+		l.next = at
+	}
+}
+
+func (c *Cache) get(key string) ([]byte, bool, bool) {
+	if c == nil {
+		return nil, false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false, false
+	}
+	c.order.MoveToFront(el)
+	return e.body, e.found, true
+}
+
+func (c *Cache) put(key string, body []byte, found bool) {
+	if c == nil || c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &cacheEntry{key: key, body: body, found: found, expires: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
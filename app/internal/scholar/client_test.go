@@ -0,0 +1,187 @@
+package scholar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+
+// stubAPI stands in for a scholarly API. It answers the request paths in
+// fixtures with the recorded response in that testdata file, and everything
+// else with 404. The requests it got are kept for the tests to inspect.
+type stubAPI struct {
+	*httptest.Server
+	mu			sync.Mutex
+	requests	[]*http.Request
+}
+
+func newStubAPI(t *testing.T, fixtures map[string]string) *stubAPI {
+	s := &stubAPI{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.mu.Unlock()
+
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Errorf("fixture %s: %v", name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if filepath.Ext(name) == ".atom" {
+			w.Header().Set("Content-Type", "application/atom+xml; charset=UTF-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the requests the stub has answered so far.
+func (s *stubAPI) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func TestClientCachesResponses(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/works/10.1038/nature14539": "crossref_work.json"})
+	cr := NewCrossref(NewClient(NewCache(time.Hour, 10), "test"), api.URL)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		md, err := cr.Resolve(ctx, Identifier{DOI: "10.1038/nature14539"})
+		if err != nil {
+			t.Fatalf("lookup %d: %v", i+1, err)
+		}
+		if md.Title != "Deep learning" {
+			t.Fatalf("lookup %d: title %q", i+1, md.Title)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cr.Resolve(ctx, Identifier{DOI: "10.1000/missing"}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("missing DOI, lookup %d: err %v, want ErrNotFound", i+1, err)
+		}
+	}
+
+	if n := len(api.Requests()); n != 2 {
+		t.Errorf("API got %d requests, want 2: one per DOI, with repeats and not found answered from the cache", n)
+	}
+}
+
+func TestClientWithoutCache(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/works/10.1038/nature14539": "crossref_work.json"})
+	cr := NewCrossref(NewClient(nil, "test"), api.URL)
+	cr.limiter = newLimiter(0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cr.Resolve(context.Background(), Identifier{DOI: "10.1038/nature14539"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.Requests()); n != 2 {
+		t.Errorf("API got %d requests, want 2", n)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(time.Hour, 2)
+	c.put("a", []byte("A"), true)
+	c.put("b", []byte("B"), true)
+	c.get("a")
+	c.put("c", []byte("C"), true)
+
+	if _, _, ok := c.get("b"); ok {
+		t.Error("b is still cached; it was the least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, ok := c.get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	c := NewCache(time.Millisecond, 10)
+	c.put("a", []byte("A"), true)
+	time.Sleep(5 * time.Millisecond)
+	if _, _, ok := c.get("a"); ok {
+		t.Error("entry outlived its ttl")
+	}
+}
+
+func TestLimiterSpacesRequests(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	lim := newLimiter(interval)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := lim.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("three requests took %v, want at least %v", elapsed, 2*interval)
+	}
+}
+
+func TestLimiterStopsWithContext(t *testing.T) {
+	lim := newLimiter(time.Hour)
+	lim.wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := lim.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestClientHonoursRetryAfter(t *testing.T) {
+	var (
+		mu		sync.Mutex
+		times	[]time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	cr := NewCrossref(NewClient(NewCache(time.Hour, 10), "test"), srv.URL)
+	ctx := context.Background()
+
+	_, err := cr.Resolve(ctx, Identifier{DOI: "10.1038/nature14539"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Temporary() || apiErr.RetryAfter != time.Second {
+		t.Fatalf("err %v, want a temporary APIError with RetryAfter 1s", err)
+	}
+
+	// The error isn't cached, and the next request waits out the Retry-After.
+	cr.Resolve(ctx, Identifier{DOI: "10.1038/nature14539"})
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 2 {
+		t.Fatalf("API got %d requests, want 2", len(times))
+	}
+	if gap := times[1].Sub(times[0]); gap < 900*time.Millisecond {
+		t.Errorf("second request came %v after the first, want about 1s", gap)
+	}
+}
@@ -0,0 +1,105 @@
+package scholar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+
+// Crossref resolves DOIs with the Crossref REST API, the registry of most
+// journal and conference DOIs.
+type Crossref struct {
+	Client		*Client
+	BaseURL		string
+	limiter		*limiter
+}
+
+type crossrefWork struct {
+	Message		struct {
+		DOI				string		`json:"DOI"`
+		Title			[]string	`json:"title"`
+		ContainerTitle	[]string	`json:"container-title"`
+		Abstract		string		`json:"abstract"`
+		ReferencedBy	*int		`json:"is-referenced-by-count"`
+		Author			[]struct {
+			Given			string		`json:"given"`
+			Family			string		`json:"family"`
+			Name			string		`json:"name"`
+		}	`json:"author"`
+		Issued			struct {
+			DateParts		[][]int		`json:"date-parts"`
+		}	`json:"issued"`
+	}	`json:"message"`
+}
+
+// jatsTag matches the JATS XML markup of Crossref abstracts.
+var jatsTag = regexp.MustCompile(`<[^>]+>`)
+
+// crossrefInterval keeps well within the limits of Crossref's public pool.
+const crossrefInterval = 100 * time.Millisecond
+
+
+func NewCrossref(client *Client, baseURL string) *Crossref {
+	return &Crossref{Client: client, BaseURL: strings.TrimRight(baseURL, "/"), limiter: newLimiter(crossrefInterval)}
+}
+
+func (c *Crossref) Name() string {
+	return "crossref"
+}
+
+func (c *Crossref) Resolve(ctx context.Context, id Identifier) (*Metadata, error) {
+	if id.DOI == "" || strings.HasPrefix(strings.ToLower(id.DOI), arXivDOIPrefix) {
+		return nil, ErrNotFound
+	}
+
+	body, err := c.Client.get(ctx, c.limiter, c.BaseURL+"/works/"+escapeDOI(id.DOI), http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, err
+	}
+	var work crossrefWork
+	if err := json.Unmarshal(body, &work); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	w := work.Message
+	md := &Metadata{DOI: strings.ToLower(w.DOI), CitationCount: w.ReferencedBy}
+	if len(w.Title) > 0 {
+		md.Title = cleanText(html.UnescapeString(w.Title[0]))
+	}
+	if len(w.ContainerTitle) > 0 {
+		md.Venue = cleanText(html.UnescapeString(w.ContainerTitle[0]))
+	}
+	if len(w.Issued.DateParts) > 0 && len(w.Issued.DateParts[0]) > 0 {
+		md.Year = w.Issued.DateParts[0][0]
+	}
+	for _, a := range w.Author {
+		name := strings.TrimSpace(a.Given + " " + a.Family)
+		if name == "" {
+			name = a.Name
+		}
+		if name != "" {
+			md.Authors = append(md.Authors, name)
+		}
+	}
+	if w.Abstract != "" {
+		abstract := cleanText(html.UnescapeString(jatsTag.ReplaceAllString(w.Abstract, " ")))
+		md.Abstract = strings.TrimPrefix(abstract, "Abstract ")
+	}
+	return md, nil
+}
+
+// escapeDOI escapes a DOI for a URL path, keeping the slashes between its parts.
+func escapeDOI(doi string) string {
+	parts := strings.Split(doi, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
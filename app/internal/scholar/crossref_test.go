@@ -0,0 +1,93 @@
+package scholar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+
+func TestCrossrefResolve(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/works/10.1038/nature14539": "crossref_work.json"})
+	cr := NewCrossref(NewClient(nil, "ResearchAssistant (mailto:admin@example.org)"), api.URL+"/")
+
+	md, err := cr.Resolve(context.Background(), Identifier{DOI: "10.1038/nature14539"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Metadata{
+		Title:			"Deep learning",
+		Authors:		[]string{"Yann LeCun", "Yoshua Bengio", "Geoffrey Hinton"},
+		Year:			2015,
+		Venue:			"Nature",
+		Abstract:		"Deep learning allows computational models that are composed of multiple processing layers to learn representations of data with multiple levels of abstraction.",
+		DOI:			"10.1038/nature14539",
+		CitationCount:	intPtr(61243),
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("got  %+v\nwant %+v", md, want)
+	}
+
+	req := api.Requests()[0]
+	if got := req.Header.Get("User-Agent"); got != "ResearchAssistant (mailto:admin@example.org)" {
+		t.Errorf("User-Agent %q", got)
+	}
+	if got := req.Header.Get("Accept"); got != "application/json" {
+		t.Errorf("Accept %q", got)
+	}
+}
+
+func TestCrossrefEscapesDOI(t *testing.T) {
+	api := newStubAPI(t, nil)
+	cr := NewCrossref(NewClient(nil, ""), api.URL)
+
+	cr.Resolve(context.Background(), Identifier{DOI: "10.1002/(SICI)1097-4571#x"})
+	if got := api.Requests()[0].URL.EscapedPath(); got != "/works/10.1002/%28SICI%291097-4571%23x" {
+		t.Errorf("path %s", got)
+	}
+}
+
+func TestCrossrefSkipsArXivDOIs(t *testing.T) {
+	api := newStubAPI(t, nil)
+	cr := NewCrossref(NewClient(nil, ""), api.URL)
+
+	for _, id := range []Identifier{{ArXivID: "1706.03762"}, {DOI: "10.48550/arXiv.1706.03762"}} {
+		if _, err := cr.Resolve(context.Background(), id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err %v, want ErrNotFound", id, err)
+		}
+	}
+	if n := len(api.Requests()); n != 0 {
+		t.Errorf("API got %d requests, want none", n)
+	}
+}
+
+func TestCrossrefNotFound(t *testing.T) {
+	api := newStubAPI(t, nil)
+	cr := NewCrossref(NewClient(nil, ""), api.URL)
+
+	if _, err := cr.Resolve(context.Background(), Identifier{DOI: "10.1000/missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err %v, want ErrNotFound", err)
+	}
+}
+
+func TestCrossrefServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	cr := NewCrossref(NewClient(nil, ""), srv.URL)
+
+	_, err := cr.Resolve(context.Background(), Identifier{DOI: "10.1038/nature14539"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable || !apiErr.Temporary() {
+		t.Errorf("err %v, want a temporary APIError with status 503", err)
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,144 @@
+package scholar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+
+// Identifier names a paper to look up. Resolvers use whichever of the two they
+// understand.
+type Identifier struct {
+	DOI			string
+	ArXivID		string
+}
+
+// Metadata is what a resolver knows about a paper. Fields it doesn't know are
+// left empty.
+type Metadata struct {
+	Title			string
+	Authors			[]string
+	Year			int
+	Venue			string
+	Abstract		string
+	DOI				string
+	ArXivID			string
+	CitationCount	*int
+	OpenAccessURL	string
+}
+
+// Resolver looks up a paper in one scholarly API. It returns ErrNotFound when the
+// API doesn't know the paper or can't look up this kind of identifier.
+type Resolver interface {
+	Name() string
+	Resolve(ctx context.Context, id Identifier) (*Metadata, error)
+}
+
+var ErrNotFound = errors.New("paper not found")
+
+// arXivDOIPrefix is the prefix of the DOIs arXiv registers for its papers, which
+// end in the arXiv ID.
+const arXivDOIPrefix = "10.48550/arxiv."
+
+
+func (id Identifier) IsZero() bool {
+	return id.DOI == "" && id.ArXivID == ""
+}
+
+func (id Identifier) String() string {
+	switch {
+	case id.DOI != "" && id.ArXivID != "":
+		return fmt.Sprintf("doi:%s arXiv:%s", id.DOI, id.ArXivID)
+	case id.DOI != "":
+		return "doi:" + id.DOI
+	}
+	return "arXiv:" + id.ArXivID
+}
+
+// arXivID returns the arXiv ID of id, taken from an arXiv DOI if need be.
+func (id Identifier) arXivID() string {
+	if id.ArXivID != "" {
+		return id.ArXivID
+	}
+	if strings.HasPrefix(strings.ToLower(id.DOI), arXivDOIPrefix) {
+		return id.DOI[len(arXivDOIPrefix):]
+	}
+	return ""
+}
+
+// Lookup asks the resolvers in turn and merges their answers, each field taken
+// from the first resolver that has it. Identifiers learned along the way, such
+// as the journal DOI of an arXiv paper, are passed on to the resolvers after. It
+// returns ErrNotFound if no resolver knows the paper, and the first other error
+// if some resolver failed and none found it.
+func Lookup(ctx context.Context, resolvers []Resolver, id Identifier) (*Metadata, []string, error) {
+	var (
+		merged		Metadata
+		sources		[]string
+		failure		error
+	)
+	for _, r := range resolvers {
+		md, err := r.Resolve(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			if failure == nil {
+				failure = fmt.Errorf("%s: %w", r.Name(), err)
+			}
+			continue
+		}
+
+		sources = append(sources, r.Name())
+		merge(&merged, md)
+		if id.DOI == "" {
+			id.DOI = md.DOI
+		}
+		if id.ArXivID == "" {
+			id.ArXivID = md.ArXivID
+		}
+	}
+
+	if len(sources) == 0 {
+		if failure != nil {
+			return nil, nil, failure
+		}
+		return nil, nil, ErrNotFound
+	}
+	return &merged, sources, nil
+}
+
+func merge(dst, src *Metadata) {
+	setString(&dst.Title, src.Title)
+	if len(dst.Authors) == 0 {
+		dst.Authors = src.Authors
+	}
+	if dst.Year == 0 {
+		dst.Year = src.Year
+	}
+	setString(&dst.Venue, src.Venue)
+	setString(&dst.Abstract, src.Abstract)
+	setString(&dst.DOI, src.DOI)
+	setString(&dst.ArXivID, src.ArXivID)
+	if dst.CitationCount == nil {
+		dst.CitationCount = src.CitationCount
+	}
+	setString(&dst.OpenAccessURL, src.OpenAccessURL)
+}
+
+func setString(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+// cleanText collapses the whitespace of titles and abstracts, which APIs return
+// with the line breaks of the source.
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
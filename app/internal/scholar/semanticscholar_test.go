@@ -0,0 +1,83 @@
+package scholar
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+
+func TestSemanticScholarResolveDOI(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/graph/v1/paper/DOI:10.1038/nature14539": "semanticscholar_doi.json"})
+	s := NewSemanticScholar(NewClient(nil, ""), api.URL+"/graph/v1", "")
+
+	md, err := s.Resolve(context.Background(), Identifier{DOI: "10.1038/nature14539"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Metadata{
+		Title:			"Deep Learning",
+		Authors:		[]string{"Yann LeCun", "Yoshua Bengio", "Geoffrey E. Hinton"},
+		Year:			2015,
+		Venue:			"Nature",
+		DOI:			"10.1038/nature14539",
+		CitationCount:	intPtr(58736),
+		OpenAccessURL:	"https://www.nature.com/articles/nature14539.pdf",
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("got  %+v\nwant %+v", md, want)
+	}
+
+	req := api.Requests()[0]
+	if got := strings.Split(req.URL.Query().Get("fields"), ","); !reflect.DeepEqual(got, strings.Split(semanticScholarFields, ",")) {
+		t.Errorf("fields %v", got)
+	}
+	if _, ok := req.Header["X-Api-Key"]; ok {
+		t.Error("x-api-key sent without a key")
+	}
+}
+
+func TestSemanticScholarResolveArXiv(t *testing.T) {
+	api := newStubAPI(t, map[string]string{"/paper/ARXIV:1706.03762": "semanticscholar_paper.json"})
+	s := NewSemanticScholar(NewClient(nil, ""), api.URL, "secret-key")
+
+	md, err := s.Resolve(context.Background(), Identifier{ArXivID: "1706.03762"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Metadata{
+		Title:			"Attention is All you Need",
+		Authors:		[]string{"Ashish Vaswani", "Noam M. Shazeer", "Niki Parmar"},
+		Year:			2017,
+		Venue:			"Neural Information Processing Systems",
+		Abstract:		"The dominant sequence transduction models are based on complex recurrent or convolutional neural networks in an encoder-decoder configuration.",
+		ArXivID:		"1706.03762",
+		CitationCount:	intPtr(104117),
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("got  %+v\nwant %+v", md, want)
+	}
+
+	if got := api.Requests()[0].Header.Get("x-api-key"); got != "secret-key" {
+		t.Errorf("x-api-key %q", got)
+	}
+}
+
+func TestSemanticScholarNotFound(t *testing.T) {
+	api := newStubAPI(t, nil)
+	s := NewSemanticScholar(NewClient(nil, ""), api.URL, "")
+
+	if _, err := s.Resolve(context.Background(), Identifier{DOI: "10.1000/missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err %v, want ErrNotFound", err)
+	}
+	if _, err := s.Resolve(context.Background(), Identifier{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("no identifier: err %v, want ErrNotFound", err)
+	}
+	if n := len(api.Requests()); n != 1 {
+		t.Errorf("API got %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,202 @@
+package scholar
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// Enricher looks up the metadata of pending documents one at a time in the
+// background, like the OCR worker: the queue lives in the database as the
+// documents' pending status, and the channel only saves polling.
+type Enricher struct {
+	Resolvers		[]Resolver
+	Docs			repository.DocumentRepository
+	// Alerts, if set, is told when enrichment of a document ends, since saved
+	// searches wait for the metadata found.
+	Alerts			ingest.AlertQueue
+
+	queue			chan uint
+	overflow		atomic.Bool
+}
+
+const (
+	queueSize		= 256
+	// lookupTimeout bounds the lookup of one document, rate-limit waits included.
+	lookupTimeout	= 2 * time.Minute
+)
+
+
+func NewEnricher(resolvers []Resolver, docs repository.DocumentRepository) *Enricher {
+	log.Println("Initializing metadata enricher...")
+	return &Enricher{Resolvers: resolvers, Docs: docs, queue: make(chan uint, queueSize)}
+}
+
+// Enqueue schedules enrichment of a document whose status is already pending. It
+// never blocks; when the channel is full the pending documents are looked up
+// again once it has drained.
+func (e *Enricher) Enqueue(docID uint) {
+	select {
+	case e.queue <- docID:
+	default:
+		e.overflow.Store(true)
+		log.Printf("Enrichment queue is full; document ID=%d will be picked up later\n", docID)
+	}
+}
+
+// Run processes documents until ctx is cancelled, starting with those left
+// pending by a previous run.
+func (e *Enricher) Run(ctx context.Context) {
+	log.Println("Metadata enricher started")
+	e.overflow.Store(true)
+
+	for {
+		if len(e.queue) == 0 && e.overflow.Swap(false) {
+			e.resume(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Metadata enricher stopped")
+			return
+		case id := <-e.queue:
+			e.process(ctx, id)
+		}
+	}
+}
+
+func (e *Enricher) resume(ctx context.Context) {
+	ids, err := e.Docs.PendingEnrichment()
+	if err != nil {
+		log.Printf("Failed to list documents pending enrichment: %v\n", err)
+		return
+	}
+	if len(ids) > 0 {
+		log.Printf("Resuming enrichment of %d pending documents\n", len(ids))
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		e.process(ctx, id)
+	}
+}
+
+// process looks up a document by its DOI or arXiv ID and stores what the
+// resolvers know. The canonical title, year and authors replace those given at
+// upload. Since a new title can match references, the document's citations are
+// linked again afterwards.
+func (e *Enricher) process(ctx context.Context, id uint) {
+	doc, err := e.Docs.GetByDocumentID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Enrichment of document ID=%d: Failed to fetch document: %v\n", id, err)
+		return
+	}
+	if doc.EnrichmentStatus != model.EnrichmentPending {
+		return
+	}
+	ident := Identifier{DOI: doc.DOI, ArXivID: doc.ArXivID}
+	if ident.IsZero() {
+		e.setStatus(id, model.EnrichmentNotFound)
+		return
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	md, sources, err := Lookup(lookupCtx, e.Resolvers, ident)
+	cancel()
+	switch {
+	case ctx.Err() != nil:
+		// Still pending, so the next run starts over.
+		return
+	case errors.Is(err, ErrNotFound):
+		log.Printf("Enrichment of document ID=%d: %s not found\n", id, ident)
+		e.setStatus(id, model.EnrichmentNotFound)
+		return
+	case err != nil:
+		log.Printf("Enrichment of document ID=%d failed: %v\n", id, err)
+		e.setStatus(id, model.EnrichmentFailed)
+		return
+	}
+
+	apply(&doc, md)
+	doc.EnrichmentStatus = model.EnrichmentDone
+	err = e.Docs.SaveEnrichment(&doc)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Discarding enrichment of document ID=%d: document changed meanwhile\n", id)
+		return
+	}
+	if err != nil {
+		log.Printf("Enrichment of document ID=%d: Failed to save metadata: %v\n", id, err)
+		return
+	}
+
+	if _, err := e.Docs.LinkReferences(&doc); err != nil {
+		log.Printf("Enrichment of document ID=%d: Failed to link references: %v\n", id, err)
+	}
+	e.queueAlerts(id)
+	log.Printf("Enriched document ID=%d from %s\n", id, strings.Join(sources, ", "))
+}
+
+// apply copies the metadata found onto doc. Identifiers the document already has
+// are kept, since they were read from the file itself.
+func apply(doc *model.Document, md *Metadata) {
+	if md.Title != "" {
+		doc.Title = truncate(md.Title, 255)
+	}
+	if md.Year != 0 {
+		doc.Year = md.Year
+	}
+	if len(md.Authors) > 0 {
+		doc.Authors = make([]model.DocumentAuthor, len(md.Authors))
+		for i, name := range md.Authors {
+			doc.Authors[i] = model.DocumentAuthor{Name: truncate(name, 255), Position: i}
+		}
+	}
+	doc.Venue = truncate(md.Venue, 255)
+	doc.Abstract = truncate(md.Abstract, 16000)
+	doc.CitationCount = md.CitationCount
+	if len(md.OpenAccessURL) <= 1024 {
+		doc.OpenAccessURL = md.OpenAccessURL
+	}
+	if doc.DOI == "" && len(md.DOI) <= 255 {
+		doc.DOI = md.DOI
+	}
+	if doc.ArXivID == "" && len(md.ArXivID) <= 32 {
+		doc.ArXivID = md.ArXivID
+	}
+	now := time.Now()
+	doc.EnrichedAt = &now
+}
+
+func (e *Enricher) setStatus(id uint, status string) {
+	if err := e.Docs.SetEnrichmentStatus(id, status); err != nil {
+		log.Printf("Failed to set enrichment status of document ID=%d: %v\n", id, err)
+		return
+	}
+	e.queueAlerts(id)
+}
+
+func (e *Enricher) queueAlerts(id uint) {
+	if e.Alerts != nil {
+		e.Alerts.Enqueue(id)
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+
+// PartialPath is where the data received so far for a resumable upload is kept.
+func (s *Store) PartialPath(id string) string {
+	return filepath.Join(s.Root, "partial", id)
+}
+
+// CreatePartial creates the empty file of a new resumable upload.
+func (s *Store) CreatePartial(id string) error {
+	if err := os.MkdirAll(filepath.Join(s.Root, "partial"), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.PartialPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// AppendPartial writes r to a resumable upload starting at offset and returns how
+// many bytes were written. Anything past offset left by an interrupted request is
+// discarded first. Bytes written before a failure still count, so the client can
+// resume right after them.
+func (s *Store) AppendPartial(id string, offset int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.PartialPath(id), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if serr := f.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// RemovePartial deletes the file of a resumable upload, if it still exists.
+func (s *Store) RemovePartial(id string) error {
+	if err := os.Remove(s.PartialPath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"backend/internal/repository"
+)
+
+
+// Store keeps files under Root by the SHA-256 hash of their content, so a file
+// uploaded many times, by one user or several, is only stored once. Blob records
+// count the references, and a file is removed with its last reference.
+type Store struct {
+	Root		string
+	Blobs		repository.BlobRepository
+
+	// mu orders moving a file into place against removing it, so a Put racing the
+	// Release of the same content can't lose the file.
+	mu			sync.Mutex
+}
+
+
+func NewStore(root string, blobs repository.BlobRepository) *Store {
+	return &Store{Root: root, Blobs: blobs}
+}
+
+// Path is where the content with the given hash is stored, fanned out by the first
+// two bytes of the hash to keep directories small.
+func (s *Store) Path(hash string) string {
+	return filepath.Join(s.Root, hash[:2], hash[2:4], hash)
+}
+
+// Put stores r and takes a reference to it, returning the content hash and size.
+// Every Put must be balanced by a Release once the file is no longer used.
+func (s *Store) Put(r io.Reader) (string, int64, error) {
+	tmpDir := filepath.Join(s.Root, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	if err := s.place(tmp.Name(), hash, size); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+// Adopt moves a file that is already on disk, such as a finished resumable upload,
+// into the store and takes a reference to it like Put. The file at path is gone
+// afterwards whether or not Adopt succeeds.
+func (s *Store) Adopt(path string) (string, int64, error) {
+	defer os.Remove(path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return "", 0, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	if err := s.place(path, hash, size); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+// place renames a fully written file to its content address, unless the content is
+// already stored, and records the reference. The rename keeps readers from ever
+// seeing a partial file.
+func (s *Store) place(tmp, hash string, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.Path(hash)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return s.Blobs.Acquire(hash, size)
+}
+
+// Release drops a reference taken by Put and deletes the file with the last one.
+func (s *Store) Release(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining, err := s.Blobs.Release(hash)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		if err := os.Remove(s.Path(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+
+const (
+	DiffEqual	= " "
+	DiffInsert	= "+"
+	DiffDelete	= "-"
+)
+
+// maxDiffEdits bounds the work spent on a diff. Texts further apart than this are
+// reported as one replacement instead of a minimal edit script.
+const maxDiffEdits = 2000
+
+// DiffLine is one line of an edit script with its operation: " ", "+" or "-".
+type DiffLine struct {
+	Op				string		`json:"op"`
+	Text			string		`json:"text"`
+}
+
+// DiffHunk is a run of changes with surrounding context. Line numbers are 1-based
+// as in a unified diff.
+type DiffHunk struct {
+	FromLine		int			`json:"from_line"`
+	FromCount		int			`json:"from_count"`
+	ToLine			int			`json:"to_line"`
+	ToCount			int			`json:"to_count"`
+	Lines			[]DiffLine	`json:"lines"`
+}
+
+
+// DiffLines computes a line edit script turning a into b with Myers' algorithm.
+func DiffLines(a, b []string) []DiffLine {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var out []DiffLine
+	for _, l := range a[:pre] {
+		out = append(out, DiffLine{DiffEqual, l})
+	}
+	out = append(out, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, l := range a[len(a)-suf:] {
+		out = append(out, DiffLine{DiffEqual, l})
+	}
+	return out
+}
+
+func myers(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+
+	limit := min(n+m, maxDiffEdits)
+	off := limit + 1
+	v := make([]int, 2*off+1)
+
+	// trace[d] holds the furthest x of every diagonal -d..d before step d, which
+	// is all the backtracking needs and keeps memory quadratic in the edits only.
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replace(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string) []DiffLine {
+	x, y := len(a), len(b)
+	var rev []DiffLine
+
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			rev = append(rev, DiffLine{DiffEqual, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			rev = append(rev, DiffLine{DiffInsert, b[y-1]})
+			y--
+		} else {
+			rev = append(rev, DiffLine{DiffDelete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		rev = append(rev, DiffLine{DiffEqual, a[x-1]})
+		x--
+		y--
+	}
+
+	out := make([]DiffLine, len(rev))
+	for i, l := range rev {
+		out[len(rev)-1-i] = l
+	}
+	return out
+}
+
+func replace(a, b []string) []DiffLine {
+	out := make([]DiffLine, 0, len(a)+len(b))
+	for _, l := range a {
+		out = append(out, DiffLine{DiffDelete, l})
+	}
+	for _, l := range b {
+		out = append(out, DiffLine{DiffInsert, l})
+	}
+	return out
+}
+
+// DiffHunks groups an edit script into hunks with up to context unchanged lines
+// around each change. Changes closer than twice the context share a hunk.
+func DiffHunks(lines []DiffLine, context int) []DiffHunk {
+	// Line numbers in a and b before each position of the script.
+	from := make([]int, len(lines)+1)
+	to := make([]int, len(lines)+1)
+	for i, l := range lines {
+		from[i+1], to[i+1] = from[i], to[i]
+		if l.Op != DiffInsert {
+			from[i+1]++
+		}
+		if l.Op != DiffDelete {
+			to[i+1]++
+		}
+	}
+
+	var hunks []DiffHunk
+	start, end := -1, -1
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		hunks = append(hunks, DiffHunk{
+			FromLine:	from[start] + 1,
+			FromCount:	from[end] - from[start],
+			ToLine:		to[start] + 1,
+			ToCount:	to[end] - to[start],
+			Lines:		lines[start:end],
+		})
+	}
+
+	for i, l := range lines {
+		if l.Op == DiffEqual {
+			continue
+		}
+		lo, hi := max(i-context, 0), min(i+context+1, len(lines))
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		flush()
+		start, end = lo, hi
+	}
+	flush()
+	return hunks
+}
+
+// UnifiedDiff renders hunks in the unified diff format.
+func UnifiedDiff(fromName, toName string, hunks []DiffHunk) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.FromLine, h.FromCount, h.ToLine, h.ToCount)
+		for _, l := range h.Lines {
+			b.WriteString(l.Op)
+			b.WriteString(l.Text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
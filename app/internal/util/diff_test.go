@@ -0,0 +1,151 @@
+package util
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+
+// TestDiffLinesRoundTrip checks that every edit script rebuilds both texts and
+// is no longer than the shortest one.
+func TestDiffLinesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name	string
+		a		string
+		b		string
+	}{
+		{"both empty", "", ""},
+		{"from empty", "", "one\ntwo"},
+		{"to empty", "one\ntwo", ""},
+		{"identical", "one\ntwo\nthree", "one\ntwo\nthree"},
+		{"fully different", "a\nb\nc", "x\ny\nz\nw"},
+		{"one line changed", "a\nb\nc\nd", "a\nB\nc\nd"},
+		{"insert in middle", "a\nc", "a\nb\nc"},
+		{"delete at ends", "x\na\nb\ny", "a\nb"},
+		{"myers paper", "A\nB\nC\nA\nB\nB\nA", "C\nB\nA\nB\nA\nC"},
+		{"repeated lines", "a\na\na\nb", "b\na\na\na"},
+		{"unicode", "Grüße\n数学の論文\ncafé\n🙂 emoji", "Grüße\n数学の論文\ncafé\n🙂 emoji\nΣ ≠ ∑"},
+		{"unicode only", "日本語", "中文"},
+		{"blank lines", "\n\n\n", "\n\n"},
+	}
+	for _, tt := range tests {
+		a, b := split(tt.a), split(tt.b)
+		script := DiffLines(a, b)
+		checkScript(t, tt.name, a, b, script)
+	}
+}
+
+// TestDiffLinesRandom compares the edit scripts of random texts over a small
+// alphabet, where lines repeat often, with the edit distance found by dynamic
+// programming.
+func TestDiffLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	text := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := text(), text()
+		checkScript(t, strings.Join(a, "")+" to "+strings.Join(b, ""), a, b, DiffLines(a, b))
+	}
+}
+
+// TestDiffLinesLimit checks that texts further apart than maxDiffEdits still
+// get a script that rebuilds them.
+func TestDiffLinesLimit(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxDiffEdits; i++ {
+		a = append(a, "a"+strconv.Itoa(i))
+		b = append(b, "b"+strconv.Itoa(i))
+	}
+	script := DiffLines(a, b)
+	if len(script) != 2*maxDiffEdits {
+		t.Errorf("got %d lines, want %d", len(script), 2*maxDiffEdits)
+	}
+	from, to := rebuild(script)
+	if !slices.Equal(from, a) || !slices.Equal(to, b) {
+		t.Error("script does not rebuild the texts")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := split("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12")
+	b := split("1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13")
+	got := UnifiedDiff("v1", "v2", DiffHunks(DiffLines(a, b), 2))
+	want := "--- v1\n+++ v2\n" +
+		"@@ -1,4 +1,4 @@\n 1\n-2\n+two\n 3\n 4\n" +
+		"@@ -11,2 +11,3 @@\n 11\n 12\n+13\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if hunks := DiffHunks(DiffLines(a, a), 3); len(hunks) != 0 {
+		t.Errorf("identical texts: got %d hunks, want 0", len(hunks))
+	}
+}
+
+func checkScript(t *testing.T, name string, a, b []string, script []DiffLine) {
+	t.Helper()
+	from, to := rebuild(script)
+	if !slices.Equal(from, a) {
+		t.Errorf("%s: script rebuilds %q, want %q", name, from, a)
+	}
+	if !slices.Equal(to, b) {
+		t.Errorf("%s: script rebuilds %q, want %q", name, to, b)
+	}
+	edits := 0
+	for _, l := range script {
+		if l.Op != DiffEqual {
+			edits++
+		}
+	}
+	if want := editDistance(a, b); edits != want {
+		t.Errorf("%s: got %d edits, want %d", name, edits, want)
+	}
+}
+
+// rebuild reads the old text from the script's kept and deleted lines, and the
+// new one from its kept and inserted lines.
+func rebuild(script []DiffLine) (from, to []string) {
+	from, to = []string{}, []string{}
+	for _, l := range script {
+		if l.Op != DiffInsert {
+			from = append(from, l.Text)
+		}
+		if l.Op != DiffDelete {
+			to = append(to, l.Text)
+		}
+	}
+	return from, to
+}
+
+// editDistance is the number of lines inserted and deleted by the shortest
+// edit script, found from the longest common subsequence.
+func editDistance(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func split(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
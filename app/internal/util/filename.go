@@ -0,0 +1,48 @@
+package util
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+
+const maxFilenameLength = 255
+
+// SanitizeFilename reduces a client-supplied file name to a plain base name that is
+// safe to log, show and send back in headers: directories, control characters and
+// leading dots are dropped, and the result is valid UTF-8 of at most 255 bytes, shortened before its
+// extension.
+func SanitizeFilename(name string) string {
+	name = strings.ToValidUTF8(name, "")
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), r == '/', r == '"':
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+
+	if len(name) > maxFilenameLength {
+		ext := path.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		stem := strings.TrimSuffix(name, ext)
+		for len(stem)+len(ext) > maxFilenameLength {
+			_, size := utf8.DecodeLastRuneInString(stem)
+			stem = stem[:len(stem)-size]
+		}
+		name = stem + ext
+	}
+	if name == "" {
+		return "upload"
+	}
+	return name
+}
@@ -0,0 +1,185 @@
+package util
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+
+const (
+	// fingerprintHashes is the number of MinHash values in a fingerprint.
+	fingerprintHashes	= 64
+	// shingleSize is the number of words per shingle.
+	shingleSize			= 5
+	// bands and rows split a fingerprint for locality-sensitive hashing; two
+	// documents become candidates when any band matches exactly.
+	lshBands			= 16
+	lshRows				= fingerprintHashes / lshBands
+)
+
+// fingerprintSeeds are fixed so fingerprints stay comparable across restarts.
+var fingerprintSeeds = func() [fingerprintHashes]uint64 {
+	var seeds [fingerprintHashes]uint64
+	x := uint64(0x5eed)
+	for i := range seeds {
+		x = splitmix64(x)
+		seeds[i] = x
+	}
+	return seeds
+}()
+
+// FingerprintedDocument pairs a document ID with its fingerprint for grouping.
+type FingerprintedDocument struct {
+	ID				uint
+	Fingerprint		[]byte
+}
+
+// SimilarGroup is a set of documents whose texts are near duplicates. Similarity
+// is the lowest estimated similarity of the pairs that joined the group.
+type SimilarGroup struct {
+	IDs				[]uint
+	Similarity		float64
+}
+
+
+// Fingerprint computes a MinHash signature over the word shingles of text. The
+// share of equal positions in two signatures estimates the Jaccard similarity of
+// the texts, which survives the small edits between a preprint and its published
+// version. It returns an empty fingerprint for texts too short to compare.
+func Fingerprint(text string) []byte {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < shingleSize*4 {
+		return []byte{}
+	}
+
+	var mins [fingerprintHashes]uint32
+	for i := range mins {
+		mins[i] = ^uint32(0)
+	}
+
+	h := fnv.New64a()
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h.Reset()
+		for _, w := range words[i : i+shingleSize] {
+			h.Write([]byte(w))
+			h.Write([]byte{' '})
+		}
+		base := h.Sum64()
+
+		for j, seed := range fingerprintSeeds {
+			v := uint32(splitmix64(base ^ seed))
+			if v < mins[j] {
+				mins[j] = v
+			}
+		}
+	}
+
+	fp := make([]byte, 4*fingerprintHashes)
+	for i, v := range mins {
+		binary.LittleEndian.PutUint32(fp[4*i:], v)
+	}
+	return fp
+}
+
+// Similarity estimates how alike the texts behind two fingerprints are, from 0 to 1.
+func Similarity(a, b []byte) float64 {
+	if len(a) != 4*fingerprintHashes || len(b) != len(a) {
+		return 0
+	}
+
+	equal := 0
+	for i := 0; i < len(a); i += 4 {
+		if binary.LittleEndian.Uint32(a[i:]) == binary.LittleEndian.Uint32(b[i:]) {
+			equal++
+		}
+	}
+	return float64(equal) / fingerprintHashes
+}
+
+// GroupSimilar groups documents whose fingerprints are at least threshold alike.
+// Candidate pairs come from banded hashing, so the whole library isn't compared
+// pairwise, and pairs are joined transitively.
+func GroupSimilar(docs []FingerprintedDocument, threshold float64) []SimilarGroup {
+	parent := make([]int, len(docs))
+	lowest := make([]float64, len(docs))
+	for i := range parent {
+		parent[i] = i
+		lowest[i] = 1
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	checked := map[[2]int]bool{}
+	for band := 0; band < lshBands; band++ {
+		buckets := map[string][]int{}
+		for i, d := range docs {
+			if len(d.Fingerprint) != 4*fingerprintHashes {
+				continue
+			}
+			key := string(d.Fingerprint[4*lshRows*band : 4*lshRows*(band+1)])
+			buckets[key] = append(buckets[key], i)
+		}
+
+		for _, members := range buckets {
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					i, j := members[x], members[y]
+					if checked[[2]int{i, j}] {
+						continue
+					}
+					checked[[2]int{i, j}] = true
+
+					sim := Similarity(docs[i].Fingerprint, docs[j].Fingerprint)
+					if sim < threshold {
+						continue
+					}
+					ri, rj := find(i), find(j)
+					low := min(sim, lowest[ri], lowest[rj])
+					if ri != rj {
+						parent[rj] = ri
+					}
+					lowest[ri] = low
+				}
+			}
+		}
+	}
+
+	byRoot := map[int]*SimilarGroup{}
+	var roots []int
+	for i, d := range docs {
+		r := find(i)
+		g, ok := byRoot[r]
+		if !ok {
+			g = &SimilarGroup{Similarity: lowest[r]}
+			byRoot[r] = g
+			roots = append(roots, r)
+		}
+		g.IDs = append(g.IDs, d.ID)
+	}
+
+	var groups []SimilarGroup
+	for _, r := range roots {
+		if g := byRoot[r]; len(g.IDs) > 1 {
+			groups = append(groups, *g)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Similarity > groups[j].Similarity })
+	return groups
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
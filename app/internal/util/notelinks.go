@@ -0,0 +1,62 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+
+	"backend/internal/model"
+)
+
+
+// Matches [[doc:123]], [[doc:123#p5]], [[note:45]] and an optional |label suffix.
+var noteLinkPattern = regexp.MustCompile(`\[\[(doc|note):(\d+)(?:#p(\d+))?(?:\|[^\]]*)?\]\]`)
+
+// ParseNoteLinks extracts the document and note references from a Markdown note body.
+// Duplicate references are collapsed so each target/page pair appears once.
+func ParseNoteLinks(body string) []model.NoteLink {
+	var links []model.NoteLink
+	seen := make(map[model.NoteLink]bool)
+
+	for _, m := range noteLinkPattern.FindAllStringSubmatch(body, -1) {
+		id, err := strconv.ParseUint(m[2], 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+
+		link := model.NoteLink{TargetType: m[1], TargetID: uint(id)}
+		if m[3] != "" && link.TargetType == model.NoteLinkDocument {
+			page, err := strconv.Atoi(m[3])
+			if err == nil {
+				link.Page = page
+			}
+		}
+
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// Matches the start of a document link up to the character after its ID, so that
+// [[doc:5 doesn't also match [[doc:56.
+var docLinkPattern = regexp.MustCompile(`\[\[doc:(\d+)([#|\]])`)
+
+// RetargetDocLinks rewrites links to the documents in moved so they point at the
+// mapped document instead, keeping any page anchor and label.
+func RetargetDocLinks(body string, moved map[uint]uint) string {
+	return docLinkPattern.ReplaceAllStringFunc(body, func(m string) string {
+		sub := docLinkPattern.FindStringSubmatch(m)
+		id, err := strconv.ParseUint(sub[1], 10, 64)
+		if err != nil {
+			return m
+		}
+		if to, ok := moved[uint(id)]; ok {
+			return "[[doc:" + strconv.FormatUint(uint64(to), 10) + sub[2]
+		}
+		return m
+	})
+}
@@ -1,37 +1,224 @@
 package util
 
 import (
-	"bytes"
-	"io"
+	"errors"
+	"fmt"
+	"math"
 	"os"
+	"strings"
+	"unicode"
 
 	"github.com/ledongthuc/pdf"
+
+	"backend/internal/model"
 )
 
 
-func ExtractTextFromPDF(path string) (string, error) {
-	f, rErr := os.Open(path)
-	if rErr != nil {
-		return "", rErr
+// minTextRunes is how many letters or digits a text layer needs before a PDF
+// counts as having one. Scans often carry a few stray characters from headers.
+const minTextRunes = 32
+
+// ExtractTextFromPDF reads the text layer of the PDF at path, trying password if
+// the file is encrypted. The returned status classifies the outcome as one of the
+// model.Extraction* values, and err explains any status other than ok. Only a file
+// that can't be opened at all is reported as an error with an empty status.
+func ExtractTextFromPDF(path, password string) (text string, status string, err error) {
+	pages, status, err := ExtractPDFPages(path, password)
+	return strings.Join(pages, ""), status, err
+}
+
+// ExtractPDFPages is ExtractTextFromPDF with the text kept apart per page. The
+// pages are returned even when the document as a whole has no text layer, so
+// that the scanned ones can be found with PagesWithoutText.
+func ExtractPDFPages(path, password string) (pages []string, status string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
 	}
 	defer f.Close()
 
-	reader, err := pdf.NewReader(f, getSize(f))
+	size, err := getSize(f)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
-	var buf bytes.Buffer
-	b, err := reader.GetPlainText()
+	// The parser indexes into the file as the xref table says, and panics on
+	// files that lie about it.
+	defer func() {
+		if p := recover(); p != nil {
+			pages, status, err = nil, model.ExtractionCorrupt, fmt.Errorf("malformed PDF: parser panic: %v", p)
+		}
+	}()
+
+	reader, err := openPDF(f, size, password)
 	if err != nil {
-		return "", err
+		return nil, classifyPDFError(err), err
 	}
 
-	_, err = io.Copy(&buf, b)
-	return buf.String(), err
+	// Fonts are shared between pages, so their character maps are parsed once.
+	fonts := make(map[string]*pdf.Font)
+	n := reader.NumPage()
+	pages = make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		p := reader.Page(i)
+		for _, name := range p.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := p.Font(name)
+				fonts[name] = &font
+			}
+		}
+		text, err := p.GetPlainText(fonts)
+		if err != nil {
+			return nil, classifyPDFError(err), fmt.Errorf("page %d: %w", i, err)
+		}
+		pages = append(pages, text)
+	}
+
+	if !hasTextLayer(strings.Join(pages, "")) {
+		return pages, model.ExtractionNoText, errors.New("no text layer; the PDF may be scanned images")
+	}
+	return pages, model.ExtractionOK, nil
+}
+
+// PagesWithoutText returns the 1-based numbers of the pages that have too little
+// text to have a text layer, which are the ones worth running OCR on.
+func PagesWithoutText(pages []string) []int {
+	var missing []int
+	for i, text := range pages {
+		if !hasTextLayer(text) {
+			missing = append(missing, i+1)
+		}
+	}
+	return missing
 }
 
-func getSize(file *os.File) int64 {
-	fi, _ := file.Stat()
-	return fi.Size()
-}
\ No newline at end of file
+// TextLine is a line of a page with the font size most of it is set in, or 0
+// when the size isn't known, as for OCR text.
+type TextLine struct {
+	Text		string
+	Size		float64
+	Page		int
+	// Heading is the level of a line that its source marks as a heading, such
+	// as a heading of a web page, and 0 for lines left to the heuristics.
+	Heading		int
+}
+
+// ExtractPDFLines reads the text of the PDF at path as lines along with their
+// font sizes, which is what tells headings apart from body text. The lines are
+// rebuilt from glyph positions, so spacing may differ slightly from
+// ExtractPDFPages.
+func ExtractPDFLines(path, password string) (lines []TextLine, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size, err := getSize(f)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			lines, err = nil, fmt.Errorf("malformed PDF: parser panic: %v", p)
+		}
+	}()
+
+	reader, err := openPDF(f, size, password)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i <= reader.NumPage(); i++ {
+		lines = append(lines, glyphLines(reader.Page(i).Content().Text, i)...)
+	}
+	return lines, nil
+}
+
+// glyphLines joins the glyphs of a page into lines. A glyph starts a new line
+// when it moves vertically by more than half the font size, and a space is put
+// in wherever the horizontal gap between glyphs is wider than a fifth of it.
+func glyphLines(glyphs []pdf.Text, page int) []TextLine {
+	var (
+		lines		[]TextLine
+		b			strings.Builder
+		sizes		= map[float64]int{}
+		lastY		float64
+		lastEnd		float64
+		lastSize	float64
+	)
+	flush := func() {
+		text := strings.Join(strings.Fields(b.String()), " ")
+		if text != "" {
+			best, n := 0.0, 0
+			for s, c := range sizes {
+				if c > n || c == n && s > best {
+					best, n = s, c
+				}
+			}
+			lines = append(lines, TextLine{Text: text, Size: best, Page: page})
+		}
+		b.Reset()
+		clear(sizes)
+	}
+
+	for _, g := range glyphs {
+		em := math.Max(lastSize, 1)
+		switch {
+		case b.Len() == 0:
+		case math.Abs(g.Y-lastY) > em/2:
+			flush()
+		case g.X-lastEnd > em/5:
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(g.S)
+		if strings.TrimSpace(g.S) != "" {
+			sizes[math.Round(g.FontSize*2)/2]++
+		}
+		lastY, lastEnd, lastSize = g.Y, g.X+g.W, g.FontSize
+	}
+	flush()
+	return lines
+}
+
+func openPDF(f *os.File, size int64, password string) (*pdf.Reader, error) {
+	if password == "" {
+		return pdf.NewReader(f, size)
+	}
+	tried := false
+	return pdf.NewReaderEncrypted(f, size, func() string {
+		if tried {
+			return ""
+		}
+		tried = true
+		return password
+	})
+}
+
+func classifyPDFError(err error) string {
+	if errors.Is(err, pdf.ErrInvalidPassword) || strings.Contains(err.Error(), "encryption") {
+		return model.ExtractionEncrypted
+	}
+	return model.ExtractionCorrupt
+}
+
+func hasTextLayer(text string) bool {
+	n := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if n++; n >= minTextRunes {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getSize(file *os.File) (int64, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"backend/internal/model"
+)
+
+
+// The seed corpus in testdata/fuzz/FuzzExtractTextFromPDF has one PDF for each
+// outcome. The encrypted one opens with the user password "secret".
+func TestExtractTextFromPDFCorpus(t *testing.T) {
+	tests := []struct {
+		seed		string
+		password	string
+		want		string
+	}{
+		{"normal", "", model.ExtractionOK},
+		{"encrypted", "", model.ExtractionEncrypted},
+		{"encrypted", "wrong", model.ExtractionEncrypted},
+		{"encrypted", "secret", model.ExtractionOK},
+		{"image-only", "", model.ExtractionNoText},
+		{"truncated", "", model.ExtractionCorrupt},
+	}
+	for _, tt := range tests {
+		path := writePDF(t, readSeed(t, tt.seed))
+		text, status, err := ExtractTextFromPDF(path, tt.password)
+		if status != tt.want {
+			t.Errorf("%s with password %q: status %q (%v), want %q", tt.seed, tt.password, status, err, tt.want)
+			continue
+		}
+		if status == model.ExtractionOK && !strings.Contains(text, "quick brown fox") {
+			t.Errorf("%s with password %q: text %q lacks the page text", tt.seed, tt.password, text)
+		}
+	}
+}
+
+// FuzzExtractTextFromPDF checks that no input makes the parser panic through
+// the extraction functions, and that every input is classified.
+func FuzzExtractTextFromPDF(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writePDF(t, data)
+
+		_, status, err := ExtractTextFromPDF(path, "")
+		if !model.IsValidExtractionStatus(status) {
+			t.Fatalf("invalid status %q (%v)", status, err)
+		}
+		if (status == model.ExtractionOK) != (err == nil) {
+			t.Fatalf("status %q with error %v", status, err)
+		}
+
+		ExtractPDFLines(path, "")
+	})
+}
+
+func writePDF(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readSeed decodes a file of the fuzz corpus, which holds the input as a quoted
+// []byte literal after a version line.
+func readSeed(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("testdata", "fuzz", "FuzzExtractTextFromPDF", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, lit, _ := strings.Cut(strings.TrimSpace(string(b)), "\n")
+	s, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(lit, "[]byte("), ")"))
+	if err != nil {
+		t.Fatalf("seed %s: %v", name, err)
+	}
+	return []byte(s)
+}
@@ -0,0 +1,335 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"backend/internal/model"
+)
+
+
+var (
+	doiPattern			= regexp.MustCompile(`(?i)\b10\.\d{4,9}/[^\s"<>]+`)
+	// arXivPattern matches the identifiers arXiv stamps on its papers, new style
+	// "arXiv:2101.01234v2" and old style "arXiv:hep-th/9901001".
+	arXivPattern		= regexp.MustCompile(`(?i)\barxiv:\s?(\d{4}\.\d{4,5}|[a-z-]+(?:\.[A-Z]{2})?/\d{7})(?:v\d+)?\b`)
+	yearPattern			= regexp.MustCompile(`\b(19[5-9]\d|20\d\d)[a-z]?\b`)
+	// A year in parentheses is a year even when it is an old one.
+	parenYearPattern	= regexp.MustCompile(`\((1[6-9]\d\d|20\d\d)[a-z]?\)\.?\s*`)
+	// identifierPattern matches the DOIs, arXiv identifiers and links whose
+	// digits would otherwise be read as years.
+	identifierPattern	= regexp.MustCompile(`(?i)https?://\S+|\b10\.\d{4,9}/[^\s"<>]+|\barxiv:\s?\S+`)
+	quotedTitlePattern	= regexp.MustCompile(`[“"]([^”"]{10,300}?)[,.]?[”"]`)
+	bracketMarker		= regexp.MustCompile(`^\[(\d{1,3})\]\s*`)
+	numberMarker		= regexp.MustCompile(`^(\d{1,3})\.\s+`)
+	// authorStart matches the start of an author-year entry, "Smith, J." or
+	// "J. Smith" or "van der Berg, A.".
+	authorStart			= regexp.MustCompile(`^(?:(?:[a-z]{1,3} ){0,2}\p{Lu}[\p{L}'’-]+,\s+\p{Lu}\.|\p{Lu}\.\s?(?:\p{Lu}\.\s?)?\p{Lu}[\p{L}'’-]+,)`)
+)
+
+const (
+	maxReferences		= 1000
+	maxReferenceRunes	= 2000
+	// minMatchWords keeps short titles like "Introduction" from matching inside
+	// unrelated references.
+	minMatchWords		= 3
+)
+
+// CitableDocument is what a reference is matched against: a document in the
+// same library.
+type CitableDocument struct {
+	ID			uint
+	Title		string
+	Year		int
+	DOI			string
+}
+
+// ReferenceMatcher finds the library document a parsed reference refers to.
+type ReferenceMatcher struct {
+	byDOI		map[string]uint
+	titles		[]matchTitle
+}
+
+type matchTitle struct {
+	id			uint
+	year		int
+	norm		string
+}
+
+
+// FindDOI returns the first DOI in text, lowercased, or "".
+func FindDOI(text string) string {
+	return cleanDOI(doiPattern.FindString(text))
+}
+
+// FindArXivID returns the first arXiv identifier in text without its version, or "".
+func FindArXivID(text string) string {
+	m := arXivPattern.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func cleanDOI(doi string) string {
+	return strings.ToLower(strings.TrimRight(doi, ".,;)]}"))
+}
+
+// ReferencesFromSections parses the reference list in the sections of kind
+// references. Positions number the entries from 0.
+func ReferencesFromSections(sections []model.DocumentSection) []model.DocumentReference {
+	var text []string
+	for _, s := range sections {
+		if s.Kind == model.SectionReferences {
+			text = append(text, s.Text)
+		}
+	}
+	if len(text) == 0 {
+		return nil
+	}
+	return ParseReferences(strings.Join(text, "\n"))
+}
+
+// ParseReferences splits a reference list into entries and picks the authors,
+// title, year and DOI out of each. Numbered lists, "[1]" or "1.", are split at
+// the numbers; author-year lists at lines that start with an author name after a
+// line that ended an entry. Fields that can't be found are left empty, and the
+// raw entry is always kept.
+func ParseReferences(text string) []model.DocumentReference {
+	var refs []model.DocumentReference
+	for i, entry := range splitReferences(text) {
+		if i == maxReferences {
+			break
+		}
+		refs = append(refs, parseReference(entry, len(refs)))
+	}
+	return refs
+}
+
+func splitReferences(text string) []string {
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	bracketed, numbered := 0, 0
+	for _, l := range lines {
+		if bracketMarker.MatchString(l) {
+			bracketed++
+		}
+		if numberMarker.MatchString(l) {
+			numbered++
+		}
+	}
+	marker := (*regexp.Regexp)(nil)
+	switch {
+	case bracketed >= 2:
+		marker = bracketMarker
+	case numbered >= 2:
+		marker = numberMarker
+	}
+
+	var (
+		entries		[]string
+		cur			[]string
+	)
+	flush := func() {
+		if entry := joinLines(cur); len(strings.Fields(entry)) >= 3 {
+			entries = append(entries, entry)
+		}
+		cur = cur[:0]
+	}
+	for _, l := range lines {
+		var starts bool
+		if marker != nil {
+			if starts = marker.MatchString(l); starts {
+				l = marker.ReplaceAllString(l, "")
+			}
+		} else {
+			starts = len(cur) > 0 && endsEntry(cur[len(cur)-1]) && authorStart.MatchString(l)
+		}
+		if starts {
+			flush()
+		}
+		cur = append(cur, l)
+	}
+	flush()
+	return entries
+}
+
+// joinLines joins the lines of an entry, undoing hyphenation at line breaks.
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			if strings.HasSuffix(prev, "-") && unicode.IsLower(firstRune(l)) {
+				s := b.String()
+				b.Reset()
+				b.WriteString(s[:len(s)-1])
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(l)
+	}
+	return truncateRunes(b.String(), maxReferenceRunes)
+}
+
+func endsEntry(line string) bool {
+	return strings.HasSuffix(line, ".") || doiPattern.MatchString(line) && !strings.HasSuffix(line, "-")
+}
+
+func parseReference(entry string, position int) model.DocumentReference {
+	ref := model.DocumentReference{Position: position, Raw: entry}
+
+	if doi := doiPattern.FindString(entry); doi != "" {
+		ref.DOI = truncateRunes(cleanDOI(doi), 255)
+	}
+
+	loc := parenYearPattern.FindStringSubmatchIndex(entry)
+	if loc != nil && isAuthorList(entry[:loc[0]]) {
+		// Author-year styles: "Smith, J. (2020). Title. Journal."
+		ref.Year, _ = strconv.Atoi(entry[loc[2]:loc[3]])
+		ref.Authors = entry[:loc[0]]
+		ref.Title = firstSentence(entry[loc[1]:])
+	} else if loc != nil {
+		// Springer puts the year last: "Smith, J.: Title. Journal (2020)".
+		ref.Year, _ = strconv.Atoi(entry[loc[2]:loc[3]])
+	} else if m := yearPattern.FindStringSubmatch(identifierPattern.ReplaceAllString(entry, "")); m != nil {
+		ref.Year, _ = strconv.Atoi(m[1])
+	}
+
+	if m := quotedTitlePattern.FindStringSubmatchIndex(entry); m != nil {
+		// IEEE: J. Smith and A. Doe, "Title," in Proc. ...
+		ref.Title = entry[m[2]:m[3]]
+		if ref.Authors == "" {
+			ref.Authors = entry[:m[0]]
+		}
+	} else if ref.Title == "" {
+		// Author list, then title, each ending in a period that isn't after an
+		// initial, or the author list ending in a colon (Springer).
+		authors, after := splitAuthors(entry)
+		ref.Authors, ref.Title = authors, firstSentence(after)
+	}
+
+	ref.Authors = truncateRunes(strings.Trim(ref.Authors, " ,:;"), 1024)
+	ref.Title = truncateRunes(strings.Trim(ref.Title, " ,.:;"), 512)
+	if len(strings.Fields(ref.Title)) < 2 {
+		ref.Title = ""
+	}
+	return ref
+}
+
+// isAuthorList reports whether the text before a year in parentheses can be
+// all authors, with no sentence or title in it.
+func isAuthorList(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && !strings.Contains(s, ": ") && sentenceEnd(s) == 0
+}
+
+// splitAuthors cuts an entry after its author list.
+func splitAuthors(entry string) (string, string) {
+	if i := strings.Index(entry, ": "); i > 0 && i < 300 {
+		return entry[:i], entry[i+2:]
+	}
+	if i := sentenceEnd(entry); i > 0 {
+		return entry[:i], entry[i:]
+	}
+	return "", entry
+}
+
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := sentenceEnd(s); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// sentenceEnd returns the index after the first ". ", "? " or "! " that doesn't
+// follow a single-letter initial, or 0.
+func sentenceEnd(s string) int {
+	for i := 1; i < len(s)-1; i++ {
+		if s[i+1] != ' ' || !strings.ContainsRune(".?!", rune(s[i])) {
+			continue
+		}
+		if s[i] == '.' && isInitial(s[:i]) {
+			continue
+		}
+		return i + 1
+	}
+	return 0
+}
+
+func isInitial(before string) bool {
+	word := before[strings.LastIndexAny(before, " .-")+1:]
+	r := []rune(word)
+	return len(r) == 1 && unicode.IsUpper(r[0])
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+// NewReferenceMatcher indexes documents by DOI and normalised title.
+func NewReferenceMatcher(docs []CitableDocument) *ReferenceMatcher {
+	m := &ReferenceMatcher{byDOI: map[string]uint{}}
+	for _, d := range docs {
+		if d.DOI != "" {
+			m.byDOI[strings.ToLower(d.DOI)] = d.ID
+		}
+		norm := normalizeTitle(d.Title)
+		if len(strings.Fields(norm)) >= minMatchWords {
+			m.titles = append(m.titles, matchTitle{id: d.ID, year: d.Year, norm: norm})
+		}
+	}
+	return m
+}
+
+// Match returns the document ref refers to, or 0. A DOI decides on its own;
+// otherwise a document whose whole title appears in the entry matches unless
+// the years are known and disagree. The longest matching title wins, so that a
+// title contained in a longer one doesn't steal its citations.
+func (m *ReferenceMatcher) Match(ref model.DocumentReference) uint {
+	if ref.DOI != "" {
+		if id, ok := m.byDOI[ref.DOI]; ok {
+			return id
+		}
+	}
+
+	raw := " " + normalizeTitle(ref.Raw) + " "
+	var best matchTitle
+	for _, t := range m.titles {
+		if ref.Year != 0 && t.year != 0 && (ref.Year-t.year > 1 || t.year-ref.Year > 1) {
+			continue
+		}
+		if len(t.norm) > len(best.norm) && strings.Contains(raw, " "+t.norm+" ") {
+			best = t
+		}
+	}
+	return best.id
+}
+
+// normalizeTitle lowercases a title and keeps only its words, so that
+// punctuation, line breaks and hyphenation don't get in the way of matching.
+func normalizeTitle(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
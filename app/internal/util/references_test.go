@@ -0,0 +1,123 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/model"
+)
+
+
+// The reference lists in testdata/references are copied from papers in the
+// three styles most lists follow: IEEE, APA and Springer's numbered style. Their
+// entries run over several lines and break words with hyphens.
+func TestParseReferenceLists(t *testing.T) {
+	tests := []struct {
+		list	string
+		want	[]model.DocumentReference
+	}{
+		{"ieee.txt", []model.DocumentReference{
+			{Authors: "A. Vaswani, N. Shazeer, N. Parmar, J. Uszkoreit, L. Jones, A. N. Gomez, Ł. Kaiser, and I. Polosukhin", Title: "Attention is all you need", Year: 2017},
+			// The year is not read from the arXiv identifier.
+			{Authors: "I. Beltagy, M. E. Peters, and A. Cohan", Title: "Longformer: The long-document transformer", Year: 2020},
+			{Authors: "J. Devlin, M.-W. Chang, K. Lee, and K. Toutanova", Title: "BERT: Pre-training of deep bidirectional transformers for language understanding", Year: 2019, DOI: "10.18653/v1/n19-1423"},
+			{Authors: "R. Child, S. Gray, A. Radford, and I. Sutskever", Title: "Generating long sequences with sparse transformers", Year: 2019},
+		}},
+		{"apa.txt", []model.DocumentReference{
+			{Authors: "Baddeley, A. D., & Hitch, G.", Title: "Working memory", Year: 1974, DOI: "10.1016/s0079-7421(08)60452-1"},
+			{Authors: "Ebbinghaus, H.", Title: "Über das Gedächtnis: Untersuchungen zur experimentellen Psychologie", Year: 1885},
+			{Authors: "van der Berg, A., & Müller, K.", Title: "Sleep spindles and the consolidation of declarative memory", Year: 2020, DOI: "10.1111/jsr.12345"},
+			{Authors: "Walker, M. P., & Stickgold, R.", Title: "Sleep-dependent learning and memory consolidation", Year: 2004},
+		}},
+		{"springer.txt", []model.DocumentReference{
+			{Authors: "Smith, J., Li, B.", Title: "Sparse attention for long documents", Year: 2020},
+			{Authors: "Kitaev, N., Kaiser, Ł., Levskaya, A.", Title: "Reformer: the efficient transformer", Year: 2020},
+			{Authors: "Zaheer, M., et al.", Title: "Big bird: transformers for longer sequences", Year: 2020, DOI: "10.5555/3495724.3497174"},
+			// "4. Anonymous: Notes." is too short to be an entry.
+		}},
+	}
+	for _, tt := range tests {
+		b, err := os.ReadFile(filepath.Join("testdata", "references", tt.list))
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := ParseReferences(string(b))
+		if len(refs) != len(tt.want) {
+			t.Errorf("%s: got %d entries, want %d", tt.list, len(refs), len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			got := refs[i]
+			if got.Position != i || got.Authors != want.Authors || got.Title != want.Title || got.Year != want.Year || got.DOI != want.DOI {
+				t.Errorf("%s: entry %d: got %q / %q / %d / %q, want %q / %q / %d / %q", tt.list, i,
+					got.Authors, got.Title, got.Year, got.DOI, want.Authors, want.Title, want.Year, want.DOI)
+			}
+		}
+	}
+}
+
+// TestParseReferencesRaw checks that entries are joined across lines, undoing
+// the hyphens that broke words but not those of hyphenated words.
+func TestParseReferencesRaw(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "references", "apa.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := ParseReferences(string(b))
+	want := "Ebbinghaus, H. (1885). Über das Gedächtnis: Untersuchungen zur experimentellen Psychologie. Duncker & Humblot."
+	if len(refs) < 2 || refs[1].Raw != want {
+		t.Fatalf("got %+v, want second entry %q", refs, want)
+	}
+	if want := "https://doi.org/10.1016/S0079-7421(08)60452-1"; refs[0].Raw[len(refs[0].Raw)-len(want):] != want {
+		t.Errorf("got %q, want it to end with %q", refs[0].Raw, want)
+	}
+}
+
+func TestFindIdentifiers(t *testing.T) {
+	tests := []struct {
+		text	string
+		doi		string
+		arXiv	string
+	}{
+		{"doi: 10.1038/NATURE14539.", "10.1038/nature14539", ""},
+		{"(https://doi.org/10.1145/3292500.3330701)", "10.1145/3292500.3330701", ""},
+		{"arXiv:2004.05150v2 [cs.CL]", "", "2004.05150"},
+		{"arXiv: 1706.03762", "", "1706.03762"},
+		{"arXiv:hep-th/9901001v1", "", "hep-th/9901001"},
+		{"version 10.2 of the software, 2004.05150", "", ""},
+	}
+	for _, tt := range tests {
+		if got := FindDOI(tt.text); got != tt.doi {
+			t.Errorf("DOI in %q: got %q, want %q", tt.text, got, tt.doi)
+		}
+		if got := FindArXivID(tt.text); got != tt.arXiv {
+			t.Errorf("arXiv ID in %q: got %q, want %q", tt.text, got, tt.arXiv)
+		}
+	}
+}
+
+// TestReferenceMatcher matches the IEEE list against a small library.
+func TestReferenceMatcher(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "references", "ieee.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := ParseReferences(string(b))
+	m := NewReferenceMatcher([]CitableDocument{
+		{ID: 1, Title: "Attention Is All You Need", Year: 2017},
+		// Matched by DOI although the title differs.
+		{ID: 2, Title: "BERT", DOI: "10.18653/V1/N19-1423"},
+		// A title inside a longer one loses to it.
+		{ID: 3, Title: "Generating Long Sequences", Year: 2019},
+		{ID: 4, Title: "Generating Long Sequences with Sparse Transformers", Year: 2019},
+		// The years are too far apart.
+		{ID: 5, Title: "Longformer: The Long-Document Transformer", Year: 2015},
+	})
+	want := []uint{1, 0, 2, 4}
+	for i, ref := range refs {
+		if got := m.Match(ref); got != want[i] {
+			t.Errorf("entry %d: got document %d, want %d", i, got, want[i])
+		}
+	}
+}
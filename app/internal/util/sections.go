@@ -0,0 +1,382 @@
+package util
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"backend/internal/model"
+)
+
+
+// Headings are recognised from three signals: a font noticeably larger than the
+// body text, section numbering such as "3.2" or "IV.", and the names papers give
+// their standard parts. A line needs the name alone, or numbering or size
+// together with the shape of a heading: short, capitalised and not ending like
+// a sentence.
+const (
+	headingScale		= 1.15
+	titleScale			= 1.3
+	maxHeadingWords		= 12
+	maxHeadingRunes		= 120
+)
+
+var (
+	headingNumber	= regexp.MustCompile(`^((?:\d{1,2}\.)*\d{1,2}\.?|[IVX]{1,5}\.|[A-H]\.)\s+(\S.*)$`)
+	// appendixNumber matches "A Proofs" and "B.2 Datasets", which are only
+	// taken as headings after the references, where "A" isn't a word.
+	appendixNumber	= regexp.MustCompile(`^([A-H](?:\.\d{1,2})*)\.?\s+(\S.*)$`)
+	// inlineAbstract matches the run-in heading of IEEE and ACM papers,
+	// e.g. "Abstract—We present ...".
+	inlineAbstract	= regexp.MustCompile(`^(?i)abstract\s*[-—–:.]\s*(\S.*)$`)
+)
+
+// sectionNames maps normalised headings that are recognised on their own. Longer
+// headings are classified by the keywords in sectionKeywords.
+var sectionNames = map[string]string{
+	"abstract":					model.SectionAbstract,
+	"introduction":				model.SectionIntroduction,
+	"methods":					model.SectionMethods,
+	"method":					model.SectionMethods,
+	"methodology":				model.SectionMethods,
+	"materials and methods":	model.SectionMethods,
+	"results":					model.SectionResults,
+	"discussion":				model.SectionDiscussion,
+	"results and discussion":	model.SectionResults,
+	"conclusion":				model.SectionConclusion,
+	"conclusions":				model.SectionConclusion,
+	"references":				model.SectionReferences,
+	"bibliography":				model.SectionReferences,
+	"works cited":				model.SectionReferences,
+	"literature cited":			model.SectionReferences,
+	"appendix":					model.SectionAppendix,
+	"appendices":				model.SectionAppendix,
+	"acknowledgments":			model.SectionOther,
+	"acknowledgements":			model.SectionOther,
+	"related work":				model.SectionOther,
+	"background":				model.SectionOther,
+}
+
+var sectionKeywords = []struct {
+	word	string
+	kind	string
+}{
+	{"abstract", model.SectionAbstract},
+	{"introduction", model.SectionIntroduction},
+	{"method", model.SectionMethods},
+	{"materials", model.SectionMethods},
+	{"experimental setup", model.SectionMethods},
+	{"study design", model.SectionMethods},
+	{"approach", model.SectionMethods},
+	{"result", model.SectionResults},
+	{"evaluation", model.SectionResults},
+	{"experiments", model.SectionResults},
+	{"findings", model.SectionResults},
+	{"discussion", model.SectionDiscussion},
+	{"conclu", model.SectionConclusion},
+	{"future work", model.SectionConclusion},
+	{"reference", model.SectionReferences},
+	{"bibliograph", model.SectionReferences},
+	{"appendi", model.SectionAppendix},
+	{"supplementa", model.SectionAppendix},
+}
+
+// minorWords are left in lower case by title case.
+var minorWords = map[string]bool{"a": true, "an": true, "the": true, "and": true, "or": true, "of": true,
+	"in": true, "on": true, "to": true, "for": true, "with": true, "by": true, "at": true, "from": true, "as": true}
+
+
+// Segment splits the lines of a document into sections at its headings. Lines
+// before the first heading become a title section when the first page opens with
+// a line set larger than the body text, or an untitled other section otherwise.
+// Sections are numbered by Position from 0 in reading order.
+func Segment(lines []TextLine) []model.DocumentSection {
+	body := bodySize(lines)
+	titleEnd := titleLines(lines, body)
+
+	var (
+		sections	[]model.DocumentSection
+		text		[]string
+	)
+	flush := func() {
+		if len(sections) > 0 {
+			sections[len(sections)-1].Text = strings.Join(text, "\n")
+		}
+		text = text[:0]
+	}
+	open := func(s model.DocumentSection) {
+		flush()
+		s.Position = len(sections)
+		sections = append(sections, s)
+	}
+
+	started := false
+	begin := func(before []TextLine) {
+		started = true
+		if len(before) > 0 {
+			open(front(before, lines[:titleEnd]))
+			for _, l := range before {
+				text = append(text, l.Text)
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if i < titleEnd {
+			continue
+		}
+		if !started {
+			if m := inlineAbstract.FindStringSubmatch(line.Text); m != nil {
+				begin(lines[:i])
+				open(model.DocumentSection{Kind: model.SectionAbstract, Heading: "Abstract", Level: 1, Page: line.Page})
+				text = append(text, m[1])
+				continue
+			}
+		}
+
+		heading, level, ok := parseHeading(line, body, started)
+		inAppendix := len(sections) > 0 && isBackMatter(sections[len(sections)-1].Kind)
+		lettered := false
+		if inAppendix && line.Heading == 0 {
+			if h, l, isLettered := parseAppendixHeading(line.Text); isLettered {
+				heading, level, ok, lettered = h, l, true, true
+			}
+		}
+		if !ok {
+			if started {
+				text = append(text, line.Text)
+			}
+			continue
+		}
+		if !started {
+			begin(lines[:i])
+		}
+
+		// Subsections belong to the part they are in, and other headings after
+		// the references are appendices, as are all lettered ones there.
+		kind := classifyHeading(heading)
+		if lettered && level == 1 {
+			kind = model.SectionAppendix
+		}
+		if kind == model.SectionOther {
+			for j := len(sections) - 1; j >= 0; j-- {
+				if sections[j].Level < level {
+					if sections[j].Kind != model.SectionTitle {
+						kind = sections[j].Kind
+					}
+					break
+				}
+			}
+		}
+		if kind == model.SectionOther && inAppendix {
+			kind = model.SectionAppendix
+		}
+		open(model.DocumentSection{Kind: kind, Heading: truncateRunes(heading, 255), Level: level, Page: line.Page})
+	}
+
+	if !started {
+		begin(lines)
+	}
+	flush()
+	return sections
+}
+
+// SegmentPages is Segment for text without font sizes, such as OCR output.
+func SegmentPages(pages []string) []model.DocumentSection {
+	var lines []TextLine
+	for i, page := range pages {
+		for _, l := range strings.Split(page, "\n") {
+			if l = strings.Join(strings.Fields(l), " "); l != "" {
+				lines = append(lines, TextLine{Text: l, Page: i + 1})
+			}
+		}
+	}
+	return Segment(lines)
+}
+
+// titleLines finds the title of a paper: the lines at the start of the first
+// page set in its largest font, when that is well above the body size. It returns
+// the index of the line after the title, or 0 without one.
+func titleLines(lines []TextLine, body float64) int {
+	largest, first := 0.0, -1
+	for i, l := range lines {
+		if l.Page != 1 || i >= 20 {
+			break
+		}
+		if l.Size > largest {
+			largest, first = l.Size, i
+		}
+	}
+	if body == 0 || largest < body*titleScale {
+		return 0
+	}
+
+	end := first
+	for end < len(lines) && lines[end].Page == 1 && lines[end].Size == largest {
+		end++
+	}
+	return end
+}
+
+// front makes the section of the lines before the first heading, which hold the
+// title, authors and affiliations of a paper.
+func front(lines, title []TextLine) model.DocumentSection {
+	s := model.DocumentSection{Kind: model.SectionOther, Level: 1, Page: lines[0].Page}
+	if len(title) == 0 {
+		return s
+	}
+
+	heading := make([]string, len(title))
+	for i, l := range title {
+		heading[i] = l.Text
+	}
+	s.Kind, s.Heading = model.SectionTitle, truncateRunes(strings.Join(heading, " "), 255)
+	return s
+}
+
+// parseHeading decides whether a line is a heading and returns its text without
+// the numbering, and its level: 1 for top-level sections, more for each level of
+// numbered subsection. Until the first heading only standard names and larger
+// fonts count, since numbered lines there are mostly affiliations. Lines marked
+// as headings by their source are taken as they are.
+func parseHeading(line TextLine, body float64, started bool) (string, int, bool) {
+	text := strings.TrimSpace(line.Text)
+	if text != "" && line.Heading > 0 {
+		return text, line.Heading, true
+	}
+	if text == "" || len([]rune(text)) > maxHeadingRunes {
+		return "", 0, false
+	}
+
+	name, level, numbered := text, 1, false
+	if m := headingNumber.FindStringSubmatch(text); m != nil {
+		name, numbered = m[2], true
+		switch c := m[1][0]; {
+		case c >= '0' && c <= '9':
+			level = strings.Count(strings.TrimSuffix(m[1], "."), ".") + 1
+		case c >= 'A' && c <= 'H' && len(m[1]) == 2:
+			// IEEE letters subsections of roman-numbered sections.
+			level = 2
+		}
+	}
+
+	if _, ok := sectionNames[normalizeHeading(name)]; ok {
+		return name, level, true
+	}
+
+	words := strings.Fields(name)
+	if len(words) > maxHeadingWords || endsSentence(name) || !startsUpper(name) {
+		return "", 0, false
+	}
+	larger := body > 0 && line.Size >= body*headingScale
+	switch {
+	case larger:
+		return name, level, true
+	case !started:
+		return "", 0, false
+	case numbered && (body == 0 || line.Size >= body) && isTitleCase(words):
+		return name, level, true
+	case !numbered && isUpper(name) && len(words) <= 6:
+		return name, level, true
+	}
+	return "", 0, false
+}
+
+func classifyHeading(heading string) string {
+	norm := normalizeHeading(heading)
+	if kind, ok := sectionNames[norm]; ok {
+		return kind
+	}
+	for _, k := range sectionKeywords {
+		if strings.Contains(norm, k.word) {
+			return k.kind
+		}
+	}
+	return model.SectionOther
+}
+
+func normalizeHeading(s string) string {
+	s = strings.ToLower(strings.TrimRight(s, " .:"))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// bodySize is the font size most characters are set in, or 0 without sizes.
+func bodySize(lines []TextLine) float64 {
+	count := map[float64]int{}
+	for _, l := range lines {
+		if l.Size > 0 {
+			count[l.Size] += len(l.Text)
+		}
+	}
+
+	sizes := make([]float64, 0, len(count))
+	for s := range count {
+		sizes = append(sizes, s)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return count[sizes[i]] > count[sizes[j]] || count[sizes[i]] == count[sizes[j]] && sizes[i] < sizes[j]
+	})
+	if len(sizes) == 0 {
+		return 0
+	}
+	return sizes[0]
+}
+
+func startsUpper(s string) bool {
+	for _, r := range s {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+func isUpper(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 3
+}
+
+// isTitleCase reports whether most words of a numbered line are capitalised, as
+// in "3 Experimental Setup", which tells a heading from a numbered list item
+// that merely starts with a capital. Articles, short prepositions and numbers
+// aren't counted, as title case leaves them be.
+func isTitleCase(words []string) bool {
+	upper, counted := 0, 0
+	for _, w := range words {
+		if minorWords[strings.ToLower(w)] || !unicode.IsLetter(firstRune(w)) {
+			continue
+		}
+		counted++
+		if startsUpper(w) {
+			upper++
+		}
+	}
+	return len(words) <= 3 || upper*2 > counted
+}
+
+func isBackMatter(kind string) bool {
+	return kind == model.SectionReferences || kind == model.SectionAppendix
+}
+
+func parseAppendixHeading(text string) (string, int, bool) {
+	m := appendixNumber.FindStringSubmatch(text)
+	if m == nil {
+		return "", 0, false
+	}
+	words := strings.Fields(m[2])
+	if len(words) > maxHeadingWords || endsSentence(m[2]) || !startsUpper(m[2]) || !isTitleCase(words) {
+		return "", 0, false
+	}
+	return m[2], strings.Count(m[1], ".") + 1, true
+}
+
+func endsSentence(s string) bool {
+	return strings.TrimRight(s, ".,;:") != s
+}
@@ -0,0 +1,193 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"backend/internal/model"
+)
+
+
+// The layouts in testdata/sections are papers as the PDF reader sees them, a
+// line per row of "page size text", and a scan as OCR reads it, with pages
+// separated by form feeds. Each section is written as "kind level page heading".
+func TestSegmentLayouts(t *testing.T) {
+	tests := []struct {
+		layout	string
+		want	[]string
+		text	map[int]string
+	}{
+		{
+			layout: "neurips.txt",
+			want: []string{
+				"title 1 1 Linear-Time Attention for Long Documents with Sliding Windows",
+				"abstract 1 1 Abstract",
+				"introduction 1 1 Introduction",
+				"other 1 2 Background",
+				"other 2 2 Self-Attention",
+				"other 2 2 Sparse Patterns",
+				"methods 1 2 Method",
+				"results 1 3 Experimental Results",
+				"results 2 3 Translation Benchmarks",
+				"conclusion 1 3 Conclusion",
+				"other 1 3 Acknowledgments",
+				"references 1 4 References",
+				"appendix 1 5 Proofs",
+				"appendix 2 5 Complexity of the Window",
+				"appendix 3 5 Lower Bounds",
+				"appendix 1 5 Additional Experiments",
+			},
+			text: map[int]string{
+				// The affiliations are numbered but come before the first heading,
+				// and the numbered sentences are list items.
+				0: "Linear-Time Attention for Long Documents\nwith Sliding Windows\nAnn Smith¹ Bo Li² Carla Díaz¹\n1 University of Edinburgh\n2 Tsinghua University",
+				2: "Long inputs are common in science, law and medicine, where documents run to many pages.\n" +
+					"1. We show that windows of 512 tokens suffice for most tasks.\n" +
+					"2 experiments were run on eight GPUs for each of the settings.",
+			},
+		},
+		{
+			layout: "ieee.txt",
+			want: []string{
+				"title 1 1 Sparse Attention on Edge Devices",
+				"abstract 1 1 Abstract",
+				"introduction 1 1 INTRODUCTION",
+				"other 1 1 RELATED WORK",
+				"other 2 1 Sparse Transformers",
+				"other 2 1 Quantisation",
+				"methods 1 2 PROPOSED APPROACH",
+				"results 1 2 EVALUATION",
+				"conclusion 1 2 CONCLUSION",
+				"references 1 2 REFERENCES",
+			},
+			text: map[int]string{
+				1: "We run sparse transformers on phones. Memory drops fourfold.\nIndex Terms—transformers, attention, mobile.",
+			},
+		},
+		{
+			layout: "scan.txt",
+			want: []string{
+				"other 1 1 ",
+				"abstract 1 1 ABSTRACT",
+				"methods 1 1 METHODS",
+				"results 1 1 RESULTS",
+				"discussion 1 1 DISCUSSION",
+				"references 1 1 REFERENCES",
+				"appendix 1 2 APPENDIX",
+			},
+			text: map[int]string{
+				0: "THE EFFECT OF SLEEP ON RECALL\nJ. Doe and R. Roe\nDepartment of Psychology",
+				2: "Participants were paid for their time.\n1. Lists were read aloud twice.",
+			},
+		},
+	}
+	for _, tt := range tests {
+		sections := readLayout(t, tt.layout)
+		var got []string
+		for i, s := range sections {
+			if s.Position != i {
+				t.Errorf("%s: section %d has position %d", tt.layout, i, s.Position)
+			}
+			got = append(got, fmt.Sprintf("%s %d %d %s", s.Kind, s.Level, s.Page, s.Heading))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got sections\n%s\nwant\n%s", tt.layout, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			continue
+		}
+		for i, want := range tt.text {
+			if sections[i].Text != want {
+				t.Errorf("%s: section %d has text %q, want %q", tt.layout, i, sections[i].Text, want)
+			}
+		}
+	}
+}
+
+// TestParseHeading checks single lines against a body size of 10.
+func TestParseHeading(t *testing.T) {
+	tests := []struct {
+		text		string
+		size		float64
+		started		bool
+		heading		string
+		level		int
+	}{
+		{"Introduction", 10, false, "Introduction", 1},
+		{"3.2.1 Materials and Methods", 10, true, "Materials and Methods", 3},
+		{"IV. Results", 10, true, "Results", 1},
+		{"B. Datasets Used for Training", 10, true, "Datasets Used for Training", 2},
+		{"Scaling to a Million Tokens", 12, true, "Scaling to a Million Tokens", 1},
+		{"Scaling to a Million Tokens", 11, true, "", 0},
+		{"Scaling to a Million Tokens", 12, false, "Scaling to a Million Tokens", 1},
+		{"2 Effect of the Window Size", 10, true, "Effect of the Window Size", 1},
+		{"2 Effect of the Window Size", 9, true, "", 0},
+		{"2 Effect of the Window Size", 10, false, "", 0},
+		{"2 We collect data from the web", 10, true, "", 0},
+		{"Large Models Are Expensive.", 14, true, "", 0},
+		{"lowercase heading", 14, true, "", 0},
+		{"RELATED WORK", 10, true, "RELATED WORK", 1},
+		{"RELATED WORK", 10, false, "RELATED WORK", 1},
+		{"DEPARTMENT OF PSYCHOLOGY", 10, false, "", 0},
+		{"A Very Long Heading That Goes On and On With Far Too Many Words To Be One", 14, true, "", 0},
+	}
+	for _, tt := range tests {
+		heading, level, ok := parseHeading(TextLine{Text: tt.text, Size: tt.size, Page: 1}, 10, tt.started)
+		if heading != tt.heading || level != tt.level || ok != (tt.heading != "") {
+			t.Errorf("%q at %v (started %v): got %q, %d, %v, want %q, %d", tt.text, tt.size, tt.started, heading, level, ok, tt.heading, tt.level)
+		}
+	}
+}
+
+// TestBodySize checks that the body size is the one most characters are set in
+// rather than the one most lines are.
+func TestBodySize(t *testing.T) {
+	lines := []TextLine{
+		{Text: "Title", Size: 20},
+		{Text: "1", Size: 8},
+		{Text: "2", Size: 8},
+		{Text: "3", Size: 8},
+		{Text: "A paragraph of body text that runs on.", Size: 10},
+	}
+	if got := bodySize(lines); got != 10 {
+		t.Errorf("got %v, want 10", got)
+	}
+	if got := bodySize([]TextLine{{Text: "no sizes"}}); got != 0 {
+		t.Errorf("without sizes: got %v, want 0", got)
+	}
+}
+
+// readLayout segments a layout in testdata/sections. Lines starting with "#"
+// describe the layout.
+func readLayout(t *testing.T, name string) []model.DocumentSection {
+	b, err := os.ReadFile(filepath.Join("testdata", "sections", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "scan.txt" {
+		return SegmentPages(strings.Split(string(b), "\f"))
+	}
+
+	var lines []TextLine
+	for i, l := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if strings.HasPrefix(l, "#") {
+			continue
+		}
+		f := strings.SplitN(l, " ", 3)
+		if len(f) < 3 {
+			t.Fatalf("%s:%d: want page, size and text", name, i+1)
+		}
+		page, err := strconv.Atoi(f[0])
+		if err != nil {
+			t.Fatalf("%s:%d: %v", name, i+1, err)
+		}
+		size, err := strconv.ParseFloat(f[1], 64)
+		if err != nil {
+			t.Fatalf("%s:%d: %v", name, i+1, err)
+		}
+		lines = append(lines, TextLine{Text: f[2], Size: size, Page: page})
+	}
+	return Segment(lines)
+}
@@ -0,0 +1,76 @@
+package validate
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+
+//go:embed breached.txt
+var breachedList string
+
+// breached holds the SHA-1 sums of the passwords the policy refuses. Keeping sums
+// rather than the passwords lets a list in the Have I Been Pwned download format
+// be loaded as is.
+var breached = map[[sha1.Size]byte]struct{}{}
+
+
+func init() {
+	readBreached(strings.NewReader(breachedList))
+}
+
+// LoadBreached adds the passwords in a file to those the policy refuses. Each line
+// is a password, or the hex SHA-1 of one optionally followed by ":count", as in
+// the Have I Been Pwned download; blank lines and lines starting with # are
+// skipped. It must be called before requests are served.
+func LoadBreached(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n, err := readBreached(f)
+	if err != nil {
+		return n, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+func readBreached(r io.Reader) (int, error) {
+	n := 0
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sum [sha1.Size]byte
+		hash, _, _ := strings.Cut(line, ":")
+		if b, err := hex.DecodeString(hash); err == nil && len(b) == sha1.Size {
+			copy(sum[:], b)
+		} else {
+			sum = sha1.Sum([]byte(strings.ToLower(line)))
+		}
+		breached[sum] = struct{}{}
+		n++
+	}
+	return n, sc.Err()
+}
+
+// IsBreached reports whether a password is on the list of breached passwords,
+// as typed or in lower case.
+func IsBreached(password string) bool {
+	if _, ok := breached[sha1.Sum([]byte(password))]; ok {
+		return true
+	}
+	_, ok := breached[sha1.Sum([]byte(strings.ToLower(password)))]
+	return ok
+}
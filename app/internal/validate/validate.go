@@ -0,0 +1,298 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"backend/internal/repository"
+)
+
+
+// rule is one rule of a validate tag with its argument parsed.
+type rule struct {
+	name		string
+	n			float64
+	options		[]string
+}
+
+// field is an exported field of a struct and the rules of its validate tag.
+type field struct {
+	index		int
+	name		string
+	rules		[]rule
+}
+
+// compiled is what a struct type's tags compile to, or the error in them.
+type compiled struct {
+	fields		[]field
+	err			error
+}
+
+// types caches the compiled tags of every struct type seen so far, keyed by
+// reflect.Type, so that each type's tags are parsed and checked only once.
+var types sync.Map
+
+
+// Struct checks the `validate` tags on the fields of a request DTO and returns a
+// *repository.ValidationError listing every failing field, or nil. Values that
+// aren't structs have nothing to check. A tag with an unknown rule or a bad
+// argument is reported as an error the first time its type is seen, and on
+// every call after that.
+//
+// Supported rules, comma separated:
+//
+//	required        the value must not be the zero value
+//	omitempty       skip the remaining rules when the value is zero
+//	min=N, max=N    string length in characters, slice length, or numeric bounds
+//	email           a bare e-mail address
+//	username        letters, digits, '.', '_' and '-'
+//	password        the server password policy, see Password
+//	oneof=a|b|c     one of the listed values
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields, err := fieldsOf(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	var failed []repository.FieldError
+	for _, f := range fields {
+		if msg := check(rv.Field(f.index), f.rules); msg != "" {
+			failed = append(failed, repository.FieldError{Field: f.name, Message: msg})
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return &repository.ValidationError{Message: "Request validation failed", Fields: failed}
+}
+
+func fieldsOf(rt reflect.Type) ([]field, error) {
+	if c, ok := types.Load(rt); ok {
+		return c.(compiled).fields, c.(compiled).err
+	}
+	fields, err := compile(rt)
+	types.Store(rt, compiled{fields: fields, err: err})
+	return fields, err
+}
+
+// compile parses the validate tags of a struct type.
+func compile(rt reflect.Type) ([]field, error) {
+	var fields []field
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || !sf.IsExported() {
+			continue
+		}
+
+		f := field{index: i, name: fieldName(sf)}
+		for _, s := range strings.Split(tag, ",") {
+			r, err := parseRule(s, sf.Type)
+			if err != nil {
+				return nil, fmt.Errorf("validate: field %s of %s: %w", sf.Name, rt, err)
+			}
+			f.rules = append(f.rules, r)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseRule parses one rule of a tag on a field of type t, checking that the
+// rule exists, that its argument is well formed and that it applies to t.
+func parseRule(s string, t reflect.Type) (rule, error) {
+	name, arg, hasArg := strings.Cut(s, "=")
+	r := rule{name: name}
+
+	switch name {
+	case "omitempty", "required", "email", "username", "password":
+		if hasArg {
+			return r, fmt.Errorf("rule %s takes no argument", name)
+		}
+	case "min", "max":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return r, fmt.Errorf("bad %s argument %q", name, arg)
+		}
+		r.n = n
+	case "oneof":
+		if arg == "" {
+			return r, errors.New("rule oneof lists no values")
+		}
+		r.options = strings.Split(arg, "|")
+	default:
+		return r, fmt.Errorf("unknown rule %q", s)
+	}
+
+	switch kind := t.Kind(); name {
+	case "email", "username", "password":
+		if kind != reflect.String {
+			return r, fmt.Errorf("rule %s needs a string, not %s", name, t)
+		}
+	case "min", "max":
+		if kind == reflect.Pointer {
+			kind = t.Elem().Kind()
+		}
+		switch kind {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return r, fmt.Errorf("rule %s has no size to check on %s", name, t)
+		}
+	}
+	return r, nil
+}
+
+// check applies the rules to one value and returns the first failure message.
+func check(v reflect.Value, rules []rule) string {
+	for _, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if v.IsZero() {
+				return ""
+			}
+		case "required":
+			if v.IsZero() {
+				return "is required"
+			}
+		case "min", "max":
+			if msg := bound(v, r.name, r.n); msg != "" {
+				return msg
+			}
+		case "email":
+			if !IsEmail(v.String()) {
+				return "must be a valid email address"
+			}
+		case "username":
+			if !isUsername(v.String()) {
+				return "may only contain letters, digits, '.', '_' and '-'"
+			}
+		case "password":
+			if msg := Password(v.String()); msg != "" {
+				return msg
+			}
+		case "oneof":
+			if !oneOf(v, r.options) {
+				return "must be one of " + strings.Join(r.options, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+func bound(v reflect.Value, rule string, n float64) string {
+	var size float64
+	unit := ""
+
+	switch v.Kind() {
+	case reflect.String:
+		size, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map:
+		size, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	case reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return bound(v.Elem(), rule, n)
+	}
+
+	if rule == "min" && size < n {
+		if unit == "" {
+			return fmt.Sprintf("must be at least %g", n)
+		}
+		return fmt.Sprintf("must be at least %g%s", n, unit)
+	}
+	if rule == "max" && size > n {
+		if unit == "" {
+			return fmt.Sprintf("must be at most %g", n)
+		}
+		return fmt.Sprintf("must be at most %g%s", n, unit)
+	}
+	return ""
+}
+
+func oneOf(v reflect.Value, options []string) bool {
+	s := fmt.Sprint(reflect.Indirect(v).Interface())
+	for _, o := range options {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmail reports whether s is a bare address such as "ada@example.org",
+// without a display name or angle brackets.
+func IsEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	_, domain, _ := strings.Cut(s, "@")
+	return strings.Contains(domain, ".")
+}
+
+func isUsername(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Password checks a new password against the server policy: 8 to 72 bytes
+// (bcrypt ignores anything longer) with at least one letter and one digit, and
+// not on the list of breached passwords. It returns a message describing the
+// first failed requirement, or "".
+func Password(s string) string {
+	if len(s) < 8 {
+		return "must be at least 8 characters"
+	}
+	if len(s) > 72 {
+		return "must be at most 72 bytes"
+	}
+
+	var letter, digit bool
+	for _, r := range s {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	if !letter || !digit {
+		return "must contain at least one letter and one digit"
+	}
+	if IsBreached(s) {
+		return "is too common; it appears in lists of breached passwords"
+	}
+	return ""
+}
+
+func fieldName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	if name := sf.Tag.Get("form"); name != "" {
+		return name
+	}
+	return sf.Name
+}
@@ -0,0 +1,169 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"backend/internal/repository"
+)
+
+
+type sample struct {
+	Name		string		`json:"name" validate:"required,min=2,max=5"`
+	Nick		string		`json:"nick,omitempty" validate:"omitempty,min=3"`
+	Email		string		`json:"email" validate:"omitempty,email"`
+	Username	string		`json:"username" validate:"omitempty,username"`
+	Password	string		`json:"password" validate:"omitempty,password"`
+	Mode		string		`json:"mode" validate:"omitempty,oneof=fast|slow"`
+	ModePtr		*string		`json:"mode_ptr" validate:"omitempty,oneof=fast|slow"`
+	Title		*string		`json:"title" validate:"min=1,max=3"`
+	IDs			[]uint		`json:"ids" validate:"max=2"`
+	Count		int			`json:"count" validate:"min=-1,max=10"`
+	Size		uint		`form:"size" validate:"max=100"`
+	Score		float64		`json:"-" validate:"min=0,max=1"`
+	hidden		string		`validate:"required"`
+}
+
+// valid returns a sample that passes every rule, for cases to break one of.
+func valid() sample {
+	return sample{Name: "Ada"}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+// TestStructRules breaks one rule at a time and checks the field and message
+// it is reported with.
+func TestStructRules(t *testing.T) {
+	tests := []struct {
+		name	string
+		edit	func(*sample)
+		field	string
+		message	string
+	}{
+		{"valid", func(s *sample) {}, "", ""},
+		{"required", func(s *sample) { s.Name = "" }, "name", "is required"},
+		{"min string", func(s *sample) { s.Name = "A" }, "name", "must be at least 2 characters"},
+		{"max string", func(s *sample) { s.Name = "Adelaide" }, "name", "must be at most 5 characters"},
+		{"max counts characters", func(s *sample) { s.Name = "Zoë" + "ß" }, "", ""},
+		{"omitempty skips zero", func(s *sample) { s.Nick = "" }, "", ""},
+		{"omitempty checks the rest", func(s *sample) { s.Nick = "al" }, "nick", "must be at least 3 characters"},
+		{"email", func(s *sample) { s.Email = "ada@example.org" }, "", ""},
+		{"email without domain dot", func(s *sample) { s.Email = "ada@localhost" }, "email", "must be a valid email address"},
+		{"email with name", func(s *sample) { s.Email = "Ada <ada@example.org>" }, "email", "must be a valid email address"},
+		{"username", func(s *sample) { s.Username = "ada.l_ovelace-1" }, "", ""},
+		{"username with space", func(s *sample) { s.Username = "ada l" }, "username", "may only contain letters, digits, '.', '_' and '-'"},
+		{"password", func(s *sample) { s.Password = "correct horse 42" }, "", ""},
+		{"password short", func(s *sample) { s.Password = "abc123" }, "password", "must be at least 8 characters"},
+		{"password long", func(s *sample) { s.Password = strings.Repeat("a1", 37) }, "password", "must be at most 72 bytes"},
+		{"password without digit", func(s *sample) { s.Password = "abcdefghij" }, "password", "must contain at least one letter and one digit"},
+		{"password breached", func(s *sample) { s.Password = "Password123" }, "password", "is too common; it appears in lists of breached passwords"},
+		{"oneof", func(s *sample) { s.Mode = "slow" }, "", ""},
+		{"oneof other", func(s *sample) { s.Mode = "medium" }, "mode", "must be one of fast, slow"},
+		{"oneof pointer", func(s *sample) { s.ModePtr = ptr("fast") }, "", ""},
+		{"oneof pointer other", func(s *sample) { s.ModePtr = ptr("medium") }, "mode_ptr", "must be one of fast, slow"},
+		{"min nil pointer", func(s *sample) { s.Title = nil }, "", ""},
+		{"min pointer", func(s *sample) { s.Title = ptr("") }, "title", "must be at least 1 characters"},
+		{"max pointer", func(s *sample) { s.Title = ptr("abcd") }, "title", "must be at most 3 characters"},
+		{"max slice", func(s *sample) { s.IDs = []uint{1, 2, 3} }, "ids", "must be at most 2 items"},
+		{"min int", func(s *sample) { s.Count = -2 }, "count", "must be at least -1"},
+		{"max int", func(s *sample) { s.Count = 11 }, "count", "must be at most 10"},
+		{"max uint by form name", func(s *sample) { s.Size = 101 }, "size", "must be at most 100"},
+		{"max float by Go name", func(s *sample) { s.Score = 1.5 }, "Score", "must be at most 1"},
+	}
+	for _, tt := range tests {
+		s := valid()
+		tt.edit(&s)
+		err := Struct(&s)
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("%s: got %v, want nil", tt.name, err)
+			}
+			continue
+		}
+
+		var ve *repository.ValidationError
+		if !errors.As(err, &ve) {
+			t.Errorf("%s: got %v, want a validation error", tt.name, err)
+			continue
+		}
+		if len(ve.Fields) != 1 || ve.Fields[0].Field != tt.field || ve.Fields[0].Message != tt.message {
+			t.Errorf("%s: got %+v, want %s %q", tt.name, ve.Fields, tt.field, tt.message)
+		}
+	}
+}
+
+// TestStructAllFields checks that every failing field is listed, in the order
+// of the struct, each with its first failure only.
+func TestStructAllFields(t *testing.T) {
+	s := sample{Nick: "x", Count: 20}
+	err := Struct(s)
+
+	var ve *repository.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	var got []string
+	for _, f := range ve.Fields {
+		got = append(got, f.Field+": "+f.Message)
+	}
+	want := "name: is required; nick: must be at least 3 characters; count: must be at most 10"
+	if strings.Join(got, "; ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, "; "), want)
+	}
+}
+
+// TestStructBadTags checks that a mistake in a tag is an error rather than a
+// panic, every time the type is validated.
+func TestStructBadTags(t *testing.T) {
+	type unknownRule struct {
+		Name	string	`validate:"required,nonempty"`
+	}
+	type badBound struct {
+		Name	string	`validate:"max=ten"`
+	}
+	type emptyOneOf struct {
+		Mode	string	`validate:"oneof="`
+	}
+	type argument struct {
+		Email	string	`validate:"email=strict"`
+	}
+	type stringRule struct {
+		Age		int		`validate:"username"`
+	}
+	type noSize struct {
+		On		bool	`validate:"max=1"`
+	}
+
+	tests := []struct {
+		v		any
+		want	string
+	}{
+		{unknownRule{Name: "x"}, `unknown rule "nonempty"`},
+		{badBound{}, `bad max argument "ten"`},
+		{emptyOneOf{}, "rule oneof lists no values"},
+		{argument{}, "rule email takes no argument"},
+		{stringRule{}, "rule username needs a string, not int"},
+		{noSize{}, "rule max has no size to check on bool"},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ {
+			err := Struct(tt.v)
+			var ve *repository.ValidationError
+			if err == nil || errors.As(err, &ve) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%T, call %d: got %v, want an error with %q", tt.v, i+1, err, tt.want)
+			}
+		}
+	}
+}
+
+// TestStructNotStruct checks that values without fields have nothing to fail.
+func TestStructNotStruct(t *testing.T) {
+	for _, v := range []any{map[string]any{"a": 1}, []int{1}, "text"} {
+		if err := Struct(v); err != nil {
+			t.Errorf("%T: got %v, want nil", v, err)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+	"backend/internal/repository"
+)
+
+
+// Dispatcher sends the events of users' libraries to their webhooks. Events are
+// recorded as deliveries when they happen and sent in the background, one at a
+// time; like the other workers, the queue lives in the database as the pending
+// deliveries, and the channel only saves waiting for the next tick.
+//
+// Each delivery is a POST of the JSON Payload with these headers:
+//
+//	X-Webhook-Event       the event, e.g. document.created
+//	X-Webhook-Delivery    the ID of the delivery
+//	X-Webhook-Timestamp   Unix time of the attempt
+//	X-Webhook-Signature   sha256=HEX, the HMAC-SHA256 with the webhook's secret
+//	                      of the timestamp, a dot and the body
+//
+// Receivers should check the signature and reject old timestamps, which stops
+// replays, and can tell redeliveries apart by the event ID in the payload.
+type Dispatcher struct {
+	Hooks			repository.WebhookRepository
+	// Client posts the deliveries. It should keep them off private networks, as
+	// the fetcher's client does.
+	Client			*http.Client
+
+	queue			chan uint
+	// mu keeps EmitTo from creating a URL's webhook twice.
+	mu				sync.Mutex
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	ID				string		`json:"id"`
+	Event			string		`json:"event"`
+	CreatedAt		time.Time	`json:"created_at"`
+	Data			any			`json:"data"`
+}
+
+// Document is the data of document events: the document without its text.
+type Document struct {
+	ID					uint		`json:"id"`
+	UserID				uint		`json:"user_id"`
+	WorkspaceID			uint		`json:"workspace_id"`
+	Title				string		`json:"title"`
+	Format				string		`json:"format"`
+	Year				int			`json:"year,omitempty"`
+	DOI					string		`json:"doi,omitempty"`
+	ArXivID				string		`json:"arxiv_id,omitempty"`
+	SourceURL			string		`json:"source_url,omitempty"`
+	ExtractionStatus	string		`json:"extraction_status"`
+	OCRStatus			string		`json:"ocr_status,omitempty"`
+	TextLength			int			`json:"text_length"`
+}
+
+// WorkspaceDocument is the data of workspace.document_added.
+type WorkspaceDocument struct {
+	WorkspaceID			uint		`json:"workspace_id"`
+	DocumentID			uint		`json:"document_id"`
+}
+
+const (
+	queueSize		= 256
+	// tick is how often due retries are looked for.
+	tick			= 10 * time.Second
+	// maxAttempts bounds the attempts of a delivery. The waits between them
+	// double from firstRetry, so the last is about four hours after the first.
+	maxAttempts		= 10
+	firstRetry		= 30 * time.Second
+	// maxResponseBody is how much of a response the delivery log keeps.
+	maxResponseBody	= 1024
+)
+
+
+func NewDispatcher(hooks repository.WebhookRepository, client *http.Client) *Dispatcher {
+	log.Println("Initializing webhook dispatcher...")
+	return &Dispatcher{Hooks: hooks, Client: client, queue: make(chan uint, queueSize)}
+}
+
+// Emit records an event for the webhooks of a user that take it, and schedules
+// their deliveries. workspaceID is the workspace the event happened in, or 0.
+func (d *Dispatcher) Emit(event string, userID, workspaceID uint, data any) {
+	hooks, err := d.Hooks.ForEvent(userID, workspaceID)
+	if err != nil {
+		log.Printf("Failed to find webhooks for %s of user ID=%d: %v\n", event, userID, err)
+		return
+	}
+
+	var list []model.Webhook
+	for _, hook := range hooks {
+		if takes(&hook, event) {
+			list = append(list, hook)
+		}
+	}
+	d.record(list, event, data)
+}
+
+// EmitTo records an event for the user's webhook with the URL, whichever events it
+// takes, and schedules its delivery. The webhook is created, with its own secret,
+// the first time a URL is used; the user finds it among their webhooks, where its
+// secret can be rotated to learn it.
+func (d *Dispatcher) EmitTo(event string, userID uint, url string, data any) {
+	d.mu.Lock()
+	hook, err := d.Hooks.GetByURL(userID, url)
+	if errors.Is(err, repository.ErrNotFound) {
+		hook = model.Webhook{UserID: userID, URL: url, Secret: NewSecret(), Events: event, Enabled: true}
+		err = d.Hooks.Create(&hook)
+	}
+	d.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to find the webhook of user ID=%d for %s: %v\n", userID, url, err)
+		return
+	}
+	d.record([]model.Webhook{hook}, event, data)
+}
+
+// record stores a delivery of the event to each webhook and schedules them.
+func (d *Dispatcher) record(hooks []model.Webhook, event string, data any) {
+	if len(hooks) == 0 {
+		return
+	}
+	payload := Payload{ID: newEventID(), Event: event, CreatedAt: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v\n", event, err)
+		return
+	}
+	for _, hook := range hooks {
+		delivery := model.WebhookDelivery{
+			WebhookID:		hook.ID,
+			EventID:		payload.ID,
+			Event:			event,
+			Payload:		string(body),
+			Status:			model.DeliveryPending,
+			NextAttemptAt:	payload.CreatedAt,
+		}
+		if err := d.Hooks.CreateDelivery(&delivery); err != nil {
+			log.Printf("Failed to record %s delivery to webhook ID=%d: %v\n", event, hook.ID, err)
+			continue
+		}
+		d.enqueue(delivery.ID)
+	}
+}
+
+// DocumentEvent emits an event about a document.
+func (d *Dispatcher) DocumentEvent(event string, doc *model.Document) {
+	d.Emit(event, doc.UserID, doc.WorkspaceID, Document{
+		ID:					doc.ID,
+		UserID:				doc.UserID,
+		WorkspaceID:		doc.WorkspaceID,
+		Title:				doc.Title,
+		Format:				doc.Format,
+		Year:				doc.Year,
+		DOI:				doc.DOI,
+		ArXivID:			doc.ArXivID,
+		SourceURL:			doc.SourceURL,
+		ExtractionStatus:	doc.ExtractionStatus,
+		OCRStatus:			doc.OCRStatus,
+		TextLength:			len(doc.ExtractedText),
+	})
+}
+
+// Redeliver sends the event of a delivery again, as a new delivery with its own
+// attempts. The webhook's current URL and secret are used.
+func (d *Dispatcher) Redeliver(id uint) (model.WebhookDelivery, error) {
+	old, err := d.Hooks.GetDelivery(id)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	delivery := model.WebhookDelivery{
+		WebhookID:		old.WebhookID,
+		EventID:		old.EventID,
+		Event:			old.Event,
+		Payload:		old.Payload,
+		Status:			model.DeliveryPending,
+		NextAttemptAt:	time.Now(),
+	}
+	if err := d.Hooks.CreateDelivery(&delivery); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	d.enqueue(delivery.ID)
+	return delivery, nil
+}
+
+// enqueue schedules the first attempt of a delivery. It never blocks; when the
+// channel is full the delivery waits for the next tick.
+func (d *Dispatcher) enqueue(id uint) {
+	select {
+	case d.queue <- id:
+	default:
+	}
+}
+
+// Run sends deliveries until ctx is cancelled, starting with those left pending
+// by a previous run.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Println("Webhook dispatcher started")
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	d.deliverDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook dispatcher stopped")
+			return
+		case id := <-d.queue:
+			d.deliver(ctx, id)
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	ids, err := d.Hooks.DueDeliveries(time.Now(), 100)
+	if err != nil {
+		log.Printf("Failed to list due webhook deliveries: %v\n", err)
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, id)
+	}
+}
+
+// deliver makes an attempt at a pending delivery and records its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, id uint) {
+	delivery, err := d.Hooks.GetDelivery(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Delivery ID=%d: Failed to fetch delivery: %v\n", id, err)
+		return
+	}
+	// A delivery can be both queued and found due; the second attempt waits for
+	// its retry.
+	if delivery.Status != model.DeliveryPending || delivery.NextAttemptAt.After(time.Now()) {
+		return
+	}
+	hook, err := d.Hooks.GetByID(delivery.WebhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Delivery ID=%d: Failed to fetch webhook: %v\n", id, err)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus, delivery.ResponseBody, delivery.Error = 0, "", ""
+	if !hook.Enabled {
+		delivery.Error = "webhook is disabled"
+		delivery.Attempts = maxAttempts
+	} else {
+		d.post(ctx, &hook, &delivery)
+	}
+
+	now := time.Now()
+	switch {
+	case delivery.Error == "":
+		delivery.Status, delivery.DeliveredAt = model.DeliveryDelivered, &now
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = model.DeliveryFailed
+		log.Printf("Delivery ID=%d to webhook ID=%d failed after %d attempts: %s\n", id, hook.ID, delivery.Attempts, delivery.Error)
+	default:
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+	}
+	if err := d.Hooks.SaveAttempt(&delivery); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Delivery ID=%d: Failed to save attempt: %v\n", id, err)
+	}
+}
+
+// post sends the delivery and sets its response, or its error when the webhook
+// couldn't be reached or didn't answer with a 2xx status.
+func (d *Dispatcher) post(ctx context.Context, hook *model.Webhook, delivery *model.WebhookDelivery) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		delivery.Error = truncate(err.Error(), 255)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ResearchAssistant-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		delivery.Error = truncate(err.Error(), 255)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = truncate(string(bytes.ToValidUTF8(body, nil)), maxResponseBody)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		delivery.Error = fmt.Sprintf("webhook answered %s", resp.Status)
+	}
+}
+
+// Sign returns the X-Webhook-Signature of a body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait after a failed attempt: firstRetry, doubling with every
+// attempt after the first.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return firstRetry << min(attempts-1, 16)
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// takes reports whether a webhook subscribes to an event.
+func takes(hook *model.Webhook, event string) bool {
+	if hook.Events == "" {
+		return true
+	}
+	for _, e := range strings.Split(hook.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,164 @@
+// Package client is a typed Go client for the ResearchAssistant /api/v2 API.
+//
+// The request and response types and one method per operation live in
+// client_gen.go, which is generated from the server's OpenAPI document:
+//
+//	go generate ./pkg/client
+package client
+
+//go:generate go run ../../cmd/apigen -out client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+
+// Client calls the API at BaseURL, e.g. "http://localhost:8080". Token, when set,
+// is sent as a bearer token; Login returns one.
+type Client struct {
+	BaseURL			string
+	Token			string
+	HTTPClient		*http.Client
+}
+
+// APIError is a non-2xx response decoded from the server's error envelope.
+type APIError struct {
+	StatusCode		int
+	Code			string				`json:"code"`
+	Message			string				`json:"message"`
+	Details			json.RawMessage		`json:"details,omitempty"`
+	RequestID		string				`json:"request_id"`
+}
+
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("api: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// do sends a JSON request and decodes a JSON response into out, if non-nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var r io.Reader
+	contentType := ""
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r, contentType = bytes.NewReader(b), "application/json"
+	}
+
+	resp, err := c.send(ctx, method, path, query, r, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// doMultipart uploads a form with one file field and decodes the JSON response.
+func (c *Client) doMultipart(ctx context.Context, method, path string, fields map[string]string, fileField, filename string, file io.Reader, out any) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	fw, err := mw.CreateFormFile(fileField, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fw, file); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	resp, err := c.send(ctx, method, path, nil, &buf, mw.FormDataContentType())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// doRaw returns the body of a non-JSON response, which the caller must close.
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, method, path, query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, decode(resp, nil)
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+func decode(resp *http.Response, out any) error {
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		b, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(b, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(b))
+		}
+		return apiErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func setString(q url.Values, name, v string) {
+	if v != "" {
+		q.Set(name, v)
+	}
+}
+
+func setInt(q url.Values, name string, v int64) {
+	if v != 0 {
+		q.Set(name, strconv.FormatInt(v, 10))
+	}
+}
\ No newline at end of file
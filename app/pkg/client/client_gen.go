@@ -0,0 +1,1927 @@
+// Code generated by apigen from the OpenAPI document. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type AuthRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type BulkTagRequest struct {
+	UserID      int64   `json:"user_id,omitempty"`
+	DocumentIDs []int64 `json:"document_ids"`
+	TagIDs      []int64 `json:"tag_ids"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type CitationEdge struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+type CitationGraph struct {
+	Nodes []CitationNode `json:"nodes"`
+	Edges []CitationEdge `json:"edges"`
+}
+
+type CitationNode struct {
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	Year    int64  `json:"year,omitempty"`
+	Doi     string `json:"doi,omitempty"`
+	Cites   int64  `json:"cites"`
+	CitedBy int64  `json:"cited_by"`
+}
+
+type ClipRequest struct {
+	UserID      int64  `json:"user_id,omitempty"`
+	WorkspaceID int64  `json:"workspace_id,omitempty"`
+	URL         string `json:"url"`
+	Html        string `json:"html"`
+	Title       string `json:"title,omitempty"`
+	OnDuplicate string `json:"on_duplicate,omitempty"`
+}
+
+type CreateFeedRequest struct {
+	UserID          int64  `json:"user_id,omitempty"`
+	WorkspaceID     int64  `json:"workspace_id"`
+	Kind            string `json:"kind"`
+	URL             string `json:"url,omitempty"`
+	Query           string `json:"query,omitempty"`
+	Title           string `json:"title,omitempty"`
+	Mode            string `json:"mode,omitempty"`
+	Keywords        string `json:"keywords,omitempty"`
+	IntervalMinutes int64  `json:"interval_minutes,omitempty"`
+	Backfill        bool   `json:"backfill,omitempty"`
+}
+
+type CreateNoteRequest struct {
+	WorkspaceID int64  `json:"workspace_id"`
+	UserID      int64  `json:"user_id,omitempty"`
+	Title       string `json:"title"`
+	Body        string `json:"body,omitempty"`
+}
+
+type CreateTagRequest struct {
+	UserID   int64  `json:"user_id,omitempty"`
+	ParentID *int64 `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+}
+
+type CreateWebhookRequest struct {
+	UserID      int64    `json:"user_id,omitempty"`
+	WorkspaceID int64    `json:"workspace_id,omitempty"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events,omitempty"`
+	Secret      string   `json:"secret,omitempty"`
+}
+
+type CreateWorkspaceRequest struct {
+	UserID int64  `json:"user_id,omitempty"`
+	Title  string `json:"title"`
+}
+
+type DiffHunk struct {
+	FromLine  int64      `json:"from_line"`
+	FromCount int64      `json:"from_count"`
+	ToLine    int64      `json:"to_line"`
+	ToCount   int64      `json:"to_count"`
+	Lines     []DiffLine `json:"lines"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type Document struct {
+	ID               int64            `json:"id"`
+	Title            string           `json:"title"`
+	FilePath         string           `json:"file_path"`
+	ContentHash      string           `json:"content_hash,omitempty"`
+	ExtractedText    string           `json:"extracted_text"`
+	ExtractionStatus string           `json:"extraction_status"`
+	ExtractionError  string           `json:"extraction_error,omitempty"`
+	Doi              string           `json:"doi,omitempty"`
+	ArxivID          string           `json:"arxiv_id,omitempty"`
+	SourceURL        string           `json:"source_url,omitempty"`
+	OcrStatus        string           `json:"ocr_status,omitempty"`
+	UploadedAt       time.Time        `json:"uploaded_at"`
+	Year             int64            `json:"year,omitempty"`
+	Format           string           `json:"format"`
+	ReadingStatus    string           `json:"reading_status"`
+	Version          int64            `json:"version"`
+	Abstract         string           `json:"abstract,omitempty"`
+	Venue            string           `json:"venue,omitempty"`
+	CitationCount    *int64           `json:"citation_count,omitempty"`
+	OpenAccessURL    string           `json:"open_access_url,omitempty"`
+	EnrichmentStatus string           `json:"enrichment_status,omitempty"`
+	EnrichedAt       *time.Time       `json:"enriched_at,omitempty"`
+	WorkspaceID      int64            `json:"workspace_id"`
+	UserID           int64            `json:"user_id"`
+	Authors          []DocumentAuthor `json:"authors,omitempty"`
+	Tags             []Tag            `json:"tags,omitempty"`
+}
+
+type DocumentAuthor struct {
+	Name     string `json:"name"`
+	Position int64  `json:"position"`
+}
+
+type DocumentDiff struct {
+	From    int64      `json:"from"`
+	To      int64      `json:"to"`
+	Added   int64      `json:"added"`
+	Removed int64      `json:"removed"`
+	Hunks   []DiffHunk `json:"hunks"`
+}
+
+type DocumentFacets struct {
+	Tags            []FacetCount `json:"tags"`
+	Workspaces      []FacetCount `json:"workspaces"`
+	Years           []FacetCount `json:"years"`
+	Authors         []FacetCount `json:"authors"`
+	Formats         []FacetCount `json:"formats"`
+	ReadingStatuses []FacetCount `json:"reading_statuses"`
+	Extraction      []FacetCount `json:"extraction"`
+}
+
+type DocumentList struct {
+	Items      []Document      `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Facets     *DocumentFacets `json:"facets,omitempty"`
+}
+
+type DocumentPage struct {
+	Page       int64    `json:"page"`
+	Text       string   `json:"text"`
+	Source     string   `json:"source"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+type DocumentReference struct {
+	ID              int64  `json:"id"`
+	DocumentID      int64  `json:"document_id"`
+	Position        int64  `json:"position"`
+	Raw             string `json:"raw"`
+	Authors         string `json:"authors,omitempty"`
+	Title           string `json:"title,omitempty"`
+	Year            int64  `json:"year,omitempty"`
+	Doi             string `json:"doi,omitempty"`
+	CitedDocumentID *int64 `json:"cited_document_id,omitempty"`
+}
+
+type DocumentSection struct {
+	DocumentID int64  `json:"document_id"`
+	Position   int64  `json:"position"`
+	Kind       string `json:"kind"`
+	Heading    string `json:"heading"`
+	Level      int64  `json:"level"`
+	Page       int64  `json:"page,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+type DocumentVersion struct {
+	ID          int64     `json:"id"`
+	DocumentID  int64     `json:"document_id"`
+	Version     int64     `json:"version"`
+	FilePath    string    `json:"file_path"`
+	ContentHash string    `json:"content_hash,omitempty"`
+	Format      string    `json:"format,omitempty"`
+	ReplacedAt  time.Time `json:"replaced_at"`
+}
+
+type DocumentVersionList struct {
+	Items      []DocumentVersion `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type DuplicateGroup struct {
+	Similarity float64    `json:"similarity"`
+	Documents  []Document `json:"documents"`
+}
+
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id"`
+}
+
+type ExtractRequest struct {
+	Password string `json:"password,omitempty"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type Feed struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"`
+	WorkspaceID     int64      `json:"workspace_id"`
+	Kind            string     `json:"kind"`
+	URL             string     `json:"url,omitempty"`
+	Query           string     `json:"query,omitempty"`
+	Title           string     `json:"title"`
+	Mode            string     `json:"mode"`
+	Keywords        string     `json:"keywords,omitempty"`
+	IntervalMinutes int64      `json:"interval_minutes"`
+	Enabled         bool       `json:"enabled"`
+	Backfill        bool       `json:"backfill"`
+	LastPolledAt    *time.Time `json:"last_polled_at,omitempty"`
+	NextPollAt      time.Time  `json:"next_poll_at"`
+	LastError       string     `json:"last_error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type FeedItem struct {
+	ID          int64      `json:"id"`
+	FeedID      int64      `json:"feed_id"`
+	Guid        string     `json:"guid"`
+	UserID      int64      `json:"user_id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link,omitempty"`
+	PDFURL      string     `json:"pdf_url,omitempty"`
+	Summary     string     `json:"summary,omitempty"`
+	Authors     string     `json:"authors,omitempty"`
+	Doi         string     `json:"doi,omitempty"`
+	ArxivID     string     `json:"arxiv_id,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Status      string     `json:"status"`
+	DocumentID  *int64     `json:"document_id,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type FeedItemList struct {
+	Items      []FeedItem `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type FeedList struct {
+	Items      []Feed `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ImportDirectoryRequest struct {
+	UserID      int64  `json:"user_id,omitempty"`
+	WorkspaceID int64  `json:"workspace_id,omitempty"`
+	Path        string `json:"path"`
+}
+
+type ImportReport struct {
+	Imported int64          `json:"imported"`
+	Skipped  int64          `json:"skipped"`
+	Failed   int64          `json:"failed"`
+	Files    []ImportedFile `json:"files"`
+}
+
+type ImportURLRequest struct {
+	UserID      int64  `json:"user_id,omitempty"`
+	WorkspaceID int64  `json:"workspace_id,omitempty"`
+	Source      string `json:"source"`
+	Title       string `json:"title,omitempty"`
+	Year        int64  `json:"year,omitempty"`
+	Authors     string `json:"authors,omitempty"`
+	OnDuplicate string `json:"on_duplicate,omitempty"`
+}
+
+type ImportedFile struct {
+	File       string `json:"file"`
+	Status     string `json:"status"`
+	DocumentID int64  `json:"document_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	Message   string    `json:"message"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    int64     `json:"user_id"`
+}
+
+type MarkReadRequest struct {
+	UserID int64   `json:"user_id,omitempty"`
+	IDs    []int64 `json:"ids,omitempty"`
+}
+
+type MergeRequest struct {
+	UserID       int64   `json:"user_id,omitempty"`
+	DuplicateIDs []int64 `json:"duplicate_ids"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type Note struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	UserID      int64     `json:"user_id"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type NoteBacklink struct {
+	NoteID      int64  `json:"note_id"`
+	WorkspaceID int64  `json:"workspace_id"`
+	Title       string `json:"title"`
+	Page        int64  `json:"page,omitempty"`
+}
+
+type NoteList struct {
+	Items      []Note `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type NoteRevision struct {
+	ID        int64     `json:"id"`
+	NoteID    int64     `json:"note_id"`
+	Revision  int64     `json:"revision"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NoteRevisionList struct {
+	Items      []NoteRevision `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type Notification struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Kind          string     `json:"kind"`
+	Title         string     `json:"title"`
+	Body          string     `json:"body,omitempty"`
+	DocumentID    *int64     `json:"document_id,omitempty"`
+	SavedSearchID *int64     `json:"saved_search_id,omitempty"`
+	FeedID        *int64     `json:"feed_id,omitempty"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type NotificationList struct {
+	Items      []Notification `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type NotificationSettings struct {
+	UserID       int64      `json:"user_id"`
+	Email        string     `json:"email"`
+	WebhookURL   string     `json:"webhook_url,omitempty"`
+	Muted        string     `json:"muted,omitempty"`
+	LastDigestAt *time.Time `json:"last_digest_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type NotificationSettingsRequest struct {
+	UserID     int64    `json:"user_id,omitempty"`
+	Email      string   `json:"email"`
+	WebhookURL string   `json:"webhook_url,omitempty"`
+	Muted      []string `json:"muted,omitempty"`
+}
+
+type PollResult struct {
+	NotModified bool  `json:"not_modified"`
+	Entries     int64 `json:"entries"`
+	New         int64 `json:"new"`
+	Imported    int64 `json:"imported"`
+	Inbox       int64 `json:"inbox"`
+	Duplicates  int64 `json:"duplicates"`
+	Skipped     int64 `json:"skipped"`
+	Failed      int64 `json:"failed"`
+}
+
+type ReadingStatusRequest struct {
+	UserID      int64   `json:"user_id,omitempty"`
+	DocumentIDs []int64 `json:"document_ids"`
+	Status      string  `json:"status"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type SaveSearchRequest struct {
+	UserID      int64   `json:"user_id,omitempty"`
+	WorkspaceID int64   `json:"workspace_id,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Mode        string  `json:"mode,omitempty"`
+	Query       string  `json:"query,omitempty"`
+	MinScore    float64 `json:"min_score,omitempty"`
+	Year        int64   `json:"year,omitempty"`
+	Author      string  `json:"author,omitempty"`
+	Format      string  `json:"format,omitempty"`
+	NotifyEmail bool    `json:"notify_email,omitempty"`
+	WebhookURL  string  `json:"webhook_url,omitempty"`
+}
+
+type SavedSearch struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	WorkspaceID   int64      `json:"workspace_id"`
+	Name          string     `json:"name"`
+	Mode          string     `json:"mode"`
+	Query         string     `json:"query,omitempty"`
+	MinScore      float64    `json:"min_score,omitempty"`
+	Year          int64      `json:"year,omitempty"`
+	Author        string     `json:"author,omitempty"`
+	Format        string     `json:"format,omitempty"`
+	NotifyEmail   bool       `json:"notify_email"`
+	WebhookURL    string     `json:"webhook_url,omitempty"`
+	LastMatchedAt *time.Time `json:"last_matched_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type SavedSearchList struct {
+	Items      []SavedSearch `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type SearchResponse struct {
+	Documents []Document        `json:"documents"`
+	Notes     []Note            `json:"notes"`
+	Sections  []DocumentSection `json:"sections,omitempty"`
+}
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	ParentID  *int64    `json:"parent_id"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TagList struct {
+	Items      []Tag  `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+type UpdateFeedRequest struct {
+	Title           *string `json:"title,omitempty"`
+	Mode            *string `json:"mode,omitempty"`
+	Keywords        *string `json:"keywords,omitempty"`
+	IntervalMinutes *int64  `json:"interval_minutes,omitempty"`
+	Enabled         *bool   `json:"enabled,omitempty"`
+}
+
+type UpdateNoteRequest struct {
+	Title *string `json:"title,omitempty"`
+	Body  *string `json:"body,omitempty"`
+}
+
+type UpdateSearchRequest struct {
+	WorkspaceID *int64   `json:"workspace_id,omitempty"`
+	Name        *string  `json:"name,omitempty"`
+	Mode        *string  `json:"mode,omitempty"`
+	Query       *string  `json:"query,omitempty"`
+	MinScore    *float64 `json:"min_score,omitempty"`
+	Year        *int64   `json:"year,omitempty"`
+	Author      *string  `json:"author,omitempty"`
+	Format      *string  `json:"format,omitempty"`
+	NotifyEmail *bool    `json:"notify_email,omitempty"`
+	WebhookURL  *string  `json:"webhook_url,omitempty"`
+}
+
+type UpdateWebhookRequest struct {
+	URL          *string  `json:"url,omitempty"`
+	Events       []string `json:"events,omitempty"`
+	Enabled      *bool    `json:"enabled,omitempty"`
+	RotateSecret bool     `json:"rotate_secret,omitempty"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Title       *string `json:"title,omitempty"`
+	SkipOcr     *bool   `json:"skip_ocr,omitempty"`
+	OcrLanguage *string `json:"ocr_language,omitempty"`
+}
+
+type UserResponse struct {
+	ID          int64      `json:"id"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+type Webhook struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      string    `json:"events,omitempty"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	EventID        string     `json:"event_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int64      `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	ResponseStatus int64      `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type WebhookDeliveryList struct {
+	Items      []WebhookDelivery `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type WebhookList struct {
+	Items      []Webhook `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type Workspace struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	Title       string    `json:"title"`
+	SkipOcr     bool      `json:"skip_ocr"`
+	OcrLanguage string    `json:"ocr_language,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WorkspaceList struct {
+	Items      []Workspace `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// Login calls POST /api/v2/auth/login: Check a username and password.
+func (c *Client) Login(ctx context.Context, body LoginRequest) (*LoginResponse, error) {
+	path := "/api/v2/auth/login"
+	var out LoginResponse
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Logout calls POST /api/v2/auth/logout: Revoke the bearer token.
+func (c *Client) Logout(ctx context.Context) error {
+	path := "/api/v2/auth/logout"
+	return c.do(ctx, "POST", path, nil, nil, nil)
+}
+
+// Me calls GET /api/v2/auth/me: Get the user the bearer token belongs to.
+func (c *Client) Me(ctx context.Context) (*UserResponse, error) {
+	path := "/api/v2/auth/me"
+	var out UserResponse
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangePassword calls POST /api/v2/auth/password: Change the password, revoking every session; the response has a new token.
+func (c *Client) ChangePassword(ctx context.Context, body ChangePasswordRequest) (*LoginResponse, error) {
+	path := "/api/v2/auth/password"
+	var out LoginResponse
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ForgotPassword calls POST /api/v2/auth/password/forgot: Mail a password reset link to an account's email.
+func (c *Client) ForgotPassword(ctx context.Context, body ForgotPasswordRequest) (*MessageResponse, error) {
+	path := "/api/v2/auth/password/forgot"
+	var out MessageResponse
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResetPassword calls POST /api/v2/auth/password/reset: Set a new password with the token of a reset link.
+func (c *Client) ResetPassword(ctx context.Context, body ResetPasswordRequest) (*MessageResponse, error) {
+	path := "/api/v2/auth/password/reset"
+	var out MessageResponse
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Register calls POST /api/v2/auth/register: Create a user account.
+func (c *Client) Register(ctx context.Context, body AuthRequest) (*MessageResponse, error) {
+	path := "/api/v2/auth/register"
+	var out MessageResponse
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListDocumentsParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Tag ID; includes documents tagged with its descendants.
+	Tag *int64
+	// Workspace ID; 0 selects documents in no workspace.
+	WorkspaceID *int64
+	Year        *int64
+	Author      string
+	Format      string
+	// Reading status: unread, reading or read.
+	Status string
+	// Extraction outcome: ok, encrypted, no_text or corrupt.
+	Extraction string
+	// RFC 3339 timestamp or YYYY-MM-DD date.
+	UploadedFrom string
+	// RFC 3339 timestamp or YYYY-MM-DD date, inclusive.
+	UploadedTo string
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListDocuments calls GET /api/v2/documents: List and filter a user's documents.
+func (c *Client) ListDocuments(ctx context.Context, params ListDocumentsParams) (*DocumentList, error) {
+	path := "/api/v2/documents"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Tag != nil {
+		q.Set("tag", strconv.FormatInt(*params.Tag, 10))
+	}
+	if params.WorkspaceID != nil {
+		q.Set("workspace_id", strconv.FormatInt(*params.WorkspaceID, 10))
+	}
+	if params.Year != nil {
+		q.Set("year", strconv.FormatInt(*params.Year, 10))
+	}
+	if params.Author != "" {
+		q.Set("author", params.Author)
+	}
+	if params.Format != "" {
+		q.Set("format", params.Format)
+	}
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	if params.Extraction != "" {
+		q.Set("extraction", params.Extraction)
+	}
+	if params.UploadedFrom != "" {
+		q.Set("uploaded_from", params.UploadedFrom)
+	}
+	if params.UploadedTo != "" {
+		q.Set("uploaded_to", params.UploadedTo)
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out DocumentList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type UploadDocumentForm struct {
+	UserID      int64  `json:"user_id,omitempty"`
+	WorkspaceID int64  `json:"workspace_id,omitempty"`
+	Title       string `json:"title"`
+	Year        int64  `json:"year,omitempty"`
+	Authors     string `json:"authors,omitempty"`
+	OnDuplicate string `json:"on_duplicate,omitempty"`
+	Password    string `json:"password,omitempty"`
+}
+
+// UploadDocument calls POST /api/v2/documents: Upload a PDF.
+func (c *Client) UploadDocument(ctx context.Context, form UploadDocumentForm, filename string, file io.Reader) (*Document, error) {
+	path := "/api/v2/documents"
+	fields := map[string]string{}
+	if form.UserID != 0 {
+		fields["user_id"] = strconv.FormatInt(form.UserID, 10)
+	}
+	if form.WorkspaceID != 0 {
+		fields["workspace_id"] = strconv.FormatInt(form.WorkspaceID, 10)
+	}
+	fields["title"] = form.Title
+	if form.Year != 0 {
+		fields["year"] = strconv.FormatInt(form.Year, 10)
+	}
+	if form.Authors != "" {
+		fields["authors"] = form.Authors
+	}
+	if form.OnDuplicate != "" {
+		fields["on_duplicate"] = form.OnDuplicate
+	}
+	if form.Password != "" {
+		fields["password"] = form.Password
+	}
+	var out Document
+	if err := c.doMultipart(ctx, "POST", path, fields, "pdf", filename, file, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClipPage calls POST /api/v2/documents/clip: Save the article of a web page as a document.
+func (c *Client) ClipPage(ctx context.Context, body ClipRequest) (*Document, error) {
+	path := "/api/v2/documents/clip"
+	var out Document
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListDuplicateDocumentsParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Minimum similarity from 0.5 to 1; defaults to 0.8.
+	Threshold *float64
+}
+
+// ListDuplicateDocuments calls GET /api/v2/documents/duplicates: Group documents whose text is nearly the same.
+func (c *Client) ListDuplicateDocuments(ctx context.Context, params ListDuplicateDocumentsParams) ([]DuplicateGroup, error) {
+	path := "/api/v2/documents/duplicates"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Threshold != nil {
+		q.Set("threshold", strconv.FormatFloat(*params.Threshold, 'g', -1, 64))
+	}
+	var out []DuplicateGroup
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type ImportArchiveForm struct {
+	UserID      int64 `json:"user_id,omitempty"`
+	WorkspaceID int64 `json:"workspace_id,omitempty"`
+}
+
+// ImportArchive calls POST /api/v2/documents/import: Import every PDF in a ZIP or tar archive.
+func (c *Client) ImportArchive(ctx context.Context, form ImportArchiveForm, filename string, file io.Reader) (*ImportReport, error) {
+	path := "/api/v2/documents/import"
+	fields := map[string]string{}
+	if form.UserID != 0 {
+		fields["user_id"] = strconv.FormatInt(form.UserID, 10)
+	}
+	if form.WorkspaceID != 0 {
+		fields["workspace_id"] = strconv.FormatInt(form.WorkspaceID, 10)
+	}
+	var out ImportReport
+	if err := c.doMultipart(ctx, "POST", path, fields, "archive", filename, file, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportURL calls POST /api/v2/documents/import-url: Import a PDF from a URL, DOI or arXiv ID.
+func (c *Client) ImportURL(ctx context.Context, body ImportURLRequest) (*Document, error) {
+	path := "/api/v2/documents/import-url"
+	var out Document
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportDirectory calls POST /api/v2/documents/import/directory: Import every PDF below a server directory.
+func (c *Client) ImportDirectory(ctx context.Context, body ImportDirectoryRequest) (*ImportReport, error) {
+	path := "/api/v2/documents/import/directory"
+	var out ImportReport
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateReadingStatus calls POST /api/v2/documents/reading-status: Set the reading status of documents.
+func (c *Client) UpdateReadingStatus(ctx context.Context, body ReadingStatusRequest) (map[string]int64, error) {
+	path := "/api/v2/documents/reading-status"
+	var out map[string]int64
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TagDocuments calls POST /api/v2/documents/tags: Add tags to documents.
+func (c *Client) TagDocuments(ctx context.Context, body BulkTagRequest) (map[string]int64, error) {
+	path := "/api/v2/documents/tags"
+	var out map[string]int64
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UntagDocuments calls DELETE /api/v2/documents/tags: Remove tags from documents.
+func (c *Client) UntagDocuments(ctx context.Context, body BulkTagRequest) (map[string]int64, error) {
+	path := "/api/v2/documents/tags"
+	var out map[string]int64
+	if err := c.do(ctx, "DELETE", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetDocument calls GET /api/v2/documents/{id}: Get a document.
+func (c *Client) GetDocument(ctx context.Context, id int64) (*Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d", id)
+	var out Document
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteDocument calls DELETE /api/v2/documents/{id}: Delete a document and its file.
+func (c *Client) DeleteDocument(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/documents/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+// GetDocumentBacklinks calls GET /api/v2/documents/{id}/backlinks: List notes that link to a document.
+func (c *Client) GetDocumentBacklinks(ctx context.Context, id int64) ([]NoteBacklink, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/backlinks", id)
+	var out []NoteBacklink
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCitingDocuments calls GET /api/v2/documents/{id}/cited-by: List the documents in the library that cite a document.
+func (c *Client) ListCitingDocuments(ctx context.Context, id int64) ([]Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/cited-by", id)
+	var out []Document
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCitedDocuments calls GET /api/v2/documents/{id}/cites: List the documents in the library that a document cites.
+func (c *Client) ListCitedDocuments(ctx context.Context, id int64) ([]Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/cites", id)
+	var out []Document
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type DiffDocumentVersionsParams struct {
+	// Earlier version; defaults to the one before to.
+	From *int64
+	// Later version; defaults to the current version.
+	To *int64
+	// unified returns the diff as plain text.
+	Format string
+}
+
+// DiffDocumentVersions calls GET /api/v2/documents/{id}/diff: Compare the text of two versions.
+func (c *Client) DiffDocumentVersions(ctx context.Context, id int64, params DiffDocumentVersionsParams) (*DocumentDiff, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/diff", id)
+	q := url.Values{}
+	if params.From != nil {
+		q.Set("from", strconv.FormatInt(*params.From, 10))
+	}
+	if params.To != nil {
+		q.Set("to", strconv.FormatInt(*params.To, 10))
+	}
+	if params.Format != "" {
+		q.Set("format", params.Format)
+	}
+	var out DocumentDiff
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnrichDocument calls POST /api/v2/documents/{id}/enrich: Queue a metadata lookup by DOI or arXiv ID.
+func (c *Client) EnrichDocument(ctx context.Context, id int64) (*Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/enrich", id)
+	var out Document
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExtractDocument calls POST /api/v2/documents/{id}/extract: Extract the text again, e.g. with a PDF password.
+func (c *Client) ExtractDocument(ctx context.Context, id int64, body ExtractRequest) (*Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/extract", id)
+	var out Document
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDocumentFile calls GET /api/v2/documents/{id}/file: Download the original file.
+func (c *Client) GetDocumentFile(ctx context.Context, id int64) (io.ReadCloser, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/file", id)
+	return c.doRaw(ctx, "GET", path, nil)
+}
+
+type ReplaceDocumentFileForm struct {
+	Password string `json:"password,omitempty"`
+}
+
+// ReplaceDocumentFile calls PUT /api/v2/documents/{id}/file: Replace the file, keeping the old one as a version.
+func (c *Client) ReplaceDocumentFile(ctx context.Context, id int64, form ReplaceDocumentFileForm, filename string, file io.Reader) (*Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/file", id)
+	fields := map[string]string{}
+	if form.Password != "" {
+		fields["password"] = form.Password
+	}
+	var out Document
+	if err := c.doMultipart(ctx, "PUT", path, fields, "pdf", filename, file, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MergeDocuments calls POST /api/v2/documents/{id}/merge: Merge duplicate documents into this one.
+func (c *Client) MergeDocuments(ctx context.Context, id int64, body MergeRequest) (*Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/merge", id)
+	var out Document
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecognizeDocument calls POST /api/v2/documents/{id}/ocr: Queue the scanned pages of a document for OCR.
+func (c *Client) RecognizeDocument(ctx context.Context, id int64) (*Document, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/ocr", id)
+	var out Document
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDocumentOutline calls GET /api/v2/documents/{id}/outline: List the sections of a document without their text.
+func (c *Client) GetDocumentOutline(ctx context.Context, id int64) ([]DocumentSection, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/outline", id)
+	var out []DocumentSection
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListDocumentPages calls GET /api/v2/documents/{id}/pages: List the text of each page, with OCR confidence.
+func (c *Client) ListDocumentPages(ctx context.Context, id int64) ([]DocumentPage, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/pages", id)
+	var out []DocumentPage
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListDocumentReferences calls GET /api/v2/documents/{id}/references: List the parsed reference list of a document.
+func (c *Client) ListDocumentReferences(ctx context.Context, id int64) ([]DocumentReference, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/references", id)
+	var out []DocumentReference
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type ListDocumentSectionsParams struct {
+	// Kinds: title, abstract, introduction, methods, results, discussion, conclusion, references, appendix, other.
+	Kind string
+}
+
+// ListDocumentSections calls GET /api/v2/documents/{id}/sections: Get the text of a document's sections.
+func (c *Client) ListDocumentSections(ctx context.Context, id int64, params ListDocumentSectionsParams) ([]DocumentSection, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/sections", id)
+	q := url.Values{}
+	if params.Kind != "" {
+		q.Set("kind", params.Kind)
+	}
+	var out []DocumentSection
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type ListDocumentVersionsParams struct {
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListDocumentVersions calls GET /api/v2/documents/{id}/versions: List the earlier versions of a document.
+func (c *Client) ListDocumentVersions(ctx context.Context, id int64, params ListDocumentVersionsParams) (*DocumentVersionList, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/versions", id)
+	q := url.Values{}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out DocumentVersionList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDocumentVersionFile calls GET /api/v2/documents/{id}/versions/{version}/file: Download the file of an earlier version.
+func (c *Client) GetDocumentVersionFile(ctx context.Context, id int64, version int64) (io.ReadCloser, error) {
+	path := fmt.Sprintf("/api/v2/documents/%d/versions/%d/file", id, version)
+	return c.doRaw(ctx, "GET", path, nil)
+}
+
+// AcceptFeedItem calls POST /api/v2/feed-items/{id}/accept: Import a feed entry from the inbox.
+func (c *Client) AcceptFeedItem(ctx context.Context, id int64) (*FeedItem, error) {
+	path := fmt.Sprintf("/api/v2/feed-items/%d/accept", id)
+	var out FeedItem
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DismissFeedItem calls POST /api/v2/feed-items/{id}/dismiss: Dismiss a feed entry from the inbox.
+func (c *Client) DismissFeedItem(ctx context.Context, id int64) (*FeedItem, error) {
+	path := fmt.Sprintf("/api/v2/feed-items/%d/dismiss", id)
+	var out FeedItem
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateFeed calls POST /api/v2/feeds: Subscribe a workspace to an RSS or Atom feed or an arXiv search.
+func (c *Client) CreateFeed(ctx context.Context, body CreateFeedRequest) (*Feed, error) {
+	path := "/api/v2/feeds"
+	var out Feed
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFeed calls GET /api/v2/feeds/{id}: Get a feed.
+func (c *Client) GetFeed(ctx context.Context, id int64) (*Feed, error) {
+	path := fmt.Sprintf("/api/v2/feeds/%d", id)
+	var out Feed
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateFeed calls PATCH /api/v2/feeds/{id}: Rename a feed or change its settings.
+func (c *Client) UpdateFeed(ctx context.Context, id int64, body UpdateFeedRequest) (*Feed, error) {
+	path := fmt.Sprintf("/api/v2/feeds/%d", id)
+	var out Feed
+	if err := c.do(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteFeed calls DELETE /api/v2/feeds/{id}: Unsubscribe from a feed, keeping the documents imported from it.
+func (c *Client) DeleteFeed(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/feeds/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+type ListFeedItemsParams struct {
+	// inbox, imported, duplicate, dismissed, skipped or failed.
+	Status string
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListFeedItems calls GET /api/v2/feeds/{id}/items: List the entries seen in a feed.
+func (c *Client) ListFeedItems(ctx context.Context, id int64, params ListFeedItemsParams) (*FeedItemList, error) {
+	path := fmt.Sprintf("/api/v2/feeds/%d/items", id)
+	q := url.Values{}
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out FeedItemList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PollFeed calls POST /api/v2/feeds/{id}/poll: Poll a feed now.
+func (c *Client) PollFeed(ctx context.Context, id int64) (*PollResult, error) {
+	path := fmt.Sprintf("/api/v2/feeds/%d/poll", id)
+	var out PollResult
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HealthCheck calls GET /api/v2/health: Report that the server is up.
+func (c *Client) HealthCheck(ctx context.Context) (io.ReadCloser, error) {
+	path := "/api/v2/health"
+	return c.doRaw(ctx, "GET", path, nil)
+}
+
+// CreateNote calls POST /api/v2/notes: Create a note.
+func (c *Client) CreateNote(ctx context.Context, body CreateNoteRequest) (*Note, error) {
+	path := "/api/v2/notes"
+	var out Note
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNote calls GET /api/v2/notes/{id}: Get a note.
+func (c *Client) GetNote(ctx context.Context, id int64) (*Note, error) {
+	path := fmt.Sprintf("/api/v2/notes/%d", id)
+	var out Note
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateNote calls PUT /api/v2/notes/{id}: Update a note, recording a revision.
+func (c *Client) UpdateNote(ctx context.Context, id int64, body UpdateNoteRequest) (*Note, error) {
+	path := fmt.Sprintf("/api/v2/notes/%d", id)
+	var out Note
+	if err := c.do(ctx, "PUT", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteNote calls DELETE /api/v2/notes/{id}: Delete a note.
+func (c *Client) DeleteNote(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/notes/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+// GetNoteBacklinks calls GET /api/v2/notes/{id}/backlinks: List notes that link to a note.
+func (c *Client) GetNoteBacklinks(ctx context.Context, id int64) ([]NoteBacklink, error) {
+	path := fmt.Sprintf("/api/v2/notes/%d/backlinks", id)
+	var out []NoteBacklink
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type ListNoteRevisionsParams struct {
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListNoteRevisions calls GET /api/v2/notes/{id}/revisions: List the revisions of a note.
+func (c *Client) ListNoteRevisions(ctx context.Context, id int64, params ListNoteRevisionsParams) (*NoteRevisionList, error) {
+	path := fmt.Sprintf("/api/v2/notes/%d/revisions", id)
+	q := url.Values{}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out NoteRevisionList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type GetNotificationSettingsParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+}
+
+// GetNotificationSettings calls GET /api/v2/notification-settings: Get how a user is notified.
+func (c *Client) GetNotificationSettings(ctx context.Context, params GetNotificationSettingsParams) (*NotificationSettings, error) {
+	path := "/api/v2/notification-settings"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	var out NotificationSettings
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateNotificationSettings calls PUT /api/v2/notification-settings: Choose the email, digest and webhook delivery of notifications.
+func (c *Client) UpdateNotificationSettings(ctx context.Context, body NotificationSettingsRequest) (*NotificationSettings, error) {
+	path := "/api/v2/notification-settings"
+	var out NotificationSettings
+	if err := c.do(ctx, "PUT", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListNotificationsParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Only the unread notifications.
+	Unread bool
+	// search.match, document.ready or feed.entries.
+	Kind string
+	// Only the matches of this saved search.
+	SavedSearchID *int64
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListNotifications calls GET /api/v2/notifications: List a user's notifications.
+func (c *Client) ListNotifications(ctx context.Context, params ListNotificationsParams) (*NotificationList, error) {
+	path := "/api/v2/notifications"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Unread {
+		q.Set("unread", "true")
+	}
+	if params.Kind != "" {
+		q.Set("kind", params.Kind)
+	}
+	if params.SavedSearchID != nil {
+		q.Set("saved_search_id", strconv.FormatInt(*params.SavedSearchID, 10))
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out NotificationList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReadNotifications calls POST /api/v2/notifications/read: Mark notifications read.
+func (c *Client) ReadNotifications(ctx context.Context, body MarkReadRequest) (map[string]int64, error) {
+	path := "/api/v2/notifications/read"
+	var out map[string]int64
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type CountUnreadNotificationsParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+}
+
+// CountUnreadNotifications calls GET /api/v2/notifications/unread-count: Count a user's unread notifications.
+func (c *Client) CountUnreadNotifications(ctx context.Context, params CountUnreadNotificationsParams) (*UnreadCountResponse, error) {
+	path := "/api/v2/notifications/unread-count"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	var out UnreadCountResponse
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReadNotification calls POST /api/v2/notifications/{id}/read: Mark a notification read.
+func (c *Client) ReadNotification(ctx context.Context, id int64) (*Notification, error) {
+	path := fmt.Sprintf("/api/v2/notifications/%d/read", id)
+	var out Notification
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListSavedSearchesParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListSavedSearches calls GET /api/v2/saved-searches: List a user's saved searches.
+func (c *Client) ListSavedSearches(ctx context.Context, params ListSavedSearchesParams) (*SavedSearchList, error) {
+	path := "/api/v2/saved-searches"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out SavedSearchList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SaveSearch calls POST /api/v2/saved-searches: Save a search to be alerted of new matching documents.
+func (c *Client) SaveSearch(ctx context.Context, body SaveSearchRequest) (*SavedSearch, error) {
+	path := "/api/v2/saved-searches"
+	var out SavedSearch
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSavedSearch calls GET /api/v2/saved-searches/{id}: Get a saved search.
+func (c *Client) GetSavedSearch(ctx context.Context, id int64) (*SavedSearch, error) {
+	path := fmt.Sprintf("/api/v2/saved-searches/%d", id)
+	var out SavedSearch
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSavedSearch calls PATCH /api/v2/saved-searches/{id}: Change a saved search.
+func (c *Client) UpdateSavedSearch(ctx context.Context, id int64, body UpdateSearchRequest) (*SavedSearch, error) {
+	path := fmt.Sprintf("/api/v2/saved-searches/%d", id)
+	var out SavedSearch
+	if err := c.do(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSavedSearch calls DELETE /api/v2/saved-searches/{id}: Delete a saved search, keeping its notifications.
+func (c *Client) DeleteSavedSearch(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/saved-searches/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+type SearchParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	Q      string
+	// Only search this workspace.
+	WorkspaceID *int64
+	// Only search sections of this kind; notes are left out. Kinds: title, abstract, introduction, methods, results, discussion, conclusion, references, appendix, other.
+	Section string
+}
+
+// Search calls GET /api/v2/search: Search documents and notes.
+func (c *Client) Search(ctx context.Context, params SearchParams) (*SearchResponse, error) {
+	path := "/api/v2/search"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	q.Set("q", params.Q)
+	if params.WorkspaceID != nil {
+		q.Set("workspace_id", strconv.FormatInt(*params.WorkspaceID, 10))
+	}
+	if params.Section != "" {
+		q.Set("section", params.Section)
+	}
+	var out SearchResponse
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListTagsParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListTags calls GET /api/v2/tags: List a user's tags.
+func (c *Client) ListTags(ctx context.Context, params ListTagsParams) (*TagList, error) {
+	path := "/api/v2/tags"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out TagList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateTag calls POST /api/v2/tags: Create a tag.
+func (c *Client) CreateTag(ctx context.Context, body CreateTagRequest) (*Tag, error) {
+	path := "/api/v2/tags"
+	var out Tag
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTag calls DELETE /api/v2/tags/{id}: Delete a tag and its descendants.
+func (c *Client) DeleteTag(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/tags/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+// RedeliverWebhookDelivery calls POST /api/v2/webhook-deliveries/{id}/redeliver: Send the event of a delivery again.
+func (c *Client) RedeliverWebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	path := fmt.Sprintf("/api/v2/webhook-deliveries/%d/redeliver", id)
+	var out WebhookDelivery
+	if err := c.do(ctx, "POST", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListWebhooksParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListWebhooks calls GET /api/v2/webhooks: List a user's webhooks.
+func (c *Client) ListWebhooks(ctx context.Context, params ListWebhooksParams) (*WebhookList, error) {
+	path := "/api/v2/webhooks"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out WebhookList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateWebhook calls POST /api/v2/webhooks: Subscribe a URL to library events; the response has the signing secret.
+func (c *Client) CreateWebhook(ctx context.Context, body CreateWebhookRequest) (*Webhook, error) {
+	path := "/api/v2/webhooks"
+	var out Webhook
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWebhook calls GET /api/v2/webhooks/{id}: Get a webhook.
+func (c *Client) GetWebhook(ctx context.Context, id int64) (*Webhook, error) {
+	path := fmt.Sprintf("/api/v2/webhooks/%d", id)
+	var out Webhook
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateWebhook calls PATCH /api/v2/webhooks/{id}: Change a webhook or rotate its secret.
+func (c *Client) UpdateWebhook(ctx context.Context, id int64, body UpdateWebhookRequest) (*Webhook, error) {
+	path := fmt.Sprintf("/api/v2/webhooks/%d", id)
+	var out Webhook
+	if err := c.do(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWebhook calls DELETE /api/v2/webhooks/{id}: Delete a webhook and its delivery log.
+func (c *Client) DeleteWebhook(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/webhooks/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+type ListWebhookDeliveriesParams struct {
+	// pending, delivered or failed.
+	Status string
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListWebhookDeliveries calls GET /api/v2/webhooks/{id}/deliveries: List the deliveries of a webhook.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id int64, params ListWebhookDeliveriesParams) (*WebhookDeliveryList, error) {
+	path := fmt.Sprintf("/api/v2/webhooks/%d/deliveries", id)
+	q := url.Values{}
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out WebhookDeliveryList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListWorkspacesParams struct {
+	// Owner of the listed resources; defaults to, and must be, the authenticated user.
+	UserID *int64
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListWorkspaces calls GET /api/v2/workspaces: List a user's workspaces.
+func (c *Client) ListWorkspaces(ctx context.Context, params ListWorkspacesParams) (*WorkspaceList, error) {
+	path := "/api/v2/workspaces"
+	q := url.Values{}
+	if params.UserID != nil {
+		q.Set("user_id", strconv.FormatInt(*params.UserID, 10))
+	}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out WorkspaceList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateWorkspace calls POST /api/v2/workspaces: Create a workspace.
+func (c *Client) CreateWorkspace(ctx context.Context, body CreateWorkspaceRequest) (*Workspace, error) {
+	path := "/api/v2/workspaces"
+	var out Workspace
+	if err := c.do(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWorkspace calls GET /api/v2/workspaces/{id}: Get a workspace.
+func (c *Client) GetWorkspace(ctx context.Context, id int64) (*Workspace, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d", id)
+	var out Workspace
+	if err := c.do(ctx, "GET", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateWorkspace calls PATCH /api/v2/workspaces/{id}: Rename a workspace or change its OCR settings.
+func (c *Client) UpdateWorkspace(ctx context.Context, id int64, body UpdateWorkspaceRequest) (*Workspace, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d", id)
+	var out Workspace
+	if err := c.do(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWorkspace calls DELETE /api/v2/workspaces/{id}: Delete a workspace.
+func (c *Client) DeleteWorkspace(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v2/workspaces/%d", id)
+	return c.do(ctx, "DELETE", path, nil, nil, nil)
+}
+
+type GetCitationGraphParams struct {
+	// graphml returns the graph as GraphML XML.
+	Format string
+}
+
+// GetCitationGraph calls GET /api/v2/workspaces/{id}/citations: Export the citation graph of a workspace.
+func (c *Client) GetCitationGraph(ctx context.Context, id int64, params GetCitationGraphParams) (*CitationGraph, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d/citations", id)
+	q := url.Values{}
+	if params.Format != "" {
+		q.Set("format", params.Format)
+	}
+	var out CitationGraph
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddDocumentToWorkspace calls PUT /api/v2/workspaces/{id}/documents/{documentID}: Move a document into a workspace.
+func (c *Client) AddDocumentToWorkspace(ctx context.Context, id int64, documentID int64) (*MessageResponse, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d/documents/%d", id, documentID)
+	var out MessageResponse
+	if err := c.do(ctx, "PUT", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveDocumentFromWorkspace calls DELETE /api/v2/workspaces/{id}/documents/{documentID}: Take a document out of a workspace.
+func (c *Client) RemoveDocumentFromWorkspace(ctx context.Context, id int64, documentID int64) (*MessageResponse, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d/documents/%d", id, documentID)
+	var out MessageResponse
+	if err := c.do(ctx, "DELETE", path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListWorkspaceFeedsParams struct {
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListWorkspaceFeeds calls GET /api/v2/workspaces/{id}/feeds: List the feeds a workspace subscribes to.
+func (c *Client) ListWorkspaceFeeds(ctx context.Context, id int64, params ListWorkspaceFeedsParams) (*FeedList, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d/feeds", id)
+	q := url.Values{}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out FeedList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListWorkspaceInboxParams struct {
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListWorkspaceInbox calls GET /api/v2/workspaces/{id}/inbox: List the feed entries waiting to be accepted or dismissed.
+func (c *Client) ListWorkspaceInbox(ctx context.Context, id int64, params ListWorkspaceInboxParams) (*FeedItemList, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d/inbox", id)
+	q := url.Values{}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out FeedItemList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type ListWorkspaceNotesParams struct {
+	// Page size, at most 200.
+	Limit *int64
+	// The next_cursor of the previous page.
+	Cursor string
+	// Sort key; prefix with '-' for descending order.
+	Sort string
+	// Comma separated list of fields to return.
+	Fields string
+}
+
+// ListWorkspaceNotes calls GET /api/v2/workspaces/{id}/notes: List the notes in a workspace.
+func (c *Client) ListWorkspaceNotes(ctx context.Context, id int64, params ListWorkspaceNotesParams) (*NoteList, error) {
+	path := fmt.Sprintf("/api/v2/workspaces/%d/notes", id)
+	q := url.Values{}
+	if params.Limit != nil {
+		q.Set("limit", strconv.FormatInt(*params.Limit, 10))
+	}
+	if params.Cursor != "" {
+		q.Set("cursor", params.Cursor)
+	}
+	if params.Sort != "" {
+		q.Set("sort", params.Sort)
+	}
+	if params.Fields != "" {
+		q.Set("fields", params.Fields)
+	}
+	var out NoteList
+	if err := c.do(ctx, "GET", path, q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
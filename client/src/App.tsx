@@ -4,6 +4,7 @@ import RegisterPage from './pages/RegisterPage';
 import LoginPage from './pages/LoginPage';
 import HomePage from './pages/UserHome';
 import ProfilePage from './pages/Profile';
+import ResetPasswordPage from './pages/ResetPasswordPage';
 
 
 export default function App() {
@@ -14,6 +15,7 @@ export default function App() {
       <Route path="/register" element={<RegisterPage />} />
       <Route path="/home" element={<HomePage />} />
       <Route path="/profile" element={<ProfilePage />} />
+      <Route path="/reset-password" element={<ResetPasswordPage />} />
     </Routes>
   )
 }
\ No newline at end of file
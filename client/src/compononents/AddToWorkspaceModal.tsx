@@ -38,7 +38,10 @@ export default function AddToWorkspaceModal({ documents, workspaces, onClose, on
             for (const docId of selectedDocs) {
                 const res = await fetch('http://localhost:8080/workspace/add-document', {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
+                    headers: {
+                        'Content-Type': 'application/json',
+                        'Authorization': `Bearer ${localStorage.getItem('token') ?? ''}`,
+                    },
                     body: JSON.stringify({ document_id: docId, workspace_id: selectedWorkspace }),
                 });
 
@@ -0,0 +1,110 @@
+import { useState } from 'react';
+
+
+type Props = {
+    onClose:    () => void;
+    onSuccess:  () => void;
+};
+
+
+export default function ChangePasswordModal({ onClose, onSuccess }: Props) {
+    const [form, setForm] = useState({current_password: '', new_password: '', confirm: ''});
+    const [error, setError] = useState<string | null>(null);
+    const [loading, setLoading] = useState(false);
+
+    const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+        setForm({ ...form, [e.target.name]: e.target.value });
+    };
+
+    const handleSubmit = async () => {
+        if (form.new_password !== form.confirm) {
+            setError('The new passwords do not match');
+            return;
+        }
+
+        setLoading(true);
+        setError(null);
+
+        try {
+            const res = await fetch('http://localhost:8080/api/v2/auth/password', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${localStorage.getItem('token') ?? ''}`,
+                },
+                body: JSON.stringify({current_password: form.current_password, new_password: form.new_password}),
+            });
+
+            const data = await res.json().catch(() => null);
+            if (!res.ok) {
+                const field = data?.details?.[0];
+                throw new Error(field ? `${field.field.replace('_', ' ')} ${field.message}` : data?.message || 'Failed to change password');
+            }
+
+            // Every session was revoked; this one continues with the new token.
+            localStorage.setItem('token', data.token);
+            onSuccess();
+            onClose();
+        } catch (err: any) {
+            setError(err.message || 'Something went wrong');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    return (
+        <div className="fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center z-50">
+            <div className="bg-white p-6 rounded shadow w-full max-w-md space-y-4">
+                <h2 className="text-xl font-bold text-gray-700">Change Password</h2>
+
+                <input
+                    type="password"
+                    name="current_password"
+                    placeholder="Current password"
+                    value={form.current_password}
+                    onChange={handleChange}
+                    className="w-full border px-3 py-2 rounded"
+                    disabled={loading}
+                />
+                <input
+                    type="password"
+                    name="new_password"
+                    placeholder="New password"
+                    value={form.new_password}
+                    onChange={handleChange}
+                    className="w-full border px-3 py-2 rounded"
+                    disabled={loading}
+                />
+                <input
+                    type="password"
+                    name="confirm"
+                    placeholder="Repeat new password"
+                    value={form.confirm}
+                    onChange={handleChange}
+                    className="w-full border px-3 py-2 rounded"
+                    disabled={loading}
+                />
+                <p className="text-gray-500 text-xs">
+                    At least 8 characters with a letter and a digit. Other sessions will be logged out.
+                </p>
+
+                {error && <p className="text-red-600 text-sm">{error}</p>}
+
+                <div className="flex justify-end space-x-3">
+                    <button
+                        onClick={onClose}
+                        className="px-4 py-2 border rounded hover:bg-gray-100"
+                        disabled={loading}
+                    >Cancel</button>
+                    <button
+                        onClick={handleSubmit}
+                        className="px-4 py-2 bg-blue-600 text-white rounded hover:bg-blue-700"
+                        disabled={loading}
+                    >
+                        {loading ? 'Saving...' : 'Change Password'}
+                    </button>
+                </div>
+            </div>
+        </div>
+    );
+}
\ No newline at end of file
@@ -25,13 +25,16 @@ export default function CreateWorkspaceModal({ userId, onClose, onSuccess}: Prop
         try {
             const res = await fetch('http://localhost:8080/workspace/create', {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: {
+                    'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${localStorage.getItem('token') ?? ''}`,
+                },
                 body: JSON.stringify({user_id: userId, title}),
             });
 
             if (!res.ok) {
-                const msg = await res.text();
-                throw new Error(msg || 'Failed to create workspace')
+                const data = await res.json().catch(() => null);
+                throw new Error(data?.message || 'Failed to create workspace')
             }
 
             onSuccess();
@@ -46,12 +46,13 @@ export default function UploadModal({onClose, onSuccess, userId, workspaceId}: P
         try {
             const res = await fetch('http://localhost:8080/documents/upload', {
                 method: 'POST',
+                headers: { 'Authorization': `Bearer ${localStorage.getItem('token') ?? ''}` },
                 body: formData,
             });
 
             if (!res.ok) {
-                const text = await res.text();
-                throw new Error(text || 'Upload failed');
+                const data = await res.json().catch(() => null);
+                throw new Error(data?.message || 'Upload failed');
             }
 
             onSuccess();
@@ -1,3 +1,5 @@
+import { useEffect, useState } from 'react';
+
 type Props = {
   pdfUrl: string;
   title: string;
@@ -5,16 +7,44 @@ type Props = {
 };
 
 export default function ViewDocumentModal({ pdfUrl, title, onClose }: Props) {
+  const [src, setSrc] = useState<string | null>(null);
+  const [error, setError] = useState('');
+
+  // The file needs the bearer token, which an iframe can't send, so it is
+  // fetched here and shown from a blob URL.
+  useEffect(() => {
+    let url: string | null = null;
+    let cancelled = false;
+    fetch(pdfUrl, { headers: { 'Authorization': `Bearer ${localStorage.getItem('token') ?? ''}` } })
+      .then(async (res) => {
+        if (!res.ok) throw new Error(`Failed to load document (${res.status})`);
+        const blob = await res.blob();
+        if (cancelled) return;
+        url = URL.createObjectURL(blob);
+        setSrc(url);
+      })
+      .catch((err) => {
+        if (!cancelled) setError(err.message);
+      });
+    return () => {
+      cancelled = true;
+      if (url) URL.revokeObjectURL(url);
+    };
+  }, [pdfUrl]);
+
   return (
     <div className="fixed inset-0 bg-black bg-opacity-50 z-50 flex items-center justify-center">
       <div className="bg-white rounded shadow-lg p-4 max-w-4xl w-full h-[90vh] relative">
         <button onClick={onClose} className="absolute top-2 right-2 text-gray-600 text-lg">✕</button>
         <h2 className="text-lg font-bold mb-3">{title}</h2>
-        <iframe
-          src={pdfUrl}
-          title="PDF Viewer"
-          className="w-full h-full border"
-        />
+        {error && <p className="text-red-600">{error}</p>}
+        {src && (
+          <iframe
+            src={src}
+            title="PDF Viewer"
+            className="w-full h-full border"
+          />
+        )}
       </div>
     </div>
   );
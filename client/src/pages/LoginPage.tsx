@@ -1,5 +1,5 @@
 import { useState } from 'react';
-import { useNavigate } from 'react-router-dom';
+import { Link, useNavigate } from 'react-router-dom';
 
 
 export default function LoginPage() {
@@ -24,11 +24,13 @@ export default function LoginPage() {
                 body: JSON.stringify(form),
             });
 
+            const data = await res.json();
             if (!res.ok) {
-                const data = await res.json();
-                throw new Error(data.error || 'Login failed');
+                throw new Error(data.message || 'Login failed');
             }
 
+            localStorage.setItem('token', data.token);
+            localStorage.setItem('user_id', String(data.user_id));
             navigate('/home');
         } catch (err: any) {
             setError(err.message || 'Something went wrong');
@@ -72,6 +74,10 @@ export default function LoginPage() {
                 >
                     {loading ? 'Logging in...' : 'Login'}
                 </button>
+
+                <p className="text-sm text-center">
+                    <Link to="/reset-password" className="text-blue-600 hover:underline">Forgot password?</Link>
+                </p>
             </form>
         </div>
     );
@@ -1,9 +1,12 @@
 import { useState } from 'react';
 import { Link } from 'react-router-dom';
+import ChangePasswordModal from '../compononents/ChangePasswordModal';
 
 
 export default function ProfilePage() {
     const [activeTab, setActiveTab] = useState<'account' | 'api' | 'none'>('account');
+    const [showChangePassword, setShowChangePassword] = useState(false);
+    const [message, setMessage] = useState<string | null>(null);
 
     const renderContent = () => {
         switch (activeTab) {
@@ -15,7 +18,11 @@ export default function ProfilePage() {
                             <p className="text-gray-700">Username: <span className="font-medium">[username]</span></p>
                             <p className="text-gray-700">Email: <span className="font-medium">[email@example.com]</span></p>
                         </div>
-                        <button className="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700 text-sm">
+                        {message && <p className="text-green-700 text-sm">{message}</p>}
+                        <button
+                            onClick={() => setShowChangePassword(true)}
+                            className="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700 text-sm"
+                        >
                             Change Password
                         </button>
                     </div>
@@ -89,6 +96,13 @@ export default function ProfilePage() {
                     {renderContent()}
                 </main>
             </div>
+
+            {showChangePassword && (
+                <ChangePasswordModal
+                    onClose={() => setShowChangePassword(false)}
+                    onSuccess={() => setMessage('Password changed. Your other sessions have been logged out.')}
+                />
+            )}
         </div>
     );
 }
\ No newline at end of file
@@ -26,7 +26,7 @@ export default function RegisterPage() {
 
             if (!res.ok) {
                 const data = await res.json();
-                throw new Error (data.error || 'Registration failed');
+                throw new Error (data.message || 'Registration failed');
             }
 
             navigate('/login');
@@ -0,0 +1,110 @@
+import { useState } from 'react';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
+
+
+// ResetPasswordPage asks for the email of an account to mail a reset link to, or,
+// opened from that link, for the new password.
+export default function ResetPasswordPage() {
+    const navigate = useNavigate();
+    const [params] = useSearchParams();
+    const token = params.get('token');
+    const [form, setForm] = useState({email: '', new_password: '', confirm: ''});
+    const [loading, setLoading] = useState(false);
+    const [error, setError] = useState<string | null>(null);
+    const [message, setMessage] = useState<string | null>(null);
+
+    const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+        setForm({ ...form, [e.target.name]: e.target.value });
+    };
+
+    const handleSubmit = async (e: React.FormEvent) => {
+        e.preventDefault();
+        if (token && form.new_password !== form.confirm) {
+            setError('The passwords do not match');
+            return;
+        }
+
+        setLoading(true);
+        setError(null);
+
+        try {
+            const res = await fetch(`http://localhost:8080/api/v2/auth/password/${token ? 'reset' : 'forgot'}`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(token ? {token, new_password: form.new_password} : {email: form.email}),
+            });
+
+            const data = await res.json().catch(() => null);
+            if (!res.ok) {
+                const field = data?.details?.[0];
+                throw new Error(field ? `Password ${field.message}` : data?.message || 'Failed to reset password');
+            }
+
+            if (token) {
+                navigate('/login');
+            } else {
+                setMessage(data.message);
+            }
+        } catch (err: any) {
+            setError(err.message || 'Something went wrong');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-gray-100">
+            <form
+                onSubmit={handleSubmit}
+                className="bg-white p-8 rounded shadow-md w-full max-w-md space-y-4"
+            >
+                <h1 className="text-2xl font-bold text-center text-blue-600">Reset Password</h1>
+
+                {error && <p className="text-red-600 text-sm text-center">{error}</p>}
+                {message && <p className="text-green-700 text-sm text-center">{message}</p>}
+
+                {token ? (
+                    <>
+                        <input
+                            type="password"
+                            name="new_password"
+                            placeholder="New password"
+                            onChange={handleChange}
+                            required
+                            className="w-full border px-4 py-2 rounded"
+                        />
+                        <input
+                            type="password"
+                            name="confirm"
+                            placeholder="Repeat new password"
+                            onChange={handleChange}
+                            required
+                            className="w-full border px-4 py-2 rounded"
+                        />
+                    </>
+                ) : (
+                    <input
+                        type="email"
+                        name="email"
+                        placeholder="Email"
+                        onChange={handleChange}
+                        required
+                        className="w-full border px-4 py-2 rounded"
+                    />
+                )}
+
+                <button
+                    type="submit"
+                    disabled={loading || message !== null}
+                    className="w-full bg-blue-600 text-white py-2 rounded hover:bg-blue-700"
+                >
+                    {loading ? 'Sending...' : token ? 'Set Password' : 'Send Reset Link'}
+                </button>
+
+                <p className="text-sm text-center">
+                    <Link to="/login" className="text-blue-600 hover:underline">Back to login</Link>
+                </p>
+            </form>
+        </div>
+    );
+}
\ No newline at end of file
@@ -32,14 +32,15 @@ export default function HomePage() {
   const [loadingDocs, setLoadingDocs] = useState(true);
   const [loadingWorkspaces, setLoadingWorkspaces] = useState(true);
 
-  const userId = 1; // TEMP — this should eventually come from session/auth context
+  const userId = Number(localStorage.getItem('user_id'));
+  const authHeader = { 'Authorization': `Bearer ${localStorage.getItem('token') ?? ''}` };
 
   const fetchDocuments = async () => {
     setLoadingDocs(true);
     try {
-      const res = await fetch(`http://localhost:8080/documents/get?user_id=${userId}`);
+      const res = await fetch(`http://localhost:8080/documents/get?user_id=${userId}&limit=200`, { headers: authHeader });
       const data = await res.json();
-      setDocuments(data);
+      setDocuments(data.items ?? []);
     } catch (err) {
       console.error('Error fetching documents:', err);
     } finally {
@@ -50,9 +51,9 @@ export default function HomePage() {
   const fetchWorkspaces = async () => {
     setLoadingWorkspaces(true);
     try {
-      const res = await fetch(`http://localhost:8080/workspace/get?user_id=${userId}`);
+      const res = await fetch(`http://localhost:8080/workspace/get?user_id=${userId}&limit=200`, { headers: authHeader });
       const data = await res.json();
-      setWorkspaces(data);
+      setWorkspaces(data.items ?? []);
     } catch (err) {
       console.error('Error fetching workspaces:', err);
     } finally {
@@ -67,13 +68,13 @@ export default function HomePage() {
     try {
       const res = await fetch('http://localhost:8080/workspace/delete', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', ...authHeader },
         body: JSON.stringify({ id }),
       });
 
       if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg || 'Failed to delete workspace');
+        const data = await res.json().catch(() => null);
+        throw new Error(data?.message || 'Failed to delete workspace');
       }
 
       // Refresh workspace list